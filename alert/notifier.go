@@ -0,0 +1,107 @@
+// Package alert tells users about their favourites: new books of the
+// authors they follow and wishlisted books they can borrow again.
+package alert
+
+import (
+	mailer "awesome-api/mail"
+	"awesome-api/store"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Notifier queues the alerts of every change since its last run and mails
+// those that are due, instantly or in a weekly digest depending on what
+// each user chose for the alert type.
+type Notifier struct {
+	log         zerolog.Logger
+	txManager   store.TxManager
+	alertStore  store.AlertStore
+	mailer      mailer.EmailSender
+	digestAfter time.Duration
+}
+
+func NewNotifier(
+	log zerolog.Logger,
+	txManager store.TxManager,
+	alertStore store.AlertStore,
+	mailer mailer.EmailSender,
+	digestAfter time.Duration,
+) *Notifier {
+	return &Notifier{
+		log:         log,
+		txManager:   txManager,
+		alertStore:  alertStore,
+		mailer:      mailer,
+		digestAfter: digestAfter,
+	}
+}
+
+// Run queues a mail for every due alert. Alerts whose mail could not be
+// queued are logged and stay pending, so the next run tries them again.
+func (n *Notifier) Run(ctx context.Context) error {
+	count, err := n.alertStore.QueueAlerts(ctx)
+	if err != nil {
+		return fmt.Errorf("alertStore.QueueAlerts: %w", err)
+	}
+	if count > 0 {
+		n.log.Info().Int64("count", count).Msg("queued alerts")
+	}
+	alerts, err := n.alertStore.FindDue(ctx, n.digestAfter)
+	if err != nil {
+		return fmt.Errorf("alertStore.FindDue: %w", err)
+	}
+	// Alerts come ordered by user, every user gets at most one instant mail
+	// and one digest per run.
+	for start := 0; start < len(alerts); {
+		end := start
+		for end < len(alerts) && alerts[end].UserID == alerts[start].UserID {
+			end++
+		}
+		var instant, weekly []*store.Alert
+		for _, alert := range alerts[start:end] {
+			if alert.Frequency == store.AlertFrequencyWeekly {
+				weekly = append(weekly, alert)
+			} else {
+				instant = append(instant, alert)
+			}
+		}
+		n.send(ctx, instant, false)
+		n.send(ctx, weekly, true)
+		start = end
+	}
+	return nil
+}
+
+func (n *Notifier) send(ctx context.Context, alerts []*store.Alert, digest bool) {
+	if len(alerts) == 0 {
+		return
+	}
+	userId := alerts[0].UserID
+	to := mailer.Recipient{
+		ID:     alerts[0].UserID,
+		Email:  alerts[0].Email,
+		Name:   alerts[0].Fullname,
+		Locale: alerts[0].Locale,
+	}
+	ids := make([]int, 0, len(alerts))
+	for _, alert := range alerts {
+		ids = append(ids, alert.ID)
+	}
+	// The mail is queued only with the alerts marked sent, so a failure
+	// neither loses them nor mails them twice.
+	err := n.txManager.WithTx(ctx, func(ctx context.Context) error {
+		if err := n.mailer.SendAlerts(ctx, to, alerts, digest); err != nil {
+			return fmt.Errorf("mailer.SendAlerts: %w", err)
+		}
+		if err := n.alertStore.MarkSent(ctx, ids); err != nil {
+			return fmt.Errorf("alertStore.MarkSent: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		n.log.Error().Err(err).Int("user_id", userId).Msg("failed to queue alerts")
+	}
+}
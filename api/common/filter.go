@@ -0,0 +1,64 @@
+package common
+
+import (
+	apierror "awesome-api/api/error"
+	"awesome-api/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const filterDateForm = "2006-01-02"
+
+// ParseBookFilter reads the catalogue filters shared by the book listing
+// and the exports, so an export holds exactly what the listing shows.
+func ParseBookFilter(r *http.Request) (store.BookFilter, *apierror.UnprocessableEntity) {
+	query := r.URL.Query()
+	filter := store.BookFilter{
+		Query:    strings.TrimSpace(query.Get("q")),
+		Language: strings.TrimSpace(query.Get("language")),
+	}
+	invalid := func(name, message string) (store.BookFilter, *apierror.UnprocessableEntity) {
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    name,
+			Message: message,
+		})
+		return filter, &fieldErr
+	}
+	for _, param := range []struct {
+		name string
+		dest *int
+	}{
+		{"category", &filter.CategoryID},
+		{"author", &filter.AuthorID},
+	} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil || v <= 0 {
+			return invalid(param.name, param.name+" must be a positive number")
+		}
+		*param.dest = v
+	}
+	for _, param := range []struct {
+		name string
+		dest *time.Time
+	}{
+		{"published_after", &filter.PublishedAfter},
+		{"published_before", &filter.PublishedBefore},
+	} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		v, err := time.Parse(filterDateForm, value)
+		if err != nil {
+			return invalid(param.name, param.name+" must be a date formatted as YYYY-MM-DD")
+		}
+		*param.dest = v
+	}
+	return filter, nil
+}
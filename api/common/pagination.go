@@ -0,0 +1,45 @@
+package common
+
+import (
+	apierror "awesome-api/api/error"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type Pagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+func ParsePagination(r *http.Request) (Pagination, *apierror.UnprocessableEntity) {
+	p := Pagination{Limit: DefaultLimit}
+	query := r.URL.Query()
+	if limit := query.Get("limit"); limit != "" {
+		v, err := strconv.Atoi(limit)
+		if err != nil || v < 1 || v > MaxLimit {
+			fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "limit",
+				Message: "limit must be a number between 1 and " + strconv.Itoa(MaxLimit),
+			})
+			return p, &fieldErr
+		}
+		p.Limit = v
+	}
+	if offset := query.Get("offset"); offset != "" {
+		v, err := strconv.Atoi(offset)
+		if err != nil || v < 0 {
+			fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "offset",
+				Message: "offset must be a positive number",
+			})
+			return p, &fieldErr
+		}
+		p.Offset = v
+	}
+	return p, nil
+}
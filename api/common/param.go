@@ -0,0 +1,21 @@
+package common
+
+import (
+	apierror "awesome-api/api/error"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func IdParam(r *http.Request, name string) (int, *apierror.UnprocessableEntity) {
+	id, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil || id <= 0 {
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    name,
+			Message: name + " must be a positive number",
+		})
+		return 0, &fieldErr
+	}
+	return id, nil
+}
@@ -95,3 +95,129 @@ func ServerError() Error {
 		Message:    "server has internal error",
 	}
 }
+
+func ClientPermissionDenied() Error {
+	return Error{
+		HttpStatus: http.StatusForbidden,
+		Message:    "you don't have permission to access this resource",
+	}
+}
+
+func ClientPayloadTooLarge() Error {
+	return Error{
+		HttpStatus: http.StatusRequestEntityTooLarge,
+		Message:    "request payload is too large",
+	}
+}
+
+func ClientUnsupportedMediaType() Error {
+	return Error{
+		HttpStatus: http.StatusUnsupportedMediaType,
+		Message:    "request media type is not supported",
+	}
+}
+
+func ClientShelfAlreadyExists() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "shelf is already exists",
+	}
+}
+
+func ClientNoCopyAvailable() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "no copy of the book is available",
+	}
+}
+
+func ClientLoanLimitReached() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "you have reached the maximum number of loans",
+	}
+}
+
+func ClientAlreadyBorrowed() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "you already borrowed this book",
+	}
+}
+
+func ClientLoanNotActive() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "loan is already returned or expired",
+	}
+}
+
+func ClientRenewalExhausted() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "loan cannot be renewed anymore",
+	}
+}
+
+func ClientHoldAlreadyPlaced() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "you already placed a hold on this book",
+	}
+}
+
+func ClientHoldCopyAvailable() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "a copy of the book is available, borrow it instead",
+	}
+}
+
+func ClientHoldNotOpen() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "hold is already fulfilled, cancelled or expired",
+	}
+}
+
+func ClientHoldsWaiting() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "other patrons are waiting for this book",
+	}
+}
+
+func ClientReviewAlreadyWritten() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "you already reviewed this book",
+	}
+}
+
+func ClientOwnReview() Error {
+	return Error{
+		HttpStatus: http.StatusForbidden,
+		Message:    "you cannot flag or vote on your own review",
+	}
+}
+
+func ClientAuthorAlreadyExists() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "author is already exists",
+	}
+}
+
+func ClientISBNAlreadyExists() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "isbn is already exists",
+	}
+}
+
+func ClientEmailNotDead() Error {
+	return Error{
+		HttpStatus: http.StatusConflict,
+		Message:    "only dead emails can be requeued",
+	}
+}
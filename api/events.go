@@ -0,0 +1,63 @@
+package api
+
+import (
+	mailer "awesome-api/mail"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// subscribeEvents hands the domain events to the side effects of the
+// changes they record.
+func (s *Server) subscribeEvents() {
+	s.eventDispatcher.Subscribe("email", s.sendActivation, store.EventUserRegistered)
+	s.eventDispatcher.Subscribe("hold_notification", s.holdQueue.NotifyReady, store.EventHoldReady)
+	s.eventDispatcher.Subscribe("hold_email", s.holdQueue.MailReady, store.EventHoldReady)
+	s.eventDispatcher.Subscribe("webhooks", s.publisher.Publish, store.WebhookEvents...)
+	s.eventDispatcher.Subscribe("search_index", s.indexBooks,
+		store.EventBookCreated, store.EventBookUpdated, store.EventAuthorUpdated,
+	)
+	s.eventDispatcher.Subscribe("audit", s.stores.auditStore.Record)
+}
+
+// sendActivation mails the activation link to a new user not verified in
+// the meantime. A redelivered event mails the link again.
+func (s *Server) sendActivation(ctx context.Context, event *store.DomainEvent) error {
+	user, err := s.stores.userStore.FindOneById(ctx, event.AggregateID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("userStore.FindOneById: %w", err)
+	}
+	if user.IsVerified || !user.TokenVerification.Valid {
+		return nil
+	}
+	to := mailer.Recipient{
+		ID:     user.ID,
+		Email:  user.Email,
+		Name:   user.Fullname,
+		Locale: user.Locale,
+	}
+	if err := s.mailer.SendActivationLink(ctx, to, user.ID, user.TokenVerification.String); err != nil {
+		return fmt.Errorf("mailer.SendActivationLink: %w", err)
+	}
+	return nil
+}
+
+// indexBooks reindexes the book of a book event, or the books of the
+// author of an author event.
+func (s *Server) indexBooks(ctx context.Context, event *store.DomainEvent) error {
+	if event.Type == store.EventAuthorUpdated {
+		if err := s.stores.bookStore.ReindexByAuthorId(ctx, event.AggregateID); err != nil {
+			return fmt.Errorf("bookStore.ReindexByAuthorId: %w", err)
+		}
+		return nil
+	}
+	if err := s.stores.bookStore.Reindex(ctx, event.AggregateID); err != nil {
+		return fmt.Errorf("bookStore.Reindex: %w", err)
+	}
+	return nil
+}
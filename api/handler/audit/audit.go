@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type EntryResponse struct {
+	ID          int64           `json:"id"`
+	EventID     int64           `json:"event_id"`
+	Event       string          `json:"event"`
+	AggregateID int             `json:"aggregate_id"`
+	ActorID     *int64          `json:"actor_id"`
+	Payload     json.RawMessage `json:"payload"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+func newEntryResponse(entry *store.AuditEntry) EntryResponse {
+	res := EntryResponse{
+		ID:          entry.ID,
+		EventID:     entry.EventID,
+		Event:       entry.EventType,
+		AggregateID: entry.AggregateID,
+		Payload:     entry.Payload,
+		OccurredAt:  entry.OccurredAt,
+	}
+	if entry.ActorID.Valid {
+		res.ActorID = &entry.ActorID.Int64
+	}
+	return res
+}
+
+// List pages through the audit log, newest first, narrowed by the event
+// and actor_id query parameters.
+func List(
+	zlog zerolog.Logger,
+	auditStore store.AuditStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		query := r.URL.Query()
+		filter := store.AuditFilter{
+			EventType: query.Get("event"),
+		}
+		if actorId := query.Get("actor_id"); actorId != "" {
+			id, err := strconv.Atoi(actorId)
+			if err != nil || id <= 0 {
+				response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+					Name:    "actor_id",
+					Message: "actor_id must be a positive number",
+				}))
+				return
+			}
+			filter.ActorID = id
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		entries, err := auditStore.FindAll(ctx, filter, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("auditStore.FindAll: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find audit entries")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]EntryResponse, 0, len(entries))
+		for _, entry := range entries {
+			res = append(res, newEntryResponse(entry))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
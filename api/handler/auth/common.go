@@ -88,6 +88,25 @@ func ValidateName(name string) error {
 	return nil
 }
 
+// ValidateLocale accepts language tags such as en or pt-BR, the mails fall
+// back to English for languages without templates.
+func ValidateLocale(locale string) error {
+	if len(locale) > 16 {
+		return fmt.Errorf("locale cannot exceed 16 characters")
+	}
+	for i, part := range strings.Split(locale, "-") {
+		if len(part) < 2 || len(part) > 8 {
+			return fmt.Errorf("locale must be a language tag such as en or pt-BR")
+		}
+		for _, v := range part {
+			if v > unicode.MaxASCII || !(unicode.IsLetter(v) || (i > 0 && unicode.IsDigit(v))) {
+				return fmt.Errorf("locale must be a language tag such as en or pt-BR")
+			}
+		}
+	}
+	return nil
+}
+
 func RandString(n int) (string, error) {
 	const letter = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-"
 	generatedString := make([]byte, n)
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+type LocaleRequest struct {
+	Locale string `json:"locale"`
+}
+
+type LocaleResponse struct {
+	Locale string `json:"locale"`
+}
+
+func (lr *LocaleRequest) validateRequest() *apierror.UnprocessableEntity {
+	if err := ValidateLocale(lr.Locale); err != nil {
+		field := apierror.InvalidField{
+			Name:    "locale",
+			Message: err.Error(),
+		}
+		fieldErr := apierror.ClientInvalidField(field)
+		return &fieldErr
+	}
+	return nil
+}
+
+// UpdateLocale sets the language the mails of the user are written in.
+func UpdateLocale(
+	zlog zerolog.Logger,
+	userStore store.UserStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := LocaleRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := userStore.UpdateLocaleById(ctx, req.Locale, middleware.UserID(ctx)); err != nil {
+			err = fmt.Errorf("userStore.UpdateLocaleById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to update locale by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, LocaleResponse{Locale: req.Locale})
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+type RoleResponse struct {
+	ID   int    `json:"id"`
+	Role string `json:"role"`
+}
+
+func (rr *RoleRequest) validateRequest() *apierror.UnprocessableEntity {
+	switch rr.Role {
+	case store.RolePatron, store.RoleLibrarian, store.RoleAdmin:
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    "role",
+		Message: fmt.Sprintf("role must be one of %s, %s or %s", store.RolePatron, store.RoleLibrarian, store.RoleAdmin),
+	})
+	return &fieldErr
+}
+
+// UpdateRole sets the role of a user. Admins cannot change their own, so
+// there is always one left.
+func UpdateRole(
+	zlog zerolog.Logger,
+	userStore store.UserStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := RoleRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		if userId == middleware.UserID(ctx) {
+			response.Error(w, apierror.ClientPermissionDenied())
+			return
+		}
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := userStore.UpdateRoleById(ctx, req.Role, userId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("userStore.UpdateRoleById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to update role by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, RoleResponse{ID: userId, Role: req.Role})
+	}
+}
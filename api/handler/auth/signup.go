@@ -7,7 +7,9 @@ import (
 	mailer "awesome-api/mail"
 	"awesome-api/store"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -21,6 +23,7 @@ type SignUpRequest struct {
 	Fullname          string `json:"fullname"`
 	Email             string `json:"email"`
 	Password          string `json:"password"`
+	Locale            string `json:"locale"`
 	IsVerified        bool   `json:"is_verified"`
 	TokenVerification string `json:"token_verification"`
 	TokenExpiration   string `json:"token_expiration"`
@@ -54,14 +57,25 @@ func (sr *SignUpRequest) validateRequest() *apierror.UnprocessableEntity {
 		fieldErr := apierror.ClientInvalidField(field)
 		return &fieldErr
 	}
+	if sr.Locale == "" {
+		sr.Locale = mailer.DefaultLocale
+	}
+	if err = ValidateLocale(sr.Locale); err != nil {
+		field := apierror.InvalidField{
+			Name:    "locale",
+			Message: err.Error(),
+		}
+		fieldErr := apierror.ClientInvalidField(field)
+		return &fieldErr
+	}
 	return nil
 }
 
 func Signup(
 	zlog zerolog.Logger,
+	txManager store.TxManager,
 	userStore store.UserStore,
 	tokenExpiration time.Duration,
-	mailer mailer.EmailSender,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		req := SignUpRequest{}
@@ -71,14 +85,10 @@ func Signup(
 		}
 		if fieldErr := req.validateRequest(); fieldErr != nil {
 			response.ValidationError(w, *fieldErr)
+			return
 		}
 		ctx := r.Context()
 		wlog := common.WrapperZlog{Logger: &zlog}
-		_, err := userStore.FindOneByEmail(ctx, req.Email)
-		if err == nil {
-			response.Error(w, apierror.ClientAlreadyExists())
-			return
-		}
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
 			wlog.Error(ctx).
@@ -96,22 +106,22 @@ func Signup(
 			return
 		}
 		req.TokenExpiration = strconv.Itoa(int(time.Now().Add(tokenExpiration * time.Minute).Unix()))
-		err = registerNewUser(ctx, userStore, req)
+		// A concurrent signup with the same email either fails this one's
+		// transaction, retried to find the user, or breaks the unique email,
+		// both end as ErrAlreadyExists.
+		err = txManager.WithTx(ctx, func(ctx context.Context) error {
+			return registerNewUser(ctx, userStore, req)
+		})
 		if err != nil {
+			if errors.Is(err, store.ErrAlreadyExists) {
+				response.Error(w, apierror.ClientAlreadyExists())
+				return
+			}
 			wlog.Error(ctx).
 				Err(err).Msg("failed to insert new user")
 			response.Error(w, apierror.ServerError())
 			return
 		}
-		user, err := userStore.FindOneByEmail(ctx, req.Email)
-		if err != nil {
-			err = fmt.Errorf("userStore.FindOneByEmail: %w", err)
-			wlog.Error(ctx).
-				Err(err).Msg("failed to find one by email")
-			response.Error(w, apierror.ServerError())
-			return
-		}
-		go mailer.SendActivationLink(user.ID, req.Email, user.TokenVerification.String)
 		res := AuthResponse{
 			Message: "email activation has been sent, please check your email",
 		}
@@ -124,16 +134,25 @@ func registerNewUser(
 	userStore store.UserStore,
 	user SignUpRequest,
 ) error {
+	_, err := userStore.FindOneByEmail(ctx, user.Email)
+	if err == nil {
+		return store.ErrAlreadyExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("userStore.FindOneByEmail: %w", err)
+	}
 	usr := &store.UserRegister{
 		Email:             user.Email,
 		Password:          user.Password,
 		Fullname:          user.Fullname,
 		IsVerified:        user.IsVerified,
+		Locale:            user.Locale,
 		TokenVerification: user.TokenVerification,
 		TokenExpiration:   user.TokenExpiration,
 	}
-	err := userStore.Insert(ctx, usr)
-	if err != nil {
+	// The activation mail goes out from the user.registered event recorded
+	// with the user, never for a signup that failed.
+	if err := userStore.Insert(ctx, usr); err != nil {
 		return fmt.Errorf("userStore.Insert: %w", err)
 	}
 	return nil
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog"
+)
+
+// Verify activates the account of the activation link, GET so the link
+// works as it is clicked in the mail.
+func Verify(
+	zlog zerolog.Logger,
+	userStore store.UserStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		userId, err := strconv.Atoi(query.Get("id"))
+		if err != nil || userId <= 0 {
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "id",
+				Message: "id must be a positive number",
+			}))
+			return
+		}
+		token := query.Get("token")
+		if err := ValidateToken(token); err != nil {
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "token",
+				Message: err.Error(),
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, err := userStore.Verify(ctx, userId, token); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientInvalidToken())
+				return
+			}
+			err = fmt.Errorf("userStore.Verify: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to verify user")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := AuthResponse{
+			Message: "account has been verified",
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,242 @@
+package author
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+type AuthorRequest struct {
+	Name string `json:"name"`
+	Bio  string `json:"bio"`
+}
+
+type AuthorResponse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio"`
+	BookCount int       `json:"book_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AuthoredBookResponse struct {
+	BookID   int    `json:"book_id"`
+	Title    string `json:"title"`
+	Role     string `json:"role"`
+	Language string `json:"language"`
+}
+
+const (
+	nameMaxLength = 128
+	bioMaxLength  = 5000
+)
+
+func (ar *AuthorRequest) validateRequest() *apierror.UnprocessableEntity {
+	ar.Name = strings.TrimSpace(ar.Name)
+	ar.Bio = strings.TrimSpace(ar.Bio)
+	var field, message string
+	switch {
+	case ar.Name == "":
+		field, message = "name", "name cannot be empty"
+	case utf8.RuneCountInString(ar.Name) > nameMaxLength:
+		field, message = "name", fmt.Sprintf("name cannot exceed %d characters", nameMaxLength)
+	case utf8.RuneCountInString(ar.Bio) > bioMaxLength:
+		field, message = "bio", fmt.Sprintf("bio cannot exceed %d characters", bioMaxLength)
+	default:
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    field,
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func newAuthorResponse(author *store.Author) AuthorResponse {
+	return AuthorResponse{
+		ID:        author.ID,
+		Name:      author.Name,
+		Bio:       author.Bio,
+		BookCount: author.BookCount,
+		CreatedAt: author.CreatedAt,
+		UpdatedAt: author.UpdatedAt,
+	}
+}
+
+func authorError(ctx context.Context, wlog common.WrapperZlog, err error, msg string) apierror.Error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return apierror.ClientNotFound()
+	case errors.Is(err, store.ErrAuthorAlreadyExists):
+		return apierror.ClientAuthorAlreadyExists()
+	}
+	wlog.Error(ctx).
+		Err(err).Msg(msg)
+	return apierror.ServerError()
+}
+
+func List(
+	zlog zerolog.Logger,
+	authorStore store.AuthorStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		authors, err := authorStore.Search(ctx, query, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("authorStore.Search: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to search authors")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]AuthorResponse, 0, len(authors))
+		for _, author := range authors {
+			res = append(res, newAuthorResponse(author))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func Get(
+	zlog zerolog.Logger,
+	authorStore store.AuthorStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		author, err := authorStore.FindOneById(ctx, authorId)
+		if err != nil {
+			err = fmt.Errorf("authorStore.FindOneById: %w", err)
+			response.Error(w, authorError(ctx, wlog, err, "failed to find one by id"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newAuthorResponse(author))
+	}
+}
+
+// ListBooks returns the bibliography of the author, one entry per role the
+// author had on a book.
+func ListBooks(
+	zlog zerolog.Logger,
+	authorStore store.AuthorStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, err := authorStore.FindOneById(ctx, authorId); err != nil {
+			err = fmt.Errorf("authorStore.FindOneById: %w", err)
+			response.Error(w, authorError(ctx, wlog, err, "failed to find one by id"))
+			return
+		}
+		books, err := authorStore.FindBooksByAuthorId(ctx, authorId, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("authorStore.FindBooksByAuthorId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find books by author_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]AuthoredBookResponse, 0, len(books))
+		for _, book := range books {
+			res = append(res, AuthoredBookResponse{
+				BookID:   book.BookID,
+				Title:    book.Title,
+				Role:     book.Role,
+				Language: book.Language,
+			})
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func Create(
+	zlog zerolog.Logger,
+	authorStore store.AuthorStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := AuthorRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		author := &store.Author{Name: req.Name, Bio: req.Bio}
+		if err := authorStore.Insert(ctx, author); err != nil {
+			err = fmt.Errorf("authorStore.Insert: %w", err)
+			response.Error(w, authorError(ctx, wlog, err, "failed to insert author"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusCreated, newAuthorResponse(author))
+	}
+}
+
+func Update(
+	zlog zerolog.Logger,
+	authorStore store.AuthorStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := AuthorRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		author := &store.Author{ID: authorId, Name: req.Name, Bio: req.Bio}
+		if err := authorStore.Update(ctx, author); err != nil {
+			err = fmt.Errorf("authorStore.Update: %w", err)
+			response.Error(w, authorError(ctx, wlog, err, "failed to update author"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newAuthorResponse(author))
+	}
+}
@@ -0,0 +1,602 @@
+package book
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/isbn"
+	"awesome-api/store"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+// BookRequest describes a book edition. PublishedDate is formatted as
+// YYYY-MM-DD and EditionOf groups the book with another edition of the same
+// work.
+type BookRequest struct {
+	Title         string              `json:"title"`
+	Synopsis      string              `json:"synopsis"`
+	Language      string              `json:"language"`
+	CategoryID    int                 `json:"category_id"`
+	Authors       []AuthorCreditInput `json:"authors"`
+	ISBN          string              `json:"isbn"`
+	Publisher     string              `json:"publisher"`
+	PublishedDate string              `json:"published_date"`
+	PageCount     int                 `json:"page_count"`
+	Edition       string              `json:"edition"`
+	EditionOf     int                 `json:"edition_of"`
+
+	isbn13        string
+	isbn10        string
+	publishedDate time.Time
+}
+
+// AuthorCreditInput accepts an author id, an author name, or an object
+// with either of them and a role:
+//
+//	[12, "Jane Doe", {"id": 7, "role": "translator"}, {"name": "John Roe", "role": "editor"}]
+type AuthorCreditInput struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func (ac *AuthorCreditInput) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 {
+		switch data[0] {
+		case '"':
+			return json.Unmarshal(data, &ac.Name)
+		case '{':
+			type credit AuthorCreditInput
+			return json.Unmarshal(data, (*credit)(ac))
+		}
+	}
+	return json.Unmarshal(data, &ac.ID)
+}
+
+type BookResponse struct {
+	ID         int                  `json:"id"`
+	Title      string               `json:"title"`
+	Author     string               `json:"author"`
+	Authors    []BookAuthorResponse `json:"authors"`
+	Synopsis   string               `json:"synopsis"`
+	Language   string               `json:"language"`
+	CategoryID int                  `json:"category_id"`
+	Copies     int                  `json:"copies"`
+	Reader     int                  `json:"reader"`
+	BibliographicResponse
+}
+
+// CatalogBookResponse is a book as listed in the catalogue.
+type CatalogBookResponse struct {
+	BookResponse
+	CategoryName  string  `json:"category_name"`
+	RatingCount   int     `json:"rating_count"`
+	RatingAverage float64 `json:"rating_average"`
+}
+
+type BibliographicResponse struct {
+	ISBN13        *string `json:"isbn_13"`
+	ISBN10        *string `json:"isbn_10"`
+	Publisher     string  `json:"publisher"`
+	PublishedDate *string `json:"published_date"`
+	PageCount     *int32  `json:"page_count"`
+	Edition       string  `json:"edition"`
+	WorkID        *int64  `json:"work_id"`
+}
+
+type EditionResponse struct {
+	ID       int    `json:"id"`
+	Title    string `json:"title"`
+	Author   string `json:"author"`
+	Language string `json:"language"`
+	BibliographicResponse
+}
+
+const (
+	publisherMaxLen   = 128
+	editionMaxLen     = 60
+	publishedDateForm = "2006-01-02"
+)
+
+type BookAuthorResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+var authorRoles = map[string]bool{
+	store.AuthorRoleAuthor:     true,
+	store.AuthorRoleEditor:     true,
+	store.AuthorRoleTranslator: true,
+}
+
+func (br *BookRequest) validateRequest() *apierror.UnprocessableEntity {
+	br.Title = strings.TrimSpace(br.Title)
+	br.Synopsis = strings.TrimSpace(br.Synopsis)
+	br.Language = strings.TrimSpace(br.Language)
+	br.Publisher = strings.TrimSpace(br.Publisher)
+	br.Edition = strings.TrimSpace(br.Edition)
+	field, message := br.invalidField()
+	if field == "" {
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    field,
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func (br *BookRequest) invalidField() (string, string) {
+	switch {
+	case br.Title == "":
+		return "title", "title cannot be empty"
+	case utf8.RuneCountInString(br.Title) > bookTitleMaxLen:
+		return "title", fmt.Sprintf("title cannot exceed %d characters", bookTitleMaxLen)
+	case utf8.RuneCountInString(br.Language) > bookLanguageMaxLen:
+		return "language", fmt.Sprintf("language cannot exceed %d characters", bookLanguageMaxLen)
+	case br.CategoryID <= 0:
+		return "category_id", "category_id must be a positive integer"
+	case len(br.Authors) == 0:
+		return "authors", "authors cannot be empty"
+	case utf8.RuneCountInString(br.Publisher) > publisherMaxLen:
+		return "publisher", fmt.Sprintf("publisher cannot exceed %d characters", publisherMaxLen)
+	case utf8.RuneCountInString(br.Edition) > editionMaxLen:
+		return "edition", fmt.Sprintf("edition cannot exceed %d characters", editionMaxLen)
+	case br.PageCount < 0:
+		return "page_count", "page_count must be a positive integer"
+	case br.EditionOf < 0:
+		return "edition_of", "edition_of must be a positive integer"
+	}
+	if strings.TrimSpace(br.ISBN) != "" {
+		isbn13, err := isbn.Normalize(br.ISBN)
+		if err != nil {
+			return "isbn", err.Error()
+		}
+		br.isbn13 = isbn13
+		br.isbn10, _ = isbn.To10(isbn13)
+	}
+	if br.PublishedDate != "" {
+		date, err := time.Parse(publishedDateForm, br.PublishedDate)
+		if err != nil {
+			return "published_date", "published_date must be formatted as YYYY-MM-DD"
+		}
+		br.publishedDate = date
+	}
+	for i := range br.Authors {
+		credit := &br.Authors[i]
+		credit.Name = strings.TrimSpace(credit.Name)
+		if credit.Role == "" {
+			credit.Role = store.AuthorRoleAuthor
+		}
+		switch {
+		case credit.ID < 0:
+			return "authors", "author id must be a positive integer"
+		case credit.ID == 0 && credit.Name == "":
+			return "authors", "author must have an id or a name"
+		case utf8.RuneCountInString(credit.Name) > authorNameMaxLen:
+			return "authors", fmt.Sprintf("author name cannot exceed %d characters", authorNameMaxLen)
+		case !authorRoles[credit.Role]:
+			return "authors", "author role must be author, editor or translator"
+		}
+	}
+	return "", ""
+}
+
+func (br *BookRequest) book() *store.Book {
+	return &store.Book{
+		Title:         br.Title,
+		Synopsis:      br.Synopsis,
+		Language:      br.Language,
+		CategoryID:    br.CategoryID,
+		ISBN13:        sql.NullString{String: br.isbn13, Valid: br.isbn13 != ""},
+		ISBN10:        sql.NullString{String: br.isbn10, Valid: br.isbn10 != ""},
+		Publisher:     br.Publisher,
+		PublishedDate: sql.NullTime{Time: br.publishedDate, Valid: !br.publishedDate.IsZero()},
+		PageCount:     sql.NullInt32{Int32: int32(br.PageCount), Valid: br.PageCount > 0},
+		Edition:       br.Edition,
+	}
+}
+
+func (br *BookRequest) credits() []store.AuthorCredit {
+	credits := make([]store.AuthorCredit, 0, len(br.Authors))
+	for _, credit := range br.Authors {
+		credits = append(credits, store.AuthorCredit{
+			AuthorID: credit.ID,
+			Name:     credit.Name,
+			Role:     credit.Role,
+		})
+	}
+	return credits
+}
+
+func newBookResponse(book *store.Book, authors []*store.BookAuthor) BookResponse {
+	res := BookResponse{
+		ID:         book.ID,
+		Title:      book.Title,
+		Author:     book.Author,
+		Authors:    make([]BookAuthorResponse, 0, len(authors)),
+		Synopsis:   book.Synopsis,
+		Language:   book.Language,
+		CategoryID: book.CategoryID,
+		Copies:     book.Copies,
+		Reader:     book.Reader,
+
+		BibliographicResponse: newBibliographicResponse(book),
+	}
+	for _, author := range authors {
+		res.Authors = append(res.Authors, BookAuthorResponse{
+			ID:   author.AuthorID,
+			Name: author.Name,
+			Role: author.Role,
+		})
+	}
+	return res
+}
+
+func newCatalogBookResponse(book *store.CatalogBook) CatalogBookResponse {
+	return CatalogBookResponse{
+		BookResponse:  newBookResponse(&book.Book, book.Authors),
+		CategoryName:  book.CategoryName,
+		RatingCount:   book.RatingCount,
+		RatingAverage: book.RatingAverage,
+	}
+}
+
+func newBibliographicResponse(book *store.Book) BibliographicResponse {
+	res := BibliographicResponse{
+		Publisher: book.Publisher,
+		Edition:   book.Edition,
+	}
+	if book.ISBN13.Valid {
+		res.ISBN13 = &book.ISBN13.String
+	}
+	if book.ISBN10.Valid {
+		res.ISBN10 = &book.ISBN10.String
+	}
+	if book.PublishedDate.Valid {
+		date := book.PublishedDate.Time.Format(publishedDateForm)
+		res.PublishedDate = &date
+	}
+	if book.PageCount.Valid {
+		res.PageCount = &book.PageCount.Int32
+	}
+	if book.WorkID.Valid {
+		res.WorkID = &book.WorkID.Int64
+	}
+	return res
+}
+
+func newEditionResponse(book *store.Book) EditionResponse {
+	return EditionResponse{
+		ID:       book.ID,
+		Title:    book.Title,
+		Author:   book.Author,
+		Language: book.Language,
+
+		BibliographicResponse: newBibliographicResponse(book),
+	}
+}
+
+// bookError maps the references a book request may get wrong onto field
+// errors, and anything else onto a logged server error.
+func bookError(ctx context.Context, wlog common.WrapperZlog, err error, msg string) (*apierror.Error, *apierror.UnprocessableEntity) {
+	var field string
+	var target error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apiErr := apierror.ClientNotFound()
+		return &apiErr, nil
+	case errors.Is(err, store.ErrBookISBNAlreadyExists):
+		apiErr := apierror.ClientISBNAlreadyExists()
+		return &apiErr, nil
+	case errors.Is(err, store.ErrBookCategoryNotFound):
+		field, target = "category_id", store.ErrBookCategoryNotFound
+	case errors.Is(err, store.ErrAuthorNotFound):
+		field, target = "authors", store.ErrAuthorNotFound
+	default:
+		wlog.Error(ctx).
+			Err(err).Msg(msg)
+		apiErr := apierror.ServerError()
+		return &apiErr, nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    field,
+		Message: target.Error(),
+	})
+	return nil, &fieldErr
+}
+
+func respondBook(
+	w http.ResponseWriter,
+	ctx context.Context,
+	wlog common.WrapperZlog,
+	authorStore store.AuthorStore,
+	status int,
+	book *store.Book,
+) {
+	authors, err := authorStore.FindByBookId(ctx, book.ID)
+	if err != nil {
+		err = fmt.Errorf("authorStore.FindByBookId: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to find authors by book_id")
+		response.Error(w, apierror.ServerError())
+		return
+	}
+	response.GenerateResponse(w, status, newBookResponse(book, authors))
+}
+
+// resolveWork groups the book with the work of editionOf, when given, and
+// reports whether the request can go on.
+func resolveWork(
+	w http.ResponseWriter,
+	ctx context.Context,
+	wlog common.WrapperZlog,
+	bookStore store.BookStore,
+	editionOf int,
+	book *store.Book,
+) bool {
+	if editionOf == 0 {
+		return true
+	}
+	workId, err := bookStore.EnsureWorkById(ctx, editionOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    "edition_of",
+			Message: "book is not found",
+		}))
+		return false
+	}
+	if err != nil {
+		err = fmt.Errorf("bookStore.EnsureWorkById: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to ensure work by id")
+		response.Error(w, apierror.ServerError())
+		return false
+	}
+	book.WorkID = sql.NullInt64{Int64: int64(workId), Valid: true}
+	return true
+}
+
+// Get also counts a view of the book for the rankings, a failure to count
+// it does not fail the request.
+func Get(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	authorStore store.AuthorStore,
+	rankingStore store.RankingStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := bookIdParam(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book, err := bookStore.FindOneById(ctx, bookId)
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, apierror.ClientNotFound())
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		// Anonymous views cannot be told apart, counting them would let
+		// anyone push a book up the trending ranking.
+		if userId := middleware.UserID(ctx); userId != 0 {
+			if err = rankingStore.RecordView(ctx, book.ID, userId); err != nil {
+				err = fmt.Errorf("rankingStore.RecordView: %w", err)
+				wlog.Warn(ctx).
+					Err(err).Msg("failed to record view")
+			}
+		}
+		respondBook(w, ctx, wlog, authorStore, http.StatusOK, book)
+	}
+}
+
+// List returns a page of the catalogue. The filters are the ones exports
+// accept, see common.ParseBookFilter.
+func List(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, fieldErr := common.ParseBookFilter(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		books, err := bookStore.FindAll(ctx, filter, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("bookStore.FindAll: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find all books")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]CatalogBookResponse, 0, len(books))
+		for _, book := range books {
+			res = append(res, newCatalogBookResponse(book))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func Create(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	authorStore store.AuthorStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := BookRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book := req.book()
+		if !resolveWork(w, ctx, wlog, bookStore, req.EditionOf, book) {
+			return
+		}
+		if err := bookStore.Insert(ctx, book, req.credits()); err != nil {
+			err = fmt.Errorf("bookStore.Insert: %w", err)
+			apiErr, fieldErr := bookError(ctx, wlog, err, "failed to insert book")
+			if fieldErr != nil {
+				response.ValidationError(w, *fieldErr)
+				return
+			}
+			response.Error(w, *apiErr)
+			return
+		}
+		respondBook(w, ctx, wlog, authorStore, http.StatusCreated, book)
+	}
+}
+
+func Update(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	authorStore store.AuthorStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := bookIdParam(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := BookRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book := req.book()
+		book.ID = bookId
+		if !resolveWork(w, ctx, wlog, bookStore, req.EditionOf, book) {
+			return
+		}
+		if err := bookStore.Update(ctx, book, req.credits()); err != nil {
+			err = fmt.Errorf("bookStore.Update: %w", err)
+			apiErr, fieldErr := bookError(ctx, wlog, err, "failed to update book")
+			if fieldErr != nil {
+				response.ValidationError(w, *fieldErr)
+				return
+			}
+			response.Error(w, *apiErr)
+			return
+		}
+		respondBook(w, ctx, wlog, authorStore, http.StatusOK, book)
+	}
+}
+
+func GetByISBN(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	authorStore store.AuthorStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isbn13, err := isbn.Normalize(chi.URLParam(r, "isbn"))
+		if err != nil {
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "isbn",
+				Message: err.Error(),
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book, err := bookStore.FindOneByISBN(ctx, isbn13)
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, apierror.ClientNotFound())
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("bookStore.FindOneByISBN: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by isbn")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		respondBook(w, ctx, wlog, authorStore, http.StatusOK, book)
+	}
+}
+
+// ListEditions returns every edition of the work the book belongs to,
+// including the book itself.
+func ListEditions(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := bookIdParam(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book, err := bookStore.FindOneById(ctx, bookId)
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, apierror.ClientNotFound())
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		editions := []*store.Book{book}
+		if book.WorkID.Valid {
+			editions, err = bookStore.FindByWorkId(ctx, int(book.WorkID.Int64))
+			if err != nil {
+				err = fmt.Errorf("bookStore.FindByWorkId: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to find books by work_id")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+		}
+		res := make([]EditionResponse, 0, len(editions))
+		for _, edition := range editions {
+			res = append(res, newEditionResponse(edition))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,39 @@
+package book
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	bookTitleMaxLen    = 60
+	bookLanguageMaxLen = 35
+	authorNameMaxLen   = 128
+)
+
+func bookIdParam(r *http.Request) (int, *apierror.UnprocessableEntity) {
+	return common.IdParam(r, "id")
+}
+
+func uploadReadError(err error) *apierror.Error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		apiErr := apierror.ClientPayloadTooLarge()
+		return &apiErr
+	}
+	apiErr := apierror.ClientBadRequest()
+	return &apiErr
+}
+
+func truncate(s string, max int) string {
+	s = strings.TrimSpace(s)
+	for len(s) > max {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s
+}
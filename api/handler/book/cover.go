@@ -0,0 +1,305 @@
+package book
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/blob"
+	"awesome-api/imaging"
+	"awesome-api/store"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // to decode gif covers
+	"image/jpeg"
+	_ "image/png" // to decode png covers
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type CoverConfig struct {
+	MaxSize int64
+}
+
+type CoverResponse struct {
+	Cover      string            `json:"cover"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+const (
+	coverFormField   = "cover"
+	coverOriginal    = "original"
+	coverMinWidth    = 100
+	coverMinHeight   = 150
+	coverMaxWidth    = 4000
+	coverMaxHeight   = 6000
+	coverJPEGQuality = 85
+	coverCacheMaxAge = 24 * time.Hour
+)
+
+var coverContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+var coverThumbnailWidths = map[string]int{
+	"small":  160,
+	"medium": 320,
+	"large":  640,
+}
+
+func UploadCover(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	blobStore blob.BlobStore,
+	cfg CoverConfig,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := bookIdParam(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book, err := bookStore.FindOneById(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxSize+1<<20)
+		data, apiErr := readCoverPart(r, cfg.MaxSize)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		ext, ok := coverContentTypes[http.DetectContentType(data)]
+		if !ok {
+			response.Error(w, apierror.ClientUnsupportedMediaType())
+			return
+		}
+		imgConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			response.ValidationError(w, invalidCoverImage())
+			return
+		}
+		if fieldErr = validateCoverDimension(imgConfig.Width, imgConfig.Height); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			response.ValidationError(w, invalidCoverImage())
+			return
+		}
+
+		prefix := fmt.Sprintf("covers/%d/%d", book.ID, time.Now().UnixNano())
+		key := prefix + "/" + coverOriginal + ext
+		contentType := http.DetectContentType(data)
+		if err = blobStore.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+			err = fmt.Errorf("blobStore.Put: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to store cover")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if err = putThumbnails(ctx, blobStore, prefix, img); err != nil {
+			wlog.Error(ctx).
+				Err(err).Msg("failed to store cover thumbnails")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if err = bookStore.UpdateCoverById(ctx, key, book.ID); err != nil {
+			err = fmt.Errorf("bookStore.UpdateCoverById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to update cover by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if book.Cover != "" {
+			deleteCover(ctx, blobStore, book.Cover, wlog)
+		}
+
+		res := CoverResponse{
+			Cover:      fmt.Sprintf("/books/%d/cover", book.ID),
+			Thumbnails: map[string]string{},
+		}
+		for size := range coverThumbnailWidths {
+			res.Thumbnails[size] = fmt.Sprintf("/books/%d/cover?size=%s", book.ID, size)
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func readCoverPart(r *http.Request, maxSize int64) ([]byte, *apierror.Error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		apiErr := apierror.ClientBadRequest()
+		return nil, &apiErr
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, uploadReadError(err)
+		}
+		if part.FormName() != coverFormField {
+			part.Close()
+			continue
+		}
+		return readLimitedPart(part, maxSize)
+	}
+	apiErr := apierror.ClientBadRequest()
+	return nil, &apiErr
+}
+
+func readLimitedPart(part *multipart.Part, maxSize int64) ([]byte, *apierror.Error) {
+	defer part.Close()
+	data, err := io.ReadAll(io.LimitReader(part, maxSize+1))
+	if err != nil {
+		return nil, uploadReadError(err)
+	}
+	if int64(len(data)) > maxSize {
+		apiErr := apierror.ClientPayloadTooLarge()
+		return nil, &apiErr
+	}
+	return data, nil
+}
+
+func invalidCoverImage() apierror.UnprocessableEntity {
+	return apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    coverFormField,
+		Message: "cover is not a valid image",
+	})
+}
+
+func validateCoverDimension(width, height int) *apierror.UnprocessableEntity {
+	var message string
+	switch {
+	case width < coverMinWidth || height < coverMinHeight:
+		message = fmt.Sprintf("cover must be at least %dx%d pixels", coverMinWidth, coverMinHeight)
+	case width > coverMaxWidth || height > coverMaxHeight:
+		message = fmt.Sprintf("cover cannot exceed %dx%d pixels", coverMaxWidth, coverMaxHeight)
+	default:
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    coverFormField,
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func putThumbnails(ctx context.Context, blobStore blob.BlobStore, prefix string, img image.Image) error {
+	for size, width := range coverThumbnailWidths {
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, imaging.Resize(img, width), &jpeg.Options{Quality: coverJPEGQuality}); err != nil {
+			return fmt.Errorf("failed to encode %s thumbnail: %w", size, err)
+		}
+		key := prefix + "/" + size + ".jpg"
+		if err := blobStore.Put(ctx, key, buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return fmt.Errorf("blobStore.Put %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func deleteCover(ctx context.Context, blobStore blob.BlobStore, cover string, wlog common.WrapperZlog) {
+	keys := []string{cover}
+	for size := range coverThumbnailWidths {
+		keys = append(keys, path.Dir(cover)+"/"+size+".jpg")
+	}
+	for _, key := range keys {
+		if err := blobStore.Delete(ctx, key); err != nil {
+			wlog.Warn(ctx).
+				Err(err).Str("key", key).Msg("failed to delete previous cover")
+		}
+	}
+}
+
+func GetCover(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	blobStore blob.BlobStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := bookIdParam(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		size := r.URL.Query().Get("size")
+		if _, ok := coverThumbnailWidths[size]; !ok && size != "" && size != coverOriginal {
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "size",
+				Message: "size must be one of small, medium, large or original",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book, err := bookStore.FindOneById(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if book.Cover == "" {
+			response.Error(w, apierror.ClientNotFound())
+			return
+		}
+		key := book.Cover
+		if size != "" && size != coverOriginal {
+			key = path.Dir(book.Cover) + "/" + size + ".jpg"
+		}
+		obj, err := blobStore.Open(ctx, key)
+		if err != nil {
+			if errors.Is(err, blob.ErrNotExist) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("blobStore.Open: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to open cover")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		defer obj.Body.Close()
+
+		modTime := obj.ModTime
+		if book.CoverUpdatedAt.Valid {
+			modTime = book.CoverUpdatedAt.Time
+		}
+		if obj.ContentType != "" {
+			w.Header().Set("Content-Type", obj.ContentType)
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(coverCacheMaxAge.Seconds())))
+		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, key))
+		http.ServeContent(w, r, path.Base(key), modTime, obj.Body)
+	}
+}
@@ -0,0 +1,333 @@
+package book
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/blob"
+	"awesome-api/epub"
+	"awesome-api/isbn"
+	"awesome-api/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+type FileConfig struct {
+	MaxSize int64
+}
+
+type BookFileResponse struct {
+	Format      string         `json:"format"`
+	ContentType string         `json:"content_type"`
+	Size        int64          `json:"size"`
+	Download    string         `json:"download"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Metadata    *epub.Metadata `json:"metadata,omitempty"`
+}
+
+const (
+	fileFormField  = "file"
+	pdfContentType = "application/pdf"
+	zipContentType = "application/zip"
+)
+
+var bookFileExtensions = map[string]string{
+	store.BookFormatEPUB: ".epub",
+	store.BookFormatPDF:  ".pdf",
+}
+
+func newBookFileResponse(file *store.BookFile) BookFileResponse {
+	res := BookFileResponse{
+		Format:      file.Format,
+		ContentType: file.ContentType,
+		Size:        file.Size,
+		Download:    fmt.Sprintf("/books/%d/files/%s", file.BookID, file.Format),
+		CreatedAt:   file.CreatedAt,
+	}
+	if len(file.Metadata) > 0 {
+		meta := &epub.Metadata{}
+		if err := json.Unmarshal(file.Metadata, meta); err == nil {
+			res.Metadata = meta
+		}
+	}
+	return res
+}
+
+func UploadFile(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	bookFileStore store.BookFileStore,
+	blobStore blob.BlobStore,
+	cfg FileConfig,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := bookIdParam(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book, err := bookStore.FindOneById(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxSize+1<<20)
+		tmp, size, apiErr := saveFilePart(r, fileFormField, cfg.MaxSize)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		head := make([]byte, 512)
+		n, _ := tmp.ReadAt(head, 0)
+		file := &store.BookFile{
+			BookID: book.ID,
+			Size:   size,
+		}
+		var meta *epub.Metadata
+		switch http.DetectContentType(head[:n]) {
+		case pdfContentType:
+			file.Format = store.BookFormatPDF
+			file.ContentType = pdfContentType
+		case zipContentType:
+			meta, err = epub.Parse(tmp, size)
+			if err != nil {
+				response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+					Name:    fileFormField,
+					Message: "file is not a valid epub",
+				}))
+				return
+			}
+			file.Format = store.BookFormatEPUB
+			file.ContentType = epub.MimeType
+			if file.Metadata, err = json.Marshal(meta); err != nil {
+				wlog.Error(ctx).
+					Err(err).Msg("failed to marshal epub metadata")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+		default:
+			response.Error(w, apierror.ClientUnsupportedMediaType())
+			return
+		}
+
+		previous, err := bookFileStore.FindOneByBookIdAndFormat(ctx, book.ID, file.Format)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("bookFileStore.FindOneByBookIdAndFormat: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by book_id and format")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		file.BlobKey = fmt.Sprintf("books/%d/%s/%d%s",
+			book.ID, file.Format, time.Now().UnixNano(), bookFileExtensions[file.Format])
+		if err = blobStore.Put(ctx, file.BlobKey, io.NewSectionReader(tmp, 0, size), size, file.ContentType); err != nil {
+			err = fmt.Errorf("blobStore.Put: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to store book file")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if err = bookFileStore.Upsert(ctx, file); err != nil {
+			err = fmt.Errorf("bookFileStore.Upsert: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to upsert book file")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if previous != nil {
+			if err = blobStore.Delete(ctx, previous.BlobKey); err != nil {
+				wlog.Warn(ctx).
+					Err(err).Str("key", previous.BlobKey).Msg("failed to delete previous book file")
+			}
+		}
+		if meta != nil {
+			bookMeta := &store.BookMetadata{
+				Title:    truncate(meta.Title, bookTitleMaxLen),
+				Synopsis: meta.Synopsis,
+				Language: truncate(meta.Language, bookLanguageMaxLen),
+			}
+			if isbn13, err := isbn.Normalize(meta.ISBN); err == nil {
+				bookMeta.ISBN13 = isbn13
+				bookMeta.ISBN10, _ = isbn.To10(isbn13)
+			}
+			for _, creator := range meta.Creators {
+				if name := truncate(creator, authorNameMaxLen); name != "" {
+					bookMeta.Authors = append(bookMeta.Authors, name)
+				}
+			}
+			if err = bookStore.PrefillMetadataById(ctx, bookMeta, book.ID); err != nil {
+				err = fmt.Errorf("bookStore.PrefillMetadataById: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to prefill metadata by id")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+		}
+		response.GenerateResponse(w, http.StatusCreated, newBookFileResponse(file))
+	}
+}
+
+// saveFilePart streams the named multipart field into a temporary file so
+// large uploads never have to fit in memory.
+func saveFilePart(r *http.Request, field string, maxSize int64) (*os.File, int64, *apierror.Error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		apiErr := apierror.ClientBadRequest()
+		return nil, 0, &apiErr
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			apiErr := apierror.ClientBadRequest()
+			return nil, 0, &apiErr
+		}
+		if err != nil {
+			return nil, 0, uploadReadError(err)
+		}
+		if part.FormName() != field {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+		tmp, err := os.CreateTemp("", "upload-*")
+		if err != nil {
+			apiErr := apierror.ServerError()
+			return nil, 0, &apiErr
+		}
+		size, err := io.Copy(tmp, io.LimitReader(part, maxSize+1))
+		if err == nil && size > maxSize {
+			err = &http.MaxBytesError{Limit: maxSize}
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, 0, uploadReadError(err)
+		}
+		return tmp, size, nil
+	}
+}
+
+func ListFiles(
+	zlog zerolog.Logger,
+	bookFileStore store.BookFileStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := bookIdParam(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		files, err := bookFileStore.FindByBookId(ctx, id)
+		if err != nil {
+			err = fmt.Errorf("bookFileStore.FindByBookId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find by book_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]BookFileResponse, 0, len(files))
+		for _, file := range files {
+			res = append(res, newBookFileResponse(file))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func DownloadFile(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	bookFileStore store.BookFileStore,
+	blobStore blob.BlobStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := bookIdParam(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		format := chi.URLParam(r, "format")
+		if _, ok := bookFileExtensions[format]; !ok {
+			response.Error(w, apierror.ClientNotFound())
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book, err := bookStore.FindOneById(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		file, err := bookFileStore.FindOneByBookIdAndFormat(ctx, id, format)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("bookFileStore.FindOneByBookIdAndFormat: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by book_id and format")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		obj, err := blobStore.Open(ctx, file.BlobKey)
+		if err != nil {
+			err = fmt.Errorf("blobStore.Open: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to open book file")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		defer obj.Body.Close()
+
+		if r.Method == http.MethodGet {
+			if err = bookStore.AddReader(ctx, book.ID, middleware.UserID(ctx)); err != nil {
+				wlog.Warn(ctx).
+					Err(err).Msg("failed to add reader")
+			}
+		}
+		filename := truncate(book.Title, bookTitleMaxLen)
+		if filename == "" {
+			filename = fmt.Sprintf("book-%d", book.ID)
+		}
+		w.Header().Set("Content-Type", file.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": filename + bookFileExtensions[format],
+		}))
+		w.Header().Set("Cache-Control", "private, no-transform")
+		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, file.BlobKey))
+		http.ServeContent(w, r, file.BlobKey, file.CreatedAt, obj.Body)
+	}
+}
@@ -0,0 +1,180 @@
+package collection
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type CollaboratorRequest struct {
+	Email string `json:"email"`
+}
+
+type CollaboratorResponse struct {
+	UserID    int       `json:"user_id"`
+	Fullname  string    `json:"fullname"`
+	Email     string    `json:"email"`
+	InvitedBy int       `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newCollaboratorResponse(collaborator *store.CollectionCollaborator) CollaboratorResponse {
+	return CollaboratorResponse{
+		UserID:    collaborator.UserID,
+		Fullname:  collaborator.Fullname,
+		Email:     collaborator.Email,
+		InvitedBy: collaborator.InvitedBy,
+		CreatedAt: collaborator.CreatedAt,
+	}
+}
+
+func ListCollaborators(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, apiErr := findMember(ctx, wlog, collectionStore, id); apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		collaborators, err := collectionStore.FindCollaborators(ctx, id)
+		if err != nil {
+			err = fmt.Errorf("collectionStore.FindCollaborators: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find collaborators")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]CollaboratorResponse, 0, len(collaborators))
+		for _, collaborator := range collaborators {
+			res = append(res, newCollaboratorResponse(collaborator))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+// AddCollaborator invites a registered user, by email, to edit the items of
+// the collection.
+func AddCollaborator(
+	zlog zerolog.Logger,
+	userStore store.UserStore,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := CollaboratorRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		req.Email = strings.TrimSpace(req.Email)
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		collection, apiErr := findOwned(ctx, wlog, collectionStore, id)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		invalidEmail := func(message string) {
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "email",
+				Message: message,
+			}))
+		}
+		if req.Email == "" {
+			invalidEmail("email cannot be empty")
+			return
+		}
+		usr, err := userStore.FindOneByEmail(ctx, req.Email)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				invalidEmail("user is not found")
+				return
+			}
+			err = fmt.Errorf("userStore.FindOneByEmail: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by email")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if usr.ID == collection.UserID {
+			invalidEmail("user already owns the collection")
+			return
+		}
+		collaborator := &store.CollectionCollaborator{
+			CollectionID: collection.ID,
+			UserID:       usr.ID,
+			Fullname:     usr.Fullname,
+			Email:        usr.Email,
+			InvitedBy:    middleware.UserID(ctx),
+		}
+		if err = collectionStore.AddCollaborator(ctx, collaborator); err != nil {
+			err = fmt.Errorf("collectionStore.AddCollaborator: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to add collaborator")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newCollaboratorResponse(collaborator))
+	}
+}
+
+// RemoveCollaborator lets the owner remove anyone, and collaborators leave.
+func RemoveCollaborator(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		userId, fieldErr := common.IdParam(r, "userId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		m, apiErr := findMember(ctx, wlog, collectionStore, id)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if !m.owner && userId != middleware.UserID(ctx) {
+			response.Error(w, apierror.ClientPermissionDenied())
+			return
+		}
+		if err := collectionStore.RemoveCollaborator(ctx, id, userId); err != nil {
+			err = fmt.Errorf("collectionStore.RemoveCollaborator: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to remove collaborator")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,312 @@
+package collection
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+type CollectionRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+const (
+	titleMaxLen       = 100
+	descriptionMaxLen = 2000
+	slugAttempts      = 3
+)
+
+func (cr *CollectionRequest) validateRequest() *apierror.UnprocessableEntity {
+	cr.Title = strings.TrimSpace(cr.Title)
+	cr.Description = strings.TrimSpace(cr.Description)
+	if cr.Visibility == "" {
+		cr.Visibility = store.CollectionVisibilityPrivate
+	}
+	var field, message string
+	switch {
+	case cr.Title == "":
+		field, message = "title", "title cannot be empty"
+	case utf8.RuneCountInString(cr.Title) > titleMaxLen:
+		field, message = "title", fmt.Sprintf("title cannot exceed %d characters", titleMaxLen)
+	case utf8.RuneCountInString(cr.Description) > descriptionMaxLen:
+		field, message = "description", fmt.Sprintf("description cannot exceed %d characters", descriptionMaxLen)
+	case cr.Visibility != store.CollectionVisibilityPrivate &&
+		cr.Visibility != store.CollectionVisibilityUnlisted &&
+		cr.Visibility != store.CollectionVisibilityPublic:
+		field, message = "visibility", "visibility must be private, unlisted or public"
+	default:
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    field,
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func decodeCollectionRequest(w http.ResponseWriter, r *http.Request) (*CollectionRequest, bool) {
+	req := &CollectionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		response.Error(w, apierror.ClientBadRequest())
+		return nil, false
+	}
+	if fieldErr := req.validateRequest(); fieldErr != nil {
+		response.ValidationError(w, *fieldErr)
+		return nil, false
+	}
+	return req, true
+}
+
+// ListMine lists the collections the user owns or collaborates on.
+func ListMine(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		collections, err := collectionStore.FindByMember(ctx, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("collectionStore.FindByMember: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find collections by member")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]CollectionResponse, 0, len(collections))
+		for _, collection := range collections {
+			res = append(res, newCollectionResponse(collection))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func Create(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeCollectionRequest(w, r)
+		if !ok {
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		collection := &store.Collection{
+			UserID:      middleware.UserID(ctx),
+			Title:       req.Title,
+			Description: req.Description,
+			Visibility:  req.Visibility,
+		}
+		var err error
+		for i := 0; i < slugAttempts; i++ {
+			if collection.Slug, err = newSlug(req.Title); err != nil {
+				break
+			}
+			if err = collectionStore.Insert(ctx, collection); !errors.Is(err, store.ErrCollectionSlugTaken) {
+				break
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("collectionStore.Insert: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to insert collection")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusCreated, newCollectionResponse(collection))
+	}
+}
+
+// Get shows a collection of any visibility to its owner and collaborators.
+func Get(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		m, apiErr := findMember(ctx, wlog, collectionStore, id)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newCollectionResponse(m.collection))
+	}
+}
+
+// Update changes the title, description and visibility. The slug is kept
+// so links already shared keep working.
+func Update(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req, ok := decodeCollectionRequest(w, r)
+		if !ok {
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		collection, apiErr := findOwned(ctx, wlog, collectionStore, id)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		collection.Title = req.Title
+		collection.Description = req.Description
+		collection.Visibility = req.Visibility
+		if err := collectionStore.Update(ctx, collection); err != nil {
+			err = fmt.Errorf("collectionStore.Update: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to update collection")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newCollectionResponse(collection))
+	}
+}
+
+func Delete(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		collection, apiErr := findOwned(ctx, wlog, collectionStore, id)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if err := collectionStore.DeleteById(ctx, collection.ID); err != nil {
+			err = fmt.Errorf("collectionStore.DeleteById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to delete collection by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+// ListPublic browses public collections, searching their title and
+// description with the q parameter.
+func ListPublic(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		collections, err := collectionStore.SearchPublic(ctx, query, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("collectionStore.SearchPublic: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to search public collections")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]CollectionResponse, 0, len(collections))
+		for _, collection := range collections {
+			res = append(res, newCollectionResponse(collection))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+// findShared resolves a slug to a collection anyone with the link may see,
+// private collections are only reachable through Get.
+func findShared(
+	w http.ResponseWriter,
+	r *http.Request,
+	wlog common.WrapperZlog,
+	collectionStore store.CollectionStore,
+) (*store.Collection, bool) {
+	ctx := r.Context()
+	collection, err := collectionStore.FindOneBySlug(ctx, chi.URLParam(r, "slug"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, apierror.ClientNotFound())
+			return nil, false
+		}
+		err = fmt.Errorf("collectionStore.FindOneBySlug: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to find collection by slug")
+		response.Error(w, apierror.ServerError())
+		return nil, false
+	}
+	if collection.Visibility == store.CollectionVisibilityPrivate {
+		response.Error(w, apierror.ClientNotFound())
+		return nil, false
+	}
+	return collection, true
+}
+
+func GetShared(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wlog := common.WrapperZlog{Logger: &zlog}
+		collection, ok := findShared(w, r, wlog, collectionStore)
+		if !ok {
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newCollectionResponse(collection))
+	}
+}
+
+func ListSharedItems(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		wlog := common.WrapperZlog{Logger: &zlog}
+		collection, ok := findShared(w, r, wlog, collectionStore)
+		if !ok {
+			return
+		}
+		listItems(w, r, wlog, collectionStore, collection.ID, page)
+	}
+}
@@ -0,0 +1,162 @@
+package collection
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/store"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type CollectionResponse struct {
+	ID          int       `json:"id"`
+	Slug        string    `json:"slug"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Visibility  string    `json:"visibility"`
+	OwnerID     int       `json:"owner_id"`
+	Owner       string    `json:"owner"`
+	ItemCount   int       `json:"item_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newCollectionResponse(collection *store.Collection) CollectionResponse {
+	return CollectionResponse{
+		ID:          collection.ID,
+		Slug:        collection.Slug,
+		Title:       collection.Title,
+		Description: collection.Description,
+		Visibility:  collection.Visibility,
+		OwnerID:     collection.UserID,
+		Owner:       collection.Owner,
+		ItemCount:   collection.ItemCount,
+		CreatedAt:   collection.CreatedAt,
+		UpdatedAt:   collection.UpdatedAt,
+	}
+}
+
+type ItemResponse struct {
+	BookID   int       `json:"book_id"`
+	Title    string    `json:"title"`
+	Position int       `json:"position"`
+	Note     string    `json:"note"`
+	AddedBy  int       `json:"added_by,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+func newItemResponse(item *store.CollectionItem) ItemResponse {
+	return ItemResponse{
+		BookID:   item.BookID,
+		Title:    item.Title,
+		Position: item.Position,
+		Note:     item.Note,
+		AddedBy:  item.AddedBy,
+		AddedAt:  item.AddedAt,
+	}
+}
+
+const (
+	slugMaxLen    = 60
+	slugSuffixLen = 4
+)
+
+// newSlug derives a readable slug from the title and appends random bytes,
+// so unlisted collections cannot be found by guessing from their title.
+func newSlug(title string) (string, error) {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= slugMaxLen {
+			break
+		}
+	}
+	suffix := make([]byte, slugSuffixLen)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate slug suffix: %w", err)
+	}
+	base := strings.Trim(b.String(), "-")
+	if base == "" {
+		return hex.EncodeToString(suffix), nil
+	}
+	return base + "-" + hex.EncodeToString(suffix), nil
+}
+
+// member is a collection seen by one of the users who may edit it.
+type member struct {
+	collection *store.Collection
+	owner      bool
+}
+
+// findMember resolves a collection for its owner or a collaborator, and
+// hides it from everyone else behind a not found error.
+func findMember(
+	ctx context.Context,
+	wlog common.WrapperZlog,
+	collectionStore store.CollectionStore,
+	id int,
+) (*member, *apierror.Error) {
+	collection, err := collectionStore.FindOneById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apiErr := apierror.ClientNotFound()
+			return nil, &apiErr
+		}
+		err = fmt.Errorf("collectionStore.FindOneById: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to find collection by id")
+		apiErr := apierror.ServerError()
+		return nil, &apiErr
+	}
+	userId := middleware.UserID(ctx)
+	if collection.UserID == userId {
+		return &member{collection: collection, owner: true}, nil
+	}
+	ok, err := collectionStore.IsCollaborator(ctx, id, userId)
+	if err != nil {
+		err = fmt.Errorf("collectionStore.IsCollaborator: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to check collaborator")
+		apiErr := apierror.ServerError()
+		return nil, &apiErr
+	}
+	if !ok {
+		apiErr := apierror.ClientNotFound()
+		return nil, &apiErr
+	}
+	return &member{collection: collection}, nil
+}
+
+// findOwned is findMember for changes only the owner may make.
+func findOwned(
+	ctx context.Context,
+	wlog common.WrapperZlog,
+	collectionStore store.CollectionStore,
+	id int,
+) (*store.Collection, *apierror.Error) {
+	m, apiErr := findMember(ctx, wlog, collectionStore, id)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if !m.owner {
+		apiErr := apierror.ClientPermissionDenied()
+		return nil, &apiErr
+	}
+	return m.collection, nil
+}
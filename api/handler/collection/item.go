@@ -0,0 +1,217 @@
+package collection
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+type ItemRequest struct {
+	Note string `json:"note"`
+}
+
+type OrderRequest struct {
+	BookIDs []int `json:"book_ids"`
+}
+
+const noteMaxLen = 1000
+
+func listItems(
+	w http.ResponseWriter,
+	r *http.Request,
+	wlog common.WrapperZlog,
+	collectionStore store.CollectionStore,
+	id int,
+	page common.Pagination,
+) {
+	ctx := r.Context()
+	items, err := collectionStore.FindItems(ctx, id, page.Limit, page.Offset)
+	if err != nil {
+		err = fmt.Errorf("collectionStore.FindItems: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to find collection items")
+		response.Error(w, apierror.ServerError())
+		return
+	}
+	res := make([]ItemResponse, 0, len(items))
+	for _, item := range items {
+		res = append(res, newItemResponse(item))
+	}
+	response.GenerateResponse(w, http.StatusOK, res)
+}
+
+func ListItems(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, apiErr := findMember(ctx, wlog, collectionStore, id); apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		listItems(w, r, wlog, collectionStore, id, page)
+	}
+}
+
+// PutItem appends a book to the collection, or changes the note of a book
+// already in it without moving it.
+func PutItem(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		bookId, fieldErr := common.IdParam(r, "bookId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := ItemRequest{}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				response.Error(w, apierror.ClientBadRequest())
+				return
+			}
+		}
+		req.Note = strings.TrimSpace(req.Note)
+		if utf8.RuneCountInString(req.Note) > noteMaxLen {
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "note",
+				Message: fmt.Sprintf("note cannot exceed %d characters", noteMaxLen),
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, apiErr := findMember(ctx, wlog, collectionStore, id); apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if _, err := bookStore.FindOneById(ctx, bookId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		item := &store.CollectionItem{
+			CollectionID: id,
+			BookID:       bookId,
+			Note:         req.Note,
+			AddedBy:      middleware.UserID(ctx),
+		}
+		if err := collectionStore.UpsertItem(ctx, item); err != nil {
+			err = fmt.Errorf("collectionStore.UpsertItem: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to upsert collection item")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newItemResponse(item))
+	}
+}
+
+func RemoveItem(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		bookId, fieldErr := common.IdParam(r, "bookId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, apiErr := findMember(ctx, wlog, collectionStore, id); apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if err := collectionStore.RemoveItem(ctx, id, bookId); err != nil {
+			err = fmt.Errorf("collectionStore.RemoveItem: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to remove collection item")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+// Reorder takes every book of the collection in the order they should be
+// listed.
+func Reorder(
+	zlog zerolog.Logger,
+	collectionStore store.CollectionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := OrderRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, apiErr := findMember(ctx, wlog, collectionStore, id); apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if err := collectionStore.Reorder(ctx, id, req.BookIDs); err != nil {
+			if errors.Is(err, store.ErrCollectionOrderMismatch) {
+				response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+					Name:    "book_ids",
+					Message: "book_ids must list every book of the collection exactly once",
+				}))
+				return
+			}
+			err = fmt.Errorf("collectionStore.Reorder: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to reorder collection")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,244 @@
+package exports
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/blob"
+	"awesome-api/catalog"
+	"awesome-api/store"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type ExportJobResponse struct {
+	ID         int              `json:"id"`
+	UserID     int              `json:"user_id"`
+	Format     string           `json:"format"`
+	Filter     store.BookFilter `json:"filter"`
+	Status     string           `json:"status"`
+	Total      int              `json:"total"`
+	Size       int64            `json:"size"`
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	StartedAt  *time.Time       `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at"`
+	Download   string           `json:"download,omitempty"`
+}
+
+func newExportJobResponse(job *store.ExportJob) ExportJobResponse {
+	res := ExportJobResponse{
+		ID:        job.ID,
+		UserID:    job.UserID,
+		Format:    job.Format,
+		Filter:    job.Filter,
+		Status:    job.Status,
+		Total:     job.Total,
+		Size:      job.Size,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+	}
+	if job.StartedAt.Valid {
+		res.StartedAt = &job.StartedAt.Time
+	}
+	if job.FinishedAt.Valid {
+		res.FinishedAt = &job.FinishedAt.Time
+	}
+	if job.Status == store.ImportStatusSucceeded {
+		res.Download = fmt.Sprintf("/exports/%d/download", job.ID)
+	}
+	return res
+}
+
+// parseExport reads the format and the catalogue filters of an export from
+// the query string. The format defaults to CSV.
+func parseExport(r *http.Request) (string, store.BookFilter, *apierror.UnprocessableEntity) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = catalog.FormatCSV
+	case catalog.FormatCSV, catalog.FormatJSONL, catalog.FormatMARCXML:
+	default:
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    "format",
+			Message: "format must be csv, jsonl or marcxml",
+		})
+		return "", store.BookFilter{}, &fieldErr
+	}
+	filter, fieldErr := common.ParseBookFilter(r)
+	return format, filter, fieldErr
+}
+
+func setAttachment(w http.ResponseWriter, format, name string) {
+	w.Header().Set("Content-Type", catalog.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": name + catalog.Extension(format),
+	}))
+	w.Header().Set("Cache-Control", "private, no-store")
+}
+
+// Stream writes the export in the response as books are read, for exports
+// small enough to wait for. Once the body has started an error can only
+// be signalled by cutting the response short.
+func Stream(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, filter, fieldErr := parseExport(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		setAttachment(w, format, "catalogue-"+time.Now().UTC().Format("20060102"))
+		n, err := catalog.Export(ctx, bookStore, w, format, filter, nil)
+		if err != nil {
+			err = fmt.Errorf("catalog.Export: %w", err)
+			wlog.Error(ctx).
+				Err(err).Int("written", n).Msg("failed to stream export")
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// Create queues an export job for large exports. The result is downloaded
+// from Download once the job has succeeded.
+func Create(
+	zlog zerolog.Logger,
+	exportStore store.ExportStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, filter, fieldErr := parseExport(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		job := &store.ExportJob{
+			UserID: middleware.UserID(ctx),
+			Format: format,
+			Filter: filter,
+		}
+		if err := exportStore.Insert(ctx, job); err != nil {
+			err = fmt.Errorf("exportStore.Insert: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to insert export job")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusAccepted, newExportJobResponse(job))
+	}
+}
+
+func List(
+	zlog zerolog.Logger,
+	exportStore store.ExportStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		jobs, err := exportStore.FindAll(ctx, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("exportStore.FindAll: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find all export jobs")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]ExportJobResponse, 0, len(jobs))
+		for _, job := range jobs {
+			res = append(res, newExportJobResponse(job))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func Get(
+	zlog zerolog.Logger,
+	exportStore store.ExportStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, ok := findJob(w, r, zlog, exportStore)
+		if !ok {
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newExportJobResponse(job))
+	}
+}
+
+func Download(
+	zlog zerolog.Logger,
+	exportStore store.ExportStore,
+	blobStore blob.BlobStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, ok := findJob(w, r, zlog, exportStore)
+		if !ok {
+			return
+		}
+		if job.Status != store.ImportStatusSucceeded {
+			response.Error(w, apierror.ClientNotFound())
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		obj, err := blobStore.Open(ctx, job.BlobKey)
+		if err != nil {
+			if errors.Is(err, blob.ErrNotExist) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("blobStore.Open: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to open export")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		defer obj.Body.Close()
+		setAttachment(w, job.Format, fmt.Sprintf("catalogue-export-%d", job.ID))
+		http.ServeContent(w, r, job.BlobKey, job.FinishedAt.Time, obj.Body)
+	}
+}
+
+func findJob(
+	w http.ResponseWriter,
+	r *http.Request,
+	zlog zerolog.Logger,
+	exportStore store.ExportStore,
+) (*store.ExportJob, bool) {
+	id, fieldErr := common.IdParam(r, "id")
+	if fieldErr != nil {
+		response.ValidationError(w, *fieldErr)
+		return nil, false
+	}
+	ctx := r.Context()
+	wlog := common.WrapperZlog{Logger: &zlog}
+	job, err := exportStore.FindOneById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.Error(w, apierror.ClientNotFound())
+			return nil, false
+		}
+		err = fmt.Errorf("exportStore.FindOneById: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to find one by id")
+		response.Error(w, apierror.ServerError())
+		return nil, false
+	}
+	return job, true
+}
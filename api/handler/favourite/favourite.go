@@ -0,0 +1,214 @@
+package favourite
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type FavouriteBookResponse struct {
+	BookID    int       `json:"book_id"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	Available bool      `json:"available"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FavouriteAuthorResponse struct {
+	AuthorID  int       `json:"author_id"`
+	Name      string    `json:"name"`
+	BookCount int       `json:"book_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ListBooks(
+	zlog zerolog.Logger,
+	favouriteStore store.FavouriteStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		books, err := favouriteStore.FindBooks(ctx, middleware.UserID(ctx), page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("favouriteStore.FindBooks: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find favourite books")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]FavouriteBookResponse, 0, len(books))
+		for _, book := range books {
+			res = append(res, FavouriteBookResponse{
+				BookID:    book.BookID,
+				Title:     book.Title,
+				Author:    book.Author,
+				Available: book.Available,
+				CreatedAt: book.CreatedAt,
+			})
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func AddBook(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	favouriteStore store.FavouriteStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "bookId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, err := bookStore.FindOneById(ctx, bookId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if err := favouriteStore.AddBook(ctx, middleware.UserID(ctx), bookId); err != nil {
+			err = fmt.Errorf("favouriteStore.AddBook: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to add favourite book")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func RemoveBook(
+	zlog zerolog.Logger,
+	favouriteStore store.FavouriteStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "bookId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := favouriteStore.RemoveBook(ctx, middleware.UserID(ctx), bookId); err != nil {
+			err = fmt.Errorf("favouriteStore.RemoveBook: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to remove favourite book")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func ListAuthors(
+	zlog zerolog.Logger,
+	favouriteStore store.FavouriteStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		authors, err := favouriteStore.FindAuthors(ctx, middleware.UserID(ctx), page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("favouriteStore.FindAuthors: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find favourite authors")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]FavouriteAuthorResponse, 0, len(authors))
+		for _, author := range authors {
+			res = append(res, FavouriteAuthorResponse{
+				AuthorID:  author.AuthorID,
+				Name:      author.Name,
+				BookCount: author.BookCount,
+				CreatedAt: author.CreatedAt,
+			})
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func AddAuthor(
+	zlog zerolog.Logger,
+	authorStore store.AuthorStore,
+	favouriteStore store.FavouriteStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorId, fieldErr := common.IdParam(r, "authorId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, err := authorStore.FindOneById(ctx, authorId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("authorStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if err := favouriteStore.AddAuthor(ctx, middleware.UserID(ctx), authorId); err != nil {
+			err = fmt.Errorf("favouriteStore.AddAuthor: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to add favourite author")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func RemoveAuthor(
+	zlog zerolog.Logger,
+	favouriteStore store.FavouriteStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorId, fieldErr := common.IdParam(r, "authorId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := favouriteStore.RemoveAuthor(ctx, middleware.UserID(ctx), authorId); err != nil {
+			err = fmt.Errorf("favouriteStore.RemoveAuthor: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to remove favourite author")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
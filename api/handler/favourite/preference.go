@@ -0,0 +1,114 @@
+package favourite
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+// PreferencesRequest maps an alert type to how often it is mailed, types
+// left out keep their frequency.
+type PreferencesRequest map[string]string
+
+type PreferencesResponse map[string]string
+
+func (pr PreferencesRequest) validateRequest() *apierror.UnprocessableEntity {
+	for alertType, frequency := range pr {
+		var message string
+		switch {
+		case alertType != store.AlertTypeNewBook && alertType != store.AlertTypeAvailable:
+			message = fmt.Sprintf("alert type must be one of %s or %s", store.AlertTypeNewBook, store.AlertTypeAvailable)
+		case frequency != store.AlertFrequencyInstant && frequency != store.AlertFrequencyWeekly:
+			message = fmt.Sprintf("frequency must be %s or %s", store.AlertFrequencyInstant, store.AlertFrequencyWeekly)
+		default:
+			continue
+		}
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    alertType,
+			Message: message,
+		})
+		return &fieldErr
+	}
+	return nil
+}
+
+func newPreferencesResponse(preferences []*store.AlertPreference) PreferencesResponse {
+	res := PreferencesResponse{}
+	for _, preference := range preferences {
+		res[preference.AlertType] = preference.Frequency
+	}
+	return res
+}
+
+func GetPreferences(
+	zlog zerolog.Logger,
+	alertStore store.AlertStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		preferences, err := alertStore.FindPreferences(ctx, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("alertStore.FindPreferences: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find alert preferences")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newPreferencesResponse(preferences))
+	}
+}
+
+func UpdatePreferences(
+	zlog zerolog.Logger,
+	alertStore store.AlertStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := PreferencesRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		userId := middleware.UserID(ctx)
+		for _, alertType := range store.AlertTypes {
+			frequency, ok := req[alertType]
+			if !ok {
+				continue
+			}
+			preference := &store.AlertPreference{
+				UserID:    userId,
+				AlertType: alertType,
+				Frequency: frequency,
+			}
+			if err := alertStore.UpsertPreference(ctx, preference); err != nil {
+				err = fmt.Errorf("alertStore.UpsertPreference: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to upsert alert preference")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+		}
+		preferences, err := alertStore.FindPreferences(ctx, userId)
+		if err != nil {
+			err = fmt.Errorf("alertStore.FindPreferences: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find alert preferences")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newPreferencesResponse(preferences))
+	}
+}
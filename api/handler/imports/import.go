@@ -0,0 +1,401 @@
+package imports
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/blob"
+	"awesome-api/catalog"
+	"awesome-api/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type Config struct {
+	MaxSize int64
+}
+
+type ImportJobResponse struct {
+	ID              int               `json:"id"`
+	UserID          int               `json:"user_id"`
+	Format          string            `json:"format"`
+	Status          string            `json:"status"`
+	DryRun          bool              `json:"dry_run"`
+	Mapping         map[string]string `json:"mapping,omitempty"`
+	DefaultCategory string            `json:"default_category,omitempty"`
+	Total           int               `json:"total"`
+	Created         int               `json:"created"`
+	Updated         int               `json:"updated"`
+	Failed          int               `json:"failed"`
+	Error           string            `json:"error,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	StartedAt       *time.Time        `json:"started_at"`
+	FinishedAt      *time.Time        `json:"finished_at"`
+	Errors          string            `json:"errors"`
+}
+
+type ImportErrorResponse struct {
+	Row     int    `json:"row"`
+	ISBN    string `json:"isbn,omitempty"`
+	Message string `json:"message"`
+}
+
+const (
+	fileFormField = "file"
+	// fieldMaxSize bounds the form fields sent along with the file.
+	fieldMaxSize = 64 << 10
+)
+
+var formatExtensions = map[string]string{
+	catalog.FormatCSV:     ".csv",
+	catalog.FormatMARC:    ".mrc",
+	catalog.FormatMARCXML: ".xml",
+}
+
+var formatContentTypes = map[string]string{
+	catalog.FormatCSV:     "text/csv",
+	catalog.FormatMARC:    "application/marc",
+	catalog.FormatMARCXML: "application/marcxml+xml",
+}
+
+func newImportJobResponse(job *store.ImportJob) ImportJobResponse {
+	res := ImportJobResponse{
+		ID:              job.ID,
+		UserID:          job.UserID,
+		Format:          job.Format,
+		Status:          job.Status,
+		DryRun:          job.DryRun,
+		Mapping:         job.Options.Mapping,
+		DefaultCategory: job.Options.DefaultCategory,
+		Total:           job.Total,
+		Created:         job.Created,
+		Updated:         job.Updated,
+		Failed:          job.Failed,
+		Error:           job.Error,
+		CreatedAt:       job.CreatedAt,
+		Errors:          fmt.Sprintf("/imports/%d/errors", job.ID),
+	}
+	if job.StartedAt.Valid {
+		res.StartedAt = &job.StartedAt.Time
+	}
+	if job.FinishedAt.Valid {
+		res.FinishedAt = &job.FinishedAt.Time
+	}
+	return res
+}
+
+// importForm is the multipart upload of an import: the file and the
+// options of the job, in any order.
+type importForm struct {
+	file            *os.File
+	size            int64
+	filename        string
+	format          string
+	dryRun          bool
+	mapping         map[string]string
+	defaultCategory string
+}
+
+func (f *importForm) validate() *apierror.UnprocessableEntity {
+	if f.format == "" {
+		switch strings.ToLower(path.Ext(f.filename)) {
+		case ".csv":
+			f.format = catalog.FormatCSV
+		case ".mrc", ".marc":
+			f.format = catalog.FormatMARC
+		case ".xml":
+			f.format = catalog.FormatMARCXML
+		}
+	}
+	var field, message string
+	switch {
+	case formatExtensions[f.format] == "":
+		field, message = "format", "format must be csv, marc or marcxml"
+	case len(f.mapping) > 0 && f.format != catalog.FormatCSV:
+		field, message = "mapping", "mapping only applies to csv"
+	case len([]rune(f.defaultCategory)) > 50:
+		field, message = "default_category", "default_category cannot exceed 50 characters"
+	default:
+		if f.format != catalog.FormatCSV {
+			return nil
+		}
+		// Reading the header now reports a wrong mapping to the librarian
+		// instead of failing the job later.
+		if _, err := catalog.NewCSVReader(io.NewSectionReader(f.file, 0, f.size), f.mapping); err != nil {
+			field, message = "mapping", err.Error()
+			break
+		}
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    field,
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func (f *importForm) close() {
+	if f.file != nil {
+		f.file.Close()
+		os.Remove(f.file.Name())
+	}
+}
+
+// readImportForm streams the file into a temporary file and reads the
+// other fields, which are small, into memory.
+func readImportForm(r *http.Request, maxSize int64) (*importForm, *apierror.Error, *apierror.UnprocessableEntity) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		apiErr := apierror.ClientBadRequest()
+		return nil, &apiErr, nil
+	}
+	form := &importForm{}
+	fail := func(err error) (*importForm, *apierror.Error, *apierror.UnprocessableEntity) {
+		form.close()
+		var maxBytesErr *http.MaxBytesError
+		apiErr := apierror.ClientBadRequest()
+		if errors.As(err, &maxBytesErr) {
+			apiErr = apierror.ClientPayloadTooLarge()
+		}
+		return nil, &apiErr, nil
+	}
+	invalid := func(field, message string) (*importForm, *apierror.Error, *apierror.UnprocessableEntity) {
+		form.close()
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    field,
+			Message: message,
+		})
+		return nil, nil, &fieldErr
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		name := part.FormName()
+		if name == fileFormField {
+			if form.file != nil {
+				part.Close()
+				return invalid(fileFormField, "only one file can be imported at a time")
+			}
+			if form.file, err = os.CreateTemp("", "import-*"); err != nil {
+				part.Close()
+				form.close()
+				apiErr := apierror.ServerError()
+				return nil, &apiErr, nil
+			}
+			form.filename = part.FileName()
+			form.size, err = io.Copy(form.file, io.LimitReader(part, maxSize+1))
+			part.Close()
+			if err == nil && form.size > maxSize {
+				err = &http.MaxBytesError{Limit: maxSize}
+			}
+			if err != nil {
+				return fail(err)
+			}
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, fieldMaxSize))
+		part.Close()
+		if err != nil {
+			return fail(err)
+		}
+		switch name {
+		case "format":
+			form.format = strings.ToLower(strings.TrimSpace(string(value)))
+		case "dry_run":
+			if form.dryRun, err = strconv.ParseBool(strings.TrimSpace(string(value))); err != nil {
+				return invalid("dry_run", "dry_run must be true or false")
+			}
+		case "mapping":
+			if err = json.Unmarshal(value, &form.mapping); err != nil {
+				return invalid("mapping", "mapping must be a JSON object of field to column")
+			}
+		case "default_category":
+			form.defaultCategory = strings.TrimSpace(string(value))
+		}
+	}
+	if form.file == nil {
+		return invalid(fileFormField, "file is required")
+	}
+	return form, nil, nil
+}
+
+// Create queues an import of the uploaded file. The job runs in the
+// background, its progress and report are read from Get and ListErrors.
+func Create(
+	zlog zerolog.Logger,
+	importStore store.ImportStore,
+	blobStore blob.BlobStore,
+	cfg Config,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxSize+1<<20)
+		form, apiErr, fieldErr := readImportForm(r, cfg.MaxSize)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		defer form.close()
+		if fieldErr = form.validate(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		job := &store.ImportJob{
+			UserID: middleware.UserID(ctx),
+			Format: form.format,
+			DryRun: form.dryRun,
+			BlobKey: fmt.Sprintf("imports/%d%s",
+				time.Now().UnixNano(), formatExtensions[form.format]),
+			Options: store.ImportOptions{
+				Mapping:         form.mapping,
+				DefaultCategory: form.defaultCategory,
+			},
+		}
+		err := blobStore.Put(ctx, job.BlobKey, io.NewSectionReader(form.file, 0, form.size),
+			form.size, formatContentTypes[form.format])
+		if err != nil {
+			err = fmt.Errorf("blobStore.Put: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to store import file")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if err = importStore.Insert(ctx, job); err != nil {
+			err = fmt.Errorf("importStore.Insert: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to insert import job")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusAccepted, newImportJobResponse(job))
+	}
+}
+
+func List(
+	zlog zerolog.Logger,
+	importStore store.ImportStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		jobs, err := importStore.FindAll(ctx, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("importStore.FindAll: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find all import jobs")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]ImportJobResponse, 0, len(jobs))
+		for _, job := range jobs {
+			res = append(res, newImportJobResponse(job))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func Get(
+	zlog zerolog.Logger,
+	importStore store.ImportStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		job, err := importStore.FindOneById(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("importStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newImportJobResponse(job))
+	}
+}
+
+// ListErrors returns the rows of the import that were not imported, in
+// file order.
+func ListErrors(
+	zlog zerolog.Logger,
+	importStore store.ImportStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, err := importStore.FindOneById(ctx, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("importStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		errs, err := importStore.FindErrorsByJobId(ctx, id, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("importStore.FindErrorsByJobId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find errors by job_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]ImportErrorResponse, 0, len(errs))
+		for _, e := range errs {
+			res = append(res, ImportErrorResponse{
+				Row:     e.Row,
+				ISBN:    e.ISBN,
+				Message: e.Message,
+			})
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
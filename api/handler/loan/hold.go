@@ -0,0 +1,144 @@
+package loan
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/circulation"
+	"awesome-api/store"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type HoldResponse struct {
+	ID        int        `json:"id"`
+	BookID    int        `json:"book_id"`
+	Status    string     `json:"status"`
+	Position  int        `json:"position,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadyAt   *time.Time `json:"ready_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+}
+
+func newHoldResponse(hold *store.Hold) HoldResponse {
+	res := HoldResponse{
+		ID:        hold.ID,
+		BookID:    hold.BookID,
+		Status:    hold.Status,
+		Position:  hold.Position,
+		CreatedAt: hold.CreatedAt,
+	}
+	if hold.ReadyAt.Valid {
+		res.ReadyAt = &hold.ReadyAt.Time
+	}
+	if hold.ExpiresAt.Valid {
+		res.ExpiresAt = &hold.ExpiresAt.Time
+	}
+	if hold.ClosedAt.Valid {
+		res.ClosedAt = &hold.ClosedAt.Time
+	}
+	return res
+}
+
+// promote runs after a copy may have been freed. The triggering request has
+// already succeeded, so a failure is only logged and left to the sweep job.
+func promote(ctx context.Context, wlog common.WrapperZlog, holdQueue *circulation.HoldQueue, bookId int) {
+	if err := holdQueue.Promote(ctx, bookId); err != nil {
+		err = fmt.Errorf("holdQueue.Promote: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to promote holds")
+	}
+}
+
+func PlaceHold(
+	zlog zerolog.Logger,
+	holdStore store.HoldStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		hold, err := holdStore.Place(ctx, middleware.UserID(ctx), bookId)
+		if err != nil {
+			err = fmt.Errorf("holdStore.Place: %w", err)
+			response.Error(w, loanError(ctx, wlog, err, "failed to place hold"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusCreated, newHoldResponse(hold))
+	}
+}
+
+func ListMyHolds(
+	zlog zerolog.Logger,
+	holdStore store.HoldStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		openOnly := true
+		switch r.URL.Query().Get("status") {
+		case "", "open":
+		case "all":
+			openOnly = false
+		default:
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "status",
+				Message: "status must be open or all",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		holds, err := holdStore.FindByUserId(ctx, middleware.UserID(ctx), openOnly, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("holdStore.FindByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find holds by user_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]HoldResponse, 0, len(holds))
+		for _, hold := range holds {
+			res = append(res, newHoldResponse(hold))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func CancelHold(
+	zlog zerolog.Logger,
+	holdStore store.HoldStore,
+	holdQueue *circulation.HoldQueue,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		holdId, fieldErr := common.IdParam(r, "holdId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		hold, err := holdStore.Cancel(ctx, holdId, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("holdStore.Cancel: %w", err)
+			response.Error(w, loanError(ctx, wlog, err, "failed to cancel hold"))
+			return
+		}
+		// Cancelling a ready hold releases its reserved copy.
+		promote(ctx, wlog, holdQueue, hold.BookID)
+		response.GenerateResponse(w, http.StatusOK, newHoldResponse(hold))
+	}
+}
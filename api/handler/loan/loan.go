@@ -0,0 +1,283 @@
+package loan
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/circulation"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type LoanResponse struct {
+	ID         int        `json:"id"`
+	BookID     int        `json:"book_id"`
+	Status     string     `json:"status"`
+	BorrowedAt time.Time  `json:"borrowed_at"`
+	DueAt      time.Time  `json:"due_at"`
+	ReturnedAt *time.Time `json:"returned_at"`
+	RenewCount int        `json:"renew_count"`
+}
+
+type AvailabilityResponse struct {
+	BookID    int `json:"book_id"`
+	Copies    int `json:"copies"`
+	OnLoan    int `json:"on_loan"`
+	Reserved  int `json:"reserved"`
+	Waiting   int `json:"waiting"`
+	Available int `json:"available"`
+}
+
+type CopiesRequest struct {
+	Copies int `json:"copies"`
+}
+
+func newLoanResponse(loan *store.Loan) LoanResponse {
+	res := LoanResponse{
+		ID:         loan.ID,
+		BookID:     loan.BookID,
+		Status:     loan.Status,
+		BorrowedAt: loan.BorrowedAt,
+		DueAt:      loan.DueAt,
+		RenewCount: loan.RenewCount,
+	}
+	if loan.ReturnedAt.Valid {
+		res.ReturnedAt = &loan.ReturnedAt.Time
+	}
+	return res
+}
+
+func newAvailabilityResponse(availability *store.Availability) AvailabilityResponse {
+	available := availability.Copies - availability.OnLoan - availability.Reserved - availability.Waiting
+	if available < 0 {
+		available = 0
+	}
+	return AvailabilityResponse{
+		BookID:    availability.BookID,
+		Copies:    availability.Copies,
+		OnLoan:    availability.OnLoan,
+		Reserved:  availability.Reserved,
+		Waiting:   availability.Waiting,
+		Available: available,
+	}
+}
+
+// loanError maps the circulation rules enforced by the store onto client
+// errors, and anything else onto a logged server error.
+func loanError(ctx context.Context, wlog common.WrapperZlog, err error, msg string) apierror.Error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return apierror.ClientNotFound()
+	case errors.Is(err, store.ErrLoanNoCopyAvailable):
+		return apierror.ClientNoCopyAvailable()
+	case errors.Is(err, store.ErrLoanLimitReached):
+		return apierror.ClientLoanLimitReached()
+	case errors.Is(err, store.ErrLoanAlreadyBorrowed):
+		return apierror.ClientAlreadyBorrowed()
+	case errors.Is(err, store.ErrLoanNotActive):
+		return apierror.ClientLoanNotActive()
+	case errors.Is(err, store.ErrLoanRenewalExhausted):
+		return apierror.ClientRenewalExhausted()
+	case errors.Is(err, store.ErrLoanHoldsWaiting):
+		return apierror.ClientHoldsWaiting()
+	case errors.Is(err, store.ErrHoldAlreadyPlaced):
+		return apierror.ClientHoldAlreadyPlaced()
+	case errors.Is(err, store.ErrHoldCopyAvailable):
+		return apierror.ClientHoldCopyAvailable()
+	case errors.Is(err, store.ErrHoldNotOpen):
+		return apierror.ClientHoldNotOpen()
+	}
+	wlog.Error(ctx).
+		Err(err).Msg(msg)
+	return apierror.ServerError()
+}
+
+func Borrow(
+	zlog zerolog.Logger,
+	loanStore store.LoanStore,
+	policy store.LoanPolicy,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		loan, err := loanStore.Borrow(ctx, middleware.UserID(ctx), bookId, policy)
+		if err != nil {
+			err = fmt.Errorf("loanStore.Borrow: %w", err)
+			response.Error(w, loanError(ctx, wlog, err, "failed to borrow book"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusCreated, newLoanResponse(loan))
+	}
+}
+
+func Return(
+	zlog zerolog.Logger,
+	loanStore store.LoanStore,
+	holdQueue *circulation.HoldQueue,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loanId, fieldErr := common.IdParam(r, "loanId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		loan, err := loanStore.Return(ctx, loanId, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("loanStore.Return: %w", err)
+			response.Error(w, loanError(ctx, wlog, err, "failed to return loan"))
+			return
+		}
+		promote(ctx, wlog, holdQueue, loan.BookID)
+		response.GenerateResponse(w, http.StatusOK, newLoanResponse(loan))
+	}
+}
+
+func Renew(
+	zlog zerolog.Logger,
+	loanStore store.LoanStore,
+	policy store.LoanPolicy,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loanId, fieldErr := common.IdParam(r, "loanId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		loan, err := loanStore.Renew(ctx, loanId, middleware.UserID(ctx), policy)
+		if err != nil {
+			err = fmt.Errorf("loanStore.Renew: %w", err)
+			response.Error(w, loanError(ctx, wlog, err, "failed to renew loan"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newLoanResponse(loan))
+	}
+}
+
+func ListMine(
+	zlog zerolog.Logger,
+	loanStore store.LoanStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		activeOnly := true
+		switch r.URL.Query().Get("status") {
+		case "", store.LoanStatusActive:
+		case "all":
+			activeOnly = false
+		default:
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "status",
+				Message: "status must be active or all",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		loans, err := loanStore.FindByUserId(ctx, middleware.UserID(ctx), activeOnly, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("loanStore.FindByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find loans by user_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]LoanResponse, 0, len(loans))
+		for _, loan := range loans {
+			res = append(res, newLoanResponse(loan))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func Availability(
+	zlog zerolog.Logger,
+	loanStore store.LoanStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		availability, err := loanStore.FindAvailabilityByBookId(ctx, bookId)
+		if err != nil {
+			err = fmt.Errorf("loanStore.FindAvailabilityByBookId: %w", err)
+			response.Error(w, loanError(ctx, wlog, err, "failed to find availability by book_id"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newAvailabilityResponse(availability))
+	}
+}
+
+func UpdateCopies(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	loanStore store.LoanStore,
+	holdQueue *circulation.HoldQueue,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := CopiesRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if req.Copies < 0 {
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "copies",
+				Message: "copies cannot be negative",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, err := bookStore.FindOneById(ctx, bookId); err != nil {
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			response.Error(w, loanError(ctx, wlog, err, "failed to find one by id"))
+			return
+		}
+		if err := bookStore.UpdateCopiesById(ctx, req.Copies, bookId); err != nil {
+			err = fmt.Errorf("bookStore.UpdateCopiesById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to update copies by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		promote(ctx, wlog, holdQueue, bookId)
+		availability, err := loanStore.FindAvailabilityByBookId(ctx, bookId)
+		if err != nil {
+			err = fmt.Errorf("loanStore.FindAvailabilityByBookId: %w", err)
+			response.Error(w, loanError(ctx, wlog, err, "failed to find availability by book_id"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newAvailabilityResponse(availability))
+	}
+}
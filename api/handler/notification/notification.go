@@ -0,0 +1,151 @@
+package notification
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type NotificationResponse struct {
+	ID        int             `json:"id"`
+	Kind      string          `json:"kind"`
+	Data      json.RawMessage `json:"data"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type CountResponse struct {
+	Count int64 `json:"count"`
+}
+
+func newNotificationResponse(notification *store.Notification) NotificationResponse {
+	res := NotificationResponse{
+		ID:        notification.ID,
+		Kind:      notification.Kind,
+		Data:      notification.Data,
+		CreatedAt: notification.CreatedAt,
+	}
+	if notification.ReadAt.Valid {
+		res.ReadAt = &notification.ReadAt.Time
+	}
+	return res
+}
+
+// List returns the notifications of the user, newest first, all of them or
+// the unread ones with status=unread.
+func List(
+	zlog zerolog.Logger,
+	notificationStore store.NotificationStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		unreadOnly := false
+		switch r.URL.Query().Get("status") {
+		case "", "all":
+		case "unread":
+			unreadOnly = true
+		default:
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "status",
+				Message: "status must be all or unread",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		notifications, err := notificationStore.FindByUserId(ctx, middleware.UserID(ctx), unreadOnly, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("notificationStore.FindByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find notifications by user_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]NotificationResponse, 0, len(notifications))
+		for _, notification := range notifications {
+			res = append(res, newNotificationResponse(notification))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func UnreadCount(
+	zlog zerolog.Logger,
+	notificationStore store.NotificationStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		count, err := notificationStore.CountUnread(ctx, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("notificationStore.CountUnread: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to count unread notifications")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, CountResponse{Count: int64(count)})
+	}
+}
+
+func MarkRead(
+	zlog zerolog.Logger,
+	notificationStore store.NotificationStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := notificationStore.MarkRead(ctx, id, middleware.UserID(ctx)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("notificationStore.MarkRead: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to mark notification read")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+// MarkAllRead marks every unread notification of the user read and returns
+// how many there were.
+func MarkAllRead(
+	zlog zerolog.Logger,
+	notificationStore store.NotificationStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		count, err := notificationStore.MarkAllRead(ctx, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("notificationStore.MarkAllRead: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to mark notifications read")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, CountResponse{Count: count})
+	}
+}
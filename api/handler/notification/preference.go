@@ -0,0 +1,118 @@
+package notification
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// PreferencesRequest maps a notification category to whether its mails are
+// sent, categories left out keep their preference.
+type PreferencesRequest map[string]bool
+
+type PreferencesResponse map[string]bool
+
+func (pr PreferencesRequest) validateRequest() *apierror.UnprocessableEntity {
+	for category := range pr {
+		if isCategory(category) {
+			continue
+		}
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    category,
+			Message: fmt.Sprintf("category must be one of %s", strings.Join(store.NotificationCategories, ", ")),
+		})
+		return &fieldErr
+	}
+	return nil
+}
+
+func isCategory(category string) bool {
+	for _, c := range store.NotificationCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func newPreferencesResponse(preferences []*store.NotificationPreference) PreferencesResponse {
+	res := PreferencesResponse{}
+	for _, preference := range preferences {
+		res[preference.Category] = preference.Enabled
+	}
+	return res
+}
+
+func GetPreferences(
+	zlog zerolog.Logger,
+	preferenceStore store.NotificationPreferenceStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		preferences, err := preferenceStore.FindByUserId(ctx, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("preferenceStore.FindByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find notification preferences")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newPreferencesResponse(preferences))
+	}
+}
+
+func UpdatePreferences(
+	zlog zerolog.Logger,
+	preferenceStore store.NotificationPreferenceStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := PreferencesRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		userId := middleware.UserID(ctx)
+		for _, category := range store.NotificationCategories {
+			enabled, ok := req[category]
+			if !ok {
+				continue
+			}
+			preference := &store.NotificationPreference{
+				UserID:   userId,
+				Category: category,
+				Enabled:  enabled,
+			}
+			if err := preferenceStore.Upsert(ctx, preference); err != nil {
+				err = fmt.Errorf("preferenceStore.Upsert: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to upsert notification preference")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+		}
+		preferences, err := preferenceStore.FindByUserId(ctx, userId)
+		if err != nil {
+			err = fmt.Errorf("preferenceStore.FindByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find notification preferences")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newPreferencesResponse(preferences))
+	}
+}
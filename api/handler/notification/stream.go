@@ -0,0 +1,118 @@
+package notification
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/notify"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// streamHeartbeat keeps proxies from closing an idle stream.
+	streamHeartbeat = 25 * time.Second
+	// streamCatchUpLimit caps the notifications replayed to a reconnecting
+	// client, a client gone for longer lists the rest.
+	streamCatchUpLimit = 100
+)
+
+// Stream sends the notifications of the user as Server-Sent Events while
+// the connection lasts. A client reconnecting with Last-Event-ID first
+// gets the notifications it missed.
+func Stream(
+	zlog zerolog.Logger,
+	notificationStore store.NotificationStore,
+	hub *notify.Hub,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		lastId := 0
+		if header := r.Header.Get("Last-Event-ID"); header != "" {
+			id, err := strconv.Atoi(header)
+			if err != nil || id < 0 {
+				response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+					Name:    "Last-Event-ID",
+					Message: "Last-Event-ID must be a notification id",
+				}))
+				return
+			}
+			lastId = id
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		userId := middleware.UserID(ctx)
+
+		// Subscribe before catching up, so nothing inserted in between is
+		// lost. Notifications seen twice are skipped by id.
+		notifications, unsubscribe := hub.Subscribe(userId)
+		defer unsubscribe()
+		var missed []*store.Notification
+		if lastId > 0 {
+			var err error
+			missed, err = notificationStore.FindAfter(ctx, userId, lastId, streamCatchUpLimit)
+			if err != nil {
+				err = fmt.Errorf("notificationStore.FindAfter: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to find missed notifications")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		for _, notification := range missed {
+			if err := writeEvent(w, notification); err != nil {
+				return
+			}
+			lastId = notification.ID
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case notification := <-notifications:
+				if notification.ID <= lastId {
+					continue
+				}
+				if err := writeEvent(w, notification); err != nil {
+					return
+				}
+				lastId = notification.ID
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, notification *store.Notification) error {
+	data, err := json.Marshal(newNotificationResponse(notification))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", notification.ID, data)
+	return err
+}
@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	mailer "awesome-api/mail"
+	"awesome-api/store"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+type UnsubscribeResponse struct {
+	Category string `json:"category"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// Unsubscribe is the target of the List-Unsubscribe header. Mail clients
+// POST List-Unsubscribe=One-Click to it (RFC 8058), the signed token alone
+// identifies the user, so it takes no authentication.
+func Unsubscribe(
+	zlog zerolog.Logger,
+	preferenceStore store.NotificationPreferenceStore,
+	unsubscribe *mailer.Unsubscribe,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, category, ok := unsubscribe.Verify(r.URL.Query().Get("token"))
+		if !ok || !isCategory(category) {
+			response.Error(w, apierror.ClientInvalidToken())
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		preference := &store.NotificationPreference{
+			UserID:   userId,
+			Category: category,
+			Enabled:  false,
+		}
+		if err := preferenceStore.Upsert(ctx, preference); err != nil {
+			err = fmt.Errorf("preferenceStore.Upsert: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to unsubscribe")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, UnsubscribeResponse{
+			Category: category,
+			Enabled:  false,
+		})
+	}
+}
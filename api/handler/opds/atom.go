@@ -0,0 +1,112 @@
+package opds
+
+import (
+	"encoding/xml"
+	"net/http"
+	"time"
+)
+
+// OPDS 1.2 is an Atom profile, the kinds tell navigation feeds, which
+// lead to other feeds, from acquisition feeds, which list books.
+const (
+	atomNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	atomAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	openSearchType      = "application/opensearchdescription+xml"
+
+	atomNamespace   = "http://www.w3.org/2005/Atom"
+	opdsNamespace   = "http://opds-spec.org/2010/catalog"
+	dcNamespace     = "http://purl.org/dc/terms/"
+	threadNamespace = "http://purl.org/syndication/thread/1.0"
+	searchNamespace = "http://a9.com/-/spec/opensearch/1.1/"
+)
+
+type atomFeed struct {
+	XMLName      xml.Name    `xml:"feed"`
+	Xmlns        string      `xml:"xmlns,attr"`
+	XmlnsOPDS    string      `xml:"xmlns:opds,attr"`
+	XmlnsDC      string      `xml:"xmlns:dc,attr"`
+	XmlnsThread  string      `xml:"xmlns:thr,attr"`
+	XmlnsSearch  string      `xml:"xmlns:opensearch,attr"`
+	ID           string      `xml:"id"`
+	Title        string      `xml:"title"`
+	Updated      time.Time   `xml:"updated"`
+	TotalResults *int        `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage *int        `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   *int        `xml:"opensearch:startIndex,omitempty"`
+	Links        []atomLink  `xml:"link"`
+	Entries      []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel         string `xml:"rel,attr,omitempty"`
+	Href        string `xml:"href,attr"`
+	Type        string `xml:"type,attr,omitempty"`
+	Title       string `xml:"title,attr,omitempty"`
+	FacetGroup  string `xml:"opds:facetGroup,attr,omitempty"`
+	ActiveFacet string `xml:"opds:activeFacet,attr,omitempty"`
+	Count       int    `xml:"thr:count,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    time.Time      `xml:"updated"`
+	Authors    []atomAuthor   `xml:"author,omitempty"`
+	Language   string         `xml:"dc:language,omitempty"`
+	Identifier string         `xml:"dc:identifier,omitempty"`
+	Publisher  string         `xml:"dc:publisher,omitempty"`
+	Issued     string         `xml:"dc:issued,omitempty"`
+	Categories []atomCategory `xml:"category,omitempty"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Links      []atomLink     `xml:"link"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func newAtomFeed(id, title string, now time.Time) *atomFeed {
+	return &atomFeed{
+		Xmlns:       atomNamespace,
+		XmlnsOPDS:   opdsNamespace,
+		XmlnsDC:     dcNamespace,
+		XmlnsThread: threadNamespace,
+		XmlnsSearch: searchNamespace,
+		ID:          id,
+		Title:       title,
+		Updated:     now,
+	}
+}
+
+func writeAtom(w http.ResponseWriter, contentType string, feed *atomFeed) {
+	w.Header().Set("Content-Type", contentType+";charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(feed)
+}
+
+type openSearchDescription struct {
+	XMLName        xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	ShortName      string          `xml:"ShortName"`
+	Description    string          `xml:"Description"`
+	InputEncoding  string          `xml:"InputEncoding"`
+	OutputEncoding string          `xml:"OutputEncoding"`
+	URLs           []openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
@@ -0,0 +1,330 @@
+// Package opds serves the catalogue to e-reader apps as OPDS 1.2 (Atom) and
+// OPDS 2.0 (JSON) feeds. Both versions expose the same feeds: a root
+// navigation feed, category and author navigation feeds, and paginated
+// acquisition feeds of books filtered like the book listing.
+package opds
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+const catalogTitle = "Awesome Library"
+
+// Feed paths, relative to where the OPDS routes are mounted.
+const (
+	rootPath       = "/opds"
+	v2Path         = "/opds/v2"
+	booksSegment   = "/books"
+	catalogSegment = "/categories"
+	authorsSegment = "/authors"
+	searchPath     = rootPath + "/search.xml"
+)
+
+const issuedDateForm = "2006-01-02"
+
+// booksPage is one page of an acquisition feed with what its links and
+// facets need.
+type booksPage struct {
+	filter     store.BookFilter
+	page       common.Pagination
+	books      []*store.CatalogBook
+	hasNext    bool
+	files      map[int][]*store.BookFile
+	categories []*store.Category
+	query      url.Values
+}
+
+type loader struct {
+	zlog          zerolog.Logger
+	bookStore     store.BookStore
+	bookFileStore store.BookFileStore
+	categoryStore store.CategoryStore
+}
+
+// loadBooks reads the page the request asks for, answering the request
+// itself when it cannot.
+func (l *loader) loadBooks(w http.ResponseWriter, r *http.Request) (*booksPage, bool) {
+	filter, fieldErr := common.ParseBookFilter(r)
+	if fieldErr != nil {
+		response.ValidationError(w, *fieldErr)
+		return nil, false
+	}
+	page, fieldErr := common.ParsePagination(r)
+	if fieldErr != nil {
+		response.ValidationError(w, *fieldErr)
+		return nil, false
+	}
+	ctx := r.Context()
+	books, err := l.bookStore.FindAll(ctx, filter, page.Limit+1, page.Offset)
+	if err != nil {
+		l.serverError(ctx, w, fmt.Errorf("bookStore.FindAll: %w", err), "failed to find all books")
+		return nil, false
+	}
+	res := &booksPage{
+		filter:  filter,
+		page:    page,
+		books:   books,
+		hasNext: len(books) > page.Limit,
+		files:   map[int][]*store.BookFile{},
+		query:   r.URL.Query(),
+	}
+	if res.hasNext {
+		res.books = books[:page.Limit]
+	}
+	if len(res.books) > 0 {
+		ids := make([]int, 0, len(res.books))
+		for _, book := range res.books {
+			ids = append(ids, book.ID)
+		}
+		files, err := l.bookFileStore.FindByBookIds(ctx, ids)
+		if err != nil {
+			l.serverError(ctx, w, fmt.Errorf("bookFileStore.FindByBookIds: %w", err), "failed to find files by book_ids")
+			return nil, false
+		}
+		for _, file := range files {
+			res.files[file.BookID] = append(res.files[file.BookID], file)
+		}
+	}
+	if res.categories, err = l.categoryStore.FindAll(ctx); err != nil {
+		l.serverError(ctx, w, fmt.Errorf("categoryStore.FindAll: %w", err), "failed to find all categories")
+		return nil, false
+	}
+	return res, true
+}
+
+func (l *loader) serverError(ctx context.Context, w http.ResponseWriter, err error, msg string) {
+	wlog := common.WrapperZlog{Logger: &l.zlog}
+	wlog.Error(ctx).
+		Err(err).Msg(msg)
+	response.Error(w, apierror.ServerError())
+}
+
+// link returns base with the query of the page, changed by set. Setting an
+// empty value removes the parameter.
+func (p *booksPage) link(base string, set map[string]string) string {
+	query := url.Values{}
+	for k, v := range p.query {
+		query[k] = v
+	}
+	for k, v := range set {
+		if v == "" {
+			query.Del(k)
+		} else {
+			query.Set(k, v)
+		}
+	}
+	if len(query) == 0 {
+		return base
+	}
+	return base + "?" + query.Encode()
+}
+
+func (p *booksPage) pageLinks(base string) map[string]string {
+	links := map[string]string{
+		"self": p.link(base, nil),
+	}
+	if p.page.Offset > 0 {
+		links["first"] = p.link(base, map[string]string{"offset": ""})
+		prev := p.page.Offset - p.page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links["previous"] = p.link(base, map[string]string{"offset": offsetValue(prev)})
+	}
+	if p.hasNext {
+		links["next"] = p.link(base, map[string]string{"offset": offsetValue(p.page.Offset + p.page.Limit)})
+	}
+	return links
+}
+
+func offsetValue(offset int) string {
+	if offset == 0 {
+		return ""
+	}
+	return strconv.Itoa(offset)
+}
+
+type facet struct {
+	group  string
+	title  string
+	href   string
+	count  int
+	active bool
+}
+
+// facets offers every category, and the authors credited on the page,
+// each keeping the other filters of the page.
+func (p *booksPage) facets(base string) []facet {
+	var facets []facet
+	for _, category := range p.categories {
+		if category.BookCount == 0 {
+			continue
+		}
+		facets = append(facets, facet{
+			group:  "Category",
+			title:  category.Name,
+			href:   p.link(base, map[string]string{"category": strconv.Itoa(category.ID), "offset": ""}),
+			count:  category.BookCount,
+			active: p.filter.CategoryID == category.ID,
+		})
+	}
+	seen := map[int]bool{}
+	for _, book := range p.books {
+		for _, author := range book.Authors {
+			if seen[author.AuthorID] {
+				continue
+			}
+			seen[author.AuthorID] = true
+			facets = append(facets, facet{
+				group:  "Author",
+				title:  author.Name,
+				href:   p.link(base, map[string]string{"author": strconv.Itoa(author.AuthorID), "offset": ""}),
+				active: p.filter.AuthorID == author.AuthorID,
+			})
+		}
+	}
+	return facets
+}
+
+func bookURN(id int) string {
+	return fmt.Sprintf("urn:awesome-api:book:%d", id)
+}
+
+func downloadHref(file *store.BookFile) string {
+	return fmt.Sprintf("%s/books/%d/files/%s", rootPath, file.BookID, file.Format)
+}
+
+func coverHref(book *store.CatalogBook, size string) string {
+	return fmt.Sprintf("/books/%d/cover?size=%s", book.ID, size)
+}
+
+func booksTitle(p *booksPage) string {
+	switch {
+	case p.filter.Query != "":
+		return fmt.Sprintf("Search results for %q", p.filter.Query)
+	case p.filter.CategoryID != 0:
+		for _, category := range p.categories {
+			if category.ID == p.filter.CategoryID {
+				return category.Name
+			}
+		}
+	}
+	return "All books"
+}
+
+// Handlers serves both OPDS versions from the same stores.
+type Handlers struct {
+	loader
+	authorStore store.AuthorStore
+}
+
+func NewHandlers(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	bookFileStore store.BookFileStore,
+	categoryStore store.CategoryStore,
+	authorStore store.AuthorStore,
+) *Handlers {
+	return &Handlers{
+		loader: loader{
+			zlog:          zlog,
+			bookStore:     bookStore,
+			bookFileStore: bookFileStore,
+			categoryStore: categoryStore,
+		},
+		authorStore: authorStore,
+	}
+}
+
+// Routes mounts the feeds; download is served under the same
+// authentication so e-readers can follow acquisition links.
+func (h *Handlers) Routes(r chi.Router, download http.HandlerFunc) {
+	r.Get(rootPath, h.Root)
+	r.Get(rootPath+booksSegment, h.Books)
+	r.Get(rootPath+catalogSegment, h.Categories)
+	r.Get(rootPath+authorsSegment, h.Authors)
+	r.Get(searchPath, h.OpenSearch)
+	r.Get(rootPath+"/books/{id}/files/{format}", download)
+
+	r.Get(v2Path, h.RootV2)
+	r.Get(v2Path+booksSegment, h.BooksV2)
+	r.Get(v2Path+catalogSegment, h.CategoriesV2)
+	r.Get(v2Path+authorsSegment, h.AuthorsV2)
+}
+
+// authorsPage is one page of the author navigation feed.
+type authorsPage struct {
+	authors []*store.Author
+	page    common.Pagination
+	hasNext bool
+}
+
+func (p *authorsPage) pageLinks(base string) map[string]string {
+	links := map[string]string{
+		"self": pageHref(base, p.page.Offset),
+	}
+	if p.page.Offset > 0 {
+		links["first"] = base
+		prev := p.page.Offset - p.page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links["previous"] = pageHref(base, prev)
+	}
+	if p.hasNext {
+		links["next"] = pageHref(base, p.page.Offset+p.page.Limit)
+	}
+	return links
+}
+
+func pageHref(base string, offset int) string {
+	if offset == 0 {
+		return base
+	}
+	return base + "?offset=" + strconv.Itoa(offset)
+}
+
+func (h *Handlers) loadAuthors(w http.ResponseWriter, r *http.Request) (*authorsPage, bool) {
+	page, fieldErr := common.ParsePagination(r)
+	if fieldErr != nil {
+		response.ValidationError(w, *fieldErr)
+		return nil, false
+	}
+	ctx := r.Context()
+	authors, err := h.authorStore.Search(ctx, "", page.Limit+1, page.Offset)
+	if err != nil {
+		h.serverError(ctx, w, fmt.Errorf("authorStore.Search: %w", err), "failed to search authors")
+		return nil, false
+	}
+	res := &authorsPage{authors: authors, page: page, hasNext: len(authors) > page.Limit}
+	if res.hasNext {
+		res.authors = authors[:page.Limit]
+	}
+	return res, true
+}
+
+func (h *Handlers) loadCategories(w http.ResponseWriter, r *http.Request) ([]*store.Category, bool) {
+	ctx := r.Context()
+	categories, err := h.categoryStore.FindAll(ctx)
+	if err != nil {
+		h.serverError(ctx, w, fmt.Errorf("categoryStore.FindAll: %w", err), "failed to find all categories")
+		return nil, false
+	}
+	return categories, true
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
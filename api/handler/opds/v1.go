@@ -0,0 +1,207 @@
+package opds
+
+import (
+	"awesome-api/store"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func navigationEntry(id, title, href, content string, updated time.Time) atomEntry {
+	return atomEntry{
+		ID:      id,
+		Title:   title,
+		Updated: updated,
+		Content: &atomText{Type: "text", Body: content},
+		Links: []atomLink{
+			{Rel: "subsection", Href: href, Type: atomAcquisitionType},
+		},
+	}
+}
+
+func startLinks(selfHref, selfType string) []atomLink {
+	return []atomLink{
+		{Rel: "self", Href: selfHref, Type: selfType},
+		{Rel: "start", Href: rootPath, Type: atomNavigationType, Title: catalogTitle},
+		{Rel: "search", Href: searchPath, Type: openSearchType},
+	}
+}
+
+// Root is the OPDS 1.2 entry point.
+func (h *Handlers) Root(w http.ResponseWriter, r *http.Request) {
+	updated := now()
+	feed := newAtomFeed("urn:awesome-api:opds", catalogTitle, updated)
+	feed.Links = startLinks(rootPath, atomNavigationType)
+	feed.Entries = []atomEntry{
+		navigationEntry("urn:awesome-api:opds:books", "All books", rootPath+booksSegment, "Every book in the catalogue", updated),
+		navigationEntry("urn:awesome-api:opds:categories", "Categories", rootPath+catalogSegment, "Books by category", updated),
+		navigationEntry("urn:awesome-api:opds:authors", "Authors", rootPath+authorsSegment, "Books by author", updated),
+	}
+	// Only the books entry leads straight to an acquisition feed.
+	for i := range feed.Entries[1:] {
+		feed.Entries[i+1].Links[0].Type = atomNavigationType
+	}
+	writeAtom(w, atomNavigationType, feed)
+}
+
+// Books is the acquisition feed, taking the filters and pagination of the
+// book listing.
+func (h *Handlers) Books(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.loadBooks(w, r)
+	if !ok {
+		return
+	}
+	base := rootPath + booksSegment
+	updated := now()
+	feed := newAtomFeed("urn:awesome-api:opds:books", booksTitle(p), updated)
+	feed.ItemsPerPage = &p.page.Limit
+	startIndex := p.page.Offset + 1
+	feed.StartIndex = &startIndex
+	if !p.hasNext {
+		total := p.page.Offset + len(p.books)
+		feed.TotalResults = &total
+	}
+
+	pageLinks := p.pageLinks(base)
+	feed.Links = startLinks(pageLinks["self"], atomAcquisitionType)
+	for _, rel := range []string{"first", "previous", "next"} {
+		if href, ok := pageLinks[rel]; ok {
+			feed.Links = append(feed.Links, atomLink{Rel: rel, Href: href, Type: atomAcquisitionType})
+		}
+	}
+	for _, f := range p.facets(base) {
+		link := atomLink{
+			Rel:        facetRel,
+			Href:       f.href,
+			Type:       atomAcquisitionType,
+			Title:      f.title,
+			FacetGroup: f.group,
+			Count:      f.count,
+		}
+		if f.active {
+			link.ActiveFacet = "true"
+		}
+		feed.Links = append(feed.Links, link)
+	}
+
+	feed.Entries = make([]atomEntry, 0, len(p.books))
+	for _, book := range p.books {
+		feed.Entries = append(feed.Entries, bookEntry(book, p.files[book.ID], updated))
+	}
+	writeAtom(w, atomAcquisitionType, feed)
+}
+
+func bookEntry(book *store.CatalogBook, files []*store.BookFile, updated time.Time) atomEntry {
+	entry := atomEntry{
+		ID:        bookURN(book.ID),
+		Title:     book.Title,
+		Updated:   updated,
+		Language:  book.Language,
+		Publisher: book.Publisher,
+	}
+	if book.ISBN13.Valid {
+		entry.Identifier = "urn:isbn:" + book.ISBN13.String
+	}
+	if book.PublishedDate.Valid {
+		entry.Issued = book.PublishedDate.Time.Format(issuedDateForm)
+	}
+	for _, author := range book.Authors {
+		entry.Authors = append(entry.Authors, atomAuthor{Name: author.Name})
+	}
+	if book.CategoryName != "" {
+		entry.Categories = []atomCategory{{Term: strconv.Itoa(book.CategoryID), Label: book.CategoryName}}
+	}
+	if book.Synopsis != "" {
+		entry.Summary = &atomText{Type: "text", Body: book.Synopsis}
+	}
+	if book.Cover != "" {
+		entry.Links = append(entry.Links,
+			atomLink{Rel: imageRel, Href: coverHref(book, "large"), Type: coverContentType},
+			atomLink{Rel: thumbnailRel, Href: coverHref(book, "small"), Type: coverContentType},
+		)
+	}
+	for _, file := range files {
+		entry.Links = append(entry.Links, atomLink{
+			Rel:  acquisitionRel,
+			Href: downloadHref(file),
+			Type: file.ContentType,
+		})
+	}
+	return entry
+}
+
+// Categories is a navigation feed leading to the books of each category.
+func (h *Handlers) Categories(w http.ResponseWriter, r *http.Request) {
+	categories, ok := h.loadCategories(w, r)
+	if !ok {
+		return
+	}
+	updated := now()
+	feed := newAtomFeed("urn:awesome-api:opds:categories", "Categories", updated)
+	feed.Links = startLinks(rootPath+catalogSegment, atomNavigationType)
+	feed.Entries = make([]atomEntry, 0, len(categories))
+	for _, category := range categories {
+		entry := navigationEntry(
+			fmt.Sprintf("urn:awesome-api:category:%d", category.ID),
+			category.Name,
+			fmt.Sprintf("%s%s?category=%d", rootPath, booksSegment, category.ID),
+			fmt.Sprintf("%d books", category.BookCount),
+			updated,
+		)
+		entry.Links[0].Count = category.BookCount
+		feed.Entries = append(feed.Entries, entry)
+	}
+	writeAtom(w, atomNavigationType, feed)
+}
+
+// Authors is a paginated navigation feed leading to the books of each
+// author.
+func (h *Handlers) Authors(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.loadAuthors(w, r)
+	if !ok {
+		return
+	}
+	base := rootPath + authorsSegment
+	updated := now()
+	feed := newAtomFeed("urn:awesome-api:opds:authors", "Authors", updated)
+	pageLinks := p.pageLinks(base)
+	feed.Links = startLinks(pageLinks["self"], atomNavigationType)
+	for _, rel := range []string{"first", "previous", "next"} {
+		if href, ok := pageLinks[rel]; ok {
+			feed.Links = append(feed.Links, atomLink{Rel: rel, Href: href, Type: atomNavigationType})
+		}
+	}
+	feed.Entries = make([]atomEntry, 0, len(p.authors))
+	for _, author := range p.authors {
+		entry := navigationEntry(
+			fmt.Sprintf("urn:awesome-api:author:%d", author.ID),
+			author.Name,
+			fmt.Sprintf("%s%s?author=%d", rootPath, booksSegment, author.ID),
+			fmt.Sprintf("%d books", author.BookCount),
+			updated,
+		)
+		entry.Links[0].Count = author.BookCount
+		feed.Entries = append(feed.Entries, entry)
+	}
+	writeAtom(w, atomNavigationType, feed)
+}
+
+// OpenSearch describes how OPDS 1.2 clients search the catalogue.
+func (h *Handlers) OpenSearch(w http.ResponseWriter, r *http.Request) {
+	desc := openSearchDescription{
+		Xmlns:          searchNamespace,
+		ShortName:      catalogTitle,
+		Description:    "Search the " + catalogTitle + " catalogue",
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URLs: []openSearchURL{
+			{Type: atomAcquisitionType, Template: rootPath + booksSegment + "?q={searchTerms}"},
+		},
+	}
+	w.Header().Set("Content-Type", openSearchType+";charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(desc)
+}
@@ -0,0 +1,273 @@
+package opds
+
+import (
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// OPDS 2.0 is JSON based, built on the Readium Web Publication Manifest.
+const (
+	opds2Type        = "application/opds+json"
+	opds2PubType     = "application/opds-publication+json"
+	acquisitionRel   = "http://opds-spec.org/acquisition"
+	imageRel         = "http://opds-spec.org/image"
+	thumbnailRel     = "http://opds-spec.org/image/thumbnail"
+	facetRel         = "http://opds-spec.org/facet"
+	schemaBookType   = "http://schema.org/Book"
+	coverContentType = "image/jpeg"
+)
+
+type opds2Feed struct {
+	Metadata     opds2FeedMetadata  `json:"metadata"`
+	Links        []opds2Link        `json:"links"`
+	Navigation   []opds2Link        `json:"navigation,omitempty"`
+	Facets       []opds2Facet       `json:"facets,omitempty"`
+	Publications []opds2Publication `json:"publications,omitempty"`
+}
+
+type opds2FeedMetadata struct {
+	Title         string `json:"title"`
+	NumberOfItems *int   `json:"numberOfItems,omitempty"`
+	ItemsPerPage  *int   `json:"itemsPerPage,omitempty"`
+}
+
+type opds2Link struct {
+	Href       string           `json:"href"`
+	Type       string           `json:"type,omitempty"`
+	Rel        string           `json:"rel,omitempty"`
+	Title      string           `json:"title,omitempty"`
+	Templated  bool             `json:"templated,omitempty"`
+	Properties *opds2Properties `json:"properties,omitempty"`
+}
+
+type opds2Properties struct {
+	NumberOfItems int `json:"numberOfItems,omitempty"`
+}
+
+type opds2Facet struct {
+	Metadata opds2FeedMetadata `json:"metadata"`
+	Links    []opds2Link       `json:"links"`
+}
+
+type opds2Publication struct {
+	Metadata opds2PubMetadata `json:"metadata"`
+	Links    []opds2Link      `json:"links"`
+	Images   []opds2Link      `json:"images,omitempty"`
+}
+
+type opds2PubMetadata struct {
+	Type          string         `json:"@type"`
+	Identifier    string         `json:"identifier,omitempty"`
+	Title         string         `json:"title"`
+	Author        []opds2Contrib `json:"author,omitempty"`
+	Editor        []opds2Contrib `json:"editor,omitempty"`
+	Translator    []opds2Contrib `json:"translator,omitempty"`
+	Language      string         `json:"language,omitempty"`
+	Publisher     string         `json:"publisher,omitempty"`
+	Published     string         `json:"published,omitempty"`
+	Description   string         `json:"description,omitempty"`
+	NumberOfPages int32          `json:"numberOfPages,omitempty"`
+	Subject       []opds2Contrib `json:"subject,omitempty"`
+}
+
+type opds2Contrib struct {
+	Name  string      `json:"name"`
+	Links []opds2Link `json:"links,omitempty"`
+}
+
+func writeOPDS2(w http.ResponseWriter, feed *opds2Feed) {
+	w.Header().Set("Content-Type", opds2Type)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(feed)
+}
+
+func v2StartLinks(selfHref string) []opds2Link {
+	return []opds2Link{
+		{Rel: "self", Href: selfHref, Type: opds2Type},
+		{Rel: "start", Href: v2Path, Type: opds2Type, Title: catalogTitle},
+		{Rel: "search", Href: v2Path + booksSegment + "{?q}", Type: opds2Type, Templated: true},
+	}
+}
+
+func v2PageLinks(links map[string]string) []opds2Link {
+	var res []opds2Link
+	for _, rel := range []string{"first", "previous", "next"} {
+		if href, ok := links[rel]; ok {
+			res = append(res, opds2Link{Rel: rel, Href: href, Type: opds2Type})
+		}
+	}
+	return res
+}
+
+// RootV2 is the OPDS 2.0 entry point.
+func (h *Handlers) RootV2(w http.ResponseWriter, r *http.Request) {
+	writeOPDS2(w, &opds2Feed{
+		Metadata: opds2FeedMetadata{Title: catalogTitle},
+		Links:    v2StartLinks(v2Path),
+		Navigation: []opds2Link{
+			{Href: v2Path + booksSegment, Type: opds2Type, Title: "All books"},
+			{Href: v2Path + catalogSegment, Type: opds2Type, Title: "Categories"},
+			{Href: v2Path + authorsSegment, Type: opds2Type, Title: "Authors"},
+		},
+	})
+}
+
+// BooksV2 lists publications, taking the filters and pagination of the book
+// listing.
+func (h *Handlers) BooksV2(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.loadBooks(w, r)
+	if !ok {
+		return
+	}
+	base := v2Path + booksSegment
+	pageLinks := p.pageLinks(base)
+	feed := &opds2Feed{
+		Metadata: opds2FeedMetadata{
+			Title:        booksTitle(p),
+			ItemsPerPage: &p.page.Limit,
+		},
+		Links:        append(v2StartLinks(pageLinks["self"]), v2PageLinks(pageLinks)...),
+		Publications: make([]opds2Publication, 0, len(p.books)),
+	}
+	if !p.hasNext {
+		total := p.page.Offset + len(p.books)
+		feed.Metadata.NumberOfItems = &total
+	}
+
+	groups := map[string]int{}
+	for _, f := range p.facets(base) {
+		i, ok := groups[f.group]
+		if !ok {
+			i = len(feed.Facets)
+			groups[f.group] = i
+			feed.Facets = append(feed.Facets, opds2Facet{Metadata: opds2FeedMetadata{Title: f.group}})
+		}
+		link := opds2Link{Href: f.href, Type: opds2Type, Title: f.title}
+		if f.active {
+			link.Rel = "self"
+		}
+		if f.count > 0 {
+			link.Properties = &opds2Properties{NumberOfItems: f.count}
+		}
+		feed.Facets[i].Links = append(feed.Facets[i].Links, link)
+	}
+
+	for _, book := range p.books {
+		feed.Publications = append(feed.Publications, publication(book, p.files[book.ID]))
+	}
+	writeOPDS2(w, feed)
+}
+
+func publication(book *store.CatalogBook, files []*store.BookFile) opds2Publication {
+	meta := opds2PubMetadata{
+		Type:        schemaBookType,
+		Identifier:  bookURN(book.ID),
+		Title:       book.Title,
+		Language:    book.Language,
+		Publisher:   book.Publisher,
+		Description: book.Synopsis,
+	}
+	if book.ISBN13.Valid {
+		meta.Identifier = "urn:isbn:" + book.ISBN13.String
+	}
+	if book.PublishedDate.Valid {
+		meta.Published = book.PublishedDate.Time.Format(issuedDateForm)
+	}
+	if book.PageCount.Valid {
+		meta.NumberOfPages = book.PageCount.Int32
+	}
+	for _, author := range book.Authors {
+		contrib := opds2Contrib{
+			Name: author.Name,
+			Links: []opds2Link{{
+				Href: fmt.Sprintf("%s%s?author=%d", v2Path, booksSegment, author.AuthorID),
+				Type: opds2Type,
+			}},
+		}
+		switch author.Role {
+		case store.AuthorRoleEditor:
+			meta.Editor = append(meta.Editor, contrib)
+		case store.AuthorRoleTranslator:
+			meta.Translator = append(meta.Translator, contrib)
+		default:
+			meta.Author = append(meta.Author, contrib)
+		}
+	}
+	if book.CategoryName != "" {
+		meta.Subject = []opds2Contrib{{
+			Name: book.CategoryName,
+			Links: []opds2Link{{
+				Href: v2Path + booksSegment + "?category=" + strconv.Itoa(book.CategoryID),
+				Type: opds2Type,
+			}},
+		}}
+	}
+
+	pub := opds2Publication{
+		Metadata: meta,
+		Links:    make([]opds2Link, 0, len(files)),
+	}
+	for _, file := range files {
+		pub.Links = append(pub.Links, opds2Link{
+			Rel:  acquisitionRel,
+			Href: downloadHref(file),
+			Type: file.ContentType,
+		})
+	}
+	if book.Cover != "" {
+		pub.Images = []opds2Link{
+			{Href: coverHref(book, "large"), Type: coverContentType},
+			{Href: coverHref(book, "small"), Type: coverContentType, Rel: "thumbnail"},
+		}
+	}
+	return pub
+}
+
+// CategoriesV2 is a navigation feed leading to the books of each category.
+func (h *Handlers) CategoriesV2(w http.ResponseWriter, r *http.Request) {
+	categories, ok := h.loadCategories(w, r)
+	if !ok {
+		return
+	}
+	feed := &opds2Feed{
+		Metadata:   opds2FeedMetadata{Title: "Categories"},
+		Links:      v2StartLinks(v2Path + catalogSegment),
+		Navigation: make([]opds2Link, 0, len(categories)),
+	}
+	for _, category := range categories {
+		feed.Navigation = append(feed.Navigation, opds2Link{
+			Href:       fmt.Sprintf("%s%s?category=%d", v2Path, booksSegment, category.ID),
+			Type:       opds2Type,
+			Title:      category.Name,
+			Properties: &opds2Properties{NumberOfItems: category.BookCount},
+		})
+	}
+	writeOPDS2(w, feed)
+}
+
+// AuthorsV2 is a paginated navigation feed leading to the books of each
+// author.
+func (h *Handlers) AuthorsV2(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.loadAuthors(w, r)
+	if !ok {
+		return
+	}
+	pageLinks := p.pageLinks(v2Path + authorsSegment)
+	feed := &opds2Feed{
+		Metadata:   opds2FeedMetadata{Title: "Authors", ItemsPerPage: &p.page.Limit},
+		Links:      append(v2StartLinks(pageLinks["self"]), v2PageLinks(pageLinks)...),
+		Navigation: make([]opds2Link, 0, len(p.authors)),
+	}
+	for _, author := range p.authors {
+		feed.Navigation = append(feed.Navigation, opds2Link{
+			Href:       fmt.Sprintf("%s%s?author=%d", v2Path, booksSegment, author.ID),
+			Type:       opds2Type,
+			Title:      author.Name,
+			Properties: &opds2Properties{NumberOfItems: author.BookCount},
+		})
+	}
+	writeOPDS2(w, feed)
+}
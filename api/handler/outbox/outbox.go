@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type EmailResponse struct {
+	ID            int        `json:"id"`
+	Kind          string     `json:"kind"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at"`
+}
+
+type StatsResponse struct {
+	Status string     `json:"status"`
+	Count  int        `json:"count"`
+	Oldest *time.Time `json:"oldest"`
+}
+
+func newEmailResponse(email *store.Email) EmailResponse {
+	res := EmailResponse{
+		ID:            email.ID,
+		Kind:          email.Kind,
+		Recipient:     email.Recipient,
+		Subject:       email.Subject,
+		Status:        email.Status,
+		Attempts:      email.Attempts,
+		NextAttemptAt: email.NextAttemptAt,
+		LastError:     email.LastError,
+		CreatedAt:     email.CreatedAt,
+	}
+	if email.SentAt.Valid {
+		res.SentAt = &email.SentAt.Time
+	}
+	return res
+}
+
+// Stats counts the mails of the outbox by status, a growing pending count
+// or an old oldest pending mail tell the workers are falling behind.
+func Stats(
+	zlog zerolog.Logger,
+	outboxStore store.EmailOutboxStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		stats, err := outboxStore.Stats(ctx)
+		if err != nil {
+			err = fmt.Errorf("outboxStore.Stats: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to count emails")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]StatsResponse, 0, len(stats))
+		for _, stat := range stats {
+			item := StatsResponse{
+				Status: stat.Status,
+				Count:  stat.Count,
+			}
+			if stat.Oldest.Valid {
+				item.Oldest = &stat.Oldest.Time
+			}
+			res = append(res, item)
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+// List returns the mails of a status, the dead letters unless asked
+// otherwise.
+func List(
+	zlog zerolog.Logger,
+	outboxStore store.EmailOutboxStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "":
+			status = store.EmailStatusDead
+		case store.EmailStatusPending, store.EmailStatusSending, store.EmailStatusSent, store.EmailStatusDead,
+			store.EmailStatusUndeliverable:
+		default:
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "status",
+				Message: "status must be pending, sending, sent, dead or undeliverable",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		emails, err := outboxStore.FindByStatus(ctx, status, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("outboxStore.FindByStatus: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find emails by status")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]EmailResponse, 0, len(emails))
+		for _, email := range emails {
+			res = append(res, newEmailResponse(email))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+// Requeue sends a dead mail again, once whatever made it fail is fixed.
+func Requeue(
+	zlog zerolog.Logger,
+	outboxStore store.EmailOutboxStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := outboxStore.Requeue(ctx, id); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				response.Error(w, apierror.ClientNotFound())
+			case errors.Is(err, store.ErrEmailNotDead):
+				response.Error(w, apierror.ClientEmailNotDead())
+			default:
+				err = fmt.Errorf("outboxStore.Requeue: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to requeue email")
+				response.Error(w, apierror.ServerError())
+			}
+			return
+		}
+		email, err := outboxStore.FindOneById(ctx, id)
+		if err != nil {
+			err = fmt.Errorf("outboxStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newEmailResponse(email))
+	}
+}
@@ -0,0 +1,84 @@
+package ranking
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+type RankedBookResponse struct {
+	Position   int       `json:"position"`
+	BookID     int       `json:"book_id"`
+	Title      string    `json:"title"`
+	Score      float64   `json:"score"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+func isRanking(ranking string) bool {
+	switch ranking {
+	case store.RankingTrending, store.RankingMostRead, store.RankingTopRated:
+		return true
+	}
+	return false
+}
+
+// List serves a precomputed ranking, across the catalogue or within the
+// category given by the category parameter.
+func List(
+	zlog zerolog.Logger,
+	rankingStore store.RankingStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ranking := chi.URLParam(r, "ranking")
+		if !isRanking(ranking) {
+			response.Error(w, apierror.ClientNotFound())
+			return
+		}
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		categoryId := store.RankingAllCategories
+		if value := r.URL.Query().Get("category"); value != "" {
+			v, err := strconv.Atoi(value)
+			if err != nil || v <= 0 {
+				response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+					Name:    "category",
+					Message: "category must be a positive number",
+				}))
+				return
+			}
+			categoryId = v
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		books, err := rankingStore.FindRanking(ctx, ranking, categoryId, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("rankingStore.FindRanking: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find ranking")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]RankedBookResponse, 0, len(books))
+		for _, book := range books {
+			res = append(res, RankedBookResponse{
+				Position:   book.Position,
+				BookID:     book.BookID,
+				Title:      book.Title,
+				Score:      book.Score,
+				ComputedAt: book.ComputedAt,
+			})
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
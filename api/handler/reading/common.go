@@ -0,0 +1,32 @@
+package reading
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func findBook(
+	ctx context.Context,
+	wlog common.WrapperZlog,
+	bookStore store.BookStore,
+	bookId int,
+) *apierror.Error {
+	_, err := bookStore.FindOneById(ctx, bookId)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		apiErr := apierror.ClientNotFound()
+		return &apiErr
+	}
+	err = fmt.Errorf("bookStore.FindOneById: %w", err)
+	wlog.Error(ctx).
+		Err(err).Msg("failed to find one by id")
+	apiErr := apierror.ServerError()
+	return &apiErr
+}
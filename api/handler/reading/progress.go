@@ -0,0 +1,180 @@
+package reading
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type ProgressRequest struct {
+	Position   string  `json:"position"`
+	Percentage float64 `json:"percentage"`
+}
+
+type ProgressResponse struct {
+	BookID     int       `json:"book_id"`
+	Position   string    `json:"position"`
+	Percentage float64   `json:"percentage"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+const positionMaxLen = 1024
+
+func (pr *ProgressRequest) validateRequest() *apierror.UnprocessableEntity {
+	if len(pr.Position) > positionMaxLen {
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    "position",
+			Message: fmt.Sprintf("position cannot exceed %d characters", positionMaxLen),
+		})
+		return &fieldErr
+	}
+	if pr.Percentage < 0 || pr.Percentage > 100 {
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    "percentage",
+			Message: "percentage must be between 0 and 100",
+		})
+		return &fieldErr
+	}
+	return nil
+}
+
+func newProgressResponse(progress *store.ReadingProgress) ProgressResponse {
+	return ProgressResponse{
+		BookID:     progress.BookID,
+		Position:   progress.Position,
+		Percentage: progress.Percentage,
+		UpdatedAt:  progress.UpdatedAt,
+	}
+}
+
+func UpdateProgress(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	readingStore store.ReadingStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "bookId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := ProgressRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr = req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		userId := middleware.UserID(ctx)
+		if apiErr := findBook(ctx, wlog, bookStore, bookId); apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		progress := &store.ReadingProgress{
+			UserID:     userId,
+			BookID:     bookId,
+			Position:   req.Position,
+			Percentage: req.Percentage,
+		}
+		if err := readingStore.UpsertProgress(ctx, progress); err != nil {
+			err = fmt.Errorf("readingStore.UpsertProgress: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to upsert progress")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		status := &store.ReadingStatus{
+			UserID: userId,
+			BookID: bookId,
+			Status: store.ReadingStatusReading,
+		}
+		if req.Percentage >= 100 {
+			status.Status = store.ReadingStatusFinished
+		}
+		if err := readingStore.UpsertStatus(ctx, status); err != nil {
+			err = fmt.Errorf("readingStore.UpsertStatus: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to upsert status")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		if err := bookStore.AddReader(ctx, bookId, userId); err != nil {
+			err = fmt.Errorf("bookStore.AddReader: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to add reader")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newProgressResponse(progress))
+	}
+}
+
+func GetProgress(
+	zlog zerolog.Logger,
+	readingStore store.ReadingStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "bookId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		progress, err := readingStore.FindOneProgress(ctx, middleware.UserID(ctx), bookId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("readingStore.FindOneProgress: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one progress")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newProgressResponse(progress))
+	}
+}
+
+func ListProgress(
+	zlog zerolog.Logger,
+	readingStore store.ReadingStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		progresses, err := readingStore.FindProgressByUserId(ctx, middleware.UserID(ctx), page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("readingStore.FindProgressByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find progress by user_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]ProgressResponse, 0, len(progresses))
+		for _, progress := range progresses {
+			res = append(res, newProgressResponse(progress))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,386 @@
+package reading
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+type ShelfRequest struct {
+	Name string `json:"name"`
+}
+
+type ShelfResponse struct {
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Builtin   bool   `json:"builtin"`
+	BookCount int    `json:"book_count"`
+}
+
+type ShelfBookResponse struct {
+	BookID  int       `json:"book_id"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+const shelfNameMaxLen = 60
+
+// builtinShelves are backed by reading_status, so a book sits on at most
+// one of them at a time.
+var builtinShelves = []struct {
+	Key  string
+	Name string
+}{
+	{Key: store.ReadingStatusWantToRead, Name: "Want to read"},
+	{Key: store.ReadingStatusReading, Name: "Currently reading"},
+	{Key: store.ReadingStatusFinished, Name: "Finished"},
+}
+
+func isBuiltinShelf(key string) bool {
+	for _, shelf := range builtinShelves {
+		if shelf.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (sr *ShelfRequest) validateRequest() *apierror.UnprocessableEntity {
+	sr.Name = strings.TrimSpace(sr.Name)
+	var message string
+	switch {
+	case sr.Name == "":
+		message = "name cannot be empty"
+	case len(sr.Name) > shelfNameMaxLen:
+		message = fmt.Sprintf("name cannot exceed %d characters", shelfNameMaxLen)
+	case isBuiltinShelf(sr.Name):
+		message = "name is reserved"
+	default:
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    "name",
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func ListShelves(
+	zlog zerolog.Logger,
+	readingStore store.ReadingStore,
+	shelfStore store.ShelfStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		userId := middleware.UserID(ctx)
+		counts, err := readingStore.CountStatusByUserId(ctx, userId)
+		if err != nil {
+			err = fmt.Errorf("readingStore.CountStatusByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to count status by user_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		shelves, err := shelfStore.FindByUserId(ctx, userId)
+		if err != nil {
+			err = fmt.Errorf("shelfStore.FindByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find shelves by user_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]ShelfResponse, 0, len(builtinShelves)+len(shelves))
+		for _, shelf := range builtinShelves {
+			res = append(res, ShelfResponse{
+				Key:       shelf.Key,
+				Name:      shelf.Name,
+				Builtin:   true,
+				BookCount: counts[shelf.Key],
+			})
+		}
+		for _, shelf := range shelves {
+			res = append(res, newShelfResponse(shelf))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func newShelfResponse(shelf *store.Shelf) ShelfResponse {
+	return ShelfResponse{
+		Key:       strconv.Itoa(shelf.ID),
+		Name:      shelf.Name,
+		BookCount: shelf.BookCount,
+	}
+}
+
+func CreateShelf(
+	zlog zerolog.Logger,
+	shelfStore store.ShelfStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := ShelfRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		userId := middleware.UserID(ctx)
+		shelves, err := shelfStore.FindByUserId(ctx, userId)
+		if err != nil {
+			err = fmt.Errorf("shelfStore.FindByUserId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find shelves by user_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		for _, shelf := range shelves {
+			if strings.EqualFold(shelf.Name, req.Name) {
+				response.Error(w, apierror.ClientShelfAlreadyExists())
+				return
+			}
+		}
+		shelf := &store.Shelf{
+			UserID: userId,
+			Name:   req.Name,
+		}
+		if err = shelfStore.Insert(ctx, shelf); err != nil {
+			err = fmt.Errorf("shelfStore.Insert: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to insert shelf")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusCreated, newShelfResponse(shelf))
+	}
+}
+
+// findOwnShelf resolves a custom shelf key and hides shelves of other users
+// behind a not found error.
+func findOwnShelf(
+	ctx context.Context,
+	wlog common.WrapperZlog,
+	shelfStore store.ShelfStore,
+	key string,
+) (*store.Shelf, *apierror.Error) {
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		apiErr := apierror.ClientNotFound()
+		return nil, &apiErr
+	}
+	shelf, err := shelfStore.FindOneById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			apiErr := apierror.ClientNotFound()
+			return nil, &apiErr
+		}
+		err = fmt.Errorf("shelfStore.FindOneById: %w", err)
+		wlog.Error(ctx).
+			Err(err).Msg("failed to find shelf by id")
+		apiErr := apierror.ServerError()
+		return nil, &apiErr
+	}
+	if shelf.UserID != middleware.UserID(ctx) {
+		apiErr := apierror.ClientNotFound()
+		return nil, &apiErr
+	}
+	return shelf, nil
+}
+
+func DeleteShelf(
+	zlog zerolog.Logger,
+	shelfStore store.ShelfStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		key := chi.URLParam(r, "shelf")
+		if isBuiltinShelf(key) {
+			response.Error(w, apierror.ClientPermissionDenied())
+			return
+		}
+		shelf, apiErr := findOwnShelf(ctx, wlog, shelfStore, key)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if err := shelfStore.DeleteById(ctx, shelf.ID); err != nil {
+			err = fmt.Errorf("shelfStore.DeleteById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to delete shelf by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func ListShelfBooks(
+	zlog zerolog.Logger,
+	readingStore store.ReadingStore,
+	shelfStore store.ShelfStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		key := chi.URLParam(r, "shelf")
+		res := []ShelfBookResponse{}
+		if isBuiltinShelf(key) {
+			statuses, err := readingStore.FindStatusByUserId(ctx, middleware.UserID(ctx), key, page.Limit, page.Offset)
+			if err != nil {
+				err = fmt.Errorf("readingStore.FindStatusByUserId: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to find status by user_id")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+			for _, status := range statuses {
+				res = append(res, ShelfBookResponse{BookID: status.BookID, AddedAt: status.UpdatedAt})
+			}
+			response.GenerateResponse(w, http.StatusOK, res)
+			return
+		}
+		shelf, apiErr := findOwnShelf(ctx, wlog, shelfStore, key)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		books, err := shelfStore.FindBooksByShelfId(ctx, shelf.ID, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("shelfStore.FindBooksByShelfId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find books by shelf_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		for _, book := range books {
+			res = append(res, ShelfBookResponse{BookID: book.BookID, AddedAt: book.AddedAt})
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func AddShelfBook(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	readingStore store.ReadingStore,
+	shelfStore store.ShelfStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "bookId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		userId := middleware.UserID(ctx)
+		if apiErr := findBook(ctx, wlog, bookStore, bookId); apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		key := chi.URLParam(r, "shelf")
+		if isBuiltinShelf(key) {
+			status := &store.ReadingStatus{
+				UserID: userId,
+				BookID: bookId,
+				Status: key,
+			}
+			if err := readingStore.UpsertStatus(ctx, status); err != nil {
+				err = fmt.Errorf("readingStore.UpsertStatus: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to upsert status")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+			if key != store.ReadingStatusWantToRead {
+				if err := bookStore.AddReader(ctx, bookId, userId); err != nil {
+					err = fmt.Errorf("bookStore.AddReader: %w", err)
+					wlog.Error(ctx).
+						Err(err).Msg("failed to add reader")
+					response.Error(w, apierror.ServerError())
+					return
+				}
+			}
+			response.GenerateResponse(w, http.StatusNoContent, nil)
+			return
+		}
+		shelf, apiErr := findOwnShelf(ctx, wlog, shelfStore, key)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if err := shelfStore.AddBook(ctx, shelf.ID, bookId); err != nil {
+			err = fmt.Errorf("shelfStore.AddBook: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to add book to shelf")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func RemoveShelfBook(
+	zlog zerolog.Logger,
+	readingStore store.ReadingStore,
+	shelfStore store.ShelfStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "bookId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		key := chi.URLParam(r, "shelf")
+		if isBuiltinShelf(key) {
+			if err := readingStore.DeleteStatus(ctx, middleware.UserID(ctx), bookId, key); err != nil {
+				err = fmt.Errorf("readingStore.DeleteStatus: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to delete status")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+			response.GenerateResponse(w, http.StatusNoContent, nil)
+			return
+		}
+		shelf, apiErr := findOwnShelf(ctx, wlog, shelfStore, key)
+		if apiErr != nil {
+			response.Error(w, *apiErr)
+			return
+		}
+		if err := shelfStore.RemoveBook(ctx, shelf.ID, bookId); err != nil {
+			err = fmt.Errorf("shelfStore.RemoveBook: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to remove book from shelf")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
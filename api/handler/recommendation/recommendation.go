@@ -0,0 +1,104 @@
+package recommendation
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/recommend"
+	"awesome-api/store"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+type RecommendationResponse struct {
+	BookID     int     `json:"book_id"`
+	Title      string  `json:"title"`
+	CategoryID int     `json:"category_id"`
+	Score      float64 `json:"score"`
+	Reason     string  `json:"reason"`
+}
+
+func newRecommendationResponses(recommendations []recommend.Recommendation) []RecommendationResponse {
+	res := make([]RecommendationResponse, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		res = append(res, RecommendationResponse{
+			BookID:     recommendation.BookID,
+			Title:      recommendation.Title,
+			CategoryID: recommendation.CategoryID,
+			Score:      recommendation.Score,
+			Reason:     recommendation.Reason,
+		})
+	}
+	return res
+}
+
+func ListMine(
+	zlog zerolog.Logger,
+	recommender *recommend.Recommender,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		recommendations, err := recommender.ForUser(ctx, middleware.UserID(ctx), page.Limit)
+		if err != nil {
+			err = fmt.Errorf("recommender.ForUser: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to recommend for user")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newRecommendationResponses(recommendations))
+	}
+}
+
+func ListSimilar(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	recommender *recommend.Recommender,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		book, err := bookStore.FindOneById(ctx, bookId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find one by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		recommendations, err := recommender.Similar(ctx, book, page.Limit)
+		if err != nil {
+			err = fmt.Errorf("recommender.Similar: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find similar books")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newRecommendationResponses(recommendations))
+	}
+}
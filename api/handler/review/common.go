@@ -0,0 +1,55 @@
+package review
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type ReviewResponse struct {
+	ID           int       `json:"id"`
+	BookID       int       `json:"book_id"`
+	UserID       int       `json:"user_id"`
+	Reviewer     string    `json:"reviewer"`
+	Rating       float64   `json:"rating"`
+	Body         string    `json:"body"`
+	Status       string    `json:"status"`
+	HelpfulCount int       `json:"helpful_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func newReviewResponse(review *store.Review) ReviewResponse {
+	return ReviewResponse{
+		ID:           review.ID,
+		BookID:       review.BookID,
+		UserID:       review.UserID,
+		Reviewer:     review.Reviewer,
+		Rating:       review.Rating,
+		Body:         review.Body,
+		Status:       review.Status,
+		HelpfulCount: review.HelpfulCount,
+		CreatedAt:    review.CreatedAt,
+		UpdatedAt:    review.UpdatedAt,
+	}
+}
+
+// reviewError maps the review rules enforced by the store onto client
+// errors, and anything else onto a logged server error.
+func reviewError(ctx context.Context, wlog common.WrapperZlog, err error, msg string) apierror.Error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return apierror.ClientNotFound()
+	case errors.Is(err, store.ErrReviewAlreadyWritten):
+		return apierror.ClientReviewAlreadyWritten()
+	case errors.Is(err, store.ErrReviewOwn):
+		return apierror.ClientOwnReview()
+	}
+	wlog.Error(ctx).
+		Err(err).Msg(msg)
+	return apierror.ServerError()
+}
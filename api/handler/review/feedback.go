@@ -0,0 +1,114 @@
+package review
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+type FlagRequest struct {
+	Reason string `json:"reason"`
+}
+
+const reasonMaxLength = 500
+
+func (fr *FlagRequest) validateRequest() *apierror.UnprocessableEntity {
+	fr.Reason = strings.TrimSpace(fr.Reason)
+	var message string
+	switch {
+	case fr.Reason == "":
+		message = "reason cannot be empty"
+	case utf8.RuneCountInString(fr.Reason) > reasonMaxLength:
+		message = fmt.Sprintf("reason cannot exceed %d characters", reasonMaxLength)
+	default:
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    "reason",
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func Flag(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewId, fieldErr := common.IdParam(r, "reviewId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := FlagRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := reviewStore.Flag(ctx, reviewId, middleware.UserID(ctx), req.Reason); err != nil {
+			err = fmt.Errorf("reviewStore.Flag: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to flag review"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func Vote(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewId, fieldErr := common.IdParam(r, "reviewId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		review, err := reviewStore.Vote(ctx, reviewId, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("reviewStore.Vote: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to vote review"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newReviewResponse(review))
+	}
+}
+
+func Unvote(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewId, fieldErr := common.IdParam(r, "reviewId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		review, err := reviewStore.Unvote(ctx, reviewId, middleware.UserID(ctx))
+		if err != nil {
+			err = fmt.Errorf("reviewStore.Unvote: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to unvote review"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newReviewResponse(review))
+	}
+}
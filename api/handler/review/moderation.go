@@ -0,0 +1,247 @@
+package review
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type ModerationRequest struct {
+	Action string `json:"action"`
+	Note   string `json:"note"`
+}
+
+type ModeratedReviewResponse struct {
+	ReviewResponse
+	FlagCount      int                `json:"flag_count"`
+	ModeratedBy    *int64             `json:"moderated_by"`
+	ModeratedAt    *time.Time         `json:"moderated_at"`
+	ModerationNote string             `json:"moderation_note"`
+	Flags          []FlagResponse     `json:"flags,omitempty"`
+	Revisions      []RevisionResponse `json:"revisions,omitempty"`
+}
+
+type FlagResponse struct {
+	UserID    int       `json:"user_id"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RevisionResponse struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	Rating    float64   `json:"rating"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// moderationActions maps what a moderator does onto the resulting status.
+// Hidden differs from rejected in intent only: the review was acceptable
+// once and is taken down, typically after being flagged.
+var moderationActions = map[string]string{
+	"approve": store.ReviewStatusApproved,
+	"reject":  store.ReviewStatusRejected,
+	"hide":    store.ReviewStatusHidden,
+}
+
+const noteMaxLength = 500
+
+func (mr *ModerationRequest) validateRequest() *apierror.UnprocessableEntity {
+	mr.Note = strings.TrimSpace(mr.Note)
+	var field, message string
+	switch {
+	case moderationActions[mr.Action] == "":
+		field, message = "action", "action must be approve, reject or hide"
+	case len(mr.Note) > noteMaxLength:
+		field, message = "note", fmt.Sprintf("note cannot exceed %d characters", noteMaxLength)
+	default:
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    field,
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func newModeratedReviewResponse(review *store.Review) ModeratedReviewResponse {
+	res := ModeratedReviewResponse{
+		ReviewResponse: newReviewResponse(review),
+		FlagCount:      review.FlagCount,
+		ModerationNote: review.ModerationNote,
+	}
+	if review.ModeratedBy.Valid {
+		res.ModeratedBy = &review.ModeratedBy.Int64
+	}
+	if review.ModeratedAt.Valid {
+		res.ModeratedAt = &review.ModeratedAt.Time
+	}
+	return res
+}
+
+func ListModeration(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		queue := r.URL.Query().Get("queue")
+		switch queue {
+		case "":
+			queue = store.ReviewStatusPending
+		case store.ReviewStatusPending, store.ReviewQueueFlagged,
+			store.ReviewStatusApproved, store.ReviewStatusRejected, store.ReviewStatusHidden:
+		default:
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "queue",
+				Message: "queue must be pending, flagged, approved, rejected or hidden",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		reviews, err := reviewStore.FindForModeration(ctx, queue, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("reviewStore.FindForModeration: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find reviews for moderation")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]ModeratedReviewResponse, 0, len(reviews))
+		for _, review := range reviews {
+			res = append(res, newModeratedReviewResponse(review))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+// GetModeration returns a review with its open flags and edit history,
+// everything a moderator needs to decide on it.
+func GetModeration(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewId, fieldErr := common.IdParam(r, "reviewId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		review, err := reviewStore.FindOneById(ctx, reviewId)
+		if err != nil {
+			err = fmt.Errorf("reviewStore.FindOneById: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to find one by id"))
+			return
+		}
+		flags, err := reviewStore.FindOpenFlagsByReviewId(ctx, reviewId)
+		if err != nil {
+			err = fmt.Errorf("reviewStore.FindOpenFlagsByReviewId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find open flags by review_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		revisions, err := reviewStore.FindRevisionsByReviewId(ctx, reviewId)
+		if err != nil {
+			err = fmt.Errorf("reviewStore.FindRevisionsByReviewId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find revisions by review_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := newModeratedReviewResponse(review)
+		for _, flag := range flags {
+			res.Flags = append(res.Flags, FlagResponse{
+				UserID:    flag.UserID,
+				Reason:    flag.Reason,
+				CreatedAt: flag.CreatedAt,
+			})
+		}
+		for _, rev := range revisions {
+			res.Revisions = append(res.Revisions, RevisionResponse{
+				ID:        rev.ID,
+				Body:      rev.Body,
+				Rating:    rev.Rating,
+				CreatedAt: rev.CreatedAt,
+			})
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+// Moderate applies the moderator's decision, an approved review lets its
+// author know. A failed notification is logged and does not fail the
+// moderation.
+func Moderate(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+	notificationStore store.NotificationStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewId, fieldErr := common.IdParam(r, "reviewId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := ModerationRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		status := moderationActions[req.Action]
+		review, err := reviewStore.Moderate(ctx, reviewId, middleware.UserID(ctx), status, req.Note)
+		if err != nil {
+			err = fmt.Errorf("reviewStore.Moderate: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to moderate review"))
+			return
+		}
+		if review.Status == store.ReviewStatusApproved {
+			if err := notifyApproved(ctx, notificationStore, review); err != nil {
+				wlog.Error(ctx).
+					Err(err).Msg("failed to notify review approved")
+			}
+		}
+		response.GenerateResponse(w, http.StatusOK, newModeratedReviewResponse(review))
+	}
+}
+
+func notifyApproved(ctx context.Context, notificationStore store.NotificationStore, review *store.Review) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"review_id": review.ID,
+		"book_id":   review.BookID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	notification := &store.Notification{
+		UserID: review.UserID,
+		Kind:   store.NotificationKindReviewApproved,
+		Data:   data,
+	}
+	if err := notificationStore.Insert(ctx, notification); err != nil {
+		return fmt.Errorf("notificationStore.Insert: %w", err)
+	}
+	return nil
+}
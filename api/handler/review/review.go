@@ -0,0 +1,194 @@
+package review
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+type ReviewRequest struct {
+	Rating float64 `json:"rating"`
+	Body   string  `json:"body"`
+}
+
+const (
+	ratingMin     = 1
+	ratingMax     = 5
+	bodyMaxLength = 5000
+)
+
+func (rr *ReviewRequest) validateRequest() *apierror.UnprocessableEntity {
+	rr.Body = strings.TrimSpace(rr.Body)
+	var field, message string
+	switch {
+	case rr.Rating < ratingMin || rr.Rating > ratingMax:
+		field, message = "rating", fmt.Sprintf("rating must be between %d and %d", ratingMin, ratingMax)
+	case rr.Body == "":
+		field, message = "body", "body cannot be empty"
+	case utf8.RuneCountInString(rr.Body) > bodyMaxLength:
+		field, message = "body", fmt.Sprintf("body cannot exceed %d characters", bodyMaxLength)
+	default:
+		return nil
+	}
+	fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+		Name:    field,
+		Message: message,
+	})
+	return &fieldErr
+}
+
+func decodeReviewRequest(w http.ResponseWriter, r *http.Request) (*ReviewRequest, bool) {
+	req := &ReviewRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		response.Error(w, apierror.ClientBadRequest())
+		return nil, false
+	}
+	if fieldErr := req.validateRequest(); fieldErr != nil {
+		response.ValidationError(w, *fieldErr)
+		return nil, false
+	}
+	return req, true
+}
+
+func Create(
+	zlog zerolog.Logger,
+	bookStore store.BookStore,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req, ok := decodeReviewRequest(w, r)
+		if !ok {
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, err := bookStore.FindOneById(ctx, bookId); err != nil {
+			err = fmt.Errorf("bookStore.FindOneById: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to find one by id"))
+			return
+		}
+		review := &store.Review{
+			BookID: bookId,
+			UserID: middleware.UserID(ctx),
+			Rating: req.Rating,
+			Body:   req.Body,
+		}
+		if err := reviewStore.Insert(ctx, review); err != nil {
+			err = fmt.Errorf("reviewStore.Insert: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to insert review"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusCreated, newReviewResponse(review))
+	}
+}
+
+func Update(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewId, fieldErr := common.IdParam(r, "reviewId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req, ok := decodeReviewRequest(w, r)
+		if !ok {
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		review := &store.Review{
+			ID:     reviewId,
+			UserID: middleware.UserID(ctx),
+			Rating: req.Rating,
+			Body:   req.Body,
+		}
+		if err := reviewStore.Update(ctx, review); err != nil {
+			err = fmt.Errorf("reviewStore.Update: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to update review"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newReviewResponse(review))
+	}
+}
+
+func Delete(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewId, fieldErr := common.IdParam(r, "reviewId")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := reviewStore.Delete(ctx, reviewId, middleware.UserID(ctx)); err != nil {
+			err = fmt.Errorf("reviewStore.Delete: %w", err)
+			response.Error(w, reviewError(ctx, wlog, err, "failed to delete review"))
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func ListByBook(
+	zlog zerolog.Logger,
+	reviewStore store.ReviewStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		sort := r.URL.Query().Get("sort")
+		switch sort {
+		case "":
+			sort = store.ReviewSortHelpful
+		case store.ReviewSortHelpful, store.ReviewSortNewest:
+		default:
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "sort",
+				Message: "sort must be helpful or newest",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		reviews, err := reviewStore.FindApprovedByBookId(ctx, bookId, sort, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("reviewStore.FindApprovedByBookId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find approved reviews by book_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]ReviewResponse, 0, len(reviews))
+		for _, review := range reviews {
+			res = append(res, newReviewResponse(review))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,140 @@
+package suppression
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/handler/auth"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+type SuppressionRequest struct {
+	Email  string `json:"email"`
+	Detail string `json:"detail"`
+}
+
+type SuppressionResponse struct {
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (sr SuppressionRequest) validateRequest() *apierror.UnprocessableEntity {
+	if err := auth.ValidateEmail(sr.Email); err != nil {
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    "email",
+			Message: err.Error(),
+		})
+		return &fieldErr
+	}
+	return nil
+}
+
+func newSuppressionResponse(suppression *store.Suppression) SuppressionResponse {
+	return SuppressionResponse{
+		Email:     suppression.Email,
+		Reason:    suppression.Reason,
+		Detail:    suppression.Detail,
+		CreatedAt: suppression.CreatedAt,
+		UpdatedAt: suppression.UpdatedAt,
+	}
+}
+
+func List(
+	zlog zerolog.Logger,
+	suppressionStore store.SuppressionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		suppressions, err := suppressionStore.FindAll(ctx, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("suppressionStore.FindAll: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find suppressions")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]SuppressionResponse, 0, len(suppressions))
+		for _, suppression := range suppressions {
+			res = append(res, newSuppressionResponse(suppression))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+// Create suppresses an address by hand, such as one a user asked never to
+// be mailed at.
+func Create(
+	zlog zerolog.Logger,
+	suppressionStore store.SuppressionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := SuppressionRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		suppression := &store.Suppression{
+			Email:  req.Email,
+			Reason: store.SuppressionReasonManual,
+			Detail: req.Detail,
+		}
+		if err := suppressionStore.Add(ctx, suppression); err != nil {
+			err = fmt.Errorf("suppressionStore.Add: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to add suppression")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusCreated, newSuppressionResponse(suppression))
+	}
+}
+
+// Delete lets mail go to the address again, once it is known to work.
+func Delete(
+	zlog zerolog.Logger,
+	suppressionStore store.SuppressionStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, err := url.PathUnescape(chi.URLParam(r, "email"))
+		if err != nil || email == "" {
+			response.ValidationError(w, apierror.ClientInvalidField(apierror.InvalidField{
+				Name:    "email",
+				Message: "email is not valid",
+			}))
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := suppressionStore.Remove(ctx, email); err != nil {
+			err = fmt.Errorf("suppressionStore.Remove: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to remove suppression")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
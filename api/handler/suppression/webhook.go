@@ -0,0 +1,117 @@
+package suppression
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	mailer "awesome-api/mail"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body
+	// keyed with the webhook secret.
+	SignatureHeader = "X-Webhook-Signature"
+	webhookMaxSize  = 1 << 20
+)
+
+// BounceEvent is one delivery event reported by the mail provider, Type is
+// hard_bounce, soft_bounce or complaint.
+type BounceEvent struct {
+	Type      string `json:"type"`
+	Recipient string `json:"recipient"`
+	MessageID string `json:"message_id"`
+	Detail    string `json:"detail"`
+}
+
+type WebhookResponse struct {
+	Processed int `json:"processed"`
+}
+
+func validateEvents(events []BounceEvent) *apierror.UnprocessableEntity {
+	for i, event := range events {
+		var message string
+		switch {
+		case event.Type != mailer.BounceTypeHard && event.Type != mailer.BounceTypeSoft &&
+			event.Type != mailer.BounceTypeComplaint:
+			message = fmt.Sprintf("type must be %s, %s or %s",
+				mailer.BounceTypeHard, mailer.BounceTypeSoft, mailer.BounceTypeComplaint)
+		case strings.TrimSpace(event.Recipient) == "":
+			message = "recipient cannot be empty"
+		default:
+			continue
+		}
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    fmt.Sprintf("events[%d]", i),
+			Message: message,
+		})
+		return &fieldErr
+	}
+	return nil
+}
+
+// Webhook takes the bounce and complaint callbacks of the mail provider,
+// a JSON array of events signed with the shared secret.
+func Webhook(
+	zlog zerolog.Logger,
+	processor *mailer.BounceProcessor,
+	secret string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookMaxSize))
+		if err != nil {
+			response.Error(w, apierror.ClientPayloadTooLarge())
+			return
+		}
+		if !validSignature(secret, body, r.Header.Get(SignatureHeader)) {
+			response.Error(w, apierror.ClientUnauthorized())
+			return
+		}
+		events := []BounceEvent{}
+		if err := json.Unmarshal(body, &events); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := validateEvents(events); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		for _, event := range events {
+			bounce := &mailer.Bounce{
+				Type:      event.Type,
+				Recipient: event.Recipient,
+				MessageID: event.MessageID,
+				Detail:    event.Detail,
+			}
+			if err := processor.Process(ctx, bounce); err != nil {
+				err = fmt.Errorf("processor.Process: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to process bounce")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+		}
+		response.GenerateResponse(w, http.StatusOK, WebhookResponse{Processed: len(events)})
+	}
+}
+
+func validSignature(secret string, body []byte, signature string) bool {
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,131 @@
+package webhooks
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DeliveryResponse is one entry of the delivery log, with the response of
+// the last attempt.
+type DeliveryResponse struct {
+	ID             int             `json:"id"`
+	SubscriptionID int             `json:"subscription_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	ResponseStatus *int            `json:"response_status"`
+	ResponseBody   string          `json:"response_body"`
+	LastError      string          `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+func newDeliveryResponse(delivery *store.WebhookDelivery) DeliveryResponse {
+	res := DeliveryResponse{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		Event:          delivery.Event,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseBody:   delivery.ResponseBody,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == store.WebhookDeliveryPending {
+		res.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.ResponseStatus.Valid {
+		status := int(delivery.ResponseStatus.Int64)
+		res.ResponseStatus = &status
+	}
+	if delivery.DeliveredAt.Valid {
+		res.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return res
+}
+
+func ListDeliveries(
+	zlog zerolog.Logger,
+	webhookStore store.WebhookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscriptionId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if _, err := webhookStore.FindSubscriptionById(ctx, subscriptionId); err != nil {
+			if notFound(err) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("webhookStore.FindSubscriptionById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find webhook subscription by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		deliveries, err := webhookStore.FindDeliveriesBySubscriptionId(ctx, subscriptionId, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("webhookStore.FindDeliveriesBySubscriptionId: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find webhook deliveries by subscription_id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]DeliveryResponse, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			res = append(res, newDeliveryResponse(delivery))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+// Redeliver queues the payload of a past delivery again as a new delivery,
+// leaving the log of the old one as it is. The receiver sees the same
+// event id as before.
+func Redeliver(
+	zlog zerolog.Logger,
+	webhookStore store.WebhookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deliveryId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		delivery, err := webhookStore.Redeliver(ctx, deliveryId)
+		if err != nil {
+			if notFound(err) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("webhookStore.Redeliver: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to redeliver webhook")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusAccepted, newDeliveryResponse(delivery))
+	}
+}
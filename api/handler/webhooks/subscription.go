@@ -0,0 +1,293 @@
+package webhooks
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/middleware"
+	"awesome-api/api/response"
+	"awesome-api/store"
+	"awesome-api/webhook"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SubscriptionRequest creates or replaces a subscription. A subscription
+// created without a secret gets a generated one, only returned by Create.
+// Active defaults to true.
+type SubscriptionRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+type SubscriptionResponse struct {
+	ID           int        `json:"id"`
+	URL          string     `json:"url"`
+	Events       []string   `json:"events"`
+	Secret       string     `json:"secret,omitempty"`
+	Active       bool       `json:"active"`
+	FailureCount int        `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+const secretMaxLength = 128
+
+func (sr *SubscriptionRequest) validateRequest() *apierror.UnprocessableEntity {
+	if name, message := sr.invalidField(); name != "" {
+		fieldErr := apierror.ClientInvalidField(apierror.InvalidField{
+			Name:    name,
+			Message: message,
+		})
+		return &fieldErr
+	}
+	return nil
+}
+
+func (sr *SubscriptionRequest) invalidField() (string, string) {
+	u, err := url.Parse(sr.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url", "url must be an absolute http or https url"
+	}
+	if len(sr.Events) == 0 {
+		return "events", "events cannot be empty"
+	}
+	seen := map[string]bool{}
+	for _, event := range sr.Events {
+		if !validEvent(event) {
+			return "events", fmt.Sprintf("unknown event %q", event)
+		}
+		if seen[event] {
+			return "events", fmt.Sprintf("event %q is listed twice", event)
+		}
+		seen[event] = true
+	}
+	if len(sr.Secret) > secretMaxLength {
+		return "secret", fmt.Sprintf("secret cannot be longer than %d characters", secretMaxLength)
+	}
+	return "", ""
+}
+
+func validEvent(event string) bool {
+	for _, e := range store.WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (sr *SubscriptionRequest) active() bool {
+	return sr.Active == nil || *sr.Active
+}
+
+func newSubscriptionResponse(subscription *store.WebhookSubscription) SubscriptionResponse {
+	res := SubscriptionResponse{
+		ID:           subscription.ID,
+		URL:          subscription.URL,
+		Events:       subscription.Events,
+		Active:       subscription.Active,
+		FailureCount: subscription.FailureCount,
+		CreatedAt:    subscription.CreatedAt,
+		UpdatedAt:    subscription.UpdatedAt,
+	}
+	if subscription.DisabledAt.Valid {
+		res.DisabledAt = &subscription.DisabledAt.Time
+	}
+	return res
+}
+
+func notFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+func ListSubscriptions(
+	zlog zerolog.Logger,
+	webhookStore store.WebhookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, fieldErr := common.ParsePagination(r)
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		subscriptions, err := webhookStore.FindSubscriptions(ctx, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("webhookStore.FindSubscriptions: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find webhook subscriptions")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := make([]SubscriptionResponse, 0, len(subscriptions))
+		for _, subscription := range subscriptions {
+			res = append(res, newSubscriptionResponse(subscription))
+		}
+		response.GenerateResponse(w, http.StatusOK, res)
+	}
+}
+
+func GetSubscription(
+	zlog zerolog.Logger,
+	webhookStore store.WebhookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscriptionId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		subscription, err := webhookStore.FindSubscriptionById(ctx, subscriptionId)
+		if err != nil {
+			if notFound(err) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("webhookStore.FindSubscriptionById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find webhook subscription by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newSubscriptionResponse(subscription))
+	}
+}
+
+func CreateSubscription(
+	zlog zerolog.Logger,
+	webhookStore store.WebhookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := SubscriptionRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if req.Secret == "" {
+			secret, err := webhook.NewSecret()
+			if err != nil {
+				wlog.Error(ctx).
+					Err(err).Msg("failed to generate webhook secret")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+			req.Secret = secret
+		}
+		subscription := &store.WebhookSubscription{
+			URL:       req.URL,
+			Events:    req.Events,
+			Secret:    req.Secret,
+			Active:    req.active(),
+			CreatedBy: sql.NullInt64{Int64: int64(middleware.UserID(ctx)), Valid: true},
+		}
+		if err := webhookStore.InsertSubscription(ctx, subscription); err != nil {
+			err = fmt.Errorf("webhookStore.InsertSubscription: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to insert webhook subscription")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		res := newSubscriptionResponse(subscription)
+		res.Secret = subscription.Secret
+		response.GenerateResponse(w, http.StatusCreated, res)
+	}
+}
+
+// UpdateSubscription replaces the subscription, keeping its secret when
+// none is given. Setting active again re-enables a subscription disabled
+// after repeated failures.
+func UpdateSubscription(
+	zlog zerolog.Logger,
+	webhookStore store.WebhookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscriptionId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		req := SubscriptionRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, apierror.ClientBadRequest())
+			return
+		}
+		if fieldErr := req.validateRequest(); fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		subscription, err := webhookStore.FindSubscriptionById(ctx, subscriptionId)
+		if err != nil {
+			if notFound(err) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("webhookStore.FindSubscriptionById: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to find webhook subscription by id")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		subscription.URL = req.URL
+		subscription.Events = req.Events
+		if req.Secret != "" {
+			subscription.Secret = req.Secret
+		}
+		subscription.Active = req.active()
+		if err := webhookStore.UpdateSubscription(ctx, subscription); err != nil {
+			if notFound(err) {
+				response.Error(w, apierror.ClientNotFound())
+				return
+			}
+			err = fmt.Errorf("webhookStore.UpdateSubscription: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to update webhook subscription")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusOK, newSubscriptionResponse(subscription))
+	}
+}
+
+func DeleteSubscription(
+	zlog zerolog.Logger,
+	webhookStore store.WebhookStore,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscriptionId, fieldErr := common.IdParam(r, "id")
+		if fieldErr != nil {
+			response.ValidationError(w, *fieldErr)
+			return
+		}
+		ctx := r.Context()
+		wlog := common.WrapperZlog{Logger: &zlog}
+		if err := webhookStore.DeleteSubscription(ctx, subscriptionId); err != nil {
+			err = fmt.Errorf("webhookStore.DeleteSubscription: %w", err)
+			wlog.Error(ctx).
+				Err(err).Msg("failed to delete webhook subscription")
+			response.Error(w, apierror.ServerError())
+			return
+		}
+		response.GenerateResponse(w, http.StatusNoContent, nil)
+	}
+}
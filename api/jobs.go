@@ -0,0 +1,120 @@
+package api
+
+import (
+	"awesome-api/scheduler"
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *Server) jobs() []scheduler.Job {
+	jobs := []scheduler.Job{
+		{
+			Name:     "domain_events",
+			Interval: s.events.PollInterval,
+			Run:      s.eventDispatcher.Run,
+		},
+		{
+			Name:     "email_outbox",
+			Interval: s.mail.PollInterval,
+			Run:      s.dispatcher.Run,
+		},
+		{
+			Name:     "loan_expiry",
+			Interval: s.circulation.ExpiryInterval,
+			Run:      s.expireLoans,
+		},
+		{
+			Name:     "loan_due_reminder",
+			Interval: s.circulation.ExpiryInterval,
+			Run:      s.remindDueLoans,
+		},
+		{
+			Name:     "catalog_import",
+			Interval: s.catalog.JobPollInterval,
+			Run:      s.importer.RunPending,
+		},
+		{
+			Name:     "catalog_export",
+			Interval: s.catalog.JobPollInterval,
+			Run:      s.exporter.RunPending,
+		},
+		{
+			Name:     "book_similarity",
+			Interval: s.recommendation.Interval,
+			Run:      s.similarity.Run,
+		},
+		{
+			Name:     "book_ranking",
+			Interval: s.ranking.Interval,
+			Run:      s.refreshRankings,
+		},
+		{
+			Name:     "favourite_alerts",
+			Interval: s.alert.Interval,
+			Run:      s.notifier.Run,
+		},
+		{
+			Name:     "webhook_deliveries",
+			Interval: s.webhooks.PollInterval,
+			Run:      s.webhookDispatcher.Run,
+		},
+	}
+	if s.mail.BounceMaildir != "" {
+		jobs = append(jobs, scheduler.Job{
+			Name:     "bounce_maildir",
+			Interval: s.mail.BouncePollInterval,
+			Run:      s.maildir.Run,
+		})
+	}
+	return jobs
+}
+
+// expireLoans releases the copies of loans that passed their due date and
+// hands them, together with copies from lapsed holds, to waiting patrons.
+func (s *Server) expireLoans(ctx context.Context) error {
+	loans, err := s.stores.loanStore.ExpireOverdue(ctx)
+	if err != nil {
+		return fmt.Errorf("loanStore.ExpireOverdue: %w", err)
+	}
+	if len(loans) > 0 {
+		s.logger.Info().Int("count", len(loans)).Msg("expired overdue loans")
+	}
+	if err := s.holdQueue.Sweep(ctx); err != nil {
+		return fmt.Errorf("holdQueue.Sweep: %w", err)
+	}
+	return nil
+}
+
+// remindDueLoans notifies the borrowers of loans coming due.
+func (s *Server) remindDueLoans(ctx context.Context) error {
+	count, err := s.stores.notificationStore.QueueLoanDue(ctx, s.circulation.DueReminderBefore)
+	if err != nil {
+		return fmt.Errorf("notificationStore.QueueLoanDue: %w", err)
+	}
+	if count > 0 {
+		s.logger.Info().Int64("count", count).Msg("notified loans coming due")
+	}
+	return nil
+}
+
+// refreshRankings recomputes the rankings and drops the events older than
+// any ranking looks back.
+func (s *Server) refreshRankings(ctx context.Context) error {
+	policy := s.ranking.Policy
+	if err := s.stores.rankingStore.Refresh(ctx, policy); err != nil {
+		return fmt.Errorf("rankingStore.Refresh: %w", err)
+	}
+	keep := policy.TrendingWindow
+	if policy.MostReadWindow > keep {
+		keep = policy.MostReadWindow
+	}
+	count, err := s.stores.rankingStore.PruneEvents(ctx, time.Now().Add(-keep))
+	if err != nil {
+		return fmt.Errorf("rankingStore.PruneEvents: %w", err)
+	}
+	if count > 0 {
+		s.logger.Info().Int64("count", count).Msg("pruned book events")
+	}
+	return nil
+}
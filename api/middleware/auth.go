@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/jwt"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+type userIdCtxKey struct{}
+
+const bearerPrefix = "bearer "
+
+func UserID(ctx context.Context) int {
+	if id, ok := ctx.Value(userIdCtxKey{}).(int); ok {
+		return id
+	}
+	return 0
+}
+
+// WithUserID also makes the user the actor of the events recorded under
+// ctx.
+func WithUserID(ctx context.Context, id int) context.Context {
+	ctx = store.WithActor(ctx, id)
+	return context.WithValue(ctx, userIdCtxKey{}, id)
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):])
+}
+
+func Authenticate(token jwt.JWT) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accessToken := bearerToken(r)
+			if accessToken == "" {
+				response.Error(w, apierror.ClientUnauthorized())
+				return
+			}
+			claim, err := token.ExpectAccessToken(accessToken)
+			if err != nil {
+				if errors.Is(err, jwt.JWTExpirationError) {
+					response.Error(w, apierror.ClientAccessExpired())
+					return
+				}
+				response.Error(w, apierror.ClientUnauthorized())
+				return
+			}
+			ctx := WithUserID(r.Context(), claim.UserId)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalAuthenticate identifies the user when the request carries an
+// access token and lets anonymous requests through. A token that is sent
+// must still be valid.
+func OptionalAuthenticate(token jwt.JWT) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := Authenticate(token)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if bearerToken(r) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RequireRole(
+	zlog zerolog.Logger,
+	userStore store.UserStore,
+	roles ...string,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			wlog := common.WrapperZlog{Logger: &zlog}
+			user, err := userStore.FindOneById(ctx, UserID(ctx))
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					response.Error(w, apierror.ClientUnauthorized())
+					return
+				}
+				err = fmt.Errorf("userStore.FindOneById: %w", err)
+				wlog.Error(ctx).
+					Err(err).Msg("failed to find one by id")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+			for _, role := range roles {
+				if user.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			response.Error(w, apierror.ClientPermissionDenied())
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"awesome-api/api/common"
+	apierror "awesome-api/api/error"
+	"awesome-api/api/response"
+	"awesome-api/jwt"
+	"awesome-api/store"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AuthenticateOrBasic accepts the access tokens Authenticate does and, for
+// clients that cannot sign in first such as e-reader apps, HTTP Basic
+// credentials checked the way Signin checks them. Unauthenticated requests
+// are challenged for Basic credentials.
+func AuthenticateOrBasic(
+	zlog zerolog.Logger,
+	token jwt.JWT,
+	userStore store.UserStore,
+	realm string,
+) func(http.Handler) http.Handler {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	return func(next http.Handler) http.Handler {
+		bearer := Authenticate(token)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if bearerToken(r) != "" {
+				bearer.ServeHTTP(w, r)
+				return
+			}
+			unauthorized := func(apiErr apierror.Error) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				response.Error(w, apiErr)
+			}
+			email, password, ok := r.BasicAuth()
+			if !ok {
+				unauthorized(apierror.ClientUnauthorized())
+				return
+			}
+			ctx := r.Context()
+			wlog := common.WrapperZlog{Logger: &zlog}
+			usr, err := userStore.FindOneCredentialByEmail(ctx, email)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					unauthorized(apierror.ClientInvalidCredential())
+					return
+				}
+				wlog.Error(ctx).
+					Err(err).Msg("failed to find one credential by email")
+				response.Error(w, apierror.ServerError())
+				return
+			}
+			if !usr.IsVerified {
+				response.Error(w, apierror.ClientInactiveUser())
+				return
+			}
+			if err = bcrypt.CompareHashAndPassword([]byte(usr.Password.String), []byte(password)); err != nil {
+				unauthorized(apierror.ClientInvalidCredential())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithUserID(ctx, usr.ID)))
+		})
+	}
+}
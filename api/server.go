@@ -1,11 +1,38 @@
 package api
 
 import (
+	"awesome-api/alert"
+	"awesome-api/api/handler/audit"
 	"awesome-api/api/handler/auth"
+	"awesome-api/api/handler/author"
+	"awesome-api/api/handler/book"
+	"awesome-api/api/handler/collection"
+	"awesome-api/api/handler/exports"
+	"awesome-api/api/handler/favourite"
+	"awesome-api/api/handler/imports"
+	"awesome-api/api/handler/loan"
+	"awesome-api/api/handler/notification"
+	"awesome-api/api/handler/opds"
+	"awesome-api/api/handler/outbox"
+	"awesome-api/api/handler/ranking"
+	"awesome-api/api/handler/reading"
+	"awesome-api/api/handler/recommendation"
+	"awesome-api/api/handler/review"
+	"awesome-api/api/handler/suppression"
+	"awesome-api/api/handler/webhooks"
+	"awesome-api/api/middleware"
+	"awesome-api/blob"
+	"awesome-api/catalog"
+	"awesome-api/circulation"
+	"awesome-api/events"
 	"awesome-api/jwt"
 	mailer "awesome-api/mail"
+	"awesome-api/notify"
+	"awesome-api/recommend"
+	"awesome-api/scheduler"
 	"awesome-api/store"
 	"awesome-api/store/postgresql"
+	"awesome-api/webhook"
 	"context"
 	"database/sql"
 	"fmt"
@@ -21,8 +48,34 @@ type Server struct {
 	logger            zerolog.Logger
 	stores            *stores
 	tokenVerification TokenVerificationConfig
+	users             UserConfig
+	mail              MailConfig
 	mailer            mailer.EmailSender
+	unsubscribe       *mailer.Unsubscribe
+	dispatcher        *mailer.Dispatcher
+	bounceProcessor   *mailer.BounceProcessor
+	maildir           *mailer.Maildir
 	jwt               jwt.JWT
+	blobStore         blob.BlobStore
+	cover             book.CoverConfig
+	bookFile          book.FileConfig
+	circulation       CirculationConfig
+	holdQueue         *circulation.HoldQueue
+	hub               *notify.Hub
+	catalog           CatalogConfig
+	importer          *catalog.Importer
+	exporter          *catalog.Exporter
+	recommendation    RecommendationConfig
+	ranking           RankingConfig
+	similarity        *recommend.Computer
+	recommender       *recommend.Recommender
+	alert             AlertConfig
+	notifier          *alert.Notifier
+	webhooks          WebhookConfig
+	publisher         *webhook.Publisher
+	webhookDispatcher *webhook.Dispatcher
+	events            EventConfig
+	eventDispatcher   *events.Dispatcher
 }
 
 type DB struct {
@@ -30,38 +83,292 @@ type DB struct {
 }
 
 type stores struct {
-	userStore store.UserStore
+	txManager                   store.TxManager
+	userStore                   store.UserStore
+	bookStore                   store.BookStore
+	bookFileStore               store.BookFileStore
+	readingStore                store.ReadingStore
+	shelfStore                  store.ShelfStore
+	loanStore                   store.LoanStore
+	holdStore                   store.HoldStore
+	reviewStore                 store.ReviewStore
+	authorStore                 store.AuthorStore
+	categoryStore               store.CategoryStore
+	importStore                 store.ImportStore
+	exportStore                 store.ExportStore
+	collectionStore             store.CollectionStore
+	recommendationStore         store.RecommendationStore
+	rankingStore                store.RankingStore
+	favouriteStore              store.FavouriteStore
+	alertStore                  store.AlertStore
+	emailOutboxStore            store.EmailOutboxStore
+	notificationPreferenceStore store.NotificationPreferenceStore
+	suppressionStore            store.SuppressionStore
+	notificationStore           store.NotificationStore
+	webhookStore                store.WebhookStore
+	domainEventStore            store.DomainEventStore
+	auditStore                  store.AuditStore
 }
 
 type TokenVerificationConfig struct {
 	Expiry time.Duration
 }
 
+// UserConfig lists the emails of the users made admins at startup, once
+// they have verified their account. Admins then hand out the other roles.
+type UserConfig struct {
+	AdminEmails []string
+}
+
+// MailConfig configures the mails and how often the outbox is polled for
+// mails to deliver. Bounces come in through the webhook when
+// BounceWebhookSecret is set and from BounceMaildir when that is set.
+type MailConfig struct {
+	Mailer              mailer.Config
+	Dispatcher          mailer.DispatcherConfig
+	PollInterval        time.Duration
+	BounceWebhookSecret string
+	BounceMaildir       string
+	BouncePollInterval  time.Duration
+}
+
+// CirculationConfig configures loans and holds. Borrowers are notified
+// DueReminderBefore the due date of their loans, checked for every
+// ExpiryInterval.
+type CirculationConfig struct {
+	Loan              store.LoanPolicy
+	ExpiryInterval    time.Duration
+	HoldClaimWindow   time.Duration
+	DueReminderBefore time.Duration
+}
+
+// CatalogConfig configures the import and export jobs, both polled for at
+// the same interval.
+type CatalogConfig struct {
+	ImportMaxSize   int64
+	JobPollInterval time.Duration
+}
+
+// RecommendationConfig configures how often book similarities are
+// recomputed.
+type RecommendationConfig struct {
+	Interval time.Duration
+}
+
+// RankingConfig configures how often the rankings are refreshed and what
+// they look back over.
+type RankingConfig struct {
+	Interval time.Duration
+	Policy   store.RankingPolicy
+}
+
+// AlertConfig configures how often favourite alerts are checked for and
+// how long weekly alerts wait to be mailed together.
+type AlertConfig struct {
+	Interval    time.Duration
+	DigestAfter time.Duration
+}
+
+// WebhookConfig configures the delivery of webhooks and how often
+// deliveries are polled for.
+type WebhookConfig struct {
+	Dispatcher   webhook.DispatcherConfig
+	PollInterval time.Duration
+}
+
+// EventConfig configures the dispatch of domain events and how often the
+// outbox is polled for them.
+type EventConfig struct {
+	Dispatcher   events.DispatcherConfig
+	PollInterval time.Duration
+}
+
+// catalogJobStaleAfter is how long a running import or export may go
+// without progress before another worker takes it over, as after a restart.
+const catalogJobStaleAfter = 10 * time.Minute
+
 func NewServer(
 	addr string,
 	logger zerolog.Logger,
 	db DB,
 	tokenVerification TokenVerificationConfig,
-	mailer mailer.EmailSender,
+	users UserConfig,
+	mail MailConfig,
 	jwt jwt.JWT,
+	blobStore blob.BlobStore,
+	cover book.CoverConfig,
+	bookFile book.FileConfig,
+	circulation CirculationConfig,
+	catalog CatalogConfig,
+	recommendation RecommendationConfig,
+	ranking RankingConfig,
+	alert AlertConfig,
+	webhooks WebhookConfig,
+	events EventConfig,
 ) *Server {
 	s := &Server{
 		Addr:              addr,
 		logger:            logger,
 		tokenVerification: tokenVerification,
-		mailer:            mailer,
+		users:             users,
+		mail:              mail,
 		jwt:               jwt,
+		blobStore:         blobStore,
+		cover:             cover,
+		bookFile:          bookFile,
+		circulation:       circulation,
+		catalog:           catalog,
+		recommendation:    recommendation,
+		ranking:           ranking,
+		alert:             alert,
+		webhooks:          webhooks,
+		events:            events,
 	}
 	var err error
 	s.stores, err = initStores(s, db)
 	if err != nil {
-		logger.Fatal().Err(err)
+		logger.Fatal().Err(err).Msg("failed to prepare stores")
 	}
+	s.unsubscribe, err = mailer.NewUnsubscribe(mail.Mailer.UnsubscribeSecret)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to setup mail")
+	}
+	s.mailer, s.dispatcher, err = newMail(s)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to setup mail")
+	}
+	s.bounceProcessor, s.maildir = newBounces(s)
+	s.holdQueue = newHoldQueue(s)
+	s.hub = notify.NewHub(
+		s.logger.With().Str("component", "notification_hub").Logger(),
+		s.stores.notificationStore,
+	)
+	s.importer, s.exporter = newCatalogJobs(s)
+	s.similarity = recommend.NewComputer(
+		s.logger.With().Str("component", "similarity").Logger(),
+		s.stores.recommendationStore,
+	)
+	s.recommender = recommend.NewRecommender(s.stores.recommendationStore)
+	s.notifier = newNotifier(s)
+	s.publisher, s.webhookDispatcher = newWebhooks(s)
+	s.eventDispatcher = newEventDispatcher(s)
+	s.subscribeEvents()
 	return s
 }
 
+func newEventDispatcher(s *Server) *events.Dispatcher {
+	return events.NewDispatcher(
+		s.logger.With().Str("component", "event_dispatcher").Logger(),
+		s.stores.domainEventStore,
+		s.events.Dispatcher,
+	)
+}
+
+func newWebhooks(s *Server) (*webhook.Publisher, *webhook.Dispatcher) {
+	publisher := webhook.NewPublisher(
+		s.logger.With().Str("component", "webhook_publisher").Logger(),
+		s.stores.webhookStore,
+	)
+	dispatcher := webhook.NewDispatcher(
+		s.logger.With().Str("component", "webhook_dispatcher").Logger(),
+		s.stores.webhookStore,
+		s.webhooks.Dispatcher,
+	)
+	return publisher, dispatcher
+}
+
+func newNotifier(s *Server) *alert.Notifier {
+	return alert.NewNotifier(
+		s.logger.With().Str("component", "notifier").Logger(),
+		s.stores.txManager,
+		s.stores.alertStore,
+		s.mailer,
+		s.alert.DigestAfter,
+	)
+}
+
+func newMail(s *Server) (mailer.EmailSender, *mailer.Dispatcher, error) {
+	sender, err := mailer.NewMail(
+		&s.mail.Mailer,
+		s.stores.emailOutboxStore,
+		s.stores.notificationPreferenceStore,
+		s.unsubscribe,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	transport, err := mailer.NewTransport(&s.mail.Mailer)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, err := mailer.NewDKIMSigner(&s.mail.Mailer)
+	if err != nil {
+		return nil, nil, err
+	}
+	dispatcher := mailer.NewDispatcher(
+		s.logger.With().Str("component", "mail_dispatcher").Logger(),
+		s.stores.emailOutboxStore,
+		s.stores.suppressionStore,
+		transport,
+		s.mail.Mailer.Sender(),
+		signer,
+		s.mail.Dispatcher,
+	)
+	return sender, dispatcher, nil
+}
+
+func newBounces(s *Server) (*mailer.BounceProcessor, *mailer.Maildir) {
+	processor := mailer.NewBounceProcessor(
+		s.logger.With().Str("component", "bounce_processor").Logger(),
+		s.stores.suppressionStore,
+		s.stores.emailOutboxStore,
+	)
+	maildir := mailer.NewMaildir(
+		s.logger.With().Str("component", "bounce_maildir").Logger(),
+		s.mail.BounceMaildir,
+		processor,
+	)
+	return processor, maildir
+}
+
+func newCatalogJobs(s *Server) (*catalog.Importer, *catalog.Exporter) {
+	importer := catalog.NewImporter(
+		s.logger.With().Str("component", "importer").Logger(),
+		s.stores.importStore,
+		s.stores.bookStore,
+		s.stores.categoryStore,
+		s.blobStore,
+		catalogJobStaleAfter,
+	)
+	exporter := catalog.NewExporter(
+		s.logger.With().Str("component", "exporter").Logger(),
+		s.stores.exportStore,
+		s.stores.bookStore,
+		s.blobStore,
+		catalogJobStaleAfter,
+	)
+	return importer, exporter
+}
+
+func newHoldQueue(s *Server) *circulation.HoldQueue {
+	return circulation.NewHoldQueue(
+		s.logger.With().Str("component", "hold_queue").Logger(),
+		s.stores.holdStore,
+		s.stores.userStore,
+		s.stores.bookStore,
+		s.stores.notificationStore,
+		s.mailer,
+		s.circulation.HoldClaimWindow,
+	)
+}
+
 func initStores(s *Server, db DB) (*stores, error) {
-	stores := &stores{}
+	stores := &stores{
+		txManager: postgresql.NewTxManager(
+			s.logger.With().Str("component", "tx_manager").Logger(),
+			db.ElibraryPostgres,
+		),
+	}
 	var err error
 	if stores.userStore, err = postgresql.NewUserStore(
 		s.logger.With().Str("store", "user_store").Logger(),
@@ -69,10 +376,152 @@ func initStores(s *Server, db DB) (*stores, error) {
 	); err != nil {
 		return nil, err
 	}
+	if stores.bookStore, err = postgresql.NewBookStore(
+		s.logger.With().Str("store", "book_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.bookFileStore, err = postgresql.NewBookFileStore(
+		s.logger.With().Str("store", "book_file_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.readingStore, err = postgresql.NewReadingStore(
+		s.logger.With().Str("store", "reading_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.shelfStore, err = postgresql.NewShelfStore(
+		s.logger.With().Str("store", "shelf_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.loanStore, err = postgresql.NewLoanStore(
+		s.logger.With().Str("store", "loan_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.holdStore, err = postgresql.NewHoldStore(
+		s.logger.With().Str("store", "hold_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.reviewStore, err = postgresql.NewReviewStore(
+		s.logger.With().Str("store", "review_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.authorStore, err = postgresql.NewAuthorStore(
+		s.logger.With().Str("store", "author_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.categoryStore, err = postgresql.NewCategoryStore(
+		s.logger.With().Str("store", "category_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.importStore, err = postgresql.NewImportStore(
+		s.logger.With().Str("store", "import_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.exportStore, err = postgresql.NewExportStore(
+		s.logger.With().Str("store", "export_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.collectionStore, err = postgresql.NewCollectionStore(
+		s.logger.With().Str("store", "collection_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.recommendationStore, err = postgresql.NewRecommendationStore(
+		s.logger.With().Str("store", "recommendation_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.rankingStore, err = postgresql.NewRankingStore(
+		s.logger.With().Str("store", "ranking_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.emailOutboxStore, err = postgresql.NewEmailOutboxStore(
+		s.logger.With().Str("store", "email_outbox_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.notificationPreferenceStore, err = postgresql.NewNotificationPreferenceStore(
+		s.logger.With().Str("store", "notification_preference_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.suppressionStore, err = postgresql.NewSuppressionStore(
+		s.logger.With().Str("store", "suppression_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.notificationStore, err = postgresql.NewNotificationStore(
+		s.logger.With().Str("store", "notification_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.favouriteStore, err = postgresql.NewFavouriteStore(
+		s.logger.With().Str("store", "favourite_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.alertStore, err = postgresql.NewAlertStore(
+		s.logger.With().Str("store", "alert_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.domainEventStore, err = postgresql.NewDomainEventStore(
+		s.logger.With().Str("store", "domain_event_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.auditStore, err = postgresql.NewAuditStore(
+		s.logger.With().Str("store", "audit_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
+	if stores.webhookStore, err = postgresql.NewWebhookStore(
+		s.logger.With().Str("store", "webhook_store").Logger(),
+		db.ElibraryPostgres,
+	); err != nil {
+		return nil, err
+	}
 	return stores, nil
 }
 
 func (s *Server) Run(ctx context.Context) {
+	s.promoteAdmins(ctx)
+	scheduler.Start(ctx, s.logger.With().Str("component", "scheduler").Logger(), s.jobs()...)
+	go s.hub.Run(ctx)
+
 	handler := chi.NewMux()
 	handler.Mount("/", handlers(s))
 
@@ -86,14 +535,32 @@ func (s *Server) Run(ctx context.Context) {
 	}
 }
 
+func (s *Server) promoteAdmins(ctx context.Context) {
+	if len(s.users.AdminEmails) == 0 {
+		return
+	}
+	promoted, err := s.stores.userStore.UpdateRoleByEmails(ctx, store.RoleAdmin, s.users.AdminEmails)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to promote admins")
+		return
+	}
+	if promoted > 0 {
+		s.logger.Info().Int64("count", promoted).Msg("promoted admins")
+	}
+}
+
 func handlers(s *Server) http.Handler {
 	h := chi.NewMux()
 
 	h.Post("/auth", auth.Signup(
 		s.logger,
+		s.stores.txManager,
 		s.stores.userStore,
 		s.tokenVerification.Expiry,
-		s.mailer,
+	))
+	h.Get("/auth/verify", auth.Verify(
+		s.logger,
+		s.stores.userStore,
 	))
 	h.Post("/auth/signin", auth.Signin(
 		s.logger,
@@ -105,5 +572,506 @@ func handlers(s *Server) http.Handler {
 		s.stores.userStore,
 		s.jwt,
 	))
+
+	h.Post("/unsubscribe", notification.Unsubscribe(
+		s.logger,
+		s.stores.notificationPreferenceStore,
+		s.unsubscribe,
+	))
+
+	if s.mail.BounceWebhookSecret != "" {
+		h.Post("/webhooks/bounces", suppression.Webhook(
+			s.logger,
+			s.bounceProcessor,
+			s.mail.BounceWebhookSecret,
+		))
+	}
+
+	h.Get("/books", book.List(
+		s.logger,
+		s.stores.bookStore,
+	))
+	h.With(middleware.OptionalAuthenticate(s.jwt)).Get("/books/{id}", book.Get(
+		s.logger,
+		s.stores.bookStore,
+		s.stores.authorStore,
+		s.stores.rankingStore,
+	))
+	h.Get("/books/isbn/{isbn}", book.GetByISBN(
+		s.logger,
+		s.stores.bookStore,
+		s.stores.authorStore,
+	))
+	h.Get("/books/{id}/similar", recommendation.ListSimilar(
+		s.logger,
+		s.stores.bookStore,
+		s.recommender,
+	))
+	h.Get("/books/{id}/editions", book.ListEditions(
+		s.logger,
+		s.stores.bookStore,
+	))
+	h.Get("/books/{id}/cover", book.GetCover(
+		s.logger,
+		s.stores.bookStore,
+		s.blobStore,
+	))
+	h.Get("/books/{id}/files", book.ListFiles(
+		s.logger,
+		s.stores.bookFileStore,
+	))
+	h.Get("/books/{id}/availability", loan.Availability(
+		s.logger,
+		s.stores.loanStore,
+	))
+	h.Get("/books/{id}/reviews", review.ListByBook(
+		s.logger,
+		s.stores.reviewStore,
+	))
+	h.Get("/rankings/{ranking}", ranking.List(
+		s.logger,
+		s.stores.rankingStore,
+	))
+	h.Get("/collections", collection.ListPublic(
+		s.logger,
+		s.stores.collectionStore,
+	))
+	h.Get("/collections/{slug}", collection.GetShared(
+		s.logger,
+		s.stores.collectionStore,
+	))
+	h.Get("/collections/{slug}/items", collection.ListSharedItems(
+		s.logger,
+		s.stores.collectionStore,
+	))
+	h.Get("/authors", author.List(
+		s.logger,
+		s.stores.authorStore,
+	))
+	h.Get("/authors/{id}", author.Get(
+		s.logger,
+		s.stores.authorStore,
+	))
+	h.Get("/authors/{id}/books", author.ListBooks(
+		s.logger,
+		s.stores.authorStore,
+	))
+	h.Group(func(r chi.Router) {
+		r.Use(middleware.AuthenticateOrBasic(
+			s.logger,
+			s.jwt,
+			s.stores.userStore,
+			"OPDS catalogue",
+		))
+		opds.NewHandlers(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.bookFileStore,
+			s.stores.categoryStore,
+			s.stores.authorStore,
+		).Routes(r, book.DownloadFile(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.bookFileStore,
+			s.blobStore,
+		))
+	})
+	h.Group(func(r chi.Router) {
+		r.Use(middleware.Authenticate(s.jwt))
+		r.Get("/books/{id}/files/{format}", book.DownloadFile(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.bookFileStore,
+			s.blobStore,
+		))
+
+		r.Put("/me/locale", auth.UpdateLocale(
+			s.logger,
+			s.stores.userStore,
+		))
+
+		r.Get("/me/progress", reading.ListProgress(
+			s.logger,
+			s.stores.readingStore,
+		))
+		r.Get("/me/progress/{bookId}", reading.GetProgress(
+			s.logger,
+			s.stores.readingStore,
+		))
+		r.Put("/me/progress/{bookId}", reading.UpdateProgress(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.readingStore,
+		))
+		r.Get("/me/shelves", reading.ListShelves(
+			s.logger,
+			s.stores.readingStore,
+			s.stores.shelfStore,
+		))
+		r.Post("/me/shelves", reading.CreateShelf(
+			s.logger,
+			s.stores.shelfStore,
+		))
+		r.Delete("/me/shelves/{shelf}", reading.DeleteShelf(
+			s.logger,
+			s.stores.shelfStore,
+		))
+		r.Get("/me/shelves/{shelf}/books", reading.ListShelfBooks(
+			s.logger,
+			s.stores.readingStore,
+			s.stores.shelfStore,
+		))
+		r.Put("/me/shelves/{shelf}/books/{bookId}", reading.AddShelfBook(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.readingStore,
+			s.stores.shelfStore,
+		))
+		r.Delete("/me/shelves/{shelf}/books/{bookId}", reading.RemoveShelfBook(
+			s.logger,
+			s.stores.readingStore,
+			s.stores.shelfStore,
+		))
+
+		r.Get("/me/recommendations", recommendation.ListMine(
+			s.logger,
+			s.recommender,
+		))
+
+		r.Get("/me/favourites/books", favourite.ListBooks(
+			s.logger,
+			s.stores.favouriteStore,
+		))
+		r.Put("/me/favourites/books/{bookId}", favourite.AddBook(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.favouriteStore,
+		))
+		r.Delete("/me/favourites/books/{bookId}", favourite.RemoveBook(
+			s.logger,
+			s.stores.favouriteStore,
+		))
+		r.Get("/me/favourites/authors", favourite.ListAuthors(
+			s.logger,
+			s.stores.favouriteStore,
+		))
+		r.Put("/me/favourites/authors/{authorId}", favourite.AddAuthor(
+			s.logger,
+			s.stores.authorStore,
+			s.stores.favouriteStore,
+		))
+		r.Delete("/me/favourites/authors/{authorId}", favourite.RemoveAuthor(
+			s.logger,
+			s.stores.favouriteStore,
+		))
+		r.Get("/me/alert-preferences", favourite.GetPreferences(
+			s.logger,
+			s.stores.alertStore,
+		))
+		r.Put("/me/alert-preferences", favourite.UpdatePreferences(
+			s.logger,
+			s.stores.alertStore,
+		))
+		r.Get("/me/notifications", notification.List(
+			s.logger,
+			s.stores.notificationStore,
+		))
+		r.Get("/me/notifications/unread-count", notification.UnreadCount(
+			s.logger,
+			s.stores.notificationStore,
+		))
+		r.Get("/me/notifications/stream", notification.Stream(
+			s.logger,
+			s.stores.notificationStore,
+			s.hub,
+		))
+		r.Post("/me/notifications/read", notification.MarkAllRead(
+			s.logger,
+			s.stores.notificationStore,
+		))
+		r.Post("/me/notifications/{id}/read", notification.MarkRead(
+			s.logger,
+			s.stores.notificationStore,
+		))
+		r.Get("/me/notification-preferences", notification.GetPreferences(
+			s.logger,
+			s.stores.notificationPreferenceStore,
+		))
+		r.Put("/me/notification-preferences", notification.UpdatePreferences(
+			s.logger,
+			s.stores.notificationPreferenceStore,
+		))
+
+		r.Get("/me/collections", collection.ListMine(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Post("/me/collections", collection.Create(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Get("/me/collections/{id}", collection.Get(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Put("/me/collections/{id}", collection.Update(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Delete("/me/collections/{id}", collection.Delete(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Get("/me/collections/{id}/items", collection.ListItems(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Put("/me/collections/{id}/items", collection.Reorder(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Put("/me/collections/{id}/items/{bookId}", collection.PutItem(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.collectionStore,
+		))
+		r.Delete("/me/collections/{id}/items/{bookId}", collection.RemoveItem(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Get("/me/collections/{id}/collaborators", collection.ListCollaborators(
+			s.logger,
+			s.stores.collectionStore,
+		))
+		r.Post("/me/collections/{id}/collaborators", collection.AddCollaborator(
+			s.logger,
+			s.stores.userStore,
+			s.stores.collectionStore,
+		))
+		r.Delete("/me/collections/{id}/collaborators/{userId}", collection.RemoveCollaborator(
+			s.logger,
+			s.stores.collectionStore,
+		))
+
+		r.Post("/books/{id}/loans", loan.Borrow(
+			s.logger,
+			s.stores.loanStore,
+			s.circulation.Loan,
+		))
+		r.Get("/me/loans", loan.ListMine(
+			s.logger,
+			s.stores.loanStore,
+		))
+		r.Post("/me/loans/{loanId}/return", loan.Return(
+			s.logger,
+			s.stores.loanStore,
+			s.holdQueue,
+		))
+		r.Post("/me/loans/{loanId}/renew", loan.Renew(
+			s.logger,
+			s.stores.loanStore,
+			s.circulation.Loan,
+		))
+		r.Post("/books/{id}/holds", loan.PlaceHold(
+			s.logger,
+			s.stores.holdStore,
+		))
+		r.Get("/me/holds", loan.ListMyHolds(
+			s.logger,
+			s.stores.holdStore,
+		))
+		r.Delete("/me/holds/{holdId}", loan.CancelHold(
+			s.logger,
+			s.stores.holdStore,
+			s.holdQueue,
+		))
+
+		r.Post("/books/{id}/reviews", review.Create(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.reviewStore,
+		))
+		r.Put("/reviews/{reviewId}", review.Update(
+			s.logger,
+			s.stores.reviewStore,
+		))
+		r.Delete("/reviews/{reviewId}", review.Delete(
+			s.logger,
+			s.stores.reviewStore,
+		))
+		r.Post("/reviews/{reviewId}/flags", review.Flag(
+			s.logger,
+			s.stores.reviewStore,
+		))
+		r.Put("/reviews/{reviewId}/helpful", review.Vote(
+			s.logger,
+			s.stores.reviewStore,
+		))
+		r.Delete("/reviews/{reviewId}/helpful", review.Unvote(
+			s.logger,
+			s.stores.reviewStore,
+		))
+	})
+	h.Group(func(r chi.Router) {
+		r.Use(middleware.Authenticate(s.jwt))
+		r.Use(middleware.RequireRole(s.logger, s.stores.userStore, store.RoleLibrarian, store.RoleAdmin))
+		r.Post("/books", book.Create(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.authorStore,
+		))
+		r.Put("/books/{id}", book.Update(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.authorStore,
+		))
+		r.Post("/authors", author.Create(
+			s.logger,
+			s.stores.authorStore,
+		))
+		r.Put("/authors/{id}", author.Update(
+			s.logger,
+			s.stores.authorStore,
+		))
+		r.Post("/books/{id}/cover", book.UploadCover(
+			s.logger,
+			s.stores.bookStore,
+			s.blobStore,
+			s.cover,
+		))
+		r.Post("/books/{id}/files", book.UploadFile(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.bookFileStore,
+			s.blobStore,
+			s.bookFile,
+		))
+		r.Put("/books/{id}/copies", loan.UpdateCopies(
+			s.logger,
+			s.stores.bookStore,
+			s.stores.loanStore,
+			s.holdQueue,
+		))
+
+		r.Post("/imports", imports.Create(
+			s.logger,
+			s.stores.importStore,
+			s.blobStore,
+			imports.Config{MaxSize: s.catalog.ImportMaxSize},
+		))
+		r.Get("/imports", imports.List(
+			s.logger,
+			s.stores.importStore,
+		))
+		r.Get("/imports/{id}", imports.Get(
+			s.logger,
+			s.stores.importStore,
+		))
+		r.Get("/imports/{id}/errors", imports.ListErrors(
+			s.logger,
+			s.stores.importStore,
+		))
+
+		r.Get("/exports/books", exports.Stream(
+			s.logger,
+			s.stores.bookStore,
+		))
+		r.Post("/exports/books", exports.Create(
+			s.logger,
+			s.stores.exportStore,
+		))
+		r.Get("/exports", exports.List(
+			s.logger,
+			s.stores.exportStore,
+		))
+		r.Get("/exports/{id}", exports.Get(
+			s.logger,
+			s.stores.exportStore,
+		))
+		r.Get("/exports/{id}/download", exports.Download(
+			s.logger,
+			s.stores.exportStore,
+			s.blobStore,
+		))
+
+		r.Get("/emails/outbox", outbox.List(
+			s.logger,
+			s.stores.emailOutboxStore,
+		))
+		r.Get("/emails/outbox/stats", outbox.Stats(
+			s.logger,
+			s.stores.emailOutboxStore,
+		))
+		r.Post("/emails/outbox/{id}/requeue", outbox.Requeue(
+			s.logger,
+			s.stores.emailOutboxStore,
+		))
+		r.Get("/emails/suppressions", suppression.List(
+			s.logger,
+			s.stores.suppressionStore,
+		))
+		r.Post("/emails/suppressions", suppression.Create(
+			s.logger,
+			s.stores.suppressionStore,
+		))
+		r.Delete("/emails/suppressions/{email}", suppression.Delete(
+			s.logger,
+			s.stores.suppressionStore,
+		))
+
+		r.Get("/reviews/moderation", review.ListModeration(
+			s.logger,
+			s.stores.reviewStore,
+		))
+		r.Get("/reviews/{reviewId}/moderation", review.GetModeration(
+			s.logger,
+			s.stores.reviewStore,
+		))
+		r.Post("/reviews/{reviewId}/moderation", review.Moderate(
+			s.logger,
+			s.stores.reviewStore,
+			s.stores.notificationStore,
+		))
+	})
+	h.Group(func(r chi.Router) {
+		r.Use(middleware.Authenticate(s.jwt))
+		r.Use(middleware.RequireRole(s.logger, s.stores.userStore, store.RoleAdmin))
+		r.Get("/webhooks/subscriptions", webhooks.ListSubscriptions(
+			s.logger,
+			s.stores.webhookStore,
+		))
+		r.Post("/webhooks/subscriptions", webhooks.CreateSubscription(
+			s.logger,
+			s.stores.webhookStore,
+		))
+		r.Get("/webhooks/subscriptions/{id}", webhooks.GetSubscription(
+			s.logger,
+			s.stores.webhookStore,
+		))
+		r.Put("/webhooks/subscriptions/{id}", webhooks.UpdateSubscription(
+			s.logger,
+			s.stores.webhookStore,
+		))
+		r.Delete("/webhooks/subscriptions/{id}", webhooks.DeleteSubscription(
+			s.logger,
+			s.stores.webhookStore,
+		))
+		r.Get("/webhooks/subscriptions/{id}/deliveries", webhooks.ListDeliveries(
+			s.logger,
+			s.stores.webhookStore,
+		))
+		r.Post("/webhooks/deliveries/{id}/redeliver", webhooks.Redeliver(
+			s.logger,
+			s.stores.webhookStore,
+		))
+		r.Put("/users/{id}/role", auth.UpdateRole(
+			s.logger,
+			s.stores.userStore,
+		))
+		r.Get("/audit-log", audit.List(
+			s.logger,
+			s.stores.auditStore,
+		))
+	})
 	return h
 }
@@ -0,0 +1,76 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+type BlobError string
+
+func (e BlobError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrNotExist   = BlobError("blob does not exist")
+	ErrInvalidKey = BlobError("blob key is invalid")
+)
+
+type Object struct {
+	Body        io.ReadSeekCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+type Config struct {
+	Driver       string
+	LocalPath    string
+	S3Endpoint   string
+	S3Region     string
+	S3Bucket     string
+	S3AccessKey  string
+	S3SecretKey  string
+	S3PathStyle  bool
+	S3HTTPClient HTTPDoer
+}
+
+func New(cfg Config) (BlobStore, error) {
+	switch cfg.Driver {
+	case DriverLocal, "":
+		return NewLocal(cfg.LocalPath)
+	case DriverS3:
+		return NewS3(cfg)
+	default:
+		return nil, fmt.Errorf("unknown blob driver: %s", cfg.Driver)
+	}
+}
+
+// cleanKey rejects keys that would escape the store root once joined to a
+// filesystem path or an object URL.
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,333 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// S3 talks to any S3-compatible object storage (AWS S3, MinIO, ...) using
+// plain HTTP requests signed with AWS Signature Version 4.
+type S3 struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    HTTPDoer
+}
+
+const (
+	s3Service       = "s3"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3UnsignedBody  = "UNSIGNED-PAYLOAD"
+	s3AmzDateFormat = "20060102T150405Z"
+	s3DateFormat    = "20060102"
+)
+
+func NewS3(cfg Config) (*S3, error) {
+	endpoint, err := url.Parse(cfg.S3Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %q", cfg.S3Endpoint)
+	}
+	if cfg.S3Bucket == "" {
+		return nil, fmt.Errorf("s3 blob store requires a bucket")
+	}
+	region := cfg.S3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	client := cfg.S3HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+	return &S3{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.S3Bucket,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		pathStyle: cfg.S3PathStyle,
+		client:    client,
+	}, nil
+}
+
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return &u, nil
+}
+
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	return req, nil
+}
+
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now().UTC())
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call s3: %w", err)
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotExist
+	}
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, res.Status, msg)
+	}
+	return res, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (*Object, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return &Object{
+		Body: &s3Reader{
+			s3:   s,
+			ctx:  ctx,
+			key:  key,
+			size: res.ContentLength,
+		},
+		Size:        res.ContentLength,
+		ContentType: res.Header.Get("Content-Type"),
+		ModTime:     modTime,
+	}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	res, err := s.do(req)
+	if err == ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *S3) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format(s3AmzDateFormat)
+	date := now.Format(s3DateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": s3UnsignedBody,
+		"x-amz-date":           amzDate,
+	}
+	if rng := req.Header.Get("Range"); rng != "" {
+		headers["range"] = rng
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedBody,
+	}, "\n")
+	scope := strings.Join([]string{date, s.region, s3Service, "aws4_request"}, "/")
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(hashedRequest[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func s3CanonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vals := values[k]
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func s3EscapePath(p string) string {
+	return s3Escape(p, false)
+}
+
+// s3Escape implements the URI encoding required by Signature Version 4:
+// every byte except the unreserved characters is percent-encoded, and '/' is
+// kept as-is in object paths.
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3Reader lazily issues ranged GET requests so objects can be served through
+// http.ServeContent without downloading them up front.
+type s3Reader struct {
+	s3     *S3
+	ctx    context.Context
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (r *s3Reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		req, err := r.s3.newRequest(r.ctx, http.MethodGet, r.key, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(r.offset, 10)+"-")
+		res, err := r.s3.do(req)
+		if err != nil {
+			return 0, err
+		}
+		r.body = res.Body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *s3Reader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, fmt.Errorf("s3Reader.Seek: invalid whence")
+	}
+	if abs < 0 {
+		return 0, fmt.Errorf("s3Reader.Seek: negative position")
+	}
+	if abs != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = abs
+	return abs, nil
+}
+
+func (r *s3Reader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSV fields a mapping can point at a column. Multi-valued fields separate
+// their values with a semicolon.
+const (
+	FieldTitle         = "title"
+	FieldAuthors       = "authors"
+	FieldEditors       = "editors"
+	FieldTranslators   = "translators"
+	FieldSynopsis      = "synopsis"
+	FieldLanguage      = "language"
+	FieldCategory      = "category"
+	FieldISBN          = "isbn"
+	FieldPublisher     = "publisher"
+	FieldPublishedDate = "published_date"
+	FieldPageCount     = "page_count"
+	FieldEdition       = "edition"
+)
+
+var csvFields = []string{
+	FieldTitle, FieldAuthors, FieldEditors, FieldTranslators, FieldSynopsis,
+	FieldLanguage, FieldCategory, FieldISBN, FieldPublisher, FieldPublishedDate,
+	FieldPageCount, FieldEdition,
+}
+
+const csvValueSeparator = ";"
+
+type CSVReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+// NewCSVReader reads the header row of r. The mapping names, for each
+// field, the header of the column holding it; unmapped fields are looked up
+// under their own name. Headers are matched case-insensitively.
+func NewCSVReader(r io.Reader, mapping map[string]string) (*CSVReader, error) {
+	for field := range mapping {
+		if !isCSVField(field) {
+			return nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+	columns := map[string]int{}
+	for _, field := range csvFields {
+		name := field
+		if mapped, ok := mapping[field]; ok {
+			name = mapped
+		}
+		if i, ok := positions[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[field] = i
+		} else if _, ok := mapping[field]; ok {
+			return nil, fmt.Errorf("column %q mapped to %s is not in the header", name, field)
+		}
+	}
+	if _, ok := columns[FieldTitle]; !ok {
+		return nil, errors.New("no column holds the title")
+	}
+	return &CSVReader{r: cr, columns: columns, row: 1}, nil
+}
+
+func isCSVField(field string) bool {
+	for _, f := range csvFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Row counts the header as row 1, so rows match the line numbers a
+// spreadsheet shows.
+func (cr *CSVReader) Row() int {
+	return cr.row
+}
+
+func (cr *CSVReader) Next() (*Record, error) {
+	values, err := cr.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	cr.row++
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &RecordError{Row: cr.row, Err: parseErr.Err}
+		}
+		return nil, err
+	}
+	get := func(field string) string {
+		i, ok := cr.columns[field]
+		if !ok || i >= len(values) {
+			return ""
+		}
+		return strings.TrimSpace(values[i])
+	}
+	rec := &Record{
+		Title:     get(FieldTitle),
+		Synopsis:  get(FieldSynopsis),
+		Language:  get(FieldLanguage),
+		Category:  get(FieldCategory),
+		ISBN:      get(FieldISBN),
+		Publisher: get(FieldPublisher),
+		Edition:   get(FieldEdition),
+	}
+	for _, c := range []struct{ field, role string }{
+		{FieldAuthors, RoleAuthor},
+		{FieldEditors, RoleEditor},
+		{FieldTranslators, RoleTranslator},
+	} {
+		for _, name := range strings.Split(get(c.field), csvValueSeparator) {
+			if name = strings.TrimSpace(name); name != "" {
+				rec.Authors = append(rec.Authors, Credit{Name: name, Role: c.role})
+			}
+		}
+	}
+	if rec.PublishedDate, err = parseDate(get(FieldPublishedDate)); err != nil {
+		return nil, &RecordError{Row: cr.row, Err: err}
+	}
+	if rec.PageCount, err = parsePageCount(get(FieldPageCount)); err != nil {
+		return nil, &RecordError{Row: cr.row, Err: err}
+	}
+	return rec, nil
+}
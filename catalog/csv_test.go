@@ -0,0 +1,119 @@
+package catalog
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mapping map[string]string
+		want    []*Record
+	}{
+		{
+			name: "every field",
+			csv: "\ufeffTitle,Authors,Editors,Translators,Synopsis,Language,Category,ISBN,Publisher,Published_Date,Page_Count,Edition\n" +
+				`Dune,"Herbert, Frank",,Guieu; Demuth,Desert planet.,en,Science fiction,9780441013593,Ace,1965,"xii, 412 p.",1st` + "\n",
+			want: []*Record{{
+				Title: "Dune",
+				Authors: []Credit{
+					{Name: "Herbert, Frank", Role: RoleAuthor},
+					{Name: "Guieu", Role: RoleTranslator},
+					{Name: "Demuth", Role: RoleTranslator},
+				},
+				Synopsis:      "Desert planet.",
+				Language:      "en",
+				Category:      "Science fiction",
+				ISBN:          "9780441013593",
+				Publisher:     "Ace",
+				PublishedDate: time.Date(1965, time.January, 1, 0, 0, 0, 0, time.UTC),
+				PageCount:     412,
+				Edition:       "1st",
+			}},
+		},
+		{
+			name:    "mapped columns",
+			csv:     "Nom, Auteur ,Date\nLe Petit Prince,Saint-Exupéry,1943-04-06\n",
+			mapping: map[string]string{FieldTitle: "nom", FieldAuthors: "AUTEUR", FieldPublishedDate: "date"},
+			want: []*Record{{
+				Title:         "Le Petit Prince",
+				Authors:       []Credit{{Name: "Saint-Exupéry", Role: RoleAuthor}},
+				PublishedDate: time.Date(1943, time.April, 6, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "short rows",
+			csv:  "title,isbn\nOne\n Two , 123 \n",
+			want: []*Record{{Title: "One"}, {Title: "Two", ISBN: "123"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, err := NewCSVReader(strings.NewReader(tt.csv), tt.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range tt.want {
+				rec, err := cr.Next()
+				if err != nil {
+					t.Fatalf("record %d: %v", i+1, err)
+				}
+				if !reflect.DeepEqual(rec, want) {
+					t.Errorf("record %d = %+v, want %+v", i+1, rec, want)
+				}
+				if cr.Row() != i+2 {
+					t.Errorf("row = %d, want %d", cr.Row(), i+2)
+				}
+			}
+			if _, err := cr.Next(); err != io.EOF {
+				t.Errorf("err = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestCSVReaderRecordErrors(t *testing.T) {
+	csv := "title,published_date,page_count\n" +
+		"Bad date,someday,\n" +
+		"Bad count,,many\n" +
+		"\"Bad quote,,\n"
+	cr, err := NewCSVReader(strings.NewReader(csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []int{2, 3, 4} {
+		_, err := cr.Next()
+		var recordErr *RecordError
+		if !errors.As(err, &recordErr) || recordErr.Row != row {
+			t.Errorf("err = %v, want a RecordError for row %d", err, row)
+		}
+	}
+}
+
+func TestNewCSVReaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mapping map[string]string
+		want    string
+	}{
+		{"empty", "", nil, "failed to read header: EOF"},
+		{"no title", "name,isbn\n", nil, "no column holds the title"},
+		{"unknown field", "title\n", map[string]string{"price": "cost"}, `unknown field "price" in mapping`},
+		{"missing mapped column", "title\n", map[string]string{FieldISBN: "ean"}, `column "ean" mapped to isbn is not in the header`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCSVReader(strings.NewReader(tt.csv), tt.mapping)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package catalog
+
+import (
+	"awesome-api/blob"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// exportProgressEvery is how many books are written between two heartbeats
+// of an export job.
+const exportProgressEvery = 1000
+
+// Export writes the books matching the filter to w as they are read from
+// the database, and returns how many were written. progress, when not nil,
+// is called with the running count every few books.
+func Export(
+	ctx context.Context,
+	bookStore store.BookStore,
+	w io.Writer,
+	format string,
+	filter store.BookFilter,
+	progress func(n int) error,
+) (int, error) {
+	writer, err := NewWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = bookStore.Stream(ctx, filter, func(book *store.CatalogBook) error {
+		if err := writer.Write(RecordFromBook(book)); err != nil {
+			return fmt.Errorf("failed to write book %d: %w", book.ID, err)
+		}
+		n++
+		if progress != nil && n%exportProgressEvery == 0 {
+			return progress(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, writer.Close()
+}
+
+// Exporter runs the queued export jobs, leaving each result in the blob
+// store for staff to download.
+type Exporter struct {
+	log         zerolog.Logger
+	exportStore store.ExportStore
+	bookStore   store.BookStore
+	blobStore   blob.BlobStore
+	staleAfter  time.Duration
+}
+
+func NewExporter(
+	log zerolog.Logger,
+	exportStore store.ExportStore,
+	bookStore store.BookStore,
+	blobStore blob.BlobStore,
+	staleAfter time.Duration,
+) *Exporter {
+	return &Exporter{
+		log:         log,
+		exportStore: exportStore,
+		bookStore:   bookStore,
+		blobStore:   blobStore,
+		staleAfter:  staleAfter,
+	}
+}
+
+// RunPending runs jobs one after another until none is left.
+func (ex *Exporter) RunPending(ctx context.Context) error {
+	for {
+		job, err := ex.exportStore.ClaimNext(ctx, ex.staleAfter)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("exportStore.ClaimNext: %w", err)
+		}
+		log := ex.log.With().Int("export_id", job.ID).Logger()
+		log.Info().Str("format", job.Format).Msg("export started")
+		if err = ex.run(ctx, job); err != nil {
+			job.Status = store.ImportStatusFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = store.ImportStatusSucceeded
+		}
+		if err = ex.exportStore.Finish(ctx, job); err != nil {
+			return fmt.Errorf("exportStore.Finish: %w", err)
+		}
+		log.Info().Str("status", job.Status).Int("total", job.Total).
+			Int64("size", job.Size).Msg("export finished")
+	}
+}
+
+// run writes the export to a temporary file first, the blob store needs
+// the size of what it stores upfront.
+func (ex *Exporter) run(ctx context.Context, job *store.ExportJob) error {
+	tmp, err := os.CreateTemp("", "export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	job.Total, err = Export(ctx, ex.bookStore, tmp, job.Format, job.Filter, func(n int) error {
+		job.Total = n
+		return ex.exportStore.UpdateProgress(ctx, job)
+	})
+	if err != nil {
+		return err
+	}
+	if job.Size, err = tmp.Seek(0, io.SeekCurrent); err != nil {
+		return fmt.Errorf("failed to size export: %w", err)
+	}
+	job.BlobKey = fmt.Sprintf("exports/%d%s", job.ID, Extension(job.Format))
+	err = ex.blobStore.Put(ctx, job.BlobKey, io.NewSectionReader(tmp, 0, job.Size), job.Size, ContentType(job.Format))
+	if err != nil {
+		job.BlobKey = ""
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+	return nil
+}
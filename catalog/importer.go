@@ -0,0 +1,313 @@
+package catalog
+
+import (
+	"awesome-api/blob"
+	"awesome-api/isbn"
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// Column limits of the catalogue tables records are truncated to.
+const (
+	titleMaxLen      = 60
+	languageMaxLen   = 35
+	authorNameMaxLen = 128
+	publisherMaxLen  = 128
+	editionMaxLen    = 60
+	categoryMaxLen   = 50
+)
+
+// progressEvery is how many records are processed between two progress
+// updates, each of which is also the heartbeat of the job.
+const progressEvery = 100
+
+// Importer runs the queued import jobs. Books are matched by ISBN, a record
+// whose ISBN is already catalogued updates that book instead of adding a new
+// one, so an export can be imported again safely.
+type Importer struct {
+	log           zerolog.Logger
+	importStore   store.ImportStore
+	bookStore     store.BookStore
+	categoryStore store.CategoryStore
+	blobStore     blob.BlobStore
+	staleAfter    time.Duration
+}
+
+func NewImporter(
+	log zerolog.Logger,
+	importStore store.ImportStore,
+	bookStore store.BookStore,
+	categoryStore store.CategoryStore,
+	blobStore blob.BlobStore,
+	staleAfter time.Duration,
+) *Importer {
+	return &Importer{
+		log:           log,
+		importStore:   importStore,
+		bookStore:     bookStore,
+		categoryStore: categoryStore,
+		blobStore:     blobStore,
+		staleAfter:    staleAfter,
+	}
+}
+
+// RunPending runs jobs one after another until none is left.
+func (im *Importer) RunPending(ctx context.Context) error {
+	for {
+		job, err := im.importStore.ClaimNext(ctx, im.staleAfter)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("importStore.ClaimNext: %w", err)
+		}
+		log := im.log.With().Int("import_id", job.ID).Logger()
+		log.Info().Str("format", job.Format).Bool("dry_run", job.DryRun).Msg("import started")
+		if err = im.run(ctx, job); err != nil {
+			job.Status = store.ImportStatusFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = store.ImportStatusSucceeded
+		}
+		if err = im.importStore.Finish(ctx, job); err != nil {
+			return fmt.Errorf("importStore.Finish: %w", err)
+		}
+		log.Info().Str("status", job.Status).
+			Int("total", job.Total).Int("created", job.Created).
+			Int("updated", job.Updated).Int("failed", job.Failed).
+			Msg("import finished")
+	}
+}
+
+// run imports every record of the job. Errors concerning a single record
+// are stored with the job; the returned error means the file could not be
+// read any further.
+func (im *Importer) run(ctx context.Context, job *store.ImportJob) error {
+	obj, err := im.blobStore.Open(ctx, job.BlobKey)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer obj.Body.Close()
+	reader, err := NewReader(job.Format, obj.Body, job.Options.Mapping)
+	if err != nil {
+		return err
+	}
+	run := &importRun{Importer: im, job: job, seen: map[string]bool{}}
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var recErr *RecordError
+		if errors.As(err, &recErr) {
+			job.Total++
+			if err = run.fail(ctx, recErr.Row, "", recErr.Err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("row %d: %w", reader.Row()+1, err)
+		}
+		job.Total++
+		if err = run.apply(ctx, reader.Row(), rec); err != nil {
+			return err
+		}
+		if job.Total%progressEvery == 0 {
+			if err = im.importStore.UpdateProgress(ctx, job); err != nil {
+				return fmt.Errorf("importStore.UpdateProgress: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+type importRun struct {
+	*Importer
+	job        *store.ImportJob
+	categories map[string]int
+	// seen holds the ISBNs met earlier in the file, a dry run has to count
+	// their repetitions as updates since nothing is written.
+	seen map[string]bool
+}
+
+func (run *importRun) fail(ctx context.Context, row int, isbn13, message string) error {
+	run.job.Failed++
+	err := run.importStore.InsertError(ctx, &store.ImportError{
+		JobID:   run.job.ID,
+		Row:     row,
+		ISBN:    isbn13,
+		Message: message,
+	})
+	if err != nil {
+		return fmt.Errorf("importStore.InsertError: %w", err)
+	}
+	return nil
+}
+
+// apply validates the record and upserts its book. Only failures of the
+// database are returned, anything wrong with the record is reported as a
+// row error.
+func (run *importRun) apply(ctx context.Context, row int, rec *Record) error {
+	book, credits, message := run.toBook(rec)
+	if message != "" {
+		return run.fail(ctx, row, book.ISBN13.String, message)
+	}
+	isbn13 := book.ISBN13.String
+	existing, err := run.bookStore.FindOneByISBN(ctx, isbn13)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("bookStore.FindOneByISBN: %w", err)
+	}
+	if run.job.DryRun {
+		if existing != nil || run.seen[isbn13] {
+			run.job.Updated++
+		} else {
+			run.job.Created++
+		}
+		run.seen[isbn13] = true
+		return nil
+	}
+
+	categoryName := run.categoryName(rec)
+	if book.CategoryID, err = run.category(ctx, categoryName); err != nil {
+		return err
+	}
+	if existing != nil {
+		mergeBook(book, existing)
+		err = run.bookStore.Update(ctx, book, credits)
+	} else {
+		err = run.bookStore.Insert(ctx, book, credits)
+	}
+	var bookErr store.BookError
+	if errors.As(err, &bookErr) {
+		return run.fail(ctx, row, isbn13, bookErr.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("bookStore upsert: %w", err)
+	}
+	if existing != nil {
+		run.job.Updated++
+	} else {
+		run.job.Created++
+	}
+	return nil
+}
+
+// toBook maps the record onto a book, or describes why it cannot be
+// imported.
+func (run *importRun) toBook(rec *Record) (*store.Book, []store.AuthorCredit, string) {
+	book := &store.Book{
+		Title:     truncate(rec.Title, titleMaxLen),
+		Synopsis:  strings.TrimSpace(rec.Synopsis),
+		Language:  truncate(rec.Language, languageMaxLen),
+		Publisher: truncate(rec.Publisher, publisherMaxLen),
+		Edition:   truncate(rec.Edition, editionMaxLen),
+	}
+	if rec.ISBN == "" {
+		return book, nil, "isbn is required"
+	}
+	isbn13, err := isbn.Normalize(rec.ISBN)
+	if err != nil {
+		return book, nil, fmt.Sprintf("isbn %q: %s", rec.ISBN, err)
+	}
+	book.ISBN13 = sql.NullString{String: isbn13, Valid: true}
+	if isbn10, ok := isbn.To10(isbn13); ok {
+		book.ISBN10 = sql.NullString{String: isbn10, Valid: true}
+	}
+	if !rec.PublishedDate.IsZero() {
+		book.PublishedDate = sql.NullTime{Time: rec.PublishedDate, Valid: true}
+	}
+	if rec.PageCount > 0 {
+		book.PageCount = sql.NullInt32{Int32: int32(rec.PageCount), Valid: true}
+	}
+	if book.Title == "" {
+		return book, nil, "title is required"
+	}
+	if run.categoryName(rec) == "" {
+		return book, nil, "category is required"
+	}
+	credits := make([]store.AuthorCredit, 0, len(rec.Authors))
+	credited := map[string]bool{}
+	for _, author := range rec.Authors {
+		name := truncate(author.Name, authorNameMaxLen)
+		key := strings.ToLower(name) + "\x00" + author.Role
+		if name == "" || credited[key] {
+			continue
+		}
+		credited[key] = true
+		credits = append(credits, store.AuthorCredit{Name: name, Role: author.Role})
+	}
+	if len(credits) == 0 {
+		return book, nil, "at least one author is required"
+	}
+	return book, credits, ""
+}
+
+func (run *importRun) categoryName(rec *Record) string {
+	if name := truncate(rec.Category, categoryMaxLen); name != "" {
+		return name
+	}
+	return truncate(run.job.Options.DefaultCategory, categoryMaxLen)
+}
+
+func (run *importRun) category(ctx context.Context, name string) (int, error) {
+	key := strings.ToLower(name)
+	if id, ok := run.categories[key]; ok {
+		return id, nil
+	}
+	category, err := run.categoryStore.FindOrInsertByName(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("categoryStore.FindOrInsertByName: %w", err)
+	}
+	if run.categories == nil {
+		run.categories = map[string]int{}
+	}
+	run.categories[key] = category.ID
+	return category.ID, nil
+}
+
+// mergeBook keeps what the catalogue knows about the existing book where
+// the record is silent.
+func mergeBook(book, existing *store.Book) {
+	book.ID = existing.ID
+	book.WorkID = existing.WorkID
+	if book.Synopsis == "" {
+		book.Synopsis = existing.Synopsis
+	}
+	if book.Language == "" {
+		book.Language = existing.Language
+	}
+	if book.Publisher == "" {
+		book.Publisher = existing.Publisher
+	}
+	if book.Edition == "" {
+		book.Edition = existing.Edition
+	}
+	if !book.ISBN10.Valid {
+		book.ISBN10 = existing.ISBN10
+	}
+	if !book.PublishedDate.Valid {
+		book.PublishedDate = existing.PublishedDate
+	}
+	if !book.PageCount.Valid {
+		book.PageCount = existing.PageCount
+	}
+}
+
+func truncate(s string, max int) string {
+	s = strings.TrimSpace(s)
+	for utf8.RuneCountInString(s) > max {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return strings.TrimSpace(s)
+}
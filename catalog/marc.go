@@ -0,0 +1,251 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ISO 2709 delimiters used by MARC21 transmission files.
+const (
+	marcSubfieldDelimiter = 0x1F
+	marcFieldTerminator   = 0x1E
+	marcRecordTerminator  = 0x1D
+	marcLeaderLen         = 24
+	marcDirectoryEntryLen = 12
+)
+
+type marcRecord struct {
+	Leader  string
+	Control []marcControlField
+	Data    []marcDataField
+}
+
+type marcControlField struct {
+	Tag   string
+	Value string
+}
+
+type marcDataField struct {
+	Tag       string
+	Ind1      string
+	Ind2      string
+	Subfields []marcSubfield
+}
+
+type marcSubfield struct {
+	Code  string
+	Value string
+}
+
+func (mr *marcRecord) control(tag string) string {
+	for _, f := range mr.Control {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func (mr *marcRecord) fields(tag string) []marcDataField {
+	var fields []marcDataField
+	for _, f := range mr.Data {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// first returns the first subfield with the given code among the fields
+// with the given tags, tried in order.
+func (mr *marcRecord) first(code string, tags ...string) string {
+	for _, tag := range tags {
+		for _, f := range mr.fields(tag) {
+			if v := f.subfield(code); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func (f marcDataField) subfield(code string) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return strings.TrimSpace(sf.Value)
+		}
+	}
+	return ""
+}
+
+func (f marcDataField) join(codes string) string {
+	var parts []string
+	for _, sf := range f.Subfields {
+		if strings.Contains(codes, sf.Code) {
+			if v := strings.TrimSpace(sf.Value); v != "" {
+				parts = append(parts, v)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// toRecord maps the MARC21 bibliographic fields the catalogue knows about:
+//
+//	020 $a ISBN, 041 $a or 008/35-37 language, 100/700 $a names with $e or
+//	$4 relator, 245 $a $b title, 250 $a edition, 260/264 $b publisher and
+//	$c date, 300 $a extent, 520 $a summary, 650 $a subject as category.
+func (mr *marcRecord) toRecord() (*Record, error) {
+	rec := &Record{
+		ISBN:      isbnFromMARC(mr.first("a", "020")),
+		Edition:   trimPunctuation(mr.first("a", "250")),
+		Publisher: trimPunctuation(mr.first("b", "264", "260")),
+		Synopsis:  mr.first("a", "520"),
+		Category:  trimPunctuation(mr.first("a", "650")),
+		Language:  mr.first("a", "041"),
+	}
+	if rec.Language == "" {
+		if f008 := mr.control("008"); len(f008) >= 38 {
+			rec.Language = strings.TrimSpace(f008[35:38])
+		}
+	}
+	if titles := mr.fields("245"); len(titles) > 0 {
+		rec.Title = trimPunctuation(titles[0].join("ab"))
+	}
+	for _, tag := range []string{"100", "700"} {
+		for _, f := range mr.fields(tag) {
+			name := trimPunctuation(f.subfield("a"))
+			if name == "" {
+				continue
+			}
+			rec.Authors = append(rec.Authors, Credit{Name: name, Role: marcRelator(f)})
+		}
+	}
+	var err error
+	if rec.PublishedDate, err = parseDate(mr.first("c", "264", "260")); err != nil {
+		return nil, err
+	}
+	if rec.PageCount, err = parsePageCount(mr.first("a", "300")); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// marcRelator reads the role of a name from the relator term ($e) or code
+// ($4). Relators the catalogue has no role for are credited as authors.
+func marcRelator(f marcDataField) string {
+	term := strings.ToLower(trimPunctuation(f.subfield("e") + " " + f.subfield("4")))
+	switch {
+	case strings.Contains(term, "edt") || strings.Contains(term, "editor"):
+		return RoleEditor
+	case strings.Contains(term, "trl") || strings.Contains(term, "translator"):
+		return RoleTranslator
+	}
+	return RoleAuthor
+}
+
+// isbnFromMARC drops the qualifier 020 $a may carry, as in
+// "0306406152 (pbk.)".
+func isbnFromMARC(s string) string {
+	if i := strings.IndexAny(s, " ("); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// MARCReader reads MARC21 records in ISO 2709 transmission format. Records
+// are expected in UTF-8 (leader position 09 "a"); MARC-8 content is read as
+// is, with invalid bytes replaced.
+type MARCReader struct {
+	r   *bufio.Reader
+	row int
+}
+
+func NewMARCReader(r io.Reader) *MARCReader {
+	return &MARCReader{r: bufio.NewReader(r)}
+}
+
+func (mr *MARCReader) Row() int {
+	return mr.row
+}
+
+func (mr *MARCReader) Next() (*Record, error) {
+	raw, err := mr.r.ReadBytes(marcRecordTerminator)
+	raw = bytes.TrimLeft(raw, "\r\n ")
+	if err == io.EOF && len(raw) == 0 {
+		return nil, io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	mr.row++
+	m, err := parseISO2709(raw)
+	if err != nil {
+		return nil, &RecordError{Row: mr.row, Err: err}
+	}
+	rec, err := m.toRecord()
+	if err != nil {
+		return nil, &RecordError{Row: mr.row, Err: err}
+	}
+	return rec, nil
+}
+
+func parseISO2709(raw []byte) (*marcRecord, error) {
+	if len(raw) < marcLeaderLen {
+		return nil, errors.New("record is shorter than its leader")
+	}
+	leader := string(raw[:marcLeaderLen])
+	base, err := strconv.Atoi(leader[12:17])
+	if err != nil || base <= marcLeaderLen || base > len(raw) {
+		return nil, fmt.Errorf("invalid base address %q", leader[12:17])
+	}
+	directory := raw[marcLeaderLen : base-1]
+	if len(directory)%marcDirectoryEntryLen != 0 {
+		return nil, errors.New("invalid directory length")
+	}
+	m := &marcRecord{Leader: leader}
+	for i := 0; i < len(directory); i += marcDirectoryEntryLen {
+		entry := string(directory[i : i+marcDirectoryEntryLen])
+		tag := entry[:3]
+		length, err1 := strconv.Atoi(entry[3:7])
+		start, err2 := strconv.Atoi(entry[7:12])
+		if err1 != nil || err2 != nil || length < 1 || start < 0 || base+start+length > len(raw) {
+			return nil, fmt.Errorf("invalid directory entry for tag %s", tag)
+		}
+		data := toUTF8(bytes.TrimSuffix(raw[base+start:base+start+length], []byte{marcFieldTerminator}))
+		if strings.HasPrefix(tag, "00") {
+			m.Control = append(m.Control, marcControlField{Tag: tag, Value: data})
+			continue
+		}
+		m.Data = append(m.Data, parseDataField(tag, data))
+	}
+	return m, nil
+}
+
+func parseDataField(tag, data string) marcDataField {
+	f := marcDataField{Tag: tag, Ind1: " ", Ind2: " "}
+	parts := strings.Split(data, string(rune(marcSubfieldDelimiter)))
+	if ind := parts[0]; len(ind) >= 2 {
+		f.Ind1, f.Ind2 = ind[:1], ind[1:2]
+	}
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		f.Subfields = append(f.Subfields, marcSubfield{Code: part[:1], Value: part[1:]})
+	}
+	return f
+}
+
+func toUTF8(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return strings.ToValidUTF8(string(b), string(utf8.RuneError))
+}
@@ -0,0 +1,234 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// iso2709 builds a MARC21 transmission record out of its fields, each
+// given as its tag and its data without the field terminator. Subfields
+// are written with "$" for the subfield delimiter.
+func iso2709(fields ...[2]string) []byte {
+	var directory, data bytes.Buffer
+	for _, f := range fields {
+		value := strings.ReplaceAll(f[1], "$", string(rune(marcSubfieldDelimiter))) + string(rune(marcFieldTerminator))
+		fmt.Fprintf(&directory, "%s%04d%05d", f[0], len(value), data.Len())
+		data.WriteString(value)
+	}
+	base := marcLeaderLen + directory.Len() + 1
+	length := base + data.Len() + 1
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%05dnam a22%05d i 4500", length, base)
+	b.Write(directory.Bytes())
+	b.WriteByte(marcFieldTerminator)
+	b.Write(data.Bytes())
+	b.WriteByte(marcRecordTerminator)
+	return b.Bytes()
+}
+
+var hobbitFields = [][2]string{
+	{"001", "42"},
+	{"008", "970101s1937    enk           000 1 fre d"},
+	{"020", "  $a0261102214 (pbk.)"},
+	{"100", "1 $aTolkien, J. R. R.,$eauthor."},
+	{"245", "14$aThe hobbit :$bor there and back again /"},
+	{"250", "  $a2nd ed."},
+	{"264", " 1$aLondon :$bAllen & Unwin,$cc1951."},
+	{"300", "  $a310 p. :$bill."},
+	{"520", "  $aBilbo goes on an adventure."},
+	{"650", " 0$aFantasy fiction."},
+	{"700", "1 $aAnderson, Douglas A.,$eeditor."},
+	{"700", "1 $aLauzon, Francis,$4trl"},
+}
+
+var hobbitRecord = &Record{
+	Title: "The hobbit : or there and back again",
+	Authors: []Credit{
+		{Name: "Tolkien, J. R. R.", Role: RoleAuthor},
+		{Name: "Anderson, Douglas A.", Role: RoleEditor},
+		{Name: "Lauzon, Francis", Role: RoleTranslator},
+	},
+	Synopsis:      "Bilbo goes on an adventure.",
+	Language:      "fre",
+	Category:      "Fantasy fiction",
+	ISBN:          "0261102214",
+	Publisher:     "Allen & Unwin",
+	PublishedDate: time.Date(1951, time.January, 1, 0, 0, 0, 0, time.UTC),
+	PageCount:     310,
+	Edition:       "2nd ed",
+}
+
+func TestMARCReader(t *testing.T) {
+	var input bytes.Buffer
+	input.Write(iso2709(hobbitFields...))
+	input.WriteString("\r\n")
+	input.Write(iso2709(
+		[2]string{"041", "  $aeng"},
+		[2]string{"245", "00$aUntitled"},
+	))
+	mr := NewMARCReader(&input)
+
+	rec, err := mr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rec, hobbitRecord) {
+		t.Errorf("record = %+v, want %+v", rec, hobbitRecord)
+	}
+	rec, err = mr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Title != "Untitled" || rec.Language != "eng" || mr.Row() != 2 {
+		t.Errorf("record %d = %+v", mr.Row(), rec)
+	}
+	if _, err = mr.Next(); err != io.EOF {
+		t.Errorf("err = %v, want io.EOF", err)
+	}
+}
+
+func TestMARCReaderMalformed(t *testing.T) {
+	valid := iso2709([2]string{"245", "00$aValid"})
+	// entry replaces the directory entry of the 245 of valid, at offset 24.
+	entry := func(s string) []byte {
+		b := append([]byte(nil), valid...)
+		copy(b[marcLeaderLen:], s)
+		return b
+	}
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"shorter than the leader", []byte("00012nam a22\x1d")},
+		{"non numeric base address", append([]byte("00000nam a22ABCDE i 4500"), valid[marcLeaderLen:]...)},
+		{"negative base address", append([]byte("00000nam a22-0024 i 4500"), valid[marcLeaderLen:]...)},
+		{"base address past the record", append([]byte("00000nam a2299999 i 4500"), valid[marcLeaderLen:]...)},
+		{"directory not in entries", append([]byte("00000nam a2200030 i 4500"), valid[marcLeaderLen:]...)},
+		{"non numeric length", entry("245ABCD00000")},
+		{"negative length", entry("245-00100000")},
+		{"empty field", entry("245000000000")},
+		{"negative start", entry("2450010-9999")},
+		{"field past the record", entry("245001000050")},
+		{"length past the record", entry("245999900000")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append(append([]byte(nil), tt.raw...), valid...)
+			mr := NewMARCReader(bytes.NewReader(input))
+			_, err := mr.Next()
+			var recordErr *RecordError
+			if !errors.As(err, &recordErr) || recordErr.Row != 1 {
+				t.Fatalf("err = %v, want a RecordError for row 1", err)
+			}
+			// A malformed record does not stop the reading.
+			rec, err := mr.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec.Title != "Valid" {
+				t.Errorf("title = %q, want Valid", rec.Title)
+			}
+		})
+	}
+}
+
+func TestMARCXMLReader(t *testing.T) {
+	var out bytes.Buffer
+	w := NewMARCXMLWriter(&out)
+	if err := w.Write(hobbitRecord); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mr := NewMARCXMLReader(&out)
+	rec, err := mr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rec, hobbitRecord) {
+		t.Errorf("record = %+v, want %+v", rec, hobbitRecord)
+	}
+	if _, err = mr.Next(); err != io.EOF {
+		t.Errorf("err = %v, want io.EOF", err)
+	}
+}
+
+func TestMARCXMLReaderRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		want    []*Record
+		wantErr string
+	}{
+		{
+			name: "bare record",
+			doc: `<record xmlns="http://www.loc.gov/MARC21/slim">
+  <controlfield tag="008">970101s1937    enk           000 1 eng d</controlfield>
+  <datafield tag="245" ind1="1" ind2="0"><subfield code="a">Dune /</subfield></datafield>
+  <datafield tag="260" ind1=" " ind2=" "><subfield code="c">[1965?]</subfield></datafield>
+</record>`,
+			want: []*Record{{
+				Title:         "Dune",
+				Language:      "eng",
+				PublishedDate: time.Date(1965, time.January, 1, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "collection",
+			doc: `<?xml version="1.0"?>
+<marc:collection xmlns:marc="http://www.loc.gov/MARC21/slim">
+  <marc:record><marc:datafield tag="245"><marc:subfield code="a">One</marc:subfield></marc:datafield></marc:record>
+  <marc:record><marc:datafield tag="245"><marc:subfield code="a">Two</marc:subfield></marc:datafield></marc:record>
+</marc:collection>`,
+			want: []*Record{{Title: "One"}, {Title: "Two"}},
+		},
+		{
+			name: "invalid date",
+			doc: `<collection>
+  <record><datafield tag="264"><subfield code="c">someday</subfield></datafield></record>
+  <record><datafield tag="245"><subfield code="a">After</subfield></datafield></record>
+</collection>`,
+			want:    []*Record{nil, {Title: "After"}},
+			wantErr: `row 1: invalid publication date "someday"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := NewMARCXMLReader(strings.NewReader(tt.doc))
+			for i, want := range tt.want {
+				rec, err := mr.Next()
+				if want == nil {
+					if err == nil || err.Error() != tt.wantErr {
+						t.Errorf("record %d: err = %v, want %s", i+1, err, tt.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("record %d: %v", i+1, err)
+				}
+				if !reflect.DeepEqual(rec, want) {
+					t.Errorf("record %d = %+v, want %+v", i+1, rec, want)
+				}
+			}
+			if _, err := mr.Next(); err != io.EOF {
+				t.Errorf("err = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestMARCXMLReaderNotWellFormed(t *testing.T) {
+	mr := NewMARCXMLReader(strings.NewReader(`<collection><record><datafield tag="245"></record>`))
+	_, err := mr.Next()
+	var recordErr *RecordError
+	if err == nil || err == io.EOF || errors.As(err, &recordErr) {
+		t.Errorf("err = %v, want the error ending the document", err)
+	}
+}
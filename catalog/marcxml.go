@@ -0,0 +1,73 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+type marcXMLRecord struct {
+	Leader  string `xml:"leader"`
+	Control []struct {
+		Tag   string `xml:"tag,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"controlfield"`
+	Data []struct {
+		Tag       string `xml:"tag,attr"`
+		Ind1      string `xml:"ind1,attr"`
+		Ind2      string `xml:"ind2,attr"`
+		Subfields []struct {
+			Code  string `xml:"code,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"subfield"`
+	} `xml:"datafield"`
+}
+
+// MARCXMLReader streams the record elements of a MARCXML document, whether
+// wrapped in a collection or not, without loading the whole document.
+type MARCXMLReader struct {
+	d   *xml.Decoder
+	row int
+}
+
+func NewMARCXMLReader(r io.Reader) *MARCXMLReader {
+	return &MARCXMLReader{d: xml.NewDecoder(r)}
+}
+
+func (mr *MARCXMLReader) Row() int {
+	return mr.row
+}
+
+func (mr *MARCXMLReader) Next() (*Record, error) {
+	for {
+		tok, err := mr.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		mr.row++
+		x := marcXMLRecord{}
+		if err = mr.d.DecodeElement(&x, &start); err != nil {
+			// The document is no longer well-formed past this point.
+			return nil, err
+		}
+		m := &marcRecord{Leader: x.Leader}
+		for _, c := range x.Control {
+			m.Control = append(m.Control, marcControlField{Tag: c.Tag, Value: c.Value})
+		}
+		for _, d := range x.Data {
+			f := marcDataField{Tag: d.Tag, Ind1: d.Ind1, Ind2: d.Ind2}
+			for _, sf := range d.Subfields {
+				f.Subfields = append(f.Subfields, marcSubfield{Code: sf.Code, Value: sf.Value})
+			}
+			m.Data = append(m.Data, f)
+		}
+		rec, err := m.toRecord()
+		if err != nil {
+			return nil, &RecordError{Row: mr.row, Err: err}
+		}
+		return rec, nil
+	}
+}
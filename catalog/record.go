@@ -0,0 +1,138 @@
+// Package catalog reads and writes bibliographic records in the interchange
+// formats used by library systems, CSV, MARC21 and MARCXML, and imports them
+// into the book catalogue.
+package catalog
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatMARC    = "marc"
+	FormatMARCXML = "marcxml"
+	FormatJSONL   = "jsonl"
+)
+
+// Record is one book as described by an export, before it is matched
+// against the catalogue. ID and the rating aggregates are only known for
+// catalogued books and are ignored on import.
+type Record struct {
+	ID            int
+	Title         string
+	Authors       []Credit
+	Synopsis      string
+	Language      string
+	Category      string
+	ISBN          string
+	Publisher     string
+	PublishedDate time.Time
+	PageCount     int
+	Edition       string
+	RatingCount   int
+	RatingAverage float64
+}
+
+type Credit struct {
+	Name string
+	Role string
+}
+
+const (
+	RoleAuthor     = "author"
+	RoleEditor     = "editor"
+	RoleTranslator = "translator"
+)
+
+// RecordError reports a record that could not be read. Reading can go on
+// with the next record.
+type RecordError struct {
+	Row int
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reader yields records one at a time and returns io.EOF after the last
+// one. A *RecordError only concerns the current record.
+type Reader interface {
+	Next() (*Record, error)
+	// Row is the 1-based position of the last record returned by Next.
+	Row() int
+}
+
+// NewReader returns the reader for format. The mapping only applies to CSV.
+func NewReader(format string, r io.Reader, mapping map[string]string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r, mapping)
+	case FormatMARC:
+		return NewMARCReader(r), nil
+	case FormatMARCXML:
+		return NewMARCXMLReader(r), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+// parseDate accepts a full date or, as catalogues often only record it, a
+// year possibly surrounded by noise such as "c1999." or "[2003?]".
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.Parse("2006-01-02", s); err == nil {
+		return date, nil
+	}
+	year := yearPattern.FindString(s)
+	if year == "" {
+		return time.Time{}, fmt.Errorf("invalid publication date %q", s)
+	}
+	y, _ := strconv.Atoi(year)
+	return time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+var digitsPattern = regexp.MustCompile(`\d+`)
+
+// parsePageCount takes the first number of an extent such as "xii, 352 p."
+// or "352".
+func parsePageCount(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	if m := digitsPattern.FindString(s); m != "" {
+		n, _ := strconv.Atoi(m)
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid page count %q", s)
+}
+
+// trimPunctuation strips the ISBD punctuation MARC subfields end with. A
+// final period is kept after an initial, as in "Tolkien, J. R. R.".
+func trimPunctuation(s string) string {
+	s = strings.TrimRight(strings.TrimSpace(s), " /:;,=")
+	if strings.HasSuffix(s, ".") {
+		words := strings.Fields(s)
+		if last := words[len(words)-1]; len([]rune(last)) > 2 {
+			s = strings.TrimSuffix(s, ".")
+		}
+	}
+	return strings.TrimSpace(s)
+}
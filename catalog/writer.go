@@ -0,0 +1,338 @@
+package catalog
+
+import (
+	"awesome-api/store"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Writer writes records one at a time. Close writes what the format needs
+// after the last record; it does not close the underlying writer.
+type Writer interface {
+	Write(rec *Record) error
+	Close() error
+}
+
+// NewWriter returns the writer for format. MARC21 transmission files are
+// read but not written, partners get MARCXML instead.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatJSONL:
+		return NewJSONLWriter(w), nil
+	case FormatMARCXML:
+		return NewMARCXMLWriter(w), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+var contentTypes = map[string]string{
+	FormatCSV:     "text/csv; charset=utf-8",
+	FormatJSONL:   "application/jsonl",
+	FormatMARC:    "application/marc",
+	FormatMARCXML: "application/marcxml+xml",
+}
+
+var extensions = map[string]string{
+	FormatCSV:     ".csv",
+	FormatJSONL:   ".jsonl",
+	FormatMARC:    ".mrc",
+	FormatMARCXML: ".xml",
+}
+
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+func Extension(format string) string {
+	return extensions[format]
+}
+
+// RecordFromBook describes a catalogued book as an export record.
+func RecordFromBook(book *store.CatalogBook) *Record {
+	rec := &Record{
+		ID:            book.ID,
+		Title:         book.Title,
+		Synopsis:      book.Synopsis,
+		Language:      book.Language,
+		Category:      book.CategoryName,
+		ISBN:          book.ISBN13.String,
+		Publisher:     book.Publisher,
+		PublishedDate: book.PublishedDate.Time,
+		PageCount:     int(book.PageCount.Int32),
+		Edition:       book.Edition,
+		RatingCount:   book.RatingCount,
+		RatingAverage: book.RatingAverage,
+	}
+	for _, author := range book.Authors {
+		rec.Authors = append(rec.Authors, Credit{Name: author.Name, Role: author.Role})
+	}
+	return rec
+}
+
+func (rec *Record) names(role string) []string {
+	var names []string
+	for _, c := range rec.Authors {
+		if c.Role == role || (role == RoleAuthor && c.Role == "") {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func (rec *Record) publishedDate() string {
+	if rec.PublishedDate.IsZero() {
+		return ""
+	}
+	return rec.PublishedDate.Format("2006-01-02")
+}
+
+// Export-only CSV columns, around the fields the CSV reader knows so an
+// export can be imported again as is.
+const (
+	fieldID            = "id"
+	fieldRatingCount   = "rating_count"
+	fieldRatingAverage = "rating_average"
+)
+
+type CSVWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (cw *CSVWriter) writeHeader() error {
+	if cw.wroteHeader {
+		return nil
+	}
+	cw.wroteHeader = true
+	header := append([]string{fieldID}, csvFields...)
+	return cw.w.Write(append(header, fieldRatingCount, fieldRatingAverage))
+}
+
+func (cw *CSVWriter) Write(rec *Record) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	values := map[string]string{
+		FieldTitle:         rec.Title,
+		FieldAuthors:       strings.Join(rec.names(RoleAuthor), csvValueSeparator+" "),
+		FieldEditors:       strings.Join(rec.names(RoleEditor), csvValueSeparator+" "),
+		FieldTranslators:   strings.Join(rec.names(RoleTranslator), csvValueSeparator+" "),
+		FieldSynopsis:      rec.Synopsis,
+		FieldLanguage:      rec.Language,
+		FieldCategory:      rec.Category,
+		FieldISBN:          rec.ISBN,
+		FieldPublisher:     rec.Publisher,
+		FieldPublishedDate: rec.publishedDate(),
+		FieldEdition:       rec.Edition,
+	}
+	if rec.PageCount > 0 {
+		values[FieldPageCount] = strconv.Itoa(rec.PageCount)
+	}
+	row := []string{strconv.Itoa(rec.ID)}
+	for _, field := range csvFields {
+		row = append(row, values[field])
+	}
+	row = append(row,
+		strconv.Itoa(rec.RatingCount),
+		strconv.FormatFloat(rec.RatingAverage, 'f', 2, 64),
+	)
+	return cw.w.Write(row)
+}
+
+// Close writes the header of an empty export and flushes the rows.
+func (cw *CSVWriter) Close() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonlRecord struct {
+	ID            int           `json:"id"`
+	Title         string        `json:"title"`
+	Authors       []jsonlCredit `json:"authors"`
+	Synopsis      string        `json:"synopsis"`
+	Language      string        `json:"language"`
+	Category      string        `json:"category"`
+	ISBN          string        `json:"isbn,omitempty"`
+	Publisher     string        `json:"publisher,omitempty"`
+	PublishedDate string        `json:"published_date,omitempty"`
+	PageCount     int           `json:"page_count,omitempty"`
+	Edition       string        `json:"edition,omitempty"`
+	RatingCount   int           `json:"rating_count"`
+	RatingAverage float64       `json:"rating_average"`
+}
+
+type jsonlCredit struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// JSONLWriter writes one JSON object per line.
+type JSONLWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	bw := bufio.NewWriter(w)
+	return &JSONLWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (jw *JSONLWriter) Write(rec *Record) error {
+	out := jsonlRecord{
+		ID:            rec.ID,
+		Title:         rec.Title,
+		Authors:       make([]jsonlCredit, 0, len(rec.Authors)),
+		Synopsis:      rec.Synopsis,
+		Language:      rec.Language,
+		Category:      rec.Category,
+		ISBN:          rec.ISBN,
+		Publisher:     rec.Publisher,
+		PublishedDate: rec.publishedDate(),
+		PageCount:     rec.PageCount,
+		Edition:       rec.Edition,
+		RatingCount:   rec.RatingCount,
+		RatingAverage: rec.RatingAverage,
+	}
+	for _, c := range rec.Authors {
+		out.Authors = append(out.Authors, jsonlCredit{Name: c.Name, Role: c.Role})
+	}
+	return jw.enc.Encode(out)
+}
+
+func (jw *JSONLWriter) Close() error {
+	return jw.w.Flush()
+}
+
+const marcXMLNamespace = "http://www.loc.gov/MARC21/slim"
+
+// marcLeader describes a new, language material, monograph record encoded
+// in UTF-8; lengths and base address are left to the reader in MARCXML.
+const marcLeader = "00000nam a2200000 i 4500"
+
+type marcXMLOut struct {
+	XMLName xml.Name          `xml:"record"`
+	Leader  string            `xml:"leader"`
+	Control []marcXMLOutField `xml:"controlfield"`
+	Data    []marcXMLOutData  `xml:"datafield"`
+}
+
+type marcXMLOutField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcXMLOutData struct {
+	Tag       string               `xml:"tag,attr"`
+	Ind1      string               `xml:"ind1,attr"`
+	Ind2      string               `xml:"ind2,attr"`
+	Subfields []marcXMLOutSubfield `xml:"subfield"`
+}
+
+type marcXMLOutSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// MARCXMLWriter writes a MARCXML collection, mapping the fields back the
+// way MARC records are read. Rating aggregates have no MARC21 field and are
+// left out.
+type MARCXMLWriter struct {
+	w       *bufio.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+func NewMARCXMLWriter(w io.Writer) *MARCXMLWriter {
+	bw := bufio.NewWriter(w)
+	return &MARCXMLWriter{w: bw, enc: xml.NewEncoder(bw)}
+}
+
+func (mw *MARCXMLWriter) start() error {
+	if mw.started {
+		return nil
+	}
+	mw.started = true
+	if _, err := mw.w.WriteString(xml.Header); err != nil {
+		return err
+	}
+	_, err := mw.w.WriteString(`<collection xmlns="` + marcXMLNamespace + `">` + "\n")
+	return err
+}
+
+func (mw *MARCXMLWriter) Write(rec *Record) error {
+	if err := mw.start(); err != nil {
+		return err
+	}
+	if err := mw.enc.Encode(toMARCXML(rec)); err != nil {
+		return err
+	}
+	_, err := mw.w.WriteString("\n")
+	return err
+}
+
+func (mw *MARCXMLWriter) Close() error {
+	if err := mw.start(); err != nil {
+		return err
+	}
+	if _, err := mw.w.WriteString("</collection>\n"); err != nil {
+		return err
+	}
+	return mw.w.Flush()
+}
+
+func toMARCXML(rec *Record) marcXMLOut {
+	out := marcXMLOut{Leader: marcLeader}
+	if rec.ID > 0 {
+		out.Control = append(out.Control, marcXMLOutField{Tag: "001", Value: strconv.Itoa(rec.ID)})
+	}
+	field := func(tag, ind1, ind2 string, subfields ...string) {
+		f := marcXMLOutData{Tag: tag, Ind1: ind1, Ind2: ind2}
+		for i := 0; i+1 < len(subfields); i += 2 {
+			if subfields[i+1] != "" {
+				f.Subfields = append(f.Subfields, marcXMLOutSubfield{Code: subfields[i], Value: subfields[i+1]})
+			}
+		}
+		if len(f.Subfields) > 0 {
+			out.Data = append(out.Data, f)
+		}
+	}
+	field("020", " ", " ", "a", rec.ISBN)
+	field("041", " ", " ", "a", rec.Language)
+	// The first author is the main entry, everyone else an added entry.
+	mainEntry := true
+	for _, c := range rec.Authors {
+		tag := "700"
+		if mainEntry && (c.Role == RoleAuthor || c.Role == "") {
+			tag, mainEntry = "100", false
+		}
+		relator := c.Role
+		if relator == "" {
+			relator = RoleAuthor
+		}
+		field(tag, "1", " ", "a", c.Name, "e", relator)
+	}
+	field("245", "0", "0", "a", rec.Title)
+	field("250", " ", " ", "a", rec.Edition)
+	field("264", " ", "1", "b", rec.Publisher, "c", rec.publishedDate())
+	if rec.PageCount > 0 {
+		field("300", " ", " ", "a", strconv.Itoa(rec.PageCount)+" pages")
+	}
+	field("520", " ", " ", "a", rec.Synopsis)
+	field("650", " ", "4", "a", rec.Category)
+	return out
+}
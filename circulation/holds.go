@@ -0,0 +1,150 @@
+package circulation
+
+import (
+	mailer "awesome-api/mail"
+	"awesome-api/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// HoldQueue moves holds through their lifecycle: it hands freed copies to the
+// next patrons in line and lets them know a copy is reserved for them.
+type HoldQueue struct {
+	log               zerolog.Logger
+	holdStore         store.HoldStore
+	userStore         store.UserStore
+	bookStore         store.BookStore
+	notificationStore store.NotificationStore
+	mailer            mailer.EmailSender
+	claimWindow       time.Duration
+}
+
+func NewHoldQueue(
+	log zerolog.Logger,
+	holdStore store.HoldStore,
+	userStore store.UserStore,
+	bookStore store.BookStore,
+	notificationStore store.NotificationStore,
+	mailer mailer.EmailSender,
+	claimWindow time.Duration,
+) *HoldQueue {
+	return &HoldQueue{
+		log:               log,
+		holdStore:         holdStore,
+		userStore:         userStore,
+		bookStore:         bookStore,
+		notificationStore: notificationStore,
+		mailer:            mailer,
+		claimWindow:       claimWindow,
+	}
+}
+
+// Promote reserves every free copy of the book for the oldest waiting holds.
+// Their owners are notified by NotifyReady and MailReady, subscribed to the
+// hold.ready events recorded with the promotion.
+func (q *HoldQueue) Promote(ctx context.Context, bookId int) error {
+	if _, err := q.holdStore.Promote(ctx, bookId, q.claimWindow); err != nil {
+		return fmt.Errorf("holdStore.Promote: %w", err)
+	}
+	return nil
+}
+
+// NotifyReady lists the ready hold of a hold.ready event in the
+// notifications of its owner, unless the claim window already lapsed.
+func (q *HoldQueue) NotifyReady(ctx context.Context, event *store.DomainEvent) error {
+	hold, err := holdEventData(event)
+	if err != nil || time.Now().After(hold.ExpiresAt) {
+		return err
+	}
+	title, err := q.bookTitle(ctx, hold.BookID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"hold_id":    hold.ID,
+		"book_id":    hold.BookID,
+		"title":      title,
+		"expires_at": hold.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	notification := &store.Notification{
+		UserID: hold.UserID,
+		Kind:   store.NotificationKindHoldReady,
+		Data:   data,
+	}
+	if err := q.notificationStore.Insert(ctx, notification); err != nil {
+		return fmt.Errorf("notificationStore.Insert: %w", err)
+	}
+	return nil
+}
+
+// MailReady mails the owner of the ready hold of a hold.ready event, unless
+// the claim window already lapsed.
+func (q *HoldQueue) MailReady(ctx context.Context, event *store.DomainEvent) error {
+	hold, err := holdEventData(event)
+	if err != nil || time.Now().After(hold.ExpiresAt) {
+		return err
+	}
+	title, err := q.bookTitle(ctx, hold.BookID)
+	if err != nil {
+		return err
+	}
+	user, err := q.userStore.FindOneById(ctx, hold.UserID)
+	if err != nil {
+		return fmt.Errorf("userStore.FindOneById: %w", err)
+	}
+	to := mailer.Recipient{
+		ID:     user.ID,
+		Email:  user.Email,
+		Name:   user.Fullname,
+		Locale: user.Locale,
+	}
+	if err := q.mailer.SendHoldReady(ctx, to, hold.BookID, title, hold.ExpiresAt); err != nil {
+		return fmt.Errorf("mailer.SendHoldReady: %w", err)
+	}
+	return nil
+}
+
+func holdEventData(event *store.DomainEvent) (*store.HoldEventData, error) {
+	hold := &store.HoldEventData{}
+	if err := json.Unmarshal(event.Payload, hold); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s payload: %w", event.Type, err)
+	}
+	return hold, nil
+}
+
+func (q *HoldQueue) bookTitle(ctx context.Context, bookId int) (string, error) {
+	book, err := q.bookStore.FindOneById(ctx, bookId)
+	if err != nil {
+		return "", fmt.Errorf("bookStore.FindOneById: %w", err)
+	}
+	return book.Title, nil
+}
+
+// Sweep expires ready holds whose claim window lapsed and passes their
+// copies on to the next patrons in line.
+func (q *HoldQueue) Sweep(ctx context.Context) error {
+	expired, err := q.holdStore.ExpireLapsed(ctx)
+	if err != nil {
+		return fmt.Errorf("holdStore.ExpireLapsed: %w", err)
+	}
+	if len(expired) > 0 {
+		q.log.Info().Int("count", len(expired)).Msg("expired lapsed holds")
+	}
+	bookIds, err := q.holdStore.FindBookIdsWaiting(ctx)
+	if err != nil {
+		return fmt.Errorf("holdStore.FindBookIdsWaiting: %w", err)
+	}
+	for _, bookId := range bookIds {
+		if err := q.Promote(ctx, bookId); err != nil {
+			q.log.Error().Err(err).Int("book_id", bookId).Msg("failed to promote holds")
+		}
+	}
+	return nil
+}
@@ -17,11 +17,73 @@ type Config struct {
 	MailPort                          int    `mapstructure:"MAIL_PORT"`
 	MailUsername                      string `mapstructure:"MAIL_USERNAME"`
 	MailPassword                      string `mapstructure:"MAIL_PASSWORD"`
+	MailSenderName                    string `mapstructure:"MAIL_SENDER_NAME"`
+	MailSenderAddress                 string `mapstructure:"MAIL_SENDER_ADDRESS"`
+	MailTemplateDir                   string `mapstructure:"MAIL_TEMPLATE_DIR"`
+	MailTransport                     string `mapstructure:"MAIL_TRANSPORT"`
+	MailSecurity                      string `mapstructure:"MAIL_SECURITY"`
+	MailTimeoutSecond                 int    `mapstructure:"MAIL_TIMEOUT_SECOND"`
+	MailPoolSize                      int    `mapstructure:"MAIL_POOL_SIZE"`
+	MailFileDir                       string `mapstructure:"MAIL_FILE_DIR"`
+	MailFileFormat                    string `mapstructure:"MAIL_FILE_FORMAT"`
+	MailDKIMDomain                    string `mapstructure:"MAIL_DKIM_DOMAIN"`
+	MailDKIMSelector                  string `mapstructure:"MAIL_DKIM_SELECTOR"`
+	MailDKIMPrivateKeyPath            string `mapstructure:"MAIL_DKIM_PRIVATE_KEY_PATH"`
+	MailDKIMHeaders                   string `mapstructure:"MAIL_DKIM_HEADERS"`
+	MailUnsubscribeSecret             string `mapstructure:"MAIL_UNSUBSCRIBE_SECRET"`
+	MailWorkers                       int    `mapstructure:"MAIL_WORKERS"`
+	MailBatchSize                     int    `mapstructure:"MAIL_BATCH_SIZE"`
+	MailMaxAttempts                   int    `mapstructure:"MAIL_MAX_ATTEMPTS"`
+	MailRetryBaseSecond               int    `mapstructure:"MAIL_RETRY_BASE_SECOND"`
+	MailRetryMaxMinute                int    `mapstructure:"MAIL_RETRY_MAX_MINUTE"`
+	MailPollIntervalSecond            int    `mapstructure:"MAIL_POLL_INTERVAL_SECOND"`
+	BounceWebhookSecret               string `mapstructure:"BOUNCE_WEBHOOK_SECRET"`
+	BounceMaildir                     string `mapstructure:"BOUNCE_MAILDIR"`
+	BouncePollIntervalSecond          int    `mapstructure:"BOUNCE_POLL_INTERVAL_SECOND"`
 	LoggerLevel                       string `mapstructure:"LOGGER_LEVEL"`
 	LoggerOutput                      string `mapstructure:"stdout"`
 	TokenAccessExpirationMinute       int    `mapstructure:"TOKEN_ACCESS_EXPIRATION_MINUTE"`
 	TokenRefreshExpirationMinute      int    `mapstructure:"TOKEN_REFRESH_EXPIRATION_MINUTE"`
 	TokenVerificationExpirationMinute int    `mapstructure:"TOKEN_VERIFICATION_EXPIRATION_MINUTE"`
+	AdminEmails                       string `mapstructure:"ADMIN_EMAILS"`
+	BlobDriver                        string `mapstructure:"BLOB_DRIVER"`
+	BlobLocalPath                     string `mapstructure:"BLOB_LOCAL_PATH"`
+	S3Endpoint                        string `mapstructure:"S3_ENDPOINT"`
+	S3Region                          string `mapstructure:"S3_REGION"`
+	S3Bucket                          string `mapstructure:"S3_BUCKET"`
+	S3AccessKey                       string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey                       string `mapstructure:"S3_SECRET_KEY"`
+	S3PathStyle                       bool   `mapstructure:"S3_PATH_STYLE"`
+	CoverMaxSizeKB                    int64  `mapstructure:"COVER_MAX_SIZE_KB"`
+	BookFileMaxSizeMB                 int64  `mapstructure:"BOOK_FILE_MAX_SIZE_MB"`
+	LoanPeriodDays                    int    `mapstructure:"LOAN_PERIOD_DAYS"`
+	LoanMaxPerUser                    int    `mapstructure:"LOAN_MAX_PER_USER"`
+	LoanMaxRenewals                   int    `mapstructure:"LOAN_MAX_RENEWALS"`
+	LoanExpiryIntervalMinute          int    `mapstructure:"LOAN_EXPIRY_INTERVAL_MINUTE"`
+	HoldClaimWindowHours              int    `mapstructure:"HOLD_CLAIM_WINDOW_HOURS"`
+	LoanDueReminderHours              int    `mapstructure:"LOAN_DUE_REMINDER_HOURS"`
+	ImportMaxSizeMB                   int64  `mapstructure:"IMPORT_MAX_SIZE_MB"`
+	CatalogJobPollIntervalSecond      int    `mapstructure:"CATALOG_JOB_POLL_INTERVAL_SECOND"`
+	RecommendationIntervalMinute      int    `mapstructure:"RECOMMENDATION_INTERVAL_MINUTE"`
+	RankingIntervalMinute             int    `mapstructure:"RANKING_INTERVAL_MINUTE"`
+	RankingTrendingWindowDays         int    `mapstructure:"RANKING_TRENDING_WINDOW_DAYS"`
+	RankingMostReadWindowDays         int    `mapstructure:"RANKING_MOST_READ_WINDOW_DAYS"`
+	RankingSize                       int    `mapstructure:"RANKING_SIZE"`
+	AlertIntervalMinute               int    `mapstructure:"ALERT_INTERVAL_MINUTE"`
+	AlertDigestDays                   int    `mapstructure:"ALERT_DIGEST_DAYS"`
+	WebhookWorkers                    int    `mapstructure:"WEBHOOK_WORKERS"`
+	WebhookBatchSize                  int    `mapstructure:"WEBHOOK_BATCH_SIZE"`
+	WebhookMaxAttempts                int    `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryBaseSecond            int    `mapstructure:"WEBHOOK_RETRY_BASE_SECOND"`
+	WebhookRetryMaxMinute             int    `mapstructure:"WEBHOOK_RETRY_MAX_MINUTE"`
+	WebhookDisableAfter               int    `mapstructure:"WEBHOOK_DISABLE_AFTER"`
+	WebhookTimeoutSecond              int    `mapstructure:"WEBHOOK_TIMEOUT_SECOND"`
+	WebhookPollIntervalSecond         int    `mapstructure:"WEBHOOK_POLL_INTERVAL_SECOND"`
+	EventBatchSize                    int    `mapstructure:"EVENT_BATCH_SIZE"`
+	EventMaxAttempts                  int    `mapstructure:"EVENT_MAX_ATTEMPTS"`
+	EventRetryBaseSecond              int    `mapstructure:"EVENT_RETRY_BASE_SECOND"`
+	EventRetryMaxMinute               int    `mapstructure:"EVENT_RETRY_MAX_MINUTE"`
+	EventPollIntervalSecond           int    `mapstructure:"EVENT_POLL_INTERVAL_SECOND"`
 }
 
 func LoadConfig(path string) (Config, error) {
@@ -0,0 +1,160 @@
+package epub
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+)
+
+const (
+	MimeType      = "application/epub+zip"
+	containerPath = "META-INF/container.xml"
+	mimetypePath  = "mimetype"
+)
+
+type Metadata struct {
+	Title    string   `json:"title"`
+	Creators []string `json:"creators"`
+	Language string   `json:"language"`
+	ISBN     string   `json:"isbn"`
+	Synopsis string   `json:"synopsis"`
+}
+
+type container struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type opfPackage struct {
+	Metadata struct {
+		Titles      []string        `xml:"http://purl.org/dc/elements/1.1/ title"`
+		Creators    []string        `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Languages   []string        `xml:"http://purl.org/dc/elements/1.1/ language"`
+		Description []string        `xml:"http://purl.org/dc/elements/1.1/ description"`
+		Identifiers []opfIdentifier `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+	} `xml:"metadata"`
+}
+
+type opfIdentifier struct {
+	Scheme string `xml:"http://www.idpf.org/2007/opf scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// Parse reads the OPF package document of the EPUB in r and returns its
+// Dublin Core metadata.
+func Parse(r io.ReaderAt, size int64) (*Metadata, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open epub archive: %w", err)
+	}
+	if err = checkMimetype(zr); err != nil {
+		return nil, err
+	}
+	c := container{}
+	if err = decodeXML(zr, containerPath, &c); err != nil {
+		return nil, err
+	}
+	opfPath := ""
+	for _, rootfile := range c.Rootfiles {
+		if rootfile.MediaType == "application/oebps-package+xml" || opfPath == "" {
+			opfPath = rootfile.FullPath
+		}
+	}
+	if opfPath == "" {
+		return nil, fmt.Errorf("epub container has no rootfile")
+	}
+	pkg := opfPackage{}
+	if err = decodeXML(zr, path.Clean(opfPath), &pkg); err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{
+		Title:    first(pkg.Metadata.Titles),
+		Language: first(pkg.Metadata.Languages),
+		Synopsis: first(pkg.Metadata.Description),
+	}
+	for _, creator := range pkg.Metadata.Creators {
+		if creator = strings.TrimSpace(creator); creator != "" {
+			meta.Creators = append(meta.Creators, creator)
+		}
+	}
+	for _, identifier := range pkg.Metadata.Identifiers {
+		if isbn := isbnFromIdentifier(identifier); isbn != "" {
+			meta.ISBN = isbn
+			break
+		}
+	}
+	return meta, nil
+}
+
+func checkMimetype(zr *zip.Reader) error {
+	f, err := zr.Open(mimetypePath)
+	if err != nil {
+		return fmt.Errorf("epub archive has no mimetype entry")
+	}
+	defer f.Close()
+	mimetype, err := io.ReadAll(io.LimitReader(f, 64))
+	if err != nil {
+		return fmt.Errorf("failed to read epub mimetype: %w", err)
+	}
+	if strings.TrimSpace(string(mimetype)) != MimeType {
+		return fmt.Errorf("unexpected epub mimetype: %q", mimetype)
+	}
+	return nil
+}
+
+func decodeXML(zr *zip.Reader, name string, v interface{}) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+	if err = xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+func first(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// isbnFromIdentifier recognises both EPUB 2 style identifiers
+// (opf:scheme="ISBN") and EPUB 3 style URNs ("urn:isbn:...").
+func isbnFromIdentifier(identifier opfIdentifier) string {
+	value := strings.TrimSpace(identifier.Value)
+	lower := strings.ToLower(value)
+	switch {
+	case strings.EqualFold(identifier.Scheme, "isbn"):
+	case strings.HasPrefix(lower, "urn:isbn:"):
+		value = value[len("urn:isbn:"):]
+	case strings.HasPrefix(lower, "isbn:"):
+		value = value[len("isbn:"):]
+	default:
+		return ""
+	}
+	digits := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == 'X' || r == 'x' {
+			return r
+		}
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return unicode.ReplacementChar
+	}, value)
+	if strings.ContainsRune(digits, unicode.ReplacementChar) || (len(digits) != 10 && len(digits) != 13) {
+		return ""
+	}
+	return strings.ToUpper(digits)
+}
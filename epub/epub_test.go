@@ -0,0 +1,191 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testContainer = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>`
+
+const epub2OPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0" unique-identifier="bookid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:title>  </dc:title>
+    <dc:title> Le Petit Prince </dc:title>
+    <dc:creator opf:role="aut">Antoine de Saint-Exupéry</dc:creator>
+    <dc:creator>  </dc:creator>
+    <dc:creator opf:role="ill">Antoine de Saint-Exupéry (ill.)</dc:creator>
+    <dc:language>fr</dc:language>
+    <dc:description>Un aviateur rencontre un petit prince.</dc:description>
+    <dc:identifier id="bookid" opf:scheme="UUID">3c1a0a6e-9b0e-4a0a-8f1e-1b8d2c4f5e6a</dc:identifier>
+    <dc:identifier opf:scheme="ISBN">978-2-07-040850-4</dc:identifier>
+  </metadata>
+</package>`
+
+const epub3OPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="pub-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="pub-id">urn:isbn:080442957x</dc:identifier>
+    <dc:title>Moby-Dick</dc:title>
+    <dc:creator>Herman Melville</dc:creator>
+    <dc:language>en</dc:language>
+  </metadata>
+</package>`
+
+func buildEPUB(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	// The mimetype entry comes first, as in a real EPUB.
+	names := []string{"mimetype"}
+	for name := range files {
+		if name != "mimetype" {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  *Metadata
+		err   string
+	}{
+		{
+			name: "epub 2",
+			files: map[string]string{
+				"mimetype":               MimeType,
+				"META-INF/container.xml": testContainer,
+				"OEBPS/content.opf":      epub2OPF,
+			},
+			want: &Metadata{
+				Title:    "Le Petit Prince",
+				Creators: []string{"Antoine de Saint-Exupéry", "Antoine de Saint-Exupéry (ill.)"},
+				Language: "fr",
+				ISBN:     "9782070408504",
+				Synopsis: "Un aviateur rencontre un petit prince.",
+			},
+		},
+		{
+			name: "epub 3",
+			files: map[string]string{
+				"mimetype":               MimeType + "\n",
+				"META-INF/container.xml": testContainer,
+				"OEBPS/content.opf":      epub3OPF,
+			},
+			want: &Metadata{
+				Title:    "Moby-Dick",
+				Creators: []string{"Herman Melville"},
+				Language: "en",
+				ISBN:     "080442957X",
+			},
+		},
+		{
+			name: "no mimetype",
+			files: map[string]string{
+				"META-INF/container.xml": testContainer,
+				"OEBPS/content.opf":      epub3OPF,
+			},
+			err: "no mimetype",
+		},
+		{
+			name: "other mimetype",
+			files: map[string]string{
+				"mimetype":               "application/zip",
+				"META-INF/container.xml": testContainer,
+			},
+			err: "unexpected epub mimetype",
+		},
+		{
+			name: "no rootfile",
+			files: map[string]string{
+				"mimetype":               MimeType,
+				"META-INF/container.xml": `<container><rootfiles></rootfiles></container>`,
+			},
+			err: "no rootfile",
+		},
+		{
+			name: "missing package document",
+			files: map[string]string{
+				"mimetype":               MimeType,
+				"META-INF/container.xml": testContainer,
+			},
+			err: "failed to open OEBPS/content.opf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := buildEPUB(t, tt.files)
+			got, err := Parse(r, r.Size())
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metadata = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNotZip(t *testing.T) {
+	r := strings.NewReader("%PDF-1.7")
+	if _, err := Parse(r, r.Size()); err == nil || !strings.Contains(err.Error(), "failed to open epub archive") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestIsbnFromIdentifier(t *testing.T) {
+	tests := []struct {
+		scheme string
+		value  string
+		want   string
+	}{
+		{"ISBN", "978-2-07-040850-4", "9782070408504"},
+		{"isbn", " 2 07 040850 7 ", "2070408507"},
+		{"", "urn:isbn:9782070408504", "9782070408504"},
+		{"", "URN:ISBN:080442957x", "080442957X"},
+		{"", "isbn:9782070408504", "9782070408504"},
+		{"UUID", "9782070408504", ""},
+		{"", "9782070408504", ""},
+		{"ISBN", "978-2-07-04085", ""},
+		{"ISBN", "978/2/07/040850/4", ""},
+	}
+	for _, tt := range tests {
+		got := isbnFromIdentifier(opfIdentifier{Scheme: tt.scheme, Value: tt.value})
+		if got != tt.want {
+			t.Errorf("isbnFromIdentifier(%q, %q) = %q, want %q", tt.scheme, tt.value, got, tt.want)
+		}
+	}
+}
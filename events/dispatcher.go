@@ -0,0 +1,127 @@
+package events
+
+import (
+	"awesome-api/scheduler"
+	"awesome-api/store"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Handler takes an event for a subscriber. Delivery is at least once, an
+// event is handled again when the dispatcher stops between the handler
+// returning and the event being marked, so handlers must be idempotent.
+type Handler func(ctx context.Context, event *store.DomainEvent) error
+
+// DispatcherConfig sets how many events are claimed at once and how failed
+// ones are retried. An event failing MaxAttempts times is given up, retries
+// wait RetryBase doubled after every attempt, up to RetryMax.
+type DispatcherConfig struct {
+	BatchSize   int
+	MaxAttempts int
+	RetryBase   time.Duration
+	RetryMax    time.Duration
+}
+
+type subscriber struct {
+	name    string
+	types   map[string]bool
+	handler Handler
+}
+
+// Dispatcher hands the events of the outbox to the subscribers of this
+// process. Events are handled one at a time in the order they were
+// recorded, a retried event comes after the ones recorded since. Only the
+// subscribers that failed an event take it again.
+type Dispatcher struct {
+	log         zerolog.Logger
+	eventStore  store.DomainEventStore
+	config      DispatcherConfig
+	backoff     scheduler.Backoff
+	subscribers []subscriber
+}
+
+func NewDispatcher(log zerolog.Logger, eventStore store.DomainEventStore, config DispatcherConfig) *Dispatcher {
+	return &Dispatcher{
+		log:        log,
+		eventStore: eventStore,
+		config:     config,
+		backoff:    scheduler.Backoff{Base: config.RetryBase, Max: config.RetryMax},
+	}
+}
+
+// Subscribe registers handler under name for the given event types, or for
+// every event when none are given. The name is what the outbox remembers
+// the subscriber by, renaming one hands it the pending events again.
+func (d *Dispatcher) Subscribe(name string, handler Handler, eventTypes ...string) {
+	var types map[string]bool
+	if len(eventTypes) > 0 {
+		types = map[string]bool{}
+		for _, eventType := range eventTypes {
+			types[eventType] = true
+		}
+	}
+	d.subscribers = append(d.subscribers, subscriber{
+		name:    name,
+		types:   types,
+		handler: handler,
+	})
+}
+
+// Run dispatches batches of due events until none are left, one event at
+// a time.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	return scheduler.Queue[*store.DomainEvent]{
+		BatchSize: d.config.BatchSize,
+		Workers:   1,
+		Claim:     d.eventStore.Claim,
+		Process:   d.dispatch,
+	}.Run(ctx)
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, event *store.DomainEvent) {
+	log := d.log.With().
+		Int64("event_id", event.ID).
+		Str("event", event.Type).
+		Int("attempt", event.Attempts).
+		Logger()
+	handled := map[string]bool{}
+	for _, name := range event.Handled {
+		handled[name] = true
+	}
+	var failures []string
+	for _, sub := range d.subscribers {
+		if handled[sub.name] || (sub.types != nil && !sub.types[event.Type]) {
+			continue
+		}
+		if err := sub.handler(ctx, event); err != nil {
+			log.Warn().Err(err).Str("subscriber", sub.name).Msg("event handler failed")
+			failures = append(failures, fmt.Sprintf("%s: %s", sub.name, err))
+			continue
+		}
+		event.Handled = append(event.Handled, sub.name)
+	}
+	if len(failures) == 0 {
+		if err := d.eventStore.MarkDispatched(ctx, event.ID); err != nil {
+			err = fmt.Errorf("eventStore.MarkDispatched: %w", err)
+			log.Error().Err(err).Msg("failed to mark event dispatched")
+		}
+		return
+	}
+	dead := event.Attempts >= d.config.MaxAttempts
+	next := time.Now().Add(d.backoff.Delay(event.Attempts))
+	lastError := strings.Join(failures, "; ")
+	if err := d.eventStore.MarkFailed(ctx, event.ID, event.Handled, lastError, next, dead); err != nil {
+		err = fmt.Errorf("eventStore.MarkFailed: %w", err)
+		log.Error().Err(err).Msg("failed to mark event failed")
+		return
+	}
+	if dead {
+		log.Error().Str("last_error", lastError).Msg("event given up")
+		return
+	}
+	log.Warn().Time("next_attempt_at", next).Msg("event dispatch failed")
+}
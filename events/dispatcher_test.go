@@ -0,0 +1,259 @@
+package events
+
+import (
+	"awesome-api/store"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type memEvent struct {
+	event     store.DomainEvent
+	status    string
+	nextAt    time.Time
+	lockedAt  time.Time
+	lastError string
+}
+
+// memStore keeps the outbox in memory, following the claim rules of the
+// postgres store.
+type memStore struct {
+	mu     sync.Mutex
+	events []*memEvent
+}
+
+func (ms *memStore) add(eventType string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.events = append(ms.events, &memEvent{
+		event:  store.DomainEvent{ID: int64(len(ms.events) + 1), Type: eventType},
+		status: store.DomainEventPending,
+		nextAt: time.Now(),
+	})
+}
+
+// makeDue moves every pending event to now, as if its retry delay was over.
+func (ms *memStore) makeDue() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, e := range ms.events {
+		e.nextAt = time.Now()
+	}
+}
+
+func (ms *memStore) get(id int64) memEvent {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	e := *ms.events[id-1]
+	e.event.Handled = append([]string(nil), e.event.Handled...)
+	return e
+}
+
+func (ms *memStore) Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]*store.DomainEvent, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	claimed := []*store.DomainEvent{}
+	for _, e := range ms.events {
+		due := e.status == store.DomainEventPending && !e.nextAt.After(time.Now())
+		stale := e.status == store.DomainEventDispatching && e.lockedAt.Before(time.Now().Add(-staleAfter))
+		if len(claimed) == limit || !(due || stale) {
+			continue
+		}
+		e.status = store.DomainEventDispatching
+		e.lockedAt = time.Now()
+		e.event.Attempts++
+		claim := e.event
+		claim.Handled = append([]string(nil), e.event.Handled...)
+		claimed = append(claimed, &claim)
+	}
+	return claimed, nil
+}
+
+func (ms *memStore) MarkDispatched(ctx context.Context, id int64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.events[id-1].status = store.DomainEventDispatched
+	return nil
+}
+
+func (ms *memStore) MarkFailed(ctx context.Context, id int64, handled []string, lastError string, nextAttemptAt time.Time, dead bool) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	e := ms.events[id-1]
+	e.status = store.DomainEventPending
+	if dead {
+		e.status = store.DomainEventFailed
+	}
+	e.event.Handled = append([]string(nil), handled...)
+	e.lastError = lastError
+	e.nextAt = nextAttemptAt
+	return nil
+}
+
+// recorder is a subscriber remembering the events it took, failing the
+// next fails calls.
+type recorder struct {
+	mu    sync.Mutex
+	fails int
+	taken []int64
+}
+
+func (r *recorder) handle(ctx context.Context, event *store.DomainEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.taken = append(r.taken, event.ID)
+	if r.fails > 0 {
+		r.fails--
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func (r *recorder) events() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.taken...)
+}
+
+func newTestDispatcher(ms *memStore, maxAttempts int) *Dispatcher {
+	return NewDispatcher(zerolog.Nop(), ms, DispatcherConfig{
+		BatchSize:   2,
+		MaxAttempts: maxAttempts,
+		RetryBase:   time.Minute,
+		RetryMax:    time.Hour,
+	})
+}
+
+func TestDispatcherSubscribers(t *testing.T) {
+	ms := &memStore{}
+	ms.add(store.EventUserRegistered)
+	ms.add(store.EventBookCreated)
+	ms.add(store.EventBookUpdated)
+	d := newTestDispatcher(ms, 3)
+	users, books, all := &recorder{}, &recorder{}, &recorder{}
+	d.Subscribe("users", users.handle, store.EventUserRegistered)
+	d.Subscribe("books", books.handle, store.EventBookCreated, store.EventBookUpdated)
+	d.Subscribe("all", all.handle)
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := users.events(); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("users took %v, want [1]", got)
+	}
+	if got := books.events(); !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("books took %v, want [2 3]", got)
+	}
+	if got := all.events(); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("all took %v, want [1 2 3]", got)
+	}
+	for id := int64(1); id <= 3; id++ {
+		if e := ms.get(id); e.status != store.DomainEventDispatched {
+			t.Errorf("event %d is %s, want dispatched", id, e.status)
+		}
+	}
+}
+
+func TestDispatcherRetriesFailedSubscribers(t *testing.T) {
+	ms := &memStore{}
+	ms.add(store.EventBookCreated)
+	d := newTestDispatcher(ms, 3)
+	index, mail := &recorder{}, &recorder{fails: 1}
+	d.Subscribe("search_index", index.handle)
+	d.Subscribe("email", mail.handle)
+
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	e := ms.get(1)
+	if e.status != store.DomainEventPending || !reflect.DeepEqual(e.event.Handled, []string{"search_index"}) {
+		t.Fatalf("event is %s handled by %v, want pending handled by [search_index]", e.status, e.event.Handled)
+	}
+	if e.lastError != "email: unavailable" {
+		t.Errorf("last error = %q", e.lastError)
+	}
+	if !e.nextAt.After(time.Now()) {
+		t.Errorf("next attempt at %v, want a delay", e.nextAt)
+	}
+
+	// Not due yet.
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := mail.events(); len(got) != 1 {
+		t.Fatalf("email took %v before the retry delay", got)
+	}
+
+	ms.makeDue()
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := index.events(); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("search_index took %v, want it once", got)
+	}
+	if got := mail.events(); !reflect.DeepEqual(got, []int64{1, 1}) {
+		t.Errorf("email took %v, want it twice", got)
+	}
+	if e := ms.get(1); e.status != store.DomainEventDispatched || e.event.Attempts != 2 {
+		t.Errorf("event is %s after %d attempts, want dispatched after 2", e.status, e.event.Attempts)
+	}
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	ms := &memStore{}
+	ms.add(store.EventLoanCreated)
+	d := newTestDispatcher(ms, 2)
+	webhooks := &recorder{fails: 10}
+	d.Subscribe("webhooks", webhooks.handle)
+	for i := 0; i < 3; i++ {
+		if err := d.Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		ms.makeDue()
+	}
+	if got := webhooks.events(); len(got) != 2 {
+		t.Errorf("webhooks took the event %d times, want 2", len(got))
+	}
+	if e := ms.get(1); e.status != store.DomainEventFailed {
+		t.Errorf("event is %s, want failed", e.status)
+	}
+}
+
+// An event whose dispatcher stopped before marking it is taken over once
+// stale, by the subscribers that had not handled it yet.
+func TestDispatcherTakesOverStaleEvents(t *testing.T) {
+	ms := &memStore{}
+	ms.add(store.EventUserVerified)
+	d := newTestDispatcher(ms, 3)
+	audit, webhooks := &recorder{}, &recorder{}
+	d.Subscribe("audit", audit.handle)
+	d.Subscribe("webhooks", webhooks.handle)
+
+	ms.events[0].status = store.DomainEventDispatching
+	ms.events[0].event.Handled = []string{"audit"}
+	ms.events[0].lockedAt = time.Now()
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := webhooks.events(); len(got) != 0 {
+		t.Fatalf("webhooks took %v while the event was locked", got)
+	}
+
+	ms.events[0].lockedAt = time.Now().Add(-2 * time.Hour)
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := audit.events(); len(got) != 0 {
+		t.Errorf("audit took %v, it had handled the event", got)
+	}
+	if got := webhooks.events(); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("webhooks took %v, want [1]", got)
+	}
+	if e := ms.get(1); e.status != store.DomainEventDispatched {
+		t.Errorf("event is %s, want dispatched", e.status)
+	}
+}
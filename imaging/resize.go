@@ -0,0 +1,55 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+)
+
+// Resize scales src to fit within width pixels wide, keeping the aspect ratio.
+// Downscaling averages every source pixel covered by a destination pixel, which
+// is good enough for cover thumbnails without pulling in an image library.
+// Images already narrower than width are returned unchanged.
+func Resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if width <= 0 || srcW <= width {
+		return src
+	}
+	height := srcH * width / srcW
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcH/height
+		y1 := bounds.Min.Y + (y+1)*srcH/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcW/width
+			x1 := bounds.Min.X + (x+1)*srcW/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,68 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        image.Rectangle
+		width      int
+		wantWidth  int
+		wantHeight int
+	}{
+		{"cover thumbnail", image.Rect(0, 0, 600, 900), 200, 200, 300},
+		{"offset bounds", image.Rect(10, 20, 410, 320), 100, 100, 75},
+		{"wide banner", image.Rect(0, 0, 3000, 2), 300, 300, 1},
+		{"narrower than width", image.Rect(0, 0, 150, 200), 200, 150, 200},
+		{"no width", image.Rect(0, 0, 600, 900), 0, 600, 900},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(tt.src)
+			got := Resize(src, tt.width)
+			if got.Bounds().Dx() != tt.wantWidth || got.Bounds().Dy() != tt.wantHeight {
+				t.Errorf("size = %dx%d, want %dx%d", got.Bounds().Dx(), got.Bounds().Dy(), tt.wantWidth, tt.wantHeight)
+			}
+			if tt.wantWidth == tt.src.Dx() && got != image.Image(src) {
+				t.Error("image already small enough was copied")
+			}
+		})
+	}
+}
+
+func TestResizeAverages(t *testing.T) {
+	// Alternating black and white columns average to grey, the top half
+	// red and the bottom half blue stay apart.
+	src := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			c := color.RGBA{A: 255}
+			switch {
+			case y < 4 && x%2 == 0:
+				c.R = 255
+			case y >= 4 && x%2 == 0:
+				c.B = 255
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+	got := Resize(src, 4)
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, color.RGBA{R: 127, A: 255}},
+		{3, 1, color.RGBA{R: 127, A: 255}},
+		{0, 2, color.RGBA{B: 127, A: 255}},
+		{3, 3, color.RGBA{B: 127, A: 255}},
+	}
+	for _, tt := range tests {
+		if c := color.RGBAModel.Convert(got.At(tt.x, tt.y)).(color.RGBA); c != tt.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", tt.x, tt.y, c, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package isbn validates International Standard Book Numbers and converts
+// between the 10 and 13 digit forms.
+package isbn
+
+import "strings"
+
+type ISBNError string
+
+func (e ISBNError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrInvalidLength   = ISBNError("isbn must have 10 or 13 digits")
+	ErrInvalidChar     = ISBNError("isbn contains an invalid character")
+	ErrInvalidChecksum = ISBNError("isbn check digit is invalid")
+)
+
+// bookland is the only EAN prefix with an ISBN-10 counterpart.
+const bookland = "978"
+
+// Normalize validates s as an ISBN-10 or ISBN-13, ignoring hyphens and
+// spaces, and returns it in its ISBN-13 form.
+func Normalize(s string) (string, error) {
+	s = compact(s)
+	switch len(s) {
+	case 10:
+		if err := Validate10(s); err != nil {
+			return "", err
+		}
+		return To13(s)
+	case 13:
+		if err := Validate13(s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+	return "", ErrInvalidLength
+}
+
+// Validate10 checks the digits and the mod 11 check digit of a compact
+// ISBN-10, whose check digit may be an upper case X.
+func Validate10(s string) error {
+	if len(s) != 10 {
+		return ErrInvalidLength
+	}
+	if !digits(s[:9]) || !(isDigit(s[9]) || s[9] == 'X') {
+		return ErrInvalidChar
+	}
+	if s[9] != checkDigit10(s[:9]) {
+		return ErrInvalidChecksum
+	}
+	return nil
+}
+
+// Validate13 checks the digits and the mod 10 check digit of a compact
+// ISBN-13.
+func Validate13(s string) error {
+	if len(s) != 13 {
+		return ErrInvalidLength
+	}
+	if !digits(s) {
+		return ErrInvalidChar
+	}
+	if s[12] != checkDigit13(s[:12]) {
+		return ErrInvalidChecksum
+	}
+	return nil
+}
+
+// To13 converts a valid compact ISBN-10 into its ISBN-13 form.
+func To13(s string) (string, error) {
+	if err := Validate10(s); err != nil {
+		return "", err
+	}
+	body := bookland + s[:9]
+	return body + string(checkDigit13(body)), nil
+}
+
+// To10 converts a valid compact ISBN-13 into its ISBN-10 form. It returns
+// false for ISBN-13s outside the 978 prefix, which have no ISBN-10.
+func To10(s string) (string, bool) {
+	if Validate13(s) != nil || !strings.HasPrefix(s, bookland) {
+		return "", false
+	}
+	body := s[3:12]
+	return body + string(checkDigit10(body)), true
+}
+
+func checkDigit10(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += (10 - i) * int(body[i]-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(body[i]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func compact(s string) string {
+	s = strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s))
+	return strings.ToUpper(s)
+}
+
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,71 @@
+package isbn
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"978-0-306-40615-7", "9780306406157", nil},
+		{" 978 0 306 40615 7 ", "9780306406157", nil},
+		{"0-306-40615-2", "9780306406157", nil},
+		{"0-8044-2957-X", "9780804429573", nil},
+		{"080442957x", "9780804429573", nil},
+		{"979-10-90636-07-1", "9791090636071", nil},
+		{"978-0-306-40615-8", "", ErrInvalidChecksum},
+		{"0-306-40615-3", "", ErrInvalidChecksum},
+		{"0-306-40615-X", "", ErrInvalidChecksum},
+		{"978-0-306-4061A-7", "", ErrInvalidChar},
+		{"X-306-40615-2", "", ErrInvalidChar},
+		{"978030640615", "", ErrInvalidLength},
+		{"", "", ErrInvalidLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Normalize(tt.in)
+			if got != tt.want || err != tt.err {
+				t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		isbn10 string
+		isbn13 string
+	}{
+		{"0306406152", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"0000000000", "9780000000002"},
+		{"2070408507", "9782070408504"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.isbn10, func(t *testing.T) {
+			got13, err := To13(tt.isbn10)
+			if got13 != tt.isbn13 || err != nil {
+				t.Errorf("To13(%q) = %q, %v, want %q", tt.isbn10, got13, err, tt.isbn13)
+			}
+			got10, ok := To10(tt.isbn13)
+			if got10 != tt.isbn10 || !ok {
+				t.Errorf("To10(%q) = %q, %t, want %q", tt.isbn13, got10, ok, tt.isbn10)
+			}
+		})
+	}
+}
+
+func TestTo10WithoutCounterpart(t *testing.T) {
+	for _, s := range []string{"9791090636071", "9780306406158", "978-0-306-40615-7"} {
+		if got, ok := To10(s); ok {
+			t.Errorf("To10(%q) = %q, want no ISBN-10", s, got)
+		}
+	}
+}
+
+func TestTo13Invalid(t *testing.T) {
+	if _, err := To13("0306406153"); err != ErrInvalidChecksum {
+		t.Errorf("To13 of a bad check digit = %v, want ErrInvalidChecksum", err)
+	}
+}
@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"awesome-api/store"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// The bounce types double as suppression reasons.
+const (
+	BounceTypeHard      = store.SuppressionReasonHardBounce
+	BounceTypeSoft      = "soft_bounce"
+	BounceTypeComplaint = store.SuppressionReasonComplaint
+)
+
+type Bounce struct {
+	Type      string
+	Recipient string
+	MessageID string
+	Detail    string
+}
+
+// BounceProcessor only logs soft bounces, the dispatcher retries those by
+// itself.
+type BounceProcessor struct {
+	log              zerolog.Logger
+	suppressionStore store.SuppressionStore
+	outboxStore      store.EmailOutboxStore
+}
+
+func NewBounceProcessor(
+	log zerolog.Logger,
+	suppressionStore store.SuppressionStore,
+	outboxStore store.EmailOutboxStore,
+) *BounceProcessor {
+	return &BounceProcessor{
+		log:              log,
+		suppressionStore: suppressionStore,
+		outboxStore:      outboxStore,
+	}
+}
+
+func (p *BounceProcessor) Process(ctx context.Context, bounce *Bounce) error {
+	log := p.log.With().
+		Str("type", bounce.Type).
+		Str("recipient", bounce.Recipient).
+		Str("message_id", bounce.MessageID).
+		Logger()
+	if bounce.Type != BounceTypeHard && bounce.Type != BounceTypeComplaint {
+		log.Info().Str("detail", bounce.Detail).Msg("soft bounce ignored")
+		return nil
+	}
+	suppression := &store.Suppression{
+		Email:  bounce.Recipient,
+		Reason: bounce.Type,
+		Detail: bounce.Detail,
+	}
+	if err := p.suppressionStore.Add(ctx, suppression); err != nil {
+		return fmt.Errorf("suppressionStore.Add: %w", err)
+	}
+	if id, ok := outboxId(bounce.MessageID); ok && bounce.Type == BounceTypeHard {
+		if err := p.outboxStore.MarkUndeliverable(ctx, id, bounce.Detail); err != nil {
+			return fmt.Errorf("outboxStore.MarkUndeliverable: %w", err)
+		}
+	}
+	log.Info().Msg("address suppressed")
+	return nil
+}
+
+// outboxId reads the outbox id back from a Message-ID made by messageId.
+func outboxId(messageId string) (int, bool) {
+	messageId = strings.Trim(strings.TrimSpace(messageId), "<>")
+	if !strings.HasPrefix(messageId, "outbox.") {
+		return 0, false
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(messageId, "outbox."), ".")
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,128 @@
+package mailer
+
+import (
+	"awesome-api/scheduler"
+	"awesome-api/store"
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DispatcherConfig dead letters a mail after MaxAttempts failed ones.
+type DispatcherConfig struct {
+	Workers     int
+	BatchSize   int
+	MaxAttempts int
+	RetryBase   time.Duration
+	RetryMax    time.Duration
+}
+
+// Dispatcher marks the mails to suppressed addresses undeliverable instead
+// of sending them.
+type Dispatcher struct {
+	log              zerolog.Logger
+	outboxStore      store.EmailOutboxStore
+	suppressionStore store.SuppressionStore
+	transport        Transport
+	sender           mail.Address
+	signer           *DKIMSigner
+	config           DispatcherConfig
+	backoff          scheduler.Backoff
+}
+
+func NewDispatcher(
+	log zerolog.Logger,
+	outboxStore store.EmailOutboxStore,
+	suppressionStore store.SuppressionStore,
+	transport Transport,
+	sender mail.Address,
+	signer *DKIMSigner,
+	config DispatcherConfig,
+) *Dispatcher {
+	return &Dispatcher{
+		log:              log,
+		outboxStore:      outboxStore,
+		suppressionStore: suppressionStore,
+		transport:        transport,
+		sender:           sender,
+		signer:           signer,
+		config:           config,
+		backoff:          scheduler.Backoff{Base: config.RetryBase, Max: config.RetryMax},
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) error {
+	return scheduler.Queue[*store.Email]{
+		BatchSize: d.config.BatchSize,
+		Workers:   d.config.Workers,
+		Claim:     d.outboxStore.Claim,
+		Process:   d.deliver,
+	}.Run(ctx)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, email *store.Email) {
+	log := d.log.With().
+		Int("email_id", email.ID).
+		Str("kind", email.Kind).
+		Int("attempt", email.Attempts).
+		Logger()
+	suppressed, err := d.suppressionStore.IsSuppressed(ctx, email.Recipient)
+	if err != nil {
+		d.fail(ctx, log, email, fmt.Errorf("suppressionStore.IsSuppressed: %w", err))
+		return
+	}
+	if suppressed {
+		if err := d.outboxStore.MarkUndeliverable(ctx, email.ID, "recipient is suppressed"); err != nil {
+			err = fmt.Errorf("outboxStore.MarkUndeliverable: %w", err)
+			log.Error().Err(err).Msg("failed to mark email undeliverable")
+			return
+		}
+		log.Info().Msg("email to suppressed address dropped")
+		return
+	}
+	start := time.Now()
+	sendErr := d.send(ctx, email)
+	if sendErr == nil {
+		if err := d.outboxStore.MarkSent(ctx, email.ID); err != nil {
+			err = fmt.Errorf("outboxStore.MarkSent: %w", err)
+			log.Error().Err(err).Msg("failed to mark email sent")
+			return
+		}
+		log.Info().Dur("took", time.Since(start)).Msg("email sent")
+		return
+	}
+	d.fail(ctx, log, email, sendErr)
+}
+
+func (d *Dispatcher) fail(ctx context.Context, log zerolog.Logger, email *store.Email, sendErr error) {
+	dead := email.Attempts >= d.config.MaxAttempts
+	next := time.Now().Add(d.backoff.Delay(email.Attempts))
+	if err := d.outboxStore.MarkFailed(ctx, email.ID, sendErr.Error(), next, dead); err != nil {
+		err = fmt.Errorf("outboxStore.MarkFailed: %w", err)
+		log.Error().Err(err).Msg("failed to mark email failed")
+		return
+	}
+	if dead {
+		log.Error().Err(sendErr).Msg("email dead lettered")
+		return
+	}
+	log.Warn().Err(sendErr).Time("next_attempt_at", next).Msg("email delivery failed")
+}
+
+func (d *Dispatcher) send(ctx context.Context, email *store.Email) error {
+	now := time.Now()
+	msg, err := buildMessage(d.sender, email, now)
+	if err != nil {
+		return err
+	}
+	if d.signer != nil {
+		msg, err = d.signer.Sign(msg, now)
+		if err != nil {
+			return err
+		}
+	}
+	return d.transport.Send(ctx, d.sender.Address, []string{email.Recipient}, msg)
+}
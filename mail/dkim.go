@@ -0,0 +1,262 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DKIMAlgorithmRSA     = "rsa-sha256"
+	DKIMAlgorithmEd25519 = "ed25519-sha256"
+)
+
+// From is signed whatever the configuration says. Headers missing from a
+// mail are left out of its signature.
+var DefaultDKIMHeaders = []string{
+	"From", "To", "Subject", "Date", "Message-ID", "List-Unsubscribe", "List-Unsubscribe-Post",
+	"MIME-Version", "Content-Type",
+}
+
+// DKIMSigner uses relaxed canonicalisation for both the headers and the
+// body, and RSA-SHA256 or Ed25519-SHA256 (RFC 8463) depending on the key.
+type DKIMSigner struct {
+	domain    string
+	selector  string
+	key       crypto.Signer
+	algorithm string
+	headers   []string
+}
+
+// NewDKIMSigner returns nil when no DKIM domain is configured.
+func NewDKIMSigner(cfg *Config) (*DKIMSigner, error) {
+	if cfg.DKIMDomain == "" {
+		return nil, nil
+	}
+	if cfg.DKIMSelector == "" {
+		return nil, fmt.Errorf("dkim selector is not set")
+	}
+	pemBytes, err := os.ReadFile(cfg.DKIMPrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dkim private key: %w", err)
+	}
+	key, err := ParseDKIMPrivateKey(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	return NewDKIMSignerWithKey(cfg.DKIMDomain, cfg.DKIMSelector, key, cfg.DKIMHeaders)
+}
+
+func NewDKIMSignerWithKey(domain, selector string, key crypto.Signer, headers []string) (*DKIMSigner, error) {
+	var algorithm string
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		algorithm = DKIMAlgorithmRSA
+	case ed25519.PrivateKey:
+		algorithm = DKIMAlgorithmEd25519
+	default:
+		return nil, fmt.Errorf("unsupported dkim key type %T", key)
+	}
+	signed := signedHeaders(headers)
+	if len(signed) == 1 {
+		signed = signedHeaders(DefaultDKIMHeaders)
+	}
+	return &DKIMSigner{
+		domain:    domain,
+		selector:  selector,
+		key:       key,
+		algorithm: algorithm,
+		headers:   signed,
+	}, nil
+}
+
+func signedHeaders(headers []string) []string {
+	signed := []string{"From"}
+	for _, header := range headers {
+		header = strings.TrimSpace(header)
+		if header != "" && !strings.EqualFold(header, "From") {
+			signed = append(signed, header)
+		}
+	}
+	return signed
+}
+
+func ParseDKIMPrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("dkim private key is not PEM encoded")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dkim private key: %w", err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dkim private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported dkim key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported dkim PEM block %s", block.Type)
+	}
+}
+
+func (s *DKIMSigner) Sign(msg []byte, now time.Time) ([]byte, error) {
+	header, body, err := splitMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	fields := parseHeader(header)
+
+	// Each name picks the last instance not signed yet, as verifiers look
+	// them up from the bottom.
+	used := map[string]int{}
+	var names []string
+	var signed bytes.Buffer
+	for _, name := range s.headers {
+		key := strings.ToLower(name)
+		field, ok := lastField(fields, key, used[key])
+		if !ok {
+			continue
+		}
+		used[key]++
+		names = append(names, name)
+		signed.WriteString(relaxedHeader(field))
+		signed.WriteString("\r\n")
+	}
+
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s;\r\n\tt=%d; h=%s;\r\n\tbh=%s;\r\n\tb=",
+		s.algorithm, s.domain, s.selector, now.Unix(), strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+	signed.WriteString(relaxedHeader("DKIM-Signature: " + value))
+	digest := sha256.Sum256(signed.Bytes())
+
+	var signature []byte
+	switch key := s.key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, digest[:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign dkim: %w", err)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("DKIM-Signature: ")
+	out.WriteString(value)
+	out.WriteString(foldBase64(base64.StdEncoding.EncodeToString(signature)))
+	out.WriteString("\r\n")
+	out.Write(msg)
+	return out.Bytes(), nil
+}
+
+func splitMessage(msg []byte) ([]byte, []byte, error) {
+	i := bytes.Index(msg, []byte("\r\n\r\n"))
+	if i < 0 {
+		return nil, nil, fmt.Errorf("message has no end of header")
+	}
+	return msg[:i+2], msg[i+4:], nil
+}
+
+func parseHeader(header []byte) []string {
+	var fields []string
+	for _, line := range strings.SplitAfter(string(header), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	for i, field := range fields {
+		fields[i] = strings.TrimSuffix(field, "\r\n")
+	}
+	return fields
+}
+
+// lastField returns the instance of the header skip places above the last.
+func lastField(fields []string, name string, skip int) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		colon := strings.IndexByte(fields[i], ':')
+		if colon < 0 || strings.ToLower(strings.TrimRight(fields[i][:colon], " \t")) != name {
+			continue
+		}
+		if skip == 0 {
+			return fields[i], true
+		}
+		skip--
+	}
+	return "", false
+}
+
+// relaxedHeader follows RFC 6376 section 3.4.2.
+func relaxedHeader(field string) string {
+	colon := strings.IndexByte(field, ':')
+	name := strings.ToLower(strings.TrimRight(field[:colon], " \t"))
+	value := strings.NewReplacer("\r\n", "").Replace(field[colon+1:])
+	return name + ":" + strings.Join(strings.Fields(value), " ")
+}
+
+// relaxedBody follows RFC 6376 section 3.4.4.
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		var b strings.Builder
+		space := false
+		for _, r := range line {
+			if r == ' ' || r == '\t' {
+				space = true
+				continue
+			}
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteRune(r)
+		}
+		lines[i] = b.String()
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// Verifiers ignore the whitespace folding adds inside b=.
+func foldBase64(s string) string {
+	const width = 72
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width])
+		b.WriteString("\r\n\t")
+		s = s[width:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,290 @@
+package mailer
+
+import (
+	"awesome-api/store"
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDKIMSign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	email := &store.Email{
+		ID:             42,
+		Recipient:      "reader@example.org",
+		Subject:        "Your hold is ready",
+		Body:           "Hello,\n\nThe book  you held is   ready.\n\n\n",
+		HTMLBody:       "<p>The book you held is ready.</p>",
+		UnsubscribeURL: "https://example.com/unsubscribe?token=abc",
+		CreatedAt:      now,
+	}
+	msg, err := buildMessage(mail.Address{Name: "Library", Address: "noreply@example.com"}, email, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		key       crypto.Signer
+		algorithm string
+	}{
+		{"rsa", rsaKey, DKIMAlgorithmRSA},
+		{"ed25519", edKey, DKIMAlgorithmEd25519},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewDKIMSignerWithKey("example.com", "mail", tt.key, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := signer.Sign(msg, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasSuffix(signed, msg) {
+				t.Fatal("signed message does not end with the original message")
+			}
+			tags := verifyDKIM(t, signed, tt.key.Public())
+			if tags["a"] != tt.algorithm {
+				t.Errorf("a = %q, want %q", tags["a"], tt.algorithm)
+			}
+			if tags["d"] != "example.com" || tags["s"] != "mail" {
+				t.Errorf("d = %q, s = %q", tags["d"], tags["s"])
+			}
+			wantHeaders := "From:To:Subject:Date:Message-ID:List-Unsubscribe:List-Unsubscribe-Post:MIME-Version:Content-Type"
+			if tags["h"] != wantHeaders {
+				t.Errorf("h = %q, want %q", tags["h"], wantHeaders)
+			}
+		})
+	}
+}
+
+func TestDKIMTampered(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewDKIMSignerWithKey("example.com", "mail", key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	msg, err := buildMessage(mail.Address{Address: "noreply@example.com"}, &store.Email{
+		ID:        7,
+		Recipient: "reader@example.org",
+		Subject:   "Welcome",
+		Body:      "Welcome to the library.",
+		CreatedAt: now,
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := signer.Sign(msg, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func([]byte) []byte
+		want   string
+	}{
+		{
+			name: "body",
+			tamper: func(b []byte) []byte {
+				return bytes.Replace(b, []byte("Welcome to the"), []byte("Welcome to my"), 1)
+			},
+			want: "body hash",
+		},
+		{
+			name: "header",
+			tamper: func(b []byte) []byte {
+				return bytes.Replace(b, []byte("Subject: Welcome"), []byte("Subject: Urgent"), 1)
+			},
+			want: "signature",
+		},
+		{
+			name: "whitespace only",
+			tamper: func(b []byte) []byte {
+				b = bytes.Replace(b, []byte("Subject: Welcome"), []byte("subject:   Welcome "), 1)
+				return append(b, "\r\n\r\n"...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDKIM(tt.tamper(append([]byte(nil), signed...)), key.Public())
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("relaxed canonicalisation should ignore the change: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("err = %v, want a %s mismatch", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDKIMSignerHeaders(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		headers []string
+		want    []string
+	}{
+		{"nil", nil, DefaultDKIMHeaders},
+		{"unset setting", []string{""}, DefaultDKIMHeaders},
+		{"blank entries", []string{" ", "", "from"}, DefaultDKIMHeaders},
+		{"trimmed", []string{" To ", "Subject"}, []string{"From", "To", "Subject"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewDKIMSignerWithKey("example.com", "mail", key, tt.headers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(signer.headers, ":") != strings.Join(tt.want, ":") {
+				t.Errorf("headers = %v, want %v", signer.headers, tt.want)
+			}
+		})
+	}
+}
+
+func verifyDKIM(t *testing.T, msg []byte, pub crypto.PublicKey) map[string]string {
+	t.Helper()
+	if err := checkDKIM(msg, pub); err != nil {
+		t.Fatal(err)
+	}
+	tags, _, _ := dkimTags(msg)
+	return tags
+}
+
+// checkDKIM verifies the first DKIM-Signature of msg as a receiver would,
+// canonicalising independently of the signer.
+func checkDKIM(msg []byte, pub crypto.PublicKey) error {
+	tags, field, err := dkimTags(msg)
+	if err != nil {
+		return err
+	}
+	i := bytes.Index(msg, []byte("\r\n\r\n"))
+	header, body := string(msg[:i+2]), msg[i+4:]
+
+	bodyHash := sha256.Sum256(testRelaxedBody(body))
+	if got := base64.StdEncoding.EncodeToString(bodyHash[:]); got != tags["bh"] {
+		return fmt.Errorf("body hash mismatch: %s, signed %s", got, tags["bh"])
+	}
+
+	var fields []string
+	for _, line := range strings.SplitAfter(header, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+		} else {
+			fields = append(fields, line)
+		}
+	}
+	var data strings.Builder
+	used := map[string]int{}
+	for _, name := range strings.Split(tags["h"], ":") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		skip := used[name]
+		used[name]++
+		for j := len(fields) - 1; j >= 0; j-- {
+			if strings.ToLower(strings.TrimSpace(strings.SplitN(fields[j], ":", 2)[0])) != name {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			data.WriteString(testRelaxedHeader(fields[j]))
+			data.WriteString("\r\n")
+			break
+		}
+	}
+	unsigned := regexp.MustCompile(`(;\s*b=)[^;]*$`).ReplaceAllString(strings.TrimRight(field, "\r\n"), "$1")
+	data.WriteString(testRelaxedHeader(unsigned))
+	digest := sha256.Sum256([]byte(data.String()))
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return fmt.Errorf("failed to decode b=: %w", err)
+	}
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, digest[:], signature) {
+			err = fmt.Errorf("ed25519 verification failed")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("signature mismatch: %w", err)
+	}
+	return nil
+}
+
+// dkimTags returns the tags of the DKIM-Signature field at the top of msg,
+// and the field as it appears in the message.
+func dkimTags(msg []byte) (map[string]string, string, error) {
+	if !bytes.HasPrefix(msg, []byte("DKIM-Signature:")) {
+		return nil, "", fmt.Errorf("message has no DKIM-Signature")
+	}
+	end := regexp.MustCompile(`\r\n[^ \t]`).FindIndex(msg)
+	field := string(msg[:end[0]+2])
+	tags := map[string]string{}
+	value := strings.TrimPrefix(field, "DKIM-Signature:")
+	for _, tag := range strings.Split(value, ";") {
+		parts := strings.SplitN(tag, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		tags[strings.TrimSpace(parts[0])] = strings.Join(strings.Fields(parts[1]), "")
+	}
+	return tags, field, nil
+}
+
+var wsp = regexp.MustCompile(`[ \t]+`)
+
+func testRelaxedHeader(field string) string {
+	parts := strings.SplitN(field, ":", 2)
+	value := strings.ReplaceAll(parts[1], "\r\n", "")
+	value = strings.TrimSpace(wsp.ReplaceAllString(value, " "))
+	return strings.ToLower(strings.TrimSpace(parts[0])) + ":" + value
+}
+
+func testRelaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(wsp.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
@@ -0,0 +1,158 @@
+package mailer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+var ErrNotReport = errors.New("message is not a delivery or feedback report")
+
+// ParseReport reads delivery status notifications (RFC 3464) and feedback
+// reports (RFC 5965). Delivered recipients of a DSN are left out.
+func ParseReport(r io.Reader) ([]*Bounce, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" {
+		return nil, ErrNotReport
+	}
+	var bounces []*Bounce
+	var original textproto.MIMEHeader
+	isReport := false
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report part: %w", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/delivery-status":
+			isReport = true
+			found, err := parseDeliveryStatus(part)
+			if err != nil {
+				return nil, err
+			}
+			bounces = append(bounces, found...)
+		case "message/feedback-report":
+			isReport = true
+			fields, err := readFields(part)
+			if err != nil {
+				return nil, err
+			}
+			bounces = append(bounces, &Bounce{
+				Type:      BounceTypeComplaint,
+				Recipient: address(fields.Get("Original-Rcpt-To")),
+				Detail:    fields.Get("Feedback-Type"),
+			})
+		case "message/rfc822", "text/rfc822-headers", "message/rfc822-headers":
+			if original, err = readFields(part); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !isReport {
+		return nil, ErrNotReport
+	}
+	// The returned mail, or its headers, tell which mail it was and, for a
+	// feedback report without Original-Rcpt-To, whom it went to.
+	for _, bounce := range bounces {
+		bounce.MessageID = original.Get("Message-Id")
+		if bounce.Recipient == "" {
+			bounce.Recipient = address(original.Get("To"))
+		}
+	}
+	return filterBounces(bounces), nil
+}
+
+func parseDeliveryStatus(r io.Reader) ([]*Bounce, error) {
+	tp := textproto.NewReader(bufio.NewReader(r))
+	if _, err := tp.ReadMIMEHeader(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read delivery status: %w", err)
+	}
+	var bounces []*Bounce
+	for {
+		fields, err := tp.ReadMIMEHeader()
+		if len(fields) > 0 {
+			if bounce := recipientBounce(fields); bounce != nil {
+				bounces = append(bounces, bounce)
+			}
+		}
+		if err == io.EOF {
+			return bounces, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read delivery status: %w", err)
+		}
+	}
+}
+
+// Only a failure with a 5.x.x status is a hard bounce.
+func recipientBounce(fields textproto.MIMEHeader) *Bounce {
+	status := fields.Get("Status")
+	bounce := &Bounce{
+		Recipient: address(fields.Get("Final-Recipient")),
+		Detail:    status,
+	}
+	if diagnostic := fields.Get("Diagnostic-Code"); diagnostic != "" {
+		bounce.Detail = strings.TrimSpace(afterType(diagnostic))
+	}
+	switch strings.ToLower(fields.Get("Action")) {
+	case "failed":
+		if strings.HasPrefix(status, "5") {
+			bounce.Type = BounceTypeHard
+		} else {
+			bounce.Type = BounceTypeSoft
+		}
+	case "delayed":
+		bounce.Type = BounceTypeSoft
+	default:
+		return nil
+	}
+	return bounce
+}
+
+func readFields(r io.Reader) (textproto.MIMEHeader, error) {
+	fields, err := textproto.NewReader(bufio.NewReader(r)).ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read report fields: %w", err)
+	}
+	return fields, nil
+}
+
+func address(field string) string {
+	field = strings.TrimSpace(afterType(field))
+	if parsed, err := mail.ParseAddress(field); err == nil {
+		return parsed.Address
+	}
+	return strings.Trim(field, "<>")
+}
+
+func afterType(field string) string {
+	if i := strings.IndexByte(field, ';'); i >= 0 && !strings.ContainsAny(field[:i], "@<") {
+		return field[i+1:]
+	}
+	return field
+}
+
+func filterBounces(bounces []*Bounce) []*Bounce {
+	filtered := bounces[:0]
+	for _, bounce := range bounces {
+		if bounce.Recipient != "" {
+			filtered = append(filtered, bounce)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,184 @@
+package mailer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// report builds a multipart/report mail out of its parts, each given as
+// its Content-Type and body.
+func report(reportType string, parts ...[2]string) string {
+	var b strings.Builder
+	b.WriteString("From: MAILER-DAEMON@mx.example.org\r\n")
+	b.WriteString("To: bounces@example.com\r\n")
+	b.WriteString("Subject: Undelivered Mail Returned to Sender\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: multipart/report; report-type=" + reportType + "; boundary=\"XYZ\"\r\n\r\n")
+	for _, part := range parts {
+		b.WriteString("--XYZ\r\n")
+		b.WriteString("Content-Type: " + part[0] + "\r\n\r\n")
+		b.WriteString(strings.ReplaceAll(part[1], "\n", "\r\n"))
+		b.WriteString("\r\n")
+	}
+	b.WriteString("--XYZ--\r\n")
+	return b.String()
+}
+
+var (
+	humanPart    = [2]string{"text/plain; charset=us-ascii", "This is the mail system at host mx.example.org."}
+	originalPart = [2]string{"text/rfc822-headers", `From: Library <noreply@example.com>
+To: Reader <reader@example.org>
+Message-Id: <outbox.12.1709294400@example.com>
+Subject: Your hold is ready
+`}
+)
+
+func TestParseReport(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want []*Bounce
+	}{
+		{
+			name: "hard bounce",
+			msg: report("delivery-status", humanPart, [2]string{"message/delivery-status", `Reporting-MTA: dns; mx.example.org
+Arrival-Date: Fri,  1 Mar 2024 12:00:00 +0000
+
+Final-Recipient: rfc822; reader@example.org
+Original-Recipient: rfc822;reader@example.org
+Action: failed
+Status: 5.1.1
+Remote-MTA: dns; mail.example.org
+Diagnostic-Code: smtp; 550 5.1.1 <reader@example.org>: Recipient address rejected
+`}, originalPart),
+			want: []*Bounce{{
+				Type:      BounceTypeHard,
+				Recipient: "reader@example.org",
+				MessageID: "<outbox.12.1709294400@example.com>",
+				Detail:    "550 5.1.1 <reader@example.org>: Recipient address rejected",
+			}},
+		},
+		{
+			name: "one recipient of each action",
+			msg: report("delivery-status", [2]string{"message/delivery-status", `Reporting-MTA: dns; mx.example.org
+
+Final-Recipient: rfc822; <full@example.org>
+Action: failed
+Status: 4.2.2
+
+Final-Recipient: rfc822; later@example.org
+Action: delayed
+Status: 4.4.1
+
+Final-Recipient: rfc822; fine@example.org
+Action: delivered
+Status: 2.0.0
+
+Final-Recipient: rfc822; gone@example.org
+Action: Failed
+Status: 5.1.1
+`}),
+			want: []*Bounce{
+				{Type: BounceTypeSoft, Recipient: "full@example.org", Detail: "4.2.2"},
+				{Type: BounceTypeSoft, Recipient: "later@example.org", Detail: "4.4.1"},
+				{Type: BounceTypeHard, Recipient: "gone@example.org", Detail: "5.1.1"},
+			},
+		},
+		{
+			name: "complaint",
+			msg: report("feedback-report", humanPart, [2]string{"message/feedback-report", `Feedback-Type: abuse
+User-Agent: SomeISP-FBL/1.0
+Version: 1
+Original-Rcpt-To: <reader@example.org>
+`}, [2]string{"message/rfc822", `From: noreply@example.com
+To: someone-else@example.org
+Message-Id: <outbox.13.1709294400@example.com>
+
+Hello.
+`}),
+			want: []*Bounce{{
+				Type:      BounceTypeComplaint,
+				Recipient: "reader@example.org",
+				MessageID: "<outbox.13.1709294400@example.com>",
+				Detail:    "abuse",
+			}},
+		},
+		{
+			name: "complaint without original recipient",
+			msg: report("feedback-report", [2]string{"message/feedback-report", `Feedback-Type: abuse
+Version: 1
+`}, originalPart),
+			want: []*Bounce{{
+				Type:      BounceTypeComplaint,
+				Recipient: "reader@example.org",
+				MessageID: "<outbox.12.1709294400@example.com>",
+				Detail:    "abuse",
+			}},
+		},
+		{
+			name: "recipient unknown",
+			msg: report("feedback-report", [2]string{"message/feedback-report", `Feedback-Type: abuse
+Version: 1
+`}),
+			want: []*Bounce{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReport(strings.NewReader(tt.msg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bounces = %s, want %s", formatBounces(got), formatBounces(tt.want))
+			}
+		})
+	}
+}
+
+func TestParseReportNotReport(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+	}{
+		{"plain mail", "From: reader@example.org\r\nContent-Type: text/plain\r\n\r\nThanks!\r\n"},
+		{"no content type", "From: reader@example.org\r\n\r\nThanks!\r\n"},
+		{"report without status", report("delivery-status", humanPart, originalPart)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseReport(strings.NewReader(tt.msg)); !errors.Is(err, ErrNotReport) {
+				t.Errorf("err = %v, want ErrNotReport", err)
+			}
+		})
+	}
+}
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"rfc822; reader@example.org", "reader@example.org"},
+		{"rfc822;<reader@example.org>", "reader@example.org"},
+		{"Reader <reader@example.org>", "reader@example.org"},
+		{"<reader@example.org>", "reader@example.org"},
+		{"reader@example.org", "reader@example.org"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := address(tt.field); got != tt.want {
+			t.Errorf("address(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func formatBounces(bounces []*Bounce) string {
+	parts := make([]string, len(bounces))
+	for i, bounce := range bounces {
+		parts[i] = strings.Join([]string{bounce.Type, bounce.Recipient, bounce.MessageID, bounce.Detail}, "|")
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
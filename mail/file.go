@@ -0,0 +1,120 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	FileFormatEML = "eml"
+	// FileFormatMbox appends to outbox.mbox, in the mboxrd flavour.
+	FileFormatMbox = "mbox"
+)
+
+const mboxFileName = "outbox.mbox"
+
+// File writes messages to a directory instead of sending them.
+type File struct {
+	dir    string
+	format string
+	mu     sync.Mutex
+}
+
+func NewFile(dir, format string) (*File, error) {
+	switch format {
+	case FileFormatEML, FileFormatMbox:
+	case "":
+		format = FileFormatEML
+	default:
+		return nil, fmt.Errorf("unknown mail file format: %s", format)
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("mail file directory is not set")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail file directory: %w", err)
+	}
+	return &File{
+		dir:    dir,
+		format: format,
+	}, nil
+}
+
+func (f *File) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	if f.format == FileFormatMbox {
+		return f.appendMbox(from, msg)
+	}
+	return f.writeEML(msg)
+}
+
+// writeEML writes under a temporary name first, so a reader watching the
+// directory never sees half a message.
+func (f *File) writeEML(msg []byte) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("failed to name mail file: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	tmp, err := os.CreateTemp(f.dir, ".mail-*")
+	if err != nil {
+		return fmt.Errorf("failed to create mail file: %w", err)
+	}
+	if _, err = tmp.Write(msg); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(f.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	return nil
+}
+
+// appendMbox quotes any line starting with From, after some >, with one
+// more >.
+func (f *File) appendMbox(from string, msg []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From %s %s\n", from, time.Now().UTC().Format(time.ANSIC))
+	scanner := bufio.NewScanner(bytes.NewReader(msg))
+	scanner.Buffer(make([]byte, 64*1024), len(msg)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSuffix(scanner.Bytes(), []byte("\r"))
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) {
+			buf.WriteByte('>')
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read message: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(filepath.Join(f.dir, mboxFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open mbox: %w", err)
+	}
+	if _, err = file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write mbox: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to write mbox: %w", err)
+	}
+	return nil
+}
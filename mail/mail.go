@@ -1,60 +1,232 @@
 package mailer
 
 import (
+	"awesome-api/store"
+	"context"
 	"fmt"
-	"log"
-	"net/smtp"
+	"net/mail"
+	"net/url"
+	"time"
 )
 
-const sender = "eLibrary %3cno-reply@elibrary.com%3e"
+const (
+	defaultSenderName    = "eLibrary"
+	defaultSenderAddress = "no-reply@elibrary.com"
+)
 
 type Config struct {
-	AppUrl       string
-	MailHost     string
-	MailPort     int
-	MailUsername string
-	MailPassword string
-	MailSender   string
+	AppUrl            string
+	MailHost          string
+	MailPort          int
+	MailUsername      string
+	MailPassword      string
+	MailSenderName    string
+	MailSenderAddress string
+	TemplateDir       string
+	Transport         string
+	SMTPSecurity      string
+	SMTPTimeout       time.Duration
+	SMTPPoolSize      int
+	FileDir           string
+	FileFormat        string
+	// Mails are signed when DKIMDomain is set. DKIMHeaders defaults to
+	// DefaultDKIMHeaders.
+	DKIMDomain         string
+	DKIMSelector       string
+	DKIMPrivateKeyPath string
+	DKIMHeaders        []string
+	UnsubscribeSecret  string
 }
 
+func (cfg *Config) Sender() mail.Address {
+	address := mail.Address{
+		Name:    cfg.MailSenderName,
+		Address: cfg.MailSenderAddress,
+	}
+	if address.Name == "" {
+		address.Name = defaultSenderName
+	}
+	if address.Address == "" {
+		address.Address = defaultSenderAddress
+	}
+	return address
+}
+
+// Each kind of mail is also the name of its templates.
+const (
+	KindActivation = "activation"
+	KindHoldReady  = "hold_ready"
+	KindAlerts     = "alerts"
+)
+
+// Kinds of mail missing here are transactional, users cannot opt out.
+var kindCategories = map[string]string{
+	KindHoldReady: store.NotificationCategoryHolds,
+	KindAlerts:    store.NotificationCategoryAlerts,
+}
+
+// Recipient.ID is required for mails with a notification category.
+type Recipient struct {
+	ID     int
+	Email  string
+	Name   string
+	Locale string
+}
+
+// Mailer queues the mails in the outbox, the Dispatcher delivers them.
 type Mailer struct {
-	config *Config
+	config          *Config
+	templates       *Templates
+	outboxStore     store.EmailOutboxStore
+	preferenceStore store.NotificationPreferenceStore
+	unsubscribe     *Unsubscribe
 }
 
 type EmailSender interface {
-	SendActivationLink(id int, recipient, content string)
+	SendActivationLink(ctx context.Context, to Recipient, id int, token string) error
+	SendHoldReady(ctx context.Context, to Recipient, bookId int, title string, expiresAt time.Time) error
+	SendAlerts(ctx context.Context, to Recipient, alerts []*store.Alert, digest bool) error
 }
 
-func NewMail(cfg *Config) EmailSender {
-	cfg.MailSender = sender
+func NewMail(
+	cfg *Config,
+	outboxStore store.EmailOutboxStore,
+	preferenceStore store.NotificationPreferenceStore,
+	unsubscribe *Unsubscribe,
+) (EmailSender, error) {
+	templates, err := LoadTemplates(cfg.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
 	m := &Mailer{
-		config: cfg,
-	}
-	return m
-}
-
-func (m *Mailer) SendActivationLink(id int, recipient, content string) {
-	from := "elibrary"
-	to := []string{recipient}
-	link := fmt.Sprintf("%s/auth/verify/id=%d&token=%s", m.config.AppUrl, id, content)
-	msg := []byte(fmt.Sprintf("From : %s\r\n", from) +
-		fmt.Sprintf("To: %s\r\n", recipient) +
-		"Subject: Email Verification elibrary\r\n\r\n" + activationTemplate(link))
-	err := smtp.SendMail(
-		fmt.Sprintf("%s:%d", m.config.MailHost, m.config.MailPort),
-		smtp.PlainAuth("", m.config.MailUsername, m.config.MailPassword, m.config.MailHost),
-		m.config.MailSender,
-		to,
-		msg,
-	)
+		config:          cfg,
+		templates:       templates,
+		outboxStore:     outboxStore,
+		preferenceStore: preferenceStore,
+		unsubscribe:     unsubscribe,
+	}
+	return m, nil
+}
+
+type activationData struct {
+	Name string
+	Link string
+}
+
+func (m *Mailer) SendActivationLink(ctx context.Context, to Recipient, id int, token string) error {
+	email, err := m.compose(KindActivation, to, activationData{
+		Name: to.Name,
+		Link: fmt.Sprintf("%s/auth/verify?id=%d&token=%s", m.config.AppUrl, id, token),
+	})
+	if err != nil {
+		return err
+	}
+	return m.enqueue(ctx, email)
+}
+
+type holdReadyData struct {
+	Name      string
+	Title     string
+	Link      string
+	ExpiresAt time.Time
+}
+
+func (m *Mailer) SendHoldReady(ctx context.Context, to Recipient, bookId int, title string, expiresAt time.Time) error {
+	if enabled, err := m.enabled(ctx, KindHoldReady, to); err != nil || !enabled {
+		return err
+	}
+	email, err := m.compose(KindHoldReady, to, holdReadyData{
+		Name:      to.Name,
+		Title:     title,
+		Link:      m.bookLink(bookId),
+		ExpiresAt: expiresAt,
+	})
 	if err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
+	return m.enqueue(ctx, email)
 }
 
-func activationTemplate(link string) string {
-	greet := "Hi There,\n\n"
-	instruction := "Please activate you email by clicking the link below\n"
-	regards := "\n\nCheers\nelibrary team"
-	return fmt.Sprintf(greet+instruction+"%s"+regards, link)
+type alertItem struct {
+	Title  string
+	Author string
+	Link   string
+}
+
+type alertsData struct {
+	Name      string
+	Digest    bool
+	NewBooks  []alertItem
+	Available []alertItem
+}
+
+func (m *Mailer) SendAlerts(ctx context.Context, to Recipient, alerts []*store.Alert, digest bool) error {
+	if enabled, err := m.enabled(ctx, KindAlerts, to); err != nil || !enabled {
+		return err
+	}
+	data := alertsData{
+		Name:   to.Name,
+		Digest: digest,
+	}
+	for _, alert := range alerts {
+		item := alertItem{
+			Title:  alert.Title,
+			Author: alert.Author,
+			Link:   m.bookLink(alert.BookID),
+		}
+		switch alert.AlertType {
+		case store.AlertTypeNewBook:
+			data.NewBooks = append(data.NewBooks, item)
+		case store.AlertTypeAvailable:
+			data.Available = append(data.Available, item)
+		}
+	}
+	email, err := m.compose(KindAlerts, to, data)
+	if err != nil {
+		return err
+	}
+	return m.enqueue(ctx, email)
+}
+
+func (m *Mailer) bookLink(bookId int) string {
+	return fmt.Sprintf("%s/books/%d", m.config.AppUrl, bookId)
+}
+
+func (m *Mailer) enabled(ctx context.Context, kind string, to Recipient) (bool, error) {
+	category, ok := kindCategories[kind]
+	if !ok {
+		return true, nil
+	}
+	enabled, err := m.preferenceStore.IsEnabled(ctx, to.ID, category)
+	if err != nil {
+		return false, fmt.Errorf("preferenceStore.IsEnabled: %w", err)
+	}
+	return enabled, nil
+}
+
+func (m *Mailer) compose(kind string, to Recipient, data interface{}) (*store.Email, error) {
+	rendered, err := m.templates.Render(kind, to.Locale, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose %s mail: %w", kind, err)
+	}
+	email := &store.Email{
+		Kind:      kind,
+		Recipient: to.Email,
+		Subject:   rendered.Subject,
+		Body:      rendered.Text,
+		HTMLBody:  rendered.HTML,
+	}
+	if category, ok := kindCategories[kind]; ok {
+		email.UnsubscribeURL = fmt.Sprintf("%s/unsubscribe?token=%s",
+			m.config.AppUrl, url.QueryEscape(m.unsubscribe.Token(to.ID, category)),
+		)
+	}
+	return email, nil
+}
+
+func (m *Mailer) enqueue(ctx context.Context, email *store.Email) error {
+	if err := m.outboxStore.Enqueue(ctx, email); err != nil {
+		return fmt.Errorf("failed to queue %s mail: %w", email.Kind, err)
+	}
+	return nil
 }
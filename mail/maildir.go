@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+)
+
+// Maildir moves every message it read to cur, reports or not, except those
+// whose bounces could not be stored: they stay in new for the next run.
+type Maildir struct {
+	log       zerolog.Logger
+	dir       string
+	processor *BounceProcessor
+}
+
+func NewMaildir(log zerolog.Logger, dir string, processor *BounceProcessor) *Maildir {
+	return &Maildir{
+		log:       log,
+		dir:       dir,
+		processor: processor,
+	}
+}
+
+func (m *Maildir) Run(ctx context.Context) error {
+	entries, err := os.ReadDir(filepath.Join(m.dir, "new"))
+	if err != nil {
+		return fmt.Errorf("failed to read maildir: %w", err)
+	}
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			continue
+		}
+		if err := m.process(ctx, entry.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Maildir) process(ctx context.Context, name string) error {
+	log := m.log.With().Str("file", name).Logger()
+	path := filepath.Join(m.dir, "new", name)
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open report: %w", err)
+	}
+	bounces, err := ParseReport(f)
+	f.Close()
+	switch {
+	case errors.Is(err, ErrNotReport):
+		log.Warn().Msg("skipped message, not a report")
+	case err != nil:
+		log.Warn().Err(err).Msg("skipped unreadable report")
+	}
+	for _, bounce := range bounces {
+		if err := m.processor.Process(ctx, bounce); err != nil {
+			return fmt.Errorf("processor.Process: %w", err)
+		}
+	}
+	if err := os.Rename(path, filepath.Join(m.dir, "cur", name+":2,S")); err != nil {
+		return fmt.Errorf("failed to move report: %w", err)
+	}
+	return nil
+}
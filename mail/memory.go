@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type CapturedMessage struct {
+	From   string
+	To     []string
+	Data   []byte
+	SentAt time.Time
+}
+
+// Memory keeps the messages it is given instead of sending them.
+type Memory struct {
+	mu       sync.Mutex
+	messages []CapturedMessage
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, CapturedMessage{
+		From:   from,
+		To:     append([]string(nil), to...),
+		Data:   append([]byte(nil), msg...),
+		SentAt: time.Now(),
+	})
+	return nil
+}
+
+func (m *Memory) Messages() []CapturedMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]CapturedMessage(nil), m.messages...)
+}
+
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
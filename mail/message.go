@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"awesome-api/store"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// The Message-ID derives from the outbox row, so a retried delivery keeps
+// the id of the first attempt.
+func buildMessage(from mail.Address, email *store.Email, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	header := textproto.MIMEHeader{}
+	if email.HTMLBody == "" {
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		if err := writeQuotedPrintable(&body, email.Body); err != nil {
+			return nil, err
+		}
+	} else {
+		mw := multipart.NewWriter(&body)
+		header.Set("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{
+			"boundary": mw.Boundary(),
+		}))
+		if err := writePart(mw, "text/plain; charset=utf-8", email.Body); err != nil {
+			return nil, err
+		}
+		if err := writePart(mw, "text/html; charset=utf-8", email.HTMLBody); err != nil {
+			return nil, err
+		}
+		if err := mw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close multipart: %w", err)
+		}
+	}
+	var msg bytes.Buffer
+	writeHeader(&msg, "From", from.String())
+	writeHeader(&msg, "To", email.Recipient)
+	writeHeader(&msg, "Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	writeHeader(&msg, "Date", now.Format(time.RFC1123Z))
+	writeHeader(&msg, "Message-ID", messageId(email, from.Address))
+	if email.UnsubscribeURL != "" {
+		// One-click unsubscribe, RFC 8058.
+		writeHeader(&msg, "List-Unsubscribe", "<"+email.UnsubscribeURL+">")
+		writeHeader(&msg, "List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	writeHeader(&msg, "MIME-Version", "1.0")
+	writeHeader(&msg, "Content-Type", header.Get("Content-Type"))
+	if encoding := header.Get("Content-Transfer-Encoding"); encoding != "" {
+		writeHeader(&msg, "Content-Transfer-Encoding", encoding)
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+func writePart(mw *multipart.Writer, contentType, content string) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create part: %w", err)
+	}
+	return writeQuotedPrintable(part, content)
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return fmt.Errorf("failed to encode body: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("failed to encode body: %w", err)
+	}
+	return nil
+}
+
+func messageId(email *store.Email, sender string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(sender, '@'); i >= 0 {
+		domain = sender[i+1:]
+	}
+	return fmt.Sprintf("<outbox.%d.%d@%s>", email.ID, email.CreatedAt.Unix(), domain)
+}
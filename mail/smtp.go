@@ -0,0 +1,206 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+const (
+	SMTPSecurityTLS = "tls"
+	// SMTPSecuritySTARTTLS refuses servers that do not offer STARTTLS.
+	SMTPSecuritySTARTTLS = "starttls"
+	SMTPSecurityNone     = "none"
+)
+
+const (
+	defaultSMTPTimeout  = 30 * time.Second
+	defaultSMTPPoolSize = 4
+	// servers drop idle clients after a few minutes.
+	smtpIdleTimeout = time.Minute
+)
+
+// SMTP keeps a few authenticated connections open between messages.
+type SMTP struct {
+	config    *Config
+	addr      string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	idle      chan *smtpConn
+}
+
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+func NewSMTP(cfg *Config) (*SMTP, error) {
+	switch cfg.SMTPSecurity {
+	case SMTPSecurityTLS, SMTPSecuritySTARTTLS, SMTPSecurityNone:
+	case "":
+		cfg.SMTPSecurity = SMTPSecuritySTARTTLS
+	default:
+		return nil, fmt.Errorf("unknown smtp security: %s", cfg.SMTPSecurity)
+	}
+	timeout := cfg.SMTPTimeout
+	if timeout <= 0 {
+		timeout = defaultSMTPTimeout
+	}
+	poolSize := cfg.SMTPPoolSize
+	if poolSize <= 0 {
+		poolSize = defaultSMTPPoolSize
+	}
+	return &SMTP{
+		config: cfg,
+		addr:   net.JoinHostPort(cfg.MailHost, strconv.Itoa(cfg.MailPort)),
+		tlsConfig: &tls.Config{
+			ServerName: cfg.MailHost,
+			MinVersion: tls.VersionTLS12,
+		},
+		timeout: timeout,
+		idle:    make(chan *smtpConn, poolSize),
+	}, nil
+}
+
+func (s *SMTP) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	c, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+	if err = s.deadline(ctx, c); err == nil {
+		err = s.send(c, from, to, msg)
+	}
+	if err != nil {
+		// The state of the session is unknown after a failure.
+		c.conn.Close()
+		return err
+	}
+	s.put(c)
+	return nil
+}
+
+func (s *SMTP) send(c *smtpConn, from string, to []string, msg []byte) error {
+	if err := c.client.Mail(from); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, recipient := range to {
+		if err := c.client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s: %w", recipient, err)
+		}
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err = w.Write(msg); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	return nil
+}
+
+func (s *SMTP) get(ctx context.Context) (*smtpConn, error) {
+	for {
+		select {
+		case c := <-s.idle:
+			if time.Since(c.lastUsed) > smtpIdleTimeout {
+				c.close()
+				continue
+			}
+			if err := s.deadline(ctx, c); err != nil {
+				c.conn.Close()
+				return nil, err
+			}
+			if err := c.client.Reset(); err != nil {
+				c.conn.Close()
+				continue
+			}
+			return c, nil
+		default:
+			return s.dial(ctx)
+		}
+	}
+}
+
+func (s *SMTP) put(c *smtpConn) {
+	c.lastUsed = time.Now()
+	select {
+	case s.idle <- c:
+	default:
+		c.close()
+	}
+}
+
+func (s *SMTP) dial(ctx context.Context) (*smtpConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if s.config.SMTPSecurity == SMTPSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}).DialContext(ctx, "tcp", s.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial smtp server: %w", err)
+	}
+	c := &smtpConn{conn: conn}
+	if err = s.deadline(ctx, c); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if c.client, err = smtp.NewClient(conn, s.config.MailHost); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to greet smtp server: %w", err)
+	}
+	if err = s.handshake(c.client); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *SMTP) handshake(client *smtp.Client) error {
+	if s.config.SMTPSecurity == SMTPSecuritySTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not offer STARTTLS", s.addr)
+		}
+		if err := client.StartTLS(s.tlsConfig); err != nil {
+			return fmt.Errorf("smtp STARTTLS: %w", err)
+		}
+	}
+	if s.config.MailUsername == "" {
+		return nil
+	}
+	auth := smtp.PlainAuth("", s.config.MailUsername, s.config.MailPassword, s.config.MailHost)
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("smtp AUTH: %w", err)
+	}
+	return nil
+}
+
+func (s *SMTP) deadline(ctx context.Context, c *smtpConn) error {
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set smtp deadline: %w", err)
+	}
+	return nil
+}
+
+func (c *smtpConn) close() {
+	_ = c.conn.SetDeadline(time.Now().Add(time.Second))
+	if err := c.client.Quit(); err != nil {
+		c.conn.Close()
+	}
+}
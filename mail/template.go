@@ -0,0 +1,175 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const DefaultLocale = "en"
+
+//go:embed templates
+var embedded embed.FS
+
+// Every mail has a text template, <locale>/<name>.txt, defining its
+// subject in a "subject" block, and an html template, <locale>/<name>.html,
+// defining the "content" of layout.html.
+const layoutFile = "layout.html"
+
+var templateFuncs = map[string]interface{}{
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format(time.RFC1123)
+	},
+}
+
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// LoadTemplates lets the files under dir replace the embedded ones at the
+// same path and add locales.
+func LoadTemplates(dir string) (*Templates, error) {
+	base, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded templates: %w", err)
+	}
+	fsys := overlayFS{base: base}
+	if dir != "" {
+		fsys.dir = os.DirFS(dir)
+	}
+	t := &Templates{
+		text: map[string]*texttemplate.Template{},
+		html: map[string]*htmltemplate.Template{},
+	}
+	locales, err := fsys.locales()
+	if err != nil {
+		return nil, err
+	}
+	for _, locale := range locales {
+		for _, name := range []string{KindActivation, KindHoldReady, KindAlerts} {
+			if err := t.parse(fsys, locale, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, name := range []string{KindActivation, KindHoldReady, KindAlerts} {
+		if t.text[path.Join(DefaultLocale, name)] == nil {
+			return nil, fmt.Errorf("missing %s template in default locale %s", name, DefaultLocale)
+		}
+	}
+	return t, nil
+}
+
+// A locale may leave out any mail, the default locale's is used instead.
+func (t *Templates) parse(fsys fs.FS, locale, name string) error {
+	file := path.Join(locale, name)
+	textFile, htmlFile := file+".txt", file+".html"
+	if _, err := fs.Stat(fsys, textFile); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	text, err := texttemplate.New(path.Base(textFile)).Funcs(templateFuncs).ParseFS(fsys, textFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", textFile, err)
+	}
+	if text.Lookup("subject") == nil {
+		return fmt.Errorf("%s does not define a subject", textFile)
+	}
+	html, err := htmltemplate.New(layoutFile).Funcs(templateFuncs).ParseFS(fsys, layoutFile, htmlFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", htmlFile, err)
+	}
+	key := path.Join(strings.ToLower(locale), name)
+	t.text[key] = text
+	t.html[key] = html
+	return nil
+}
+
+// Render falls back to the language of the locale without its region, then
+// to the default locale.
+func (t *Templates) Render(name, locale string, data interface{}) (*Rendered, error) {
+	key := t.resolve(name, locale)
+	var subject, text, html bytes.Buffer
+	if err := t.text[key].ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", key, err)
+	}
+	if err := t.text[key].Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %w", key, err)
+	}
+	if err := t.html[key].Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s html: %w", key, err)
+	}
+	return &Rendered{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+func (t *Templates) resolve(name, locale string) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	candidates := []string{locale}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	for _, candidate := range candidates {
+		if key := path.Join(candidate, name); t.text[key] != nil {
+			return key
+		}
+	}
+	return path.Join(DefaultLocale, name)
+}
+
+type overlayFS struct {
+	dir  fs.FS
+	base fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if o.dir != nil {
+		f, err := o.dir.Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return o.base.Open(name)
+}
+
+func (o overlayFS) locales() ([]string, error) {
+	seen := map[string]bool{}
+	locales := []string{}
+	for _, fsys := range []fs.FS{o.base, o.dir} {
+		if fsys == nil {
+			continue
+		}
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list template locales: %w", err)
+		}
+		for _, entry := range entries {
+			locale := strings.ToLower(entry.Name())
+			if entry.IsDir() && !seen[locale] {
+				seen[locale] = true
+				locales = append(locales, entry.Name())
+			}
+		}
+	}
+	return locales, nil
+}
@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+)
+
+type Transport interface {
+	Send(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+const (
+	TransportSMTP   = "smtp"
+	TransportFile   = "file"
+	TransportMemory = "memory"
+)
+
+func NewTransport(cfg *Config) (Transport, error) {
+	switch cfg.Transport {
+	case TransportSMTP, "":
+		return NewSMTP(cfg)
+	case TransportFile:
+		return NewFile(cfg.FileDir, cfg.FileFormat)
+	case TransportMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %s", cfg.Transport)
+	}
+}
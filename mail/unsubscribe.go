@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The unsubscribe links do not expire, they must keep working from old
+// mails.
+type Unsubscribe struct {
+	secret []byte
+}
+
+func NewUnsubscribe(secret string) (*Unsubscribe, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("unsubscribe secret is not set")
+	}
+	return &Unsubscribe{secret: []byte(secret)}, nil
+}
+
+// Token returns userId.category.signature.
+func (u *Unsubscribe) Token(userId int, category string) string {
+	payload := strconv.Itoa(userId) + "." + category
+	return payload + "." + u.sign(payload)
+}
+
+func (u *Unsubscribe) Verify(token string) (userId int, category string, ok bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return 0, "", false
+	}
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(u.sign(payload))) {
+		return 0, "", false
+	}
+	id, category, found := strings.Cut(payload, ".")
+	if !found {
+		return 0, "", false
+	}
+	userId, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, "", false
+	}
+	return userId, category, true
+}
+
+func (u *Unsubscribe) sign(payload string) string {
+	mac := hmac.New(sha256.New, u.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribe(t *testing.T) {
+	u, err := NewUnsubscribe("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewUnsubscribe("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := u.Token(42, "newsletter")
+	signature := token[strings.LastIndexByte(token, '.')+1:]
+
+	tests := []struct {
+		name         string
+		token        string
+		wantUserId   int
+		wantCategory string
+		wantOk       bool
+	}{
+		{"own token", token, 42, "newsletter", true},
+		{"category with a dot", u.Token(7, "loan.reminder"), 7, "loan.reminder", true},
+		{"other secret", other.Token(42, "newsletter"), 0, "", false},
+		{"other user", "43.newsletter." + signature, 0, "", false},
+		{"other category", "42.hold." + signature, 0, "", false},
+		{"truncated signature", token[:len(token)-1], 0, "", false},
+		{"no signature", "42.newsletter", 0, "", false},
+		{"no dot", "42", 0, "", false},
+		{"empty", "", 0, "", false},
+		{"not a user id", "abc.newsletter." + u.sign("abc.newsletter"), 0, "", false},
+		{"no category", "42." + u.sign("42"), 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userId, category, ok := u.Verify(tt.token)
+			if userId != tt.wantUserId || category != tt.wantCategory || ok != tt.wantOk {
+				t.Errorf("Verify(%q) = %d, %q, %t, want %d, %q, %t",
+					tt.token, userId, category, ok, tt.wantUserId, tt.wantCategory, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeTokenIsURLSafe(t *testing.T) {
+	u, err := NewUnsubscribe("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id < 200; id++ {
+		if token := u.Token(id, "newsletter"); strings.ContainsAny(token, "+/=") {
+			t.Fatalf("token %q needs escaping in a URL", token)
+		}
+	}
+}
+
+func TestNewUnsubscribeWithoutSecret(t *testing.T) {
+	if _, err := NewUnsubscribe(""); err == nil {
+		t.Error("NewUnsubscribe accepted an empty secret")
+	}
+}
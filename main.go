@@ -2,16 +2,21 @@ package main
 
 import (
 	"awesome-api/api"
+	"awesome-api/api/handler/book"
+	"awesome-api/blob"
 	"awesome-api/config"
+	"awesome-api/events"
 	"awesome-api/jwt"
 	"awesome-api/logger"
 	mailer "awesome-api/mail"
 	"awesome-api/store"
+	"awesome-api/webhook"
 	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -44,11 +49,71 @@ func main() {
 	apiDB := api.DB{
 		ElibraryPostgres: db,
 	}
-	mailer := setupMail(config)
+	mail := setupMail(config)
 	jwt := setupJWT(config)
+	blobStore := setupBlob(config, zlog)
+	cover := book.CoverConfig{
+		MaxSize: config.CoverMaxSizeKB << 10,
+	}
+	bookFile := book.FileConfig{
+		MaxSize: config.BookFileMaxSizeMB << 20,
+	}
+	circulation := api.CirculationConfig{
+		Loan: store.LoanPolicy{
+			Period:      time.Duration(config.LoanPeriodDays) * 24 * time.Hour,
+			MaxLoans:    config.LoanMaxPerUser,
+			MaxRenewals: config.LoanMaxRenewals,
+		},
+		ExpiryInterval:    time.Duration(config.LoanExpiryIntervalMinute) * time.Minute,
+		HoldClaimWindow:   time.Duration(config.HoldClaimWindowHours) * time.Hour,
+		DueReminderBefore: time.Duration(config.LoanDueReminderHours) * time.Hour,
+	}
+	catalogCfg := api.CatalogConfig{
+		ImportMaxSize:   config.ImportMaxSizeMB << 20,
+		JobPollInterval: time.Duration(config.CatalogJobPollIntervalSecond) * time.Second,
+	}
+	recommendation := api.RecommendationConfig{
+		Interval: time.Duration(config.RecommendationIntervalMinute) * time.Minute,
+	}
+	ranking := api.RankingConfig{
+		Interval: time.Duration(config.RankingIntervalMinute) * time.Minute,
+		Policy: store.RankingPolicy{
+			TrendingWindow: time.Duration(config.RankingTrendingWindowDays) * 24 * time.Hour,
+			MostReadWindow: time.Duration(config.RankingMostReadWindowDays) * 24 * time.Hour,
+			Size:           config.RankingSize,
+		},
+	}
+	alert := api.AlertConfig{
+		Interval:    time.Duration(config.AlertIntervalMinute) * time.Minute,
+		DigestAfter: time.Duration(config.AlertDigestDays) * 24 * time.Hour,
+	}
+	webhooks := api.WebhookConfig{
+		Dispatcher: webhook.DispatcherConfig{
+			Workers:      config.WebhookWorkers,
+			BatchSize:    config.WebhookBatchSize,
+			MaxAttempts:  config.WebhookMaxAttempts,
+			RetryBase:    time.Duration(config.WebhookRetryBaseSecond) * time.Second,
+			RetryMax:     time.Duration(config.WebhookRetryMaxMinute) * time.Minute,
+			DisableAfter: config.WebhookDisableAfter,
+			Timeout:      time.Duration(config.WebhookTimeoutSecond) * time.Second,
+		},
+		PollInterval: time.Duration(config.WebhookPollIntervalSecond) * time.Second,
+	}
+	events := api.EventConfig{
+		Dispatcher: events.DispatcherConfig{
+			BatchSize:   config.EventBatchSize,
+			MaxAttempts: config.EventMaxAttempts,
+			RetryBase:   time.Duration(config.EventRetryBaseSecond) * time.Second,
+			RetryMax:    time.Duration(config.EventRetryMaxMinute) * time.Minute,
+		},
+		PollInterval: time.Duration(config.EventPollIntervalSecond) * time.Second,
+	}
 	tokenVerification := api.TokenVerificationConfig{
 		Expiry: time.Duration(config.TokenVerificationExpirationMinute),
 	}
+	users := api.UserConfig{
+		AdminEmails: splitList(config.AdminEmails),
+	}
 	apiLogger := zlog.With().
 		Str("component", "api").
 		Logger()
@@ -65,8 +130,19 @@ func main() {
 		apiLogger,
 		apiDB,
 		tokenVerification,
-		mailer,
+		users,
+		mail,
 		jwt,
+		blobStore,
+		cover,
+		bookFile,
+		circulation,
+		catalogCfg,
+		recommendation,
+		ranking,
+		alert,
+		webhooks,
+		events,
 	)
 	srv.Run(ctx)
 }
@@ -85,15 +161,53 @@ func setupPg(cfg config.Config, logger zerolog.Logger) *sql.DB {
 	return db
 }
 
-func setupMail(cfg config.Config) mailer.EmailSender {
-	mailerConfig := &mailer.Config{
-		AppUrl:       fmt.Sprintf("%s://%s:%d", cfg.AppProtocol, cfg.AppHost, cfg.AppPort),
-		MailHost:     cfg.MailHost,
-		MailPort:     cfg.MailPort,
-		MailUsername: cfg.MailUsername,
-		MailPassword: cfg.MailPassword,
-	}
-	return mailer.NewMail(mailerConfig)
+func setupMail(cfg config.Config) api.MailConfig {
+	return api.MailConfig{
+		Mailer: mailer.Config{
+			AppUrl:             fmt.Sprintf("%s://%s:%d", cfg.AppProtocol, cfg.AppHost, cfg.AppPort),
+			MailHost:           cfg.MailHost,
+			MailPort:           cfg.MailPort,
+			MailUsername:       cfg.MailUsername,
+			MailPassword:       cfg.MailPassword,
+			MailSenderName:     cfg.MailSenderName,
+			MailSenderAddress:  cfg.MailSenderAddress,
+			TemplateDir:        cfg.MailTemplateDir,
+			Transport:          cfg.MailTransport,
+			SMTPSecurity:       cfg.MailSecurity,
+			SMTPTimeout:        time.Duration(cfg.MailTimeoutSecond) * time.Second,
+			SMTPPoolSize:       cfg.MailPoolSize,
+			FileDir:            cfg.MailFileDir,
+			FileFormat:         cfg.MailFileFormat,
+			DKIMDomain:         cfg.MailDKIMDomain,
+			DKIMSelector:       cfg.MailDKIMSelector,
+			DKIMPrivateKeyPath: cfg.MailDKIMPrivateKeyPath,
+			DKIMHeaders:        splitList(cfg.MailDKIMHeaders),
+			UnsubscribeSecret:  cfg.MailUnsubscribeSecret,
+		},
+		Dispatcher: mailer.DispatcherConfig{
+			Workers:     cfg.MailWorkers,
+			BatchSize:   cfg.MailBatchSize,
+			MaxAttempts: cfg.MailMaxAttempts,
+			RetryBase:   time.Duration(cfg.MailRetryBaseSecond) * time.Second,
+			RetryMax:    time.Duration(cfg.MailRetryMaxMinute) * time.Minute,
+		},
+		PollInterval:        time.Duration(cfg.MailPollIntervalSecond) * time.Second,
+		BounceWebhookSecret: cfg.BounceWebhookSecret,
+		BounceMaildir:       cfg.BounceMaildir,
+		BouncePollInterval:  time.Duration(cfg.BouncePollIntervalSecond) * time.Second,
+	}
+}
+
+// splitList splits a comma separated setting, dropping empty entries so an
+// unset one gives an empty list.
+func splitList(value string) []string {
+	list := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
 }
 
 func setupJWT(cfg config.Config) jwt.JWT {
@@ -103,3 +217,21 @@ func setupJWT(cfg config.Config) jwt.JWT {
 	}
 	return jwt.NewJWT(jwtCfg)
 }
+
+func setupBlob(cfg config.Config, logger zerolog.Logger) blob.BlobStore {
+	blobStore, err := blob.New(blob.Config{
+		Driver:      cfg.BlobDriver,
+		LocalPath:   cfg.BlobLocalPath,
+		S3Endpoint:  cfg.S3Endpoint,
+		S3Region:    cfg.S3Region,
+		S3Bucket:    cfg.S3Bucket,
+		S3AccessKey: cfg.S3AccessKey,
+		S3SecretKey: cfg.S3SecretKey,
+		S3PathStyle: cfg.S3PathStyle,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to setup blob store")
+		return nil
+	}
+	return blobStore
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"awesome-api/store"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// subscriberBuffer is how many notifications a slow stream may fall
+	// behind before further ones are dropped for it.
+	subscriberBuffer = 16
+	retryMin         = time.Second
+	retryMax         = 30 * time.Second
+)
+
+// Hub listens on a single connection for the notifications inserted by
+// every instance and fans them out to the streams of this one.
+type Hub struct {
+	log               zerolog.Logger
+	notificationStore store.NotificationStore
+
+	mu          sync.Mutex
+	subscribers map[int]map[chan *store.Notification]struct{}
+}
+
+func NewHub(log zerolog.Logger, notificationStore store.NotificationStore) *Hub {
+	return &Hub{
+		log:               log,
+		notificationStore: notificationStore,
+		subscribers:       map[int]map[chan *store.Notification]struct{}{},
+	}
+}
+
+// Notifications inserted while Run reconnects are not streamed, clients
+// catch up on them with the id of the last one they got.
+func (h *Hub) Run(ctx context.Context) {
+	retry := retryMin
+	for {
+		start := time.Now()
+		err := h.notificationStore.Listen(ctx, h.publish)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > retryMax {
+			retry = retryMin
+		}
+		h.log.Error().Err(err).Dur("retry_in", retry).Msg("lost notification listener")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		if retry *= 2; retry > retryMax {
+			retry = retryMax
+		}
+	}
+}
+
+func (h *Hub) Subscribe(userId int) (notifications <-chan *store.Notification, unsubscribe func()) {
+	ch := make(chan *store.Notification, subscriberBuffer)
+	h.mu.Lock()
+	if h.subscribers[userId] == nil {
+		h.subscribers[userId] = map[chan *store.Notification]struct{}{}
+	}
+	h.subscribers[userId][ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[userId], ch)
+		if len(h.subscribers[userId]) == 0 {
+			delete(h.subscribers, userId)
+		}
+	}
+}
+
+func (h *Hub) publish(notification *store.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[notification.UserID] {
+		select {
+		case ch <- notification:
+		default:
+			h.log.Warn().
+				Int("user_id", notification.UserID).
+				Int("notification_id", notification.ID).
+				Msg("dropped notification for slow stream")
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"awesome-api/store"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// feedStore hands the notifications sent on feed to the listener of the
+// hub, as LISTEN would. A value sent on drop ends the current connection.
+type feedStore struct {
+	store.NotificationStore
+	feed    chan *store.Notification
+	drop    chan struct{}
+	listens chan struct{}
+}
+
+func newFeedStore() *feedStore {
+	return &feedStore{
+		feed:    make(chan *store.Notification),
+		drop:    make(chan struct{}),
+		listens: make(chan struct{}, 4),
+	}
+}
+
+func (fs *feedStore) Listen(ctx context.Context, fn func(notification *store.Notification)) error {
+	fs.listens <- struct{}{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-fs.drop:
+			return errors.New("connection lost")
+		case notification := <-fs.feed:
+			fn(notification)
+		}
+	}
+}
+
+func receive(t *testing.T, ch <-chan *store.Notification) *store.Notification {
+	t.Helper()
+	select {
+	case notification := <-ch:
+		return notification
+	case <-time.After(time.Second):
+		t.Fatal("no notification received")
+		return nil
+	}
+}
+
+func nothing(t *testing.T, ch <-chan *store.Notification) {
+	t.Helper()
+	select {
+	case notification := <-ch:
+		t.Fatalf("unexpected notification %d", notification.ID)
+	default:
+	}
+}
+
+func TestHubFanOut(t *testing.T) {
+	fs := newFeedStore()
+	hub := NewHub(zerolog.Nop(), fs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	first, unsubscribeFirst := hub.Subscribe(1)
+	second, unsubscribeSecond := hub.Subscribe(1)
+	defer unsubscribeSecond()
+	other, unsubscribeOther := hub.Subscribe(2)
+	defer unsubscribeOther()
+
+	fs.feed <- &store.Notification{ID: 10, UserID: 1}
+	fs.feed <- &store.Notification{ID: 11, UserID: 2}
+	fs.feed <- &store.Notification{ID: 12, UserID: 3}
+	if got := receive(t, first); got.ID != 10 {
+		t.Errorf("first stream got %d, want 10", got.ID)
+	}
+	if got := receive(t, second); got.ID != 10 {
+		t.Errorf("second stream got %d, want 10", got.ID)
+	}
+	if got := receive(t, other); got.ID != 11 {
+		t.Errorf("other stream got %d, want 11", got.ID)
+	}
+	nothing(t, first)
+	nothing(t, second)
+	nothing(t, other)
+
+	unsubscribeFirst()
+	fs.feed <- &store.Notification{ID: 13, UserID: 1}
+	if got := receive(t, second); got.ID != 13 {
+		t.Errorf("second stream got %d, want 13", got.ID)
+	}
+	nothing(t, first)
+}
+
+func TestHubDropsForSlowStreams(t *testing.T) {
+	fs := newFeedStore()
+	hub := NewHub(zerolog.Nop(), fs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	slow, unsubscribeSlow := hub.Subscribe(1)
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := hub.Subscribe(1)
+	defer unsubscribeFast()
+	for i := 1; i <= subscriberBuffer+2; i++ {
+		fs.feed <- &store.Notification{ID: i, UserID: 1}
+		if got := receive(t, fast); got.ID != i {
+			t.Fatalf("fast stream got %d, want %d", got.ID, i)
+		}
+	}
+	for i := 1; i <= subscriberBuffer; i++ {
+		if got := receive(t, slow); got.ID != i {
+			t.Fatalf("slow stream got %d, want %d", got.ID, i)
+		}
+	}
+	nothing(t, slow)
+}
+
+func TestHubReconnects(t *testing.T) {
+	fs := newFeedStore()
+	hub := NewHub(zerolog.Nop(), fs)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+	stream, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	<-fs.listens
+	fs.drop <- struct{}{}
+	select {
+	case <-fs.listens:
+	case <-time.After(retryMin + time.Second):
+		t.Fatal("hub did not listen again")
+	}
+	fs.feed <- &store.Notification{ID: 1, UserID: 1}
+	if got := receive(t, stream); got.ID != 1 {
+		t.Errorf("stream got %d, want 1", got.ID)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hub did not stop with its context")
+	}
+}
@@ -0,0 +1,89 @@
+package recommend
+
+import (
+	"awesome-api/store"
+	"context"
+	"fmt"
+)
+
+// Why a book was recommended.
+const (
+	ReasonSimilar = "similar"
+	ReasonPopular = "popular"
+)
+
+type Recommendation struct {
+	*store.Recommendation
+	Reason string
+}
+
+// Recommender serves recommendations from the computed similarities,
+// topping them up with popular books of the same categories when there are
+// not enough, as for new users or books nobody read yet.
+type Recommender struct {
+	recommendationStore store.RecommendationStore
+}
+
+func NewRecommender(recommendationStore store.RecommendationStore) *Recommender {
+	return &Recommender{recommendationStore: recommendationStore}
+}
+
+func (r *Recommender) ForUser(ctx context.Context, userId, limit int) ([]Recommendation, error) {
+	similar, err := r.recommendationStore.FindForUser(ctx, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("recommendationStore.FindForUser: %w", err)
+	}
+	res := withReason(nil, similar, ReasonSimilar, limit, nil)
+	if len(res) == limit {
+		return res, nil
+	}
+	popular, err := r.recommendationStore.FindPopular(ctx, userId, 0, limit)
+	if err != nil {
+		return nil, fmt.Errorf("recommendationStore.FindPopular: %w", err)
+	}
+	return withReason(res, popular, ReasonPopular, limit, nil), nil
+}
+
+func (r *Recommender) Similar(ctx context.Context, book *store.Book, limit int) ([]Recommendation, error) {
+	similar, err := r.recommendationStore.FindSimilar(ctx, book.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("recommendationStore.FindSimilar: %w", err)
+	}
+	skip := map[int]bool{book.ID: true}
+	res := withReason(nil, similar, ReasonSimilar, limit, skip)
+	if len(res) == limit {
+		return res, nil
+	}
+	popular, err := r.recommendationStore.FindPopular(ctx, 0, book.CategoryID, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("recommendationStore.FindPopular: %w", err)
+	}
+	return withReason(res, popular, ReasonPopular, limit, skip), nil
+}
+
+// withReason appends the books not seen yet to res, up to limit.
+func withReason(
+	res []Recommendation,
+	recommendations []*store.Recommendation,
+	reason string,
+	limit int,
+	skip map[int]bool,
+) []Recommendation {
+	if skip == nil {
+		skip = map[int]bool{}
+	}
+	for _, r := range res {
+		skip[r.BookID] = true
+	}
+	for _, recommendation := range recommendations {
+		if len(res) == limit {
+			break
+		}
+		if skip[recommendation.BookID] {
+			continue
+		}
+		skip[recommendation.BookID] = true
+		res = append(res, Recommendation{Recommendation: recommendation, Reason: reason})
+	}
+	return res
+}
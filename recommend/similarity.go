@@ -0,0 +1,137 @@
+// Package recommend computes item-to-item similarity between books from
+// what users rated and read, and serves recommendations from it.
+package recommend
+
+import (
+	"awesome-api/store"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// neighbours is how many similar books are kept per book.
+	neighbours = 30
+	// historyLimit caps the books taken from one user, pairs grow with the
+	// square of it and very heavy readers say little about any one pair.
+	historyLimit = 500
+	// shrinkage damps similarities resting on few shared users, a pair read
+	// by a single user would otherwise look perfectly similar.
+	shrinkage = 3.0
+)
+
+type pair struct {
+	a, b int
+}
+
+type cooccurrence struct {
+	dot   float64
+	users int
+}
+
+// Computer recomputes the similarity table from scratch.
+type Computer struct {
+	log                 zerolog.Logger
+	recommendationStore store.RecommendationStore
+}
+
+func NewComputer(log zerolog.Logger, recommendationStore store.RecommendationStore) *Computer {
+	return &Computer{
+		log:                 log,
+		recommendationStore: recommendationStore,
+	}
+}
+
+// Run computes the cosine similarity of every pair of books read by a
+// common user and keeps the closest neighbours of each book.
+func (c *Computer) Run(ctx context.Context) error {
+	start := time.Now()
+	norms := map[int]float64{}
+	pairs := map[pair]*cooccurrence{}
+	var history []*store.Interaction
+	userId := 0
+	flush := func() {
+		addHistory(history, norms, pairs)
+		history = history[:0]
+	}
+	err := c.recommendationStore.StreamInteractions(ctx, func(interaction *store.Interaction) error {
+		if interaction.UserID != userId {
+			flush()
+			userId = interaction.UserID
+		}
+		history = append(history, interaction)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("recommendationStore.StreamInteractions: %w", err)
+	}
+	flush()
+
+	similarities := topNeighbours(norms, pairs)
+	if err = c.recommendationStore.ReplaceSimilarities(ctx, similarities); err != nil {
+		return fmt.Errorf("recommendationStore.ReplaceSimilarities: %w", err)
+	}
+	c.log.Info().
+		Int("books", len(norms)).
+		Int("pairs", len(pairs)).
+		Int("similarities", len(similarities)).
+		Dur("took", time.Since(start)).
+		Msg("computed book similarities")
+	return nil
+}
+
+func addHistory(history []*store.Interaction, norms map[int]float64, pairs map[pair]*cooccurrence) {
+	if len(history) > historyLimit {
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].Weight > history[j].Weight
+		})
+		history = history[:historyLimit]
+	}
+	for i, a := range history {
+		norms[a.BookID] += a.Weight * a.Weight
+		for _, b := range history[i+1:] {
+			key := pair{a: a.BookID, b: b.BookID}
+			if key.a > key.b {
+				key.a, key.b = key.b, key.a
+			}
+			co, ok := pairs[key]
+			if !ok {
+				co = &cooccurrence{}
+				pairs[key] = co
+			}
+			co.dot += a.Weight * b.Weight
+			co.users++
+		}
+	}
+}
+
+func topNeighbours(norms map[int]float64, pairs map[pair]*cooccurrence) []*store.BookSimilarity {
+	byBook := map[int][]*store.BookSimilarity{}
+	for key, co := range pairs {
+		norm := math.Sqrt(norms[key.a]) * math.Sqrt(norms[key.b])
+		if norm == 0 || co.dot <= 0 {
+			continue
+		}
+		score := co.dot / norm * float64(co.users) / (float64(co.users) + shrinkage)
+		byBook[key.a] = append(byBook[key.a], &store.BookSimilarity{BookID: key.a, SimilarBookID: key.b, Score: score})
+		byBook[key.b] = append(byBook[key.b], &store.BookSimilarity{BookID: key.b, SimilarBookID: key.a, Score: score})
+	}
+	similarities := []*store.BookSimilarity{}
+	for _, list := range byBook {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Score != list[j].Score {
+				return list[i].Score > list[j].Score
+			}
+			return list[i].SimilarBookID < list[j].SimilarBookID
+		})
+		if len(list) > neighbours {
+			list = list[:neighbours]
+		}
+		similarities = append(similarities, list...)
+	}
+	return similarities
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ClaimStaleAfter is how long a claimed item may go unreported before
+// another worker takes it over.
+const ClaimStaleAfter = 10 * time.Minute
+
+// Queue drains a table queue, such as an outbox, claiming due items in
+// batches and handing them to Workers goroutines. Items are processed in
+// claim order when there is one worker.
+type Queue[T any] struct {
+	BatchSize int
+	Workers   int
+	Claim     func(ctx context.Context, limit int, staleAfter time.Duration) ([]T, error)
+	Process   func(ctx context.Context, item T)
+}
+
+// Run processes batches of due items until none are left or ctx is done.
+func (q Queue[T]) Run(ctx context.Context) error {
+	workers := q.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	batchSize := q.BatchSize
+	if batchSize < workers {
+		batchSize = workers
+	}
+	for {
+		items, err := q.Claim(ctx, batchSize, ClaimStaleAfter)
+		if err != nil {
+			return fmt.Errorf("failed to claim: %w", err)
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		q.processAll(ctx, workers, items)
+		if len(items) < batchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (q Queue[T]) processAll(ctx context.Context, workers int, items []T) {
+	queue := make(chan T)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				q.Process(ctx, item)
+			}
+		}()
+	}
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// Backoff waits Base after the first attempt, doubled after every other,
+// up to Max.
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (b Backoff) Delay(attempts int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempts && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sliceQueue hands out its items in batches, as a table queue would.
+type sliceQueue struct {
+	mu      sync.Mutex
+	pending []int
+	limits  []int
+	done    []int
+}
+
+func (sq *sliceQueue) claim(ctx context.Context, limit int, staleAfter time.Duration) ([]int, error) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.limits = append(sq.limits, limit)
+	n := limit
+	if n > len(sq.pending) {
+		n = len(sq.pending)
+	}
+	batch := sq.pending[:n]
+	sq.pending = sq.pending[n:]
+	return batch, nil
+}
+
+func (sq *sliceQueue) process(ctx context.Context, item int) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.done = append(sq.done, item)
+}
+
+func TestQueueRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		items      int
+		batchSize  int
+		workers    int
+		wantClaims []int
+	}{
+		{"empty", 0, 3, 1, []int{3}},
+		{"short batch ends the run", 5, 3, 1, []int{3, 3}},
+		{"full last batch claims again", 6, 3, 1, []int{3, 3, 3}},
+		{"batch at least one per worker", 4, 1, 4, []int{4, 4}},
+		{"defaults", 2, 0, 0, []int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sq := &sliceQueue{}
+			for i := 1; i <= tt.items; i++ {
+				sq.pending = append(sq.pending, i)
+			}
+			err := Queue[int]{
+				BatchSize: tt.batchSize,
+				Workers:   tt.workers,
+				Claim:     sq.claim,
+				Process:   sq.process,
+			}.Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(sq.limits, tt.wantClaims) {
+				t.Errorf("claims = %v, want %v", sq.limits, tt.wantClaims)
+			}
+			if len(sq.done) != tt.items {
+				t.Errorf("processed %d items, want %d", len(sq.done), tt.items)
+			}
+		})
+	}
+}
+
+func TestQueueRunInOrderWithOneWorker(t *testing.T) {
+	sq := &sliceQueue{pending: []int{1, 2, 3, 4, 5, 6, 7}}
+	err := Queue[int]{BatchSize: 3, Workers: 1, Claim: sq.claim, Process: sq.process}.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(sq.done, want) {
+		t.Errorf("processed %v, want %v", sq.done, want)
+	}
+}
+
+func TestQueueRunStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sq := &sliceQueue{pending: []int{1, 2, 3, 4, 5, 6}}
+	err := Queue[int]{
+		BatchSize: 2,
+		Claim:     sq.claim,
+		Process: func(ctx context.Context, item int) {
+			sq.process(ctx, item)
+			cancel()
+		},
+	}.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sq.limits) != 1 {
+		t.Errorf("claimed %d batches after cancel, want 1", len(sq.limits))
+	}
+}
+
+func TestQueueRunClaimError(t *testing.T) {
+	claimErr := errors.New("connection refused")
+	err := Queue[int]{
+		Claim: func(context.Context, int, time.Duration) ([]int, error) {
+			return nil, claimErr
+		},
+		Process: func(context.Context, int) {
+			t.Error("processed an item of a failed claim")
+		},
+	}.Run(context.Background())
+	if !errors.Is(err, claimErr) {
+		t.Errorf("err = %v, want %v", err, claimErr)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Base: time.Minute, Max: 10 * time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := b.Delay(tt.attempts); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start runs every job on its own ticker until ctx is cancelled. A failing
+// run is logged and retried on the next tick.
+func Start(ctx context.Context, logger zerolog.Logger, jobs ...Job) {
+	for _, job := range jobs {
+		if job.Interval <= 0 {
+			logger.Warn().Str("job", job.Name).Msg("job disabled, interval is not positive")
+			continue
+		}
+		go run(ctx, logger, job)
+	}
+}
+
+func run(ctx context.Context, logger zerolog.Logger, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		if err := job.Run(ctx); err != nil {
+			logger.Error().Err(err).Str("job", job.Name).Msg("job failed")
+		} else {
+			logger.Debug().Str("job", job.Name).Dur("took", time.Since(start)).Msg("job finished")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	AlertTypeNewBook   = "new_book"
+	AlertTypeAvailable = "available"
+)
+
+const (
+	AlertFrequencyInstant = "instant"
+	AlertFrequencyWeekly  = "weekly"
+)
+
+// AlertTypes lists every alert type, each with its own frequency.
+var AlertTypes = []string{AlertTypeNewBook, AlertTypeAvailable}
+
+type AlertPreference struct {
+	UserID    int
+	AlertType string
+	Frequency string
+}
+
+// Alert tells a user about a new book of a favourite author or about a
+// favourite book they can borrow again. AuthorID and Author are only set
+// for new books.
+type Alert struct {
+	ID        int
+	UserID    int
+	Email     string
+	Fullname  string
+	Locale    string
+	AlertType string
+	Frequency string
+	BookID    int
+	Title     string
+	AuthorID  int
+	Author    string
+	CreatedAt time.Time
+}
+
+type AlertStore interface {
+	// FindPreferences returns the frequency of every alert type, falling
+	// back to instant for those the user never set.
+	FindPreferences(ctx context.Context, userId int) ([]*AlertPreference, error)
+	UpsertPreference(ctx context.Context, preference *AlertPreference) error
+	// QueueAlerts records an alert for every favourite book that became
+	// available and every new book of a favourite author since the last
+	// call, and returns how many were queued.
+	QueueAlerts(ctx context.Context) (int64, error)
+	// FindDue returns the unsent instant alerts, and the unsent weekly
+	// alerts of the users whose oldest one waited at least digestAfter.
+	FindDue(ctx context.Context, digestAfter time.Duration) ([]*Alert, error)
+	MarkSent(ctx context.Context, ids []int) error
+}
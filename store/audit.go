@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is a domain event kept for the record.
+type AuditEntry struct {
+	ID          int64
+	EventID     int64
+	EventType   string
+	AggregateID int
+	ActorID     sql.NullInt64
+	Payload     json.RawMessage
+	OccurredAt  time.Time
+	CreatedAt   time.Time
+}
+
+// AuditFilter narrows the audit log, zero values match everything.
+type AuditFilter struct {
+	EventType string
+	ActorID   int
+}
+
+type AuditStore interface {
+	// Record adds the event to the log once, recording it again is a no-op.
+	Record(ctx context.Context, event *DomainEvent) error
+	FindAll(ctx context.Context, filter AuditFilter, limit, offset int) ([]*AuditEntry, error)
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	AuthorRoleAuthor     = "author"
+	AuthorRoleEditor     = "editor"
+	AuthorRoleTranslator = "translator"
+)
+
+type AuthorError string
+
+func (e AuthorError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrAuthorNotFound      = AuthorError("author is not found")
+	ErrAuthorAlreadyExists = AuthorError("author is already exists")
+)
+
+type Author struct {
+	ID        int
+	Name      string
+	Bio       string
+	BookCount int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AuthorCredit names a contributor of a book either by an existing author
+// id or by name, in which case the author is created when unknown.
+type AuthorCredit struct {
+	AuthorID int
+	Name     string
+	Role     string
+}
+
+type BookAuthor struct {
+	BookID   int
+	AuthorID int
+	Name     string
+	Role     string
+	Position int
+}
+
+type AuthoredBook struct {
+	BookID   int
+	Title    string
+	Role     string
+	Language string
+}
+
+type AuthorStore interface {
+	Insert(ctx context.Context, author *Author) error
+	// Update records author.updated with the change.
+	Update(ctx context.Context, author *Author) error
+	FindOneById(ctx context.Context, id int) (*Author, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]*Author, error)
+	FindByBookId(ctx context.Context, bookId int) ([]*BookAuthor, error)
+	FindBooksByAuthorId(ctx context.Context, authorId, limit, offset int) ([]*AuthoredBook, error)
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Book struct {
+	ID             int
+	Title          string
+	Author         string
+	Synopsis       string
+	Cover          string
+	CoverUpdatedAt sql.NullTime
+	Language       string
+	Reader         int
+	Copies         int
+	CategoryID     int
+	ISBN13         sql.NullString
+	ISBN10         sql.NullString
+	Publisher      string
+	PublishedDate  sql.NullTime
+	PageCount      sql.NullInt32
+	Edition        string
+	WorkID         sql.NullInt64
+}
+
+type BookError string
+
+func (e BookError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrBookCategoryNotFound  = BookError("category is not found")
+	ErrBookISBNAlreadyExists = BookError("isbn is already exists")
+)
+
+// BookMetadata is extracted from uploaded files. Authors are only linked
+// when the book has no credited authors yet.
+type BookMetadata struct {
+	Title    string
+	Authors  []string
+	Synopsis string
+	Language string
+	ISBN13   string
+	ISBN10   string
+}
+
+// BookFilter narrows the catalogue listing. Zero values do not filter.
+type BookFilter struct {
+	Query           string    `json:"q,omitempty"`
+	CategoryID      int       `json:"category_id,omitempty"`
+	AuthorID        int       `json:"author_id,omitempty"`
+	Language        string    `json:"language,omitempty"`
+	PublishedAfter  time.Time `json:"published_after,omitempty"`
+	PublishedBefore time.Time `json:"published_before,omitempty"`
+}
+
+// CatalogBook is a book as listed in the catalogue, together with its
+// category, credited contributors and rating aggregates.
+type CatalogBook struct {
+	Book
+	CategoryName  string
+	Authors       []*BookAuthor
+	RatingCount   int
+	RatingAverage float64
+}
+
+type BookStore interface {
+	FindOneById(ctx context.Context, id int) (*Book, error)
+	FindOneByISBN(ctx context.Context, isbn13 string) (*Book, error)
+	FindByWorkId(ctx context.Context, workId int) ([]*Book, error)
+	FindAll(ctx context.Context, filter BookFilter, limit, offset int) ([]*CatalogBook, error)
+	// Stream calls fn for every book matching the filter, in id order,
+	// without holding more than one book in memory.
+	Stream(ctx context.Context, filter BookFilter, fn func(*CatalogBook) error) error
+	EnsureWorkById(ctx context.Context, id int) (int, error)
+	// Insert and Update record book.created and book.updated with the
+	// change.
+	Insert(ctx context.Context, book *Book, credits []AuthorCredit) error
+	Update(ctx context.Context, book *Book, credits []AuthorCredit) error
+	UpdateCoverById(ctx context.Context, cover string, id int) error
+	PrefillMetadataById(ctx context.Context, meta *BookMetadata, id int) error
+	AddReader(ctx context.Context, id, userId int) error
+	UpdateCopiesById(ctx context.Context, copies, id int) error
+	// Reindex refreshes the text the book is searched by from its title,
+	// contributors, ISBNs and publisher.
+	Reindex(ctx context.Context, id int) error
+	// ReindexByAuthorId reindexes every book the author contributed to.
+	ReindexByAuthorId(ctx context.Context, authorId int) error
+}
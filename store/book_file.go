@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	BookFormatEPUB = "epub"
+	BookFormatPDF  = "pdf"
+)
+
+type BookFile struct {
+	ID          int
+	BookID      int
+	Format      string
+	BlobKey     string
+	ContentType string
+	Size        int64
+	Metadata    []byte
+	CreatedAt   time.Time
+}
+
+type BookFileStore interface {
+	Upsert(ctx context.Context, file *BookFile) error
+	FindByBookId(ctx context.Context, bookId int) ([]*BookFile, error)
+	FindByBookIds(ctx context.Context, bookIds []int) ([]*BookFile, error)
+	FindOneByBookIdAndFormat(ctx context.Context, bookId int, format string) (*BookFile, error)
+}
@@ -0,0 +1,14 @@
+package store
+
+import "context"
+
+type Category struct {
+	ID        int
+	Name      string
+	BookCount int
+}
+
+type CategoryStore interface {
+	FindAll(ctx context.Context) ([]*Category, error)
+	FindOrInsertByName(ctx context.Context, name string) (*Category, error)
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	CollectionVisibilityPrivate  = "private"
+	CollectionVisibilityUnlisted = "unlisted"
+	CollectionVisibilityPublic   = "public"
+)
+
+type CollectionError string
+
+func (e CollectionError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrCollectionSlugTaken     = CollectionError("slug is already taken")
+	ErrCollectionOrderMismatch = CollectionError("order does not list every item exactly once")
+)
+
+// Collection is a curated, ordered list of books. Its owner and the
+// collaborators they invite edit the items, only the owner changes the
+// collection itself.
+type Collection struct {
+	ID          int
+	UserID      int
+	Owner       string
+	Slug        string
+	Title       string
+	Description string
+	Visibility  string
+	ItemCount   int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type CollectionItem struct {
+	CollectionID int
+	BookID       int
+	Title        string
+	Position     int
+	Note         string
+	AddedBy      int
+	AddedAt      time.Time
+}
+
+type CollectionCollaborator struct {
+	CollectionID int
+	UserID       int
+	Fullname     string
+	Email        string
+	InvitedBy    int
+	CreatedAt    time.Time
+}
+
+type CollectionStore interface {
+	Insert(ctx context.Context, collection *Collection) error
+	Update(ctx context.Context, collection *Collection) error
+	DeleteById(ctx context.Context, id int) error
+	FindOneById(ctx context.Context, id int) (*Collection, error)
+	FindOneBySlug(ctx context.Context, slug string) (*Collection, error)
+	FindByMember(ctx context.Context, userId int) ([]*Collection, error)
+	SearchPublic(ctx context.Context, query string, limit, offset int) ([]*Collection, error)
+	UpsertItem(ctx context.Context, item *CollectionItem) error
+	RemoveItem(ctx context.Context, id, bookId int) error
+	FindItems(ctx context.Context, id, limit, offset int) ([]*CollectionItem, error)
+	Reorder(ctx context.Context, id int, bookIds []int) error
+	IsCollaborator(ctx context.Context, id, userId int) (bool, error)
+	FindCollaborators(ctx context.Context, id int) ([]*CollectionCollaborator, error)
+	AddCollaborator(ctx context.Context, collaborator *CollectionCollaborator) error
+	RemoveCollaborator(ctx context.Context, id, userId int) error
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSending = "sending"
+	EmailStatusSent    = "sent"
+	EmailStatusDead    = "dead"
+	// EmailStatusUndeliverable is final, the mail bounced for good or its
+	// recipient is suppressed.
+	EmailStatusUndeliverable = "undeliverable"
+)
+
+type EmailError string
+
+func (e EmailError) Error() string {
+	return string(e)
+}
+
+const ErrEmailNotDead = EmailError("email is not dead")
+
+// Email is a mail waiting in the outbox, or already delivered from it.
+// Kind names what the mail is about, for the logs and the outbox stats.
+// UnsubscribeURL is only set on mails users can opt out of.
+type Email struct {
+	ID             int
+	Kind           string
+	Recipient      string
+	Subject        string
+	Body           string
+	HTMLBody       string
+	UnsubscribeURL string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastError      string
+	CreatedAt      time.Time
+	SentAt         sql.NullTime
+}
+
+// EmailStats counts the mails of a status, Oldest is when the oldest of
+// them was queued.
+type EmailStats struct {
+	Status string
+	Count  int
+	Oldest sql.NullTime
+}
+
+type EmailOutboxStore interface {
+	Enqueue(ctx context.Context, email *Email) error
+	// Claim moves up to limit due mails to sending and counts the attempt,
+	// taking over mails whose worker did not report back within staleAfter.
+	Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]*Email, error)
+	MarkSent(ctx context.Context, id int) error
+	// MarkFailed records the error of the last attempt and schedules the
+	// next one, or gives up on the mail when dead is set.
+	MarkFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time, dead bool) error
+	MarkUndeliverable(ctx context.Context, id int, reason string) error
+	FindOneById(ctx context.Context, id int) (*Email, error)
+	FindByStatus(ctx context.Context, status string, limit, offset int) ([]*Email, error)
+	// Requeue gives a dead mail a fresh set of attempts, it returns
+	// ErrEmailNotDead for mails in any other status.
+	Requeue(ctx context.Context, id int) error
+	Stats(ctx context.Context) ([]*EmailStats, error)
+}
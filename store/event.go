@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	EventUserRegistered = "user.registered"
+	EventUserVerified   = "user.verified"
+	EventBookCreated    = "book.created"
+	EventBookUpdated    = "book.updated"
+	EventLoanCreated    = "loan.created"
+	EventAuthorUpdated  = "author.updated"
+	EventHoldReady      = "hold.ready"
+)
+
+const (
+	DomainEventPending     = "pending"
+	DomainEventDispatching = "dispatching"
+	DomainEventDispatched  = "dispatched"
+	DomainEventFailed      = "failed"
+)
+
+// DomainEvent is a state change recorded by the store making it, in the
+// same transaction. Handled lists the subscribers that already took it.
+type DomainEvent struct {
+	ID          int64
+	Type        string
+	AggregateID int
+	ActorID     sql.NullInt64
+	Payload     json.RawMessage
+	Handled     []string
+	Attempts    int
+	CreatedAt   time.Time
+}
+
+// UserEventData is the payload of the user events.
+type UserEventData struct {
+	ID       int    `json:"id"`
+	Email    string `json:"email"`
+	Fullname string `json:"fullname"`
+	Locale   string `json:"locale"`
+}
+
+// BookEventData is the payload of the book events.
+type BookEventData struct {
+	ID         int    `json:"id"`
+	Title      string `json:"title"`
+	Language   string `json:"language"`
+	CategoryID int    `json:"category_id"`
+	ISBN13     string `json:"isbn13,omitempty"`
+	Publisher  string `json:"publisher,omitempty"`
+	WorkID     int64  `json:"work_id,omitempty"`
+}
+
+// AuthorEventData is the payload of the author events.
+type AuthorEventData struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// LoanEventData is the payload of the loan events.
+type LoanEventData struct {
+	ID         int       `json:"id"`
+	BookID     int       `json:"book_id"`
+	UserID     int       `json:"user_id"`
+	BorrowedAt time.Time `json:"borrowed_at"`
+	DueAt      time.Time `json:"due_at"`
+}
+
+// HoldEventData is the payload of the hold events.
+type HoldEventData struct {
+	ID        int       `json:"id"`
+	BookID    int       `json:"book_id"`
+	UserID    int       `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewBookEventData(book *Book) BookEventData {
+	return BookEventData{
+		ID:         book.ID,
+		Title:      book.Title,
+		Language:   book.Language,
+		CategoryID: book.CategoryID,
+		ISBN13:     book.ISBN13.String,
+		Publisher:  book.Publisher,
+		WorkID:     book.WorkID.Int64,
+	}
+}
+
+type actorCtxKey struct{}
+
+// WithActor sets the user acting in ctx, recorded with the events written
+// under it.
+func WithActor(ctx context.Context, userId int) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, userId)
+}
+
+// NewDomainEvent marshals data as the payload of an event about the
+// aggregate, acted on by the user of ctx if any.
+func NewDomainEvent(ctx context.Context, eventType string, aggregateId int, data interface{}) (*DomainEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+	event := &DomainEvent{
+		Type:        eventType,
+		AggregateID: aggregateId,
+		Payload:     payload,
+	}
+	if actorId, ok := ctx.Value(actorCtxKey{}).(int); ok {
+		event.ActorID = sql.NullInt64{Int64: int64(actorId), Valid: true}
+	}
+	return event, nil
+}
+
+type DomainEventStore interface {
+	// Claim moves up to limit due events to dispatching, oldest first, and
+	// counts the attempt, taking over events whose dispatcher did not report
+	// back within staleAfter.
+	Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]*DomainEvent, error)
+	MarkDispatched(ctx context.Context, id int64) error
+	// MarkFailed saves the subscribers that took the event and schedules the
+	// others for nextAttemptAt, or gives up when dead is set.
+	MarkFailed(ctx context.Context, id int64, handled []string, lastError string, nextAttemptAt time.Time, dead bool) error
+}
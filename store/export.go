@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Export jobs go through the same statuses as import jobs.
+type ExportJob struct {
+	ID          int
+	UserID      int
+	Format      string
+	Filter      BookFilter
+	Status      string
+	BlobKey     string
+	Total       int
+	Size        int64
+	Error       string
+	CreatedAt   time.Time
+	StartedAt   sql.NullTime
+	FinishedAt  sql.NullTime
+	HeartbeatAt sql.NullTime
+}
+
+type ExportStore interface {
+	Insert(ctx context.Context, job *ExportJob) error
+	FindOneById(ctx context.Context, id int) (*ExportJob, error)
+	FindAll(ctx context.Context, limit, offset int) ([]*ExportJob, error)
+	// ClaimNext marks the oldest queued job as running, or a running job
+	// whose worker stopped sending heartbeats, and returns sql.ErrNoRows
+	// when there is none.
+	ClaimNext(ctx context.Context, staleAfter time.Duration) (*ExportJob, error)
+	UpdateProgress(ctx context.Context, job *ExportJob) error
+	Finish(ctx context.Context, job *ExportJob) error
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// FavouriteBook is a book on the wishlist of a user, Available tells
+// whether a copy can be borrowed right now.
+type FavouriteBook struct {
+	UserID    int
+	BookID    int
+	Title     string
+	Author    string
+	Available bool
+	CreatedAt time.Time
+}
+
+type FavouriteAuthor struct {
+	UserID    int
+	AuthorID  int
+	Name      string
+	BookCount int
+	CreatedAt time.Time
+}
+
+type FavouriteStore interface {
+	AddBook(ctx context.Context, userId, bookId int) error
+	RemoveBook(ctx context.Context, userId, bookId int) error
+	FindBooks(ctx context.Context, userId, limit, offset int) ([]*FavouriteBook, error)
+	AddAuthor(ctx context.Context, userId, authorId int) error
+	RemoveAuthor(ctx context.Context, userId, authorId int) error
+	FindAuthors(ctx context.Context, userId, limit, offset int) ([]*FavouriteAuthor, error)
+}
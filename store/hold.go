@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	HoldStatusWaiting   = "waiting"
+	HoldStatusReady     = "ready"
+	HoldStatusFulfilled = "fulfilled"
+	HoldStatusCancelled = "cancelled"
+	HoldStatusExpired   = "expired"
+)
+
+type HoldError string
+
+func (e HoldError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrHoldAlreadyPlaced = HoldError("hold is already placed for the book")
+	ErrHoldCopyAvailable = HoldError("a copy of the book is available")
+	ErrHoldNotOpen       = HoldError("hold is not open")
+)
+
+type Hold struct {
+	ID        int
+	BookID    int
+	UserID    int
+	Status    string
+	Position  int
+	CreatedAt time.Time
+	ReadyAt   sql.NullTime
+	ExpiresAt sql.NullTime
+	ClosedAt  sql.NullTime
+}
+
+type HoldStore interface {
+	Place(ctx context.Context, userId, bookId int) (*Hold, error)
+	Cancel(ctx context.Context, id, userId int) (*Hold, error)
+	FindByUserId(ctx context.Context, userId int, openOnly bool, limit, offset int) ([]*Hold, error)
+	// Promote records hold.ready for every hold it makes ready.
+	Promote(ctx context.Context, bookId int, claimWindow time.Duration) ([]*Hold, error)
+	ExpireLapsed(ctx context.Context) ([]*Hold, error)
+	FindBookIdsWaiting(ctx context.Context) ([]int, error)
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	ImportStatusQueued    = "queued"
+	ImportStatusRunning   = "running"
+	ImportStatusSucceeded = "succeeded"
+	ImportStatusFailed    = "failed"
+)
+
+type ImportJob struct {
+	ID          int
+	UserID      int
+	Format      string
+	Status      string
+	DryRun      bool
+	BlobKey     string
+	Options     ImportOptions
+	Total       int
+	Created     int
+	Updated     int
+	Failed      int
+	Error       string
+	CreatedAt   time.Time
+	StartedAt   sql.NullTime
+	FinishedAt  sql.NullTime
+	HeartbeatAt sql.NullTime
+}
+
+// ImportOptions are stored with the job as JSON.
+type ImportOptions struct {
+	Mapping         map[string]string `json:"mapping,omitempty"`
+	DefaultCategory string            `json:"default_category,omitempty"`
+}
+
+type ImportError struct {
+	JobID   int
+	Row     int
+	ISBN    string
+	Message string
+}
+
+type ImportStore interface {
+	Insert(ctx context.Context, job *ImportJob) error
+	FindOneById(ctx context.Context, id int) (*ImportJob, error)
+	FindAll(ctx context.Context, limit, offset int) ([]*ImportJob, error)
+	// ClaimNext marks the oldest queued job as running, or a running job
+	// whose worker stopped sending heartbeats, and returns sql.ErrNoRows
+	// when there is none.
+	ClaimNext(ctx context.Context, staleAfter time.Duration) (*ImportJob, error)
+	UpdateProgress(ctx context.Context, job *ImportJob) error
+	Finish(ctx context.Context, job *ImportJob) error
+	InsertError(ctx context.Context, e *ImportError) error
+	FindErrorsByJobId(ctx context.Context, jobId, limit, offset int) ([]*ImportError, error)
+}
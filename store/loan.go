@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	LoanStatusActive   = "active"
+	LoanStatusReturned = "returned"
+	LoanStatusExpired  = "expired"
+)
+
+type LoanError string
+
+func (e LoanError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrLoanNoCopyAvailable  = LoanError("no copy of the book is available")
+	ErrLoanLimitReached     = LoanError("loan limit has been reached")
+	ErrLoanAlreadyBorrowed  = LoanError("book is already borrowed by the user")
+	ErrLoanNotActive        = LoanError("loan is not active")
+	ErrLoanRenewalExhausted = LoanError("loan cannot be renewed anymore")
+	ErrLoanHoldsWaiting     = LoanError("loan cannot be renewed while holds are waiting")
+)
+
+type Loan struct {
+	ID         int
+	BookID     int
+	UserID     int
+	Status     string
+	BorrowedAt time.Time
+	DueAt      time.Time
+	ReturnedAt sql.NullTime
+	RenewCount int
+}
+
+type LoanPolicy struct {
+	Period      time.Duration
+	MaxLoans    int
+	MaxRenewals int
+}
+
+type Availability struct {
+	BookID   int
+	Copies   int
+	OnLoan   int
+	Reserved int
+	Waiting  int
+}
+
+type LoanStore interface {
+	// Borrow records loan.created with the loan.
+	Borrow(ctx context.Context, userId, bookId int, policy LoanPolicy) (*Loan, error)
+	Return(ctx context.Context, id, userId int) (*Loan, error)
+	Renew(ctx context.Context, id, userId int, policy LoanPolicy) (*Loan, error)
+	FindOneById(ctx context.Context, id int) (*Loan, error)
+	FindByUserId(ctx context.Context, userId int, activeOnly bool, limit, offset int) ([]*Loan, error)
+	FindAvailabilityByBookId(ctx context.Context, bookId int) (*Availability, error)
+	ExpireOverdue(ctx context.Context) ([]*Loan, error)
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	NotificationKindLoanDue        = "loan_due"
+	NotificationKindHoldReady      = "hold_ready"
+	NotificationKindReviewApproved = "review_approved"
+)
+
+// Notification is shown to a user inside the app. Data is a JSON object
+// with what the kind needs to be shown, such as the book and its title.
+type Notification struct {
+	ID        int
+	UserID    int
+	Kind      string
+	Data      json.RawMessage
+	ReadAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type NotificationStore interface {
+	Insert(ctx context.Context, notification *Notification) error
+	FindByUserId(ctx context.Context, userId int, unreadOnly bool, limit, offset int) ([]*Notification, error)
+	// FindAfter returns up to limit notifications of the user newer than
+	// afterId, oldest first, for streams picking up where they left off.
+	FindAfter(ctx context.Context, userId, afterId, limit int) ([]*Notification, error)
+	CountUnread(ctx context.Context, userId int) (int, error)
+	// MarkRead returns sql.ErrNoRows when the notification is not the
+	// user's.
+	MarkRead(ctx context.Context, id, userId int) error
+	MarkAllRead(ctx context.Context, userId int) (int64, error)
+	// QueueLoanDue notifies the borrowers of active loans due within the
+	// given duration, once for every due date, and returns how many were
+	// notified.
+	QueueLoanDue(ctx context.Context, within time.Duration) (int64, error)
+	// Listen calls fn with every notification inserted, by this instance or
+	// any other, until ctx is done or the connection fails.
+	Listen(ctx context.Context, fn func(notification *Notification)) error
+}
@@ -0,0 +1,27 @@
+package store
+
+import "context"
+
+// Categories of the mails users can opt out of, transactional mails such as
+// the activation mail have none and are always sent.
+const (
+	NotificationCategoryHolds  = "holds"
+	NotificationCategoryAlerts = "alerts"
+)
+
+// NotificationCategories lists every category, each with its own preference.
+var NotificationCategories = []string{NotificationCategoryHolds, NotificationCategoryAlerts}
+
+type NotificationPreference struct {
+	UserID   int
+	Category string
+	Enabled  bool
+}
+
+type NotificationPreferenceStore interface {
+	// FindByUserId returns the preference of every category, falling back
+	// to enabled for those the user never set.
+	FindByUserId(ctx context.Context, userId int) ([]*NotificationPreference, error)
+	Upsert(ctx context.Context, preference *NotificationPreference) error
+	IsEnabled(ctx context.Context, userId int, category string) (bool, error)
+}
@@ -0,0 +1,214 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type AlertStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *alertPrepareStatement
+}
+
+type alertPrepareStatement struct {
+	FindPreferences  *sql.Stmt
+	UpsertPreference *sql.Stmt
+	QueueAlerts      *sql.Stmt
+	FindDue          *sql.Stmt
+	MarkSent         *sql.Stmt
+}
+
+func (as *AlertStore) prepareStatement() error {
+	storeName := "AlertStore"
+	var err error
+	if as.ps.FindPreferences, err = prepareStatement(as.db, storeName, "FindPreferences", alertFindPreferences); err != nil {
+		return err
+	}
+	if as.ps.UpsertPreference, err = prepareStatement(as.db, storeName, "UpsertPreference", alertUpsertPreference); err != nil {
+		return err
+	}
+	if as.ps.QueueAlerts, err = prepareStatement(as.db, storeName, "QueueAlerts", alertQueueAlerts); err != nil {
+		return err
+	}
+	if as.ps.FindDue, err = prepareStatement(as.db, storeName, "FindDue", alertFindDue); err != nil {
+		return err
+	}
+	if as.ps.MarkSent, err = prepareStatement(as.db, storeName, "MarkSent", alertMarkSent); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewAlertStore(log zerolog.Logger, db *sql.DB) (*AlertStore, error) {
+	as := &AlertStore{
+		db:  db,
+		log: log,
+		ps:  &alertPrepareStatement{},
+	}
+	err := as.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+const alertFindPreferences = `
+SELECT $1::INT, t.alert_type, COALESCE(p.frequency, 'instant')
+FROM (VALUES ('new_book', 1), ('available', 2)) AS t(alert_type, ord)
+LEFT JOIN "alert_preferences" p ON p.user_id = $1 AND p.alert_type = t.alert_type
+ORDER BY t.ord
+`
+
+func (as *AlertStore) FindPreferences(ctx context.Context, userId int) ([]*store.AlertPreference, error) {
+	rows, err := stmt(ctx, as.ps.FindPreferences).QueryContext(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindPreferences: %w", err)
+	}
+	defer rows.Close()
+	preferences := []*store.AlertPreference{}
+	for rows.Next() {
+		preference := &store.AlertPreference{}
+		if err := rows.Scan(&preference.UserID, &preference.AlertType, &preference.Frequency); err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		preferences = append(preferences, preference)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return preferences, nil
+}
+
+const alertUpsertPreference = `
+INSERT INTO "alert_preferences" (user_id, alert_type, frequency)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, alert_type) DO UPDATE SET
+frequency = EXCLUDED.frequency,
+updated_at = NOW()
+`
+
+func (as *AlertStore) UpsertPreference(ctx context.Context, preference *store.AlertPreference) error {
+	_, err := stmt(ctx, as.ps.UpsertPreference).ExecContext(ctx,
+		preference.UserID, preference.AlertType, preference.Frequency,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to UpsertPreference: %w", err)
+	}
+	return nil
+}
+
+// alertQueueAlerts compares every favourite book with the availability it
+// had last time and every favourite author with the newest book seen, then
+// moves both forward. All the steps read the same snapshot, so new_books
+// still sees the last_book_id from before it is advanced.
+const alertQueueAlerts = `
+WITH flipped AS (
+  UPDATE "favourite_books" f SET was_available = s.available
+  FROM (
+    SELECT f.user_id, f.book_id, ` + bookAvailable + ` AS available
+    FROM "favourite_books" f
+    JOIN "books" b ON b.id = f.book_id
+  ) s
+  WHERE f.user_id = s.user_id AND f.book_id = s.book_id
+  AND f.was_available <> s.available
+  RETURNING f.user_id, f.book_id, f.was_available
+),
+latest AS (
+  SELECT COALESCE(MAX(id), 0) AS book_id FROM "books"
+),
+new_books AS (
+  SELECT DISTINCT ON (f.user_id, ba.book_id) f.user_id, ba.book_id, ba.author_id
+  FROM "favourite_authors" f
+  CROSS JOIN latest
+  JOIN "book_authors" ba ON ba.author_id = f.author_id
+  AND ba.book_id > f.last_book_id AND ba.book_id <= latest.book_id
+  ORDER BY f.user_id, ba.book_id, ba.position
+),
+advanced AS (
+  UPDATE "favourite_authors" SET last_book_id = latest.book_id
+  FROM latest
+  WHERE last_book_id < latest.book_id
+),
+queued AS (
+  INSERT INTO "alerts" (user_id, alert_type, book_id, author_id)
+  SELECT user_id, 'available', book_id, NULL::INT FROM flipped WHERE was_available
+  UNION ALL
+  SELECT user_id, 'new_book', book_id, author_id FROM new_books
+  ON CONFLICT DO NOTHING
+  RETURNING id
+)
+SELECT COUNT(*) FROM queued
+`
+
+func (as *AlertStore) QueueAlerts(ctx context.Context) (int64, error) {
+	var count int64
+	if err := stmt(ctx, as.ps.QueueAlerts).QueryRowContext(ctx).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to QueueAlerts: %w", err)
+	}
+	return count, nil
+}
+
+// A weekly digest goes out once the oldest alert waiting for it is old
+// enough, and carries every weekly alert of the user at that point.
+const alertFindDue = `
+SELECT a.id, a.user_id, u.email, u.fullname, u.locale, a.alert_type, COALESCE(p.frequency, 'instant'),
+a.book_id, b.title, COALESCE(a.author_id, 0), COALESCE(au.name, ''), a.created_at
+FROM "alerts" a
+JOIN "users" u ON u.id = a.user_id
+JOIN "books" b ON b.id = a.book_id
+LEFT JOIN "authors" au ON au.id = a.author_id
+LEFT JOIN "alert_preferences" p ON p.user_id = a.user_id AND p.alert_type = a.alert_type
+WHERE a.sent_at IS NULL
+AND (
+  COALESCE(p.frequency, 'instant') = 'instant'
+  OR EXISTS (
+    SELECT 1
+    FROM "alerts" w
+    JOIN "alert_preferences" wp ON wp.user_id = w.user_id AND wp.alert_type = w.alert_type
+    WHERE w.user_id = a.user_id AND w.sent_at IS NULL AND wp.frequency = 'weekly'
+    AND w.created_at <= NOW() - make_interval(secs => $1)
+  )
+)
+ORDER BY a.user_id, a.id
+`
+
+func (as *AlertStore) FindDue(ctx context.Context, digestAfter time.Duration) ([]*store.Alert, error) {
+	rows, err := stmt(ctx, as.ps.FindDue).QueryContext(ctx, digestAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindDue: %w", err)
+	}
+	defer rows.Close()
+	alerts := []*store.Alert{}
+	for rows.Next() {
+		alert := &store.Alert{}
+		err := rows.Scan(
+			&alert.ID, &alert.UserID, &alert.Email, &alert.Fullname, &alert.Locale,
+			&alert.AlertType, &alert.Frequency,
+			&alert.BookID, &alert.Title, &alert.AuthorID, &alert.Author, &alert.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return alerts, nil
+}
+
+const alertMarkSent = `UPDATE "alerts" SET sent_at = NOW() WHERE id = ANY($1::INT[]) AND sent_at IS NULL`
+
+func (as *AlertStore) MarkSent(ctx context.Context, ids []int) error {
+	_, err := stmt(ctx, as.ps.MarkSent).ExecContext(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to MarkSent: %w", err)
+	}
+	return nil
+}
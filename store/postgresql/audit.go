@@ -0,0 +1,97 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type AuditStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *auditPrepareStatement
+}
+
+type auditPrepareStatement struct {
+	Record  *sql.Stmt
+	FindAll *sql.Stmt
+}
+
+func (as *AuditStore) prepareStatement() error {
+	storeName := "AuditStore"
+	var err error
+	if as.ps.Record, err = prepareStatement(as.db, storeName, "Record", auditRecord); err != nil {
+		return err
+	}
+	if as.ps.FindAll, err = prepareStatement(as.db, storeName, "FindAll", auditFindAll); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewAuditStore(log zerolog.Logger, db *sql.DB) (*AuditStore, error) {
+	as := &AuditStore{
+		db:  db,
+		log: log,
+		ps:  &auditPrepareStatement{},
+	}
+	err := as.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+const auditRecord = `
+INSERT INTO "audit_log" (event_id, event_type, aggregate_id, actor_id, payload, occurred_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (event_id) DO NOTHING
+`
+
+func (as *AuditStore) Record(ctx context.Context, event *store.DomainEvent) error {
+	_, err := stmt(ctx, as.ps.Record).ExecContext(ctx,
+		event.ID, event.Type, event.AggregateID, event.ActorID, string(event.Payload), event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to Record: %w", err)
+	}
+	return nil
+}
+
+const auditFindAll = `
+SELECT id, event_id, event_type, aggregate_id, actor_id, payload, occurred_at, created_at
+FROM "audit_log"
+WHERE ($1 = '' OR event_type = $1)
+AND ($2 = 0 OR actor_id = $2)
+ORDER BY id DESC
+LIMIT $3 OFFSET $4
+`
+
+func (as *AuditStore) FindAll(ctx context.Context, filter store.AuditFilter, limit, offset int) ([]*store.AuditEntry, error) {
+	rows, err := stmt(ctx, as.ps.FindAll).QueryContext(ctx, filter.EventType, filter.ActorID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAll: %w", err)
+	}
+	defer rows.Close()
+	entries := []*store.AuditEntry{}
+	for rows.Next() {
+		entry := &store.AuditEntry{}
+		var payload []byte
+		err := rows.Scan(
+			&entry.ID, &entry.EventID, &entry.EventType, &entry.AggregateID, &entry.ActorID,
+			&payload, &entry.OccurredAt, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		entry.Payload = payload
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return entries, nil
+}
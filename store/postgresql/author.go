@@ -0,0 +1,250 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type AuthorStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *authorPrepareStatement
+}
+
+type authorPrepareStatement struct {
+	Insert              *sql.Stmt
+	Update              *sql.Stmt
+	RefreshBooks        *sql.Stmt
+	FindOneById         *sql.Stmt
+	Search              *sql.Stmt
+	FindByBookId        *sql.Stmt
+	FindBooksByAuthorId *sql.Stmt
+	RecordEvent         *sql.Stmt
+}
+
+func (as *AuthorStore) prepareStatement() error {
+	storeName := "AuthorStore"
+	var err error
+	if as.ps.Insert, err = prepareStatement(as.db, storeName, "Insert", authorInsert); err != nil {
+		return err
+	}
+	if as.ps.Update, err = prepareStatement(as.db, storeName, "Update", authorUpdate); err != nil {
+		return err
+	}
+	if as.ps.RefreshBooks, err = prepareStatement(as.db, storeName, "RefreshBooks", authorRefreshBooks); err != nil {
+		return err
+	}
+	if as.ps.FindOneById, err = prepareStatement(as.db, storeName, "FindOneById", authorFindOneById); err != nil {
+		return err
+	}
+	if as.ps.Search, err = prepareStatement(as.db, storeName, "Search", authorSearch); err != nil {
+		return err
+	}
+	if as.ps.FindByBookId, err = prepareStatement(as.db, storeName, "FindByBookId", authorFindByBookId); err != nil {
+		return err
+	}
+	if as.ps.FindBooksByAuthorId, err = prepareStatement(as.db, storeName, "FindBooksByAuthorId", authorFindBooksByAuthorId); err != nil {
+		return err
+	}
+	if as.ps.RecordEvent, err = prepareStatement(as.db, storeName, "RecordEvent", domainEventInsert); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewAuthorStore(log zerolog.Logger, db *sql.DB) (*AuthorStore, error) {
+	as := &AuthorStore{
+		db:  db,
+		log: log,
+		ps:  &authorPrepareStatement{},
+	}
+	err := as.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+const authorColumns = `
+a.id, a.name, a.bio,
+(SELECT COUNT(DISTINCT ba.book_id) FROM "book_authors" ba WHERE ba.author_id = a.id),
+a.created_at, a.updated_at
+`
+
+const authorInsert = `
+INSERT INTO "authors" AS a (name, bio)
+VALUES ($1, $2)
+ON CONFLICT (lower(name)) DO NOTHING
+RETURNING ` + authorColumns
+
+func (as *AuthorStore) Insert(ctx context.Context, author *store.Author) error {
+	row := stmt(ctx, as.ps.Insert).QueryRowContext(ctx, author.Name, author.Bio)
+	err := as.scanInto(row, author)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = store.ErrAuthorAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	return nil
+}
+
+const authorUpdate = `
+UPDATE "authors" AS a SET
+name = $2, bio = $3, updated_at = NOW()
+WHERE a.id = $1 AND NOT EXISTS (
+	SELECT 1 FROM "authors" o WHERE lower(o.name) = lower($2) AND o.id <> $1
+)
+RETURNING ` + authorColumns
+
+// authorRefreshBooks rebuilds the books.author display string of every book
+// the author contributed to.
+const authorRefreshBooks = `
+UPDATE "books" b SET
+author = ` + bookAuthorDisplay + `
+WHERE b.id IN (SELECT book_id FROM "book_authors" WHERE author_id = $1)
+`
+
+// Update renames the author and the display string of their books within
+// the same transaction, recording author.updated so their books get
+// reindexed.
+func (as *AuthorStore) Update(ctx context.Context, author *store.Author) error {
+	err := withTx(ctx, as.db, func(tx *sql.Tx) error {
+		row := tx.StmtContext(ctx, as.ps.Update).QueryRowContext(ctx, author.ID, author.Name, author.Bio)
+		if err := as.scanInto(row, author); err != nil {
+			return err
+		}
+		if _, err := tx.StmtContext(ctx, as.ps.RefreshBooks).ExecContext(ctx, author.ID); err != nil {
+			return fmt.Errorf("failed to refresh books: %w", err)
+		}
+		event, err := store.NewDomainEvent(ctx, store.EventAuthorUpdated, author.ID, store.AuthorEventData{
+			ID:   author.ID,
+			Name: author.Name,
+		})
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, as.ps.RecordEvent, event)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, findErr := as.FindOneById(ctx, author.ID); findErr == nil {
+			err = store.ErrAuthorAlreadyExists
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to Update: %w", err)
+	}
+	return nil
+}
+
+const authorFindOneById = `SELECT ` + authorColumns + ` FROM "authors" a WHERE a.id = $1`
+
+func (as *AuthorStore) FindOneById(ctx context.Context, id int) (*store.Author, error) {
+	author := &store.Author{}
+	row := stmt(ctx, as.ps.FindOneById).QueryRowContext(ctx, id)
+	if err := as.scanInto(row, author); err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+const authorSearch = `
+SELECT ` + authorColumns + `
+FROM "authors" a
+WHERE $1 = '' OR a.name ILIKE '%' || $1 || '%'
+ORDER BY lower(a.name), a.id
+LIMIT $2 OFFSET $3
+`
+
+func (as *AuthorStore) Search(ctx context.Context, query string, limit, offset int) ([]*store.Author, error) {
+	rows, err := stmt(ctx, as.ps.Search).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Search: %w", err)
+	}
+	defer rows.Close()
+	authors := []*store.Author{}
+	for rows.Next() {
+		author := &store.Author{}
+		if err = as.scanInto(rows, author); err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return authors, nil
+}
+
+const authorFindByBookId = `
+SELECT ba.book_id, ba.author_id, a.name, ba.role, ba.position
+FROM "book_authors" ba
+JOIN "authors" a ON a.id = ba.author_id
+WHERE ba.book_id = $1
+ORDER BY ba.position, a.name
+`
+
+func (as *AuthorStore) FindByBookId(ctx context.Context, bookId int) ([]*store.BookAuthor, error) {
+	rows, err := stmt(ctx, as.ps.FindByBookId).QueryContext(ctx, bookId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByBookId: %w", err)
+	}
+	defer rows.Close()
+	credits := []*store.BookAuthor{}
+	for rows.Next() {
+		c := &store.BookAuthor{}
+		if err = rows.Scan(&c.BookID, &c.AuthorID, &c.Name, &c.Role, &c.Position); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		credits = append(credits, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return credits, nil
+}
+
+const authorFindBooksByAuthorId = `
+SELECT b.id, b.title, ba.role, b.language
+FROM "book_authors" ba
+JOIN "books" b ON b.id = ba.book_id
+WHERE ba.author_id = $1
+ORDER BY b.title, b.id, ba.role
+LIMIT $2 OFFSET $3
+`
+
+func (as *AuthorStore) FindBooksByAuthorId(ctx context.Context, authorId, limit, offset int) ([]*store.AuthoredBook, error) {
+	rows, err := stmt(ctx, as.ps.FindBooksByAuthorId).QueryContext(ctx, authorId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindBooksByAuthorId: %w", err)
+	}
+	defer rows.Close()
+	books := []*store.AuthoredBook{}
+	for rows.Next() {
+		b := &store.AuthoredBook{}
+		if err = rows.Scan(&b.BookID, &b.Title, &b.Role, &b.Language); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		books = append(books, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return books, nil
+}
+
+func (as *AuthorStore) scanInto(row scanner, author *store.Author) error {
+	err := row.Scan(
+		&author.ID, &author.Name, &author.Bio, &author.BookCount,
+		&author.CreatedAt, &author.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return nil
+}
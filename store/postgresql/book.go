@@ -0,0 +1,747 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+type BookStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *bookPrepareStatement
+}
+
+type bookPrepareStatement struct {
+	FindOneById         *sql.Stmt
+	FindOneByISBN       *sql.Stmt
+	FindByWorkId        *sql.Stmt
+	FindAll             *sql.Stmt
+	Stream              *sql.Stmt
+	FindWorkById        *sql.Stmt
+	InsertWork          *sql.Stmt
+	SetWorkById         *sql.Stmt
+	ISBNTaken           *sql.Stmt
+	CategoryExists      *sql.Stmt
+	Insert              *sql.Stmt
+	Update              *sql.Stmt
+	AuthorExists        *sql.Stmt
+	UpsertAuthorByName  *sql.Stmt
+	DeleteAuthors       *sql.Stmt
+	LinkAuthor          *sql.Stmt
+	CountAuthors        *sql.Stmt
+	RefreshAuthor       *sql.Stmt
+	UpdateCoverById     *sql.Stmt
+	PrefillMetadataById *sql.Stmt
+	AddReader           *sql.Stmt
+	UpdateCopiesById    *sql.Stmt
+	Reindex             *sql.Stmt
+	ReindexByAuthorId   *sql.Stmt
+	RecordEvent         *sql.Stmt
+}
+
+func (bs *BookStore) prepareStatement() error {
+	storeName := "BookStore"
+	var err error
+	if bs.ps.FindOneById, err = prepareStatement(bs.db, storeName, "FindOneById", bookFindOneById); err != nil {
+		return err
+	}
+	if bs.ps.FindOneByISBN, err = prepareStatement(bs.db, storeName, "FindOneByISBN", bookFindOneByISBN); err != nil {
+		return err
+	}
+	if bs.ps.FindByWorkId, err = prepareStatement(bs.db, storeName, "FindByWorkId", bookFindByWorkId); err != nil {
+		return err
+	}
+	if bs.ps.FindAll, err = prepareStatement(bs.db, storeName, "FindAll", bookFindAll); err != nil {
+		return err
+	}
+	if bs.ps.Stream, err = prepareStatement(bs.db, storeName, "Stream", bookStream); err != nil {
+		return err
+	}
+	if bs.ps.FindWorkById, err = prepareStatement(bs.db, storeName, "FindWorkById", bookFindWorkById); err != nil {
+		return err
+	}
+	if bs.ps.InsertWork, err = prepareStatement(bs.db, storeName, "InsertWork", bookInsertWork); err != nil {
+		return err
+	}
+	if bs.ps.SetWorkById, err = prepareStatement(bs.db, storeName, "SetWorkById", bookSetWorkById); err != nil {
+		return err
+	}
+	if bs.ps.ISBNTaken, err = prepareStatement(bs.db, storeName, "ISBNTaken", bookISBNTaken); err != nil {
+		return err
+	}
+	if bs.ps.CategoryExists, err = prepareStatement(bs.db, storeName, "CategoryExists", bookCategoryExists); err != nil {
+		return err
+	}
+	if bs.ps.Insert, err = prepareStatement(bs.db, storeName, "Insert", bookInsert); err != nil {
+		return err
+	}
+	if bs.ps.Update, err = prepareStatement(bs.db, storeName, "Update", bookUpdate); err != nil {
+		return err
+	}
+	if bs.ps.AuthorExists, err = prepareStatement(bs.db, storeName, "AuthorExists", bookAuthorExists); err != nil {
+		return err
+	}
+	if bs.ps.UpsertAuthorByName, err = prepareStatement(bs.db, storeName, "UpsertAuthorByName", bookUpsertAuthorByName); err != nil {
+		return err
+	}
+	if bs.ps.DeleteAuthors, err = prepareStatement(bs.db, storeName, "DeleteAuthors", bookDeleteAuthors); err != nil {
+		return err
+	}
+	if bs.ps.LinkAuthor, err = prepareStatement(bs.db, storeName, "LinkAuthor", bookLinkAuthor); err != nil {
+		return err
+	}
+	if bs.ps.CountAuthors, err = prepareStatement(bs.db, storeName, "CountAuthors", bookCountAuthors); err != nil {
+		return err
+	}
+	if bs.ps.RefreshAuthor, err = prepareStatement(bs.db, storeName, "RefreshAuthor", bookRefreshAuthor); err != nil {
+		return err
+	}
+	if bs.ps.UpdateCoverById, err = prepareStatement(bs.db, storeName, "UpdateCoverById", bookUpdateCoverById); err != nil {
+		return err
+	}
+	if bs.ps.PrefillMetadataById, err = prepareStatement(bs.db, storeName, "PrefillMetadataById", bookPrefillMetadataById); err != nil {
+		return err
+	}
+	if bs.ps.AddReader, err = prepareStatement(bs.db, storeName, "AddReader", bookAddReader); err != nil {
+		return err
+	}
+	if bs.ps.UpdateCopiesById, err = prepareStatement(bs.db, storeName, "UpdateCopiesById", bookUpdateCopiesById); err != nil {
+		return err
+	}
+	if bs.ps.Reindex, err = prepareStatement(bs.db, storeName, "Reindex", bookReindex); err != nil {
+		return err
+	}
+	if bs.ps.ReindexByAuthorId, err = prepareStatement(bs.db, storeName, "ReindexByAuthorId", bookReindexByAuthorId); err != nil {
+		return err
+	}
+	if bs.ps.RecordEvent, err = prepareStatement(bs.db, storeName, "RecordEvent", domainEventInsert); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewBookStore(log zerolog.Logger, db *sql.DB) (*BookStore, error) {
+	bs := &BookStore{
+		db:  db,
+		log: log,
+		ps:  &bookPrepareStatement{},
+	}
+	err := bs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+const bookFindOneBase = `
+SELECT id, title, author, synopsis, cover,
+cover_updated_at, language, reader, copies, category_id,
+isbn13, isbn10, publisher, published_date, page_count,
+edition, work_id
+FROM "books"
+`
+
+const bookFindOneById = bookFindOneBase + "WHERE id = $1"
+
+func (bs *BookStore) FindOneById(ctx context.Context, id int) (*store.Book, error) {
+	row := stmt(ctx, bs.ps.FindOneById).QueryRowContext(ctx, id)
+	return bs.scanRow(row)
+}
+
+const bookFindOneByISBN = bookFindOneBase + "WHERE isbn13 = $1"
+
+func (bs *BookStore) FindOneByISBN(ctx context.Context, isbn13 string) (*store.Book, error) {
+	row := stmt(ctx, bs.ps.FindOneByISBN).QueryRowContext(ctx, isbn13)
+	return bs.scanRow(row)
+}
+
+const bookFindByWorkId = bookFindOneBase + `
+WHERE work_id = $1
+ORDER BY published_date NULLS LAST, id
+`
+
+func (bs *BookStore) FindByWorkId(ctx context.Context, workId int) ([]*store.Book, error) {
+	rows, err := stmt(ctx, bs.ps.FindByWorkId).QueryContext(ctx, workId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByWorkId: %w", err)
+	}
+	defer rows.Close()
+	books := []*store.Book{}
+	for rows.Next() {
+		book, err := bs.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return books, nil
+}
+
+// bookCatalogBase matches the LIKE pattern of searchPattern against the
+// title and author, so new books are found at once, and against
+// search_text once the book is indexed.
+const bookCatalogBase = `
+SELECT b.id, b.title, b.author, b.synopsis, b.cover,
+b.cover_updated_at, b.language, b.reader, b.copies, b.category_id,
+b.isbn13, b.isbn10, b.publisher, b.published_date, b.page_count,
+b.edition, b.work_id, c.name,
+COALESCE((
+	SELECT json_agg(json_build_object(
+		'author_id', a.id, 'name', a.name, 'role', ba.role, 'position', ba.position
+	) ORDER BY ba.position)
+	FROM "book_authors" ba
+	JOIN "authors" a ON a.id = ba.author_id
+	WHERE ba.book_id = b.id
+), '[]'),
+r.count, COALESCE(r.average, 0)
+FROM "books" b
+JOIN "category" c ON c.id = b.category_id
+CROSS JOIN LATERAL (
+	SELECT COUNT(*) AS count, AVG(rating) AS average
+	FROM "book_rating"
+	WHERE book_id = b.id
+) r
+WHERE (lower(b.title) LIKE $1 OR lower(b.author) LIKE $1 OR b.search_text LIKE $1)
+AND ($2 = 0 OR b.category_id = $2)
+AND ($3 = 0 OR EXISTS (SELECT 1 FROM "book_authors" ba WHERE ba.book_id = b.id AND ba.author_id = $3))
+AND ($4 = '' OR lower(b.language) = lower($4))
+AND ($5::DATE IS NULL OR b.published_date >= $5)
+AND ($6::DATE IS NULL OR b.published_date <= $6)
+ORDER BY b.id
+`
+
+const bookFindAll = bookCatalogBase + "LIMIT $7 OFFSET $8"
+
+func (bs *BookStore) FindAll(ctx context.Context, filter store.BookFilter, limit, offset int) ([]*store.CatalogBook, error) {
+	args := append(bookFilterArgs(filter), limit, offset)
+	rows, err := stmt(ctx, bs.ps.FindAll).QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAll: %w", err)
+	}
+	defer rows.Close()
+	books := []*store.CatalogBook{}
+	for rows.Next() {
+		book, err := bs.scanCatalogRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return books, nil
+}
+
+const bookStream = bookCatalogBase
+
+func (bs *BookStore) Stream(ctx context.Context, filter store.BookFilter, fn func(*store.CatalogBook) error) error {
+	rows, err := stmt(ctx, bs.ps.Stream).QueryContext(ctx, bookFilterArgs(filter)...)
+	if err != nil {
+		return fmt.Errorf("failed to Stream: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		book, err := bs.scanCatalogRow(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(book); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return nil
+}
+
+func bookFilterArgs(filter store.BookFilter) []interface{} {
+	return []interface{}{
+		searchPattern(filter.Query), filter.CategoryID, filter.AuthorID, filter.Language,
+		sql.NullTime{Time: filter.PublishedAfter, Valid: !filter.PublishedAfter.IsZero()},
+		sql.NullTime{Time: filter.PublishedBefore, Valid: !filter.PublishedBefore.IsZero()},
+	}
+}
+
+// searchPattern is the LIKE pattern finding the query anywhere in
+// search_text, or matching every book when the query is empty.
+func searchPattern(query string) string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return "%"
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query)
+	return "%" + escaped + "%"
+}
+
+const bookFindWorkById = `SELECT work_id, title FROM "books" WHERE id = $1 FOR UPDATE`
+
+const bookInsertWork = `INSERT INTO "works" (title) VALUES ($1) RETURNING id`
+
+const bookSetWorkById = `UPDATE "books" SET work_id = $1 WHERE id = $2`
+
+// EnsureWorkById returns the work the book is an edition of, starting a new
+// work named after the book when it is not grouped yet.
+func (bs *BookStore) EnsureWorkById(ctx context.Context, id int) (int, error) {
+	var workId int
+	err := withTx(ctx, bs.db, func(tx *sql.Tx) error {
+		var current sql.NullInt64
+		var title string
+		err := tx.StmtContext(ctx, bs.ps.FindWorkById).QueryRowContext(ctx, id).Scan(&current, &title)
+		if err != nil {
+			return err
+		}
+		if current.Valid {
+			workId = int(current.Int64)
+			return nil
+		}
+		if err = tx.StmtContext(ctx, bs.ps.InsertWork).QueryRowContext(ctx, title).Scan(&workId); err != nil {
+			return fmt.Errorf("failed to insert work: %w", err)
+		}
+		if _, err = tx.StmtContext(ctx, bs.ps.SetWorkById).ExecContext(ctx, workId, id); err != nil {
+			return fmt.Errorf("failed to set work: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to EnsureWorkById: %w", err)
+	}
+	return workId, nil
+}
+
+const bookISBNTaken = `
+SELECT EXISTS (
+	SELECT 1 FROM "books"
+	WHERE (isbn13 = $1 OR isbn10 = $2) AND id <> $3
+)
+`
+
+// checkISBN is a fast path only, a concurrent write can still take the
+// ISBN before the book is saved, isbnTaken then reports the violation.
+func (bs *BookStore) checkISBN(ctx context.Context, tx *sql.Tx, book *store.Book) error {
+	if !book.ISBN13.Valid {
+		return nil
+	}
+	var taken bool
+	err := tx.StmtContext(ctx, bs.ps.ISBNTaken).QueryRowContext(ctx, book.ISBN13, book.ISBN10, book.ID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check isbn: %w", err)
+	}
+	if taken {
+		return store.ErrBookISBNAlreadyExists
+	}
+	return nil
+}
+
+// isbnTaken reports a violation of the unique isbn columns as
+// store.ErrBookISBNAlreadyExists.
+func isbnTaken(err error) error {
+	switch violatedConstraint(err) {
+	case "books__isbn13__key", "books__isbn10__key":
+		return fmt.Errorf("%w: %v", store.ErrBookISBNAlreadyExists, err)
+	}
+	return err
+}
+
+const bookCategoryExists = `SELECT EXISTS (SELECT 1 FROM "category" WHERE id = $1)`
+
+const bookInsert = `
+INSERT INTO "books" (
+	title, author, synopsis, cover, language, category_id,
+	isbn13, isbn10, publisher, published_date, page_count,
+	edition, work_id
+) VALUES (
+	$1, '', $2, '', $3, $4,
+	$5, $6, $7, $8, $9,
+	$10, $11
+)
+RETURNING id
+`
+
+// Insert creates the book and credits its contributors in one transaction,
+// unknown author names are created on the way.
+func (bs *BookStore) Insert(ctx context.Context, book *store.Book, credits []store.AuthorCredit) error {
+	err := withTx(ctx, bs.db, func(tx *sql.Tx) error {
+		if err := bs.checkCategory(ctx, tx, book.CategoryID); err != nil {
+			return err
+		}
+		if err := bs.checkISBN(ctx, tx, book); err != nil {
+			return err
+		}
+		var id int
+		err := tx.StmtContext(ctx, bs.ps.Insert).QueryRowContext(ctx,
+			book.Title, book.Synopsis, book.Language, book.CategoryID,
+			book.ISBN13, book.ISBN10, book.Publisher, book.PublishedDate,
+			book.PageCount, book.Edition, book.WorkID,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to insert book: %w", err)
+		}
+		if err = bs.setAuthors(ctx, tx, id, credits); err != nil {
+			return err
+		}
+		if err = bs.reload(ctx, tx, id, book); err != nil {
+			return err
+		}
+		return bs.recordEvent(ctx, tx, store.EventBookCreated, book)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to Insert: %w", isbnTaken(err))
+	}
+	return nil
+}
+
+const bookUpdate = `
+UPDATE "books" SET
+title = $1, synopsis = $2, language = $3, category_id = $4,
+isbn13 = $5, isbn10 = $6, publisher = $7, published_date = $8,
+page_count = $9, edition = $10, work_id = COALESCE($11, work_id)
+WHERE id = $12
+`
+
+// Update replaces the descriptive fields and the credited contributors of
+// the book. Circulation and cover columns are managed by their own calls.
+func (bs *BookStore) Update(ctx context.Context, book *store.Book, credits []store.AuthorCredit) error {
+	err := withTx(ctx, bs.db, func(tx *sql.Tx) error {
+		if err := bs.checkCategory(ctx, tx, book.CategoryID); err != nil {
+			return err
+		}
+		if err := bs.checkISBN(ctx, tx, book); err != nil {
+			return err
+		}
+		res, err := tx.StmtContext(ctx, bs.ps.Update).ExecContext(ctx,
+			book.Title, book.Synopsis, book.Language, book.CategoryID,
+			book.ISBN13, book.ISBN10, book.Publisher, book.PublishedDate,
+			book.PageCount, book.Edition, book.WorkID, book.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update book: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to update book: %w", err)
+		}
+		if affected == 0 {
+			return fmt.Errorf("book %d: %w", book.ID, sql.ErrNoRows)
+		}
+		if err = bs.setAuthors(ctx, tx, book.ID, credits); err != nil {
+			return err
+		}
+		if err = bs.reload(ctx, tx, book.ID, book); err != nil {
+			return err
+		}
+		return bs.recordEvent(ctx, tx, store.EventBookUpdated, book)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to Update: %w", isbnTaken(err))
+	}
+	return nil
+}
+
+func (bs *BookStore) checkCategory(ctx context.Context, tx *sql.Tx, categoryId int) error {
+	var exists bool
+	if err := tx.StmtContext(ctx, bs.ps.CategoryExists).QueryRowContext(ctx, categoryId).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check category: %w", err)
+	}
+	if !exists {
+		return store.ErrBookCategoryNotFound
+	}
+	return nil
+}
+
+func (bs *BookStore) reload(ctx context.Context, tx *sql.Tx, id int, book *store.Book) error {
+	loaded, err := bs.scanRow(tx.StmtContext(ctx, bs.ps.FindOneById).QueryRowContext(ctx, id))
+	if err != nil {
+		return err
+	}
+	*book = *loaded
+	return nil
+}
+
+const bookAuthorExists = `SELECT EXISTS (SELECT 1 FROM "authors" WHERE id = $1)`
+
+// bookUpsertAuthorByName matches names case-insensitively. The no-op update
+// makes RETURNING yield the id of an existing author as well.
+const bookUpsertAuthorByName = `
+INSERT INTO "authors" (name)
+VALUES ($1)
+ON CONFLICT (lower(name)) DO UPDATE SET
+name = "authors".name
+RETURNING id
+`
+
+const bookDeleteAuthors = `DELETE FROM "book_authors" WHERE book_id = $1`
+
+const bookLinkAuthor = `
+INSERT INTO "book_authors" (book_id, author_id, role, position)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING
+`
+
+const bookCountAuthors = `SELECT COUNT(*) FROM "book_authors" WHERE book_id = $1`
+
+// bookAuthorDisplay renders the credited authors, editors and translators
+// aside, as the comma separated books.author display string.
+const bookAuthorDisplay = `COALESCE((
+	SELECT string_agg(a.name, ', ' ORDER BY ba.position, a.name)
+	FROM "book_authors" ba
+	JOIN "authors" a ON a.id = ba.author_id
+	WHERE ba.book_id = b.id AND ba.role = 'author'
+), '')`
+
+const bookRefreshAuthor = `
+UPDATE "books" b SET
+author = ` + bookAuthorDisplay + `
+WHERE b.id = $1
+`
+
+// setAuthors replaces the credits of the book, in the given order.
+func (bs *BookStore) setAuthors(ctx context.Context, tx *sql.Tx, bookId int, credits []store.AuthorCredit) error {
+	if _, err := tx.StmtContext(ctx, bs.ps.DeleteAuthors).ExecContext(ctx, bookId); err != nil {
+		return fmt.Errorf("failed to delete authors: %w", err)
+	}
+	for i, credit := range credits {
+		authorId := credit.AuthorID
+		if authorId == 0 {
+			err := tx.StmtContext(ctx, bs.ps.UpsertAuthorByName).QueryRowContext(ctx, strings.TrimSpace(credit.Name)).
+				Scan(&authorId)
+			if err != nil {
+				return fmt.Errorf("failed to upsert author by name: %w", err)
+			}
+		} else {
+			var exists bool
+			if err := tx.StmtContext(ctx, bs.ps.AuthorExists).QueryRowContext(ctx, authorId).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to check author: %w", err)
+			}
+			if !exists {
+				return store.ErrAuthorNotFound
+			}
+		}
+		role := credit.Role
+		if role == "" {
+			role = store.AuthorRoleAuthor
+		}
+		if _, err := tx.StmtContext(ctx, bs.ps.LinkAuthor).ExecContext(ctx, bookId, authorId, role, i); err != nil {
+			return fmt.Errorf("failed to link author: %w", err)
+		}
+	}
+	if _, err := tx.StmtContext(ctx, bs.ps.RefreshAuthor).ExecContext(ctx, bookId); err != nil {
+		return fmt.Errorf("failed to refresh author: %w", err)
+	}
+	return nil
+}
+
+const bookUpdateCoverById = `
+UPDATE "books" SET
+cover = $1, cover_updated_at = NOW()
+WHERE id = $2
+`
+
+func (bs *BookStore) UpdateCoverById(ctx context.Context, cover string, id int) error {
+	_, err := stmt(ctx, bs.ps.UpdateCoverById).ExecContext(ctx, cover, id)
+	if err != nil {
+		return fmt.Errorf("failed to UpdateCoverById: %w", err)
+	}
+	return nil
+}
+
+// bookPrefillMetadataById only fills columns that are still blank so
+// metadata curated by librarians is never overwritten by an upload. An ISBN
+// already used by another book is left out rather than failing the upload.
+const bookPrefillMetadataById = `
+UPDATE "books" SET
+title = CASE WHEN title = '' THEN $1 ELSE title END,
+synopsis = CASE WHEN synopsis = '' THEN $2 ELSE synopsis END,
+language = CASE WHEN language = '' THEN $3 ELSE language END,
+isbn13 = CASE WHEN isbn13 IS NULL AND NOT EXISTS (` + bookISBNUsed + `) THEN NULLIF($5, '') ELSE isbn13 END,
+isbn10 = CASE WHEN isbn13 IS NULL AND NOT EXISTS (` + bookISBNUsed + `) THEN NULLIF($6, '') ELSE isbn10 END
+WHERE id = $4
+`
+
+const bookISBNUsed = `SELECT 1 FROM "books" o WHERE o.isbn13 = $5 OR o.isbn10 = $6`
+
+func (bs *BookStore) PrefillMetadataById(ctx context.Context, meta *store.BookMetadata, id int) error {
+	err := withTx(ctx, bs.db, func(tx *sql.Tx) error {
+		_, err := tx.StmtContext(ctx, bs.ps.PrefillMetadataById).ExecContext(ctx,
+			meta.Title, meta.Synopsis, meta.Language, id,
+			meta.ISBN13, meta.ISBN10,
+		)
+		if err != nil {
+			return err
+		}
+		if err = bs.prefillAuthors(ctx, tx, meta, id); err != nil {
+			return err
+		}
+		// Prefilling records no book event, the book is reindexed here.
+		if _, err = tx.StmtContext(ctx, bs.ps.Reindex).ExecContext(ctx, id); err != nil {
+			return fmt.Errorf("failed to reindex book: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to PrefillMetadataById: %w", err)
+	}
+	return nil
+}
+
+func (bs *BookStore) prefillAuthors(ctx context.Context, tx *sql.Tx, meta *store.BookMetadata, id int) error {
+	if len(meta.Authors) == 0 {
+		return nil
+	}
+	var count int
+	if err := tx.StmtContext(ctx, bs.ps.CountAuthors).QueryRowContext(ctx, id).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count authors: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	credits := make([]store.AuthorCredit, 0, len(meta.Authors))
+	for _, name := range meta.Authors {
+		credits = append(credits, store.AuthorCredit{Name: name, Role: store.AuthorRoleAuthor})
+	}
+	return bs.setAuthors(ctx, tx, id, credits)
+}
+
+// bookAddReader records the user as a reader of the book and bumps the
+// denormalised books.reader counter only the first time, so it grows with
+// the number of distinct readers. Every call also counts as a read event of
+// the day for the rankings.
+const bookAddReader = `
+WITH new_reader AS (
+	INSERT INTO "book_readers" (book_id, user_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	RETURNING book_id
+), read_event AS (
+	INSERT INTO "book_events" (book_id, user_id, kind)
+	VALUES ($1, $2, 'read')
+	ON CONFLICT DO NOTHING
+)
+UPDATE "books" SET
+reader = reader + 1
+WHERE id IN (SELECT book_id FROM new_reader)
+`
+
+func (bs *BookStore) AddReader(ctx context.Context, id, userId int) error {
+	_, err := stmt(ctx, bs.ps.AddReader).ExecContext(ctx, id, userId)
+	if err != nil {
+		return fmt.Errorf("failed to AddReader: %w", err)
+	}
+	return nil
+}
+
+const bookUpdateCopiesById = `
+UPDATE "books" SET
+copies = $1
+WHERE id = $2
+`
+
+func (bs *BookStore) UpdateCopiesById(ctx context.Context, copies, id int) error {
+	_, err := stmt(ctx, bs.ps.UpdateCopiesById).ExecContext(ctx, copies, id)
+	if err != nil {
+		return fmt.Errorf("failed to UpdateCopiesById: %w", err)
+	}
+	return nil
+}
+
+const bookSearchText = `lower(concat_ws(' ',
+	b.title, b.author, b.isbn13, b.isbn10, b.publisher,
+	(SELECT string_agg(a.name, ' ') FROM "book_authors" ba JOIN "authors" a ON a.id = ba.author_id WHERE ba.book_id = b.id)
+))`
+
+const bookReindex = `
+UPDATE "books" b SET search_text = ` + bookSearchText + `
+WHERE id = $1
+`
+
+func (bs *BookStore) Reindex(ctx context.Context, id int) error {
+	_, err := stmt(ctx, bs.ps.Reindex).ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to Reindex: %w", err)
+	}
+	return nil
+}
+
+const bookReindexByAuthorId = `
+UPDATE "books" b SET search_text = ` + bookSearchText + `
+WHERE b.id IN (SELECT book_id FROM "book_authors" WHERE author_id = $1)
+`
+
+func (bs *BookStore) ReindexByAuthorId(ctx context.Context, authorId int) error {
+	_, err := stmt(ctx, bs.ps.ReindexByAuthorId).ExecContext(ctx, authorId)
+	if err != nil {
+		return fmt.Errorf("failed to ReindexByAuthorId: %w", err)
+	}
+	return nil
+}
+
+func (bs *BookStore) recordEvent(ctx context.Context, tx *sql.Tx, eventType string, book *store.Book) error {
+	event, err := store.NewDomainEvent(ctx, eventType, book.ID, store.NewBookEventData(book))
+	if err != nil {
+		return err
+	}
+	return recordEvent(ctx, tx, bs.ps.RecordEvent, event)
+}
+
+type bookAuthorJSON struct {
+	AuthorID int    `json:"author_id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Position int    `json:"position"`
+}
+
+func (bs *BookStore) scanCatalogRow(row scanner) (*store.CatalogBook, error) {
+	book := &store.CatalogBook{}
+	var authors []byte
+	err := row.Scan(
+		&book.ID, &book.Title, &book.Author, &book.Synopsis,
+		&book.Cover, &book.CoverUpdatedAt, &book.Language, &book.Reader,
+		&book.Copies, &book.CategoryID, &book.ISBN13, &book.ISBN10,
+		&book.Publisher, &book.PublishedDate, &book.PageCount,
+		&book.Edition, &book.WorkID, &book.CategoryName, &authors,
+		&book.RatingCount, &book.RatingAverage,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	credits := []bookAuthorJSON{}
+	if err = json.Unmarshal(authors, &credits); err != nil {
+		return nil, fmt.Errorf("failed to decode authors: %w", err)
+	}
+	book.Authors = make([]*store.BookAuthor, 0, len(credits))
+	for _, c := range credits {
+		book.Authors = append(book.Authors, &store.BookAuthor{
+			BookID:   book.ID,
+			AuthorID: c.AuthorID,
+			Name:     c.Name,
+			Role:     c.Role,
+			Position: c.Position,
+		})
+	}
+	return book, nil
+}
+
+func (bs *BookStore) scanRow(row scanner) (*store.Book, error) {
+	book := &store.Book{}
+	err := row.Scan(
+		&book.ID, &book.Title, &book.Author, &book.Synopsis,
+		&book.Cover, &book.CoverUpdatedAt, &book.Language, &book.Reader,
+		&book.Copies, &book.CategoryID, &book.ISBN13, &book.ISBN10,
+		&book.Publisher, &book.PublishedDate, &book.PageCount,
+		&book.Edition, &book.WorkID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return book, nil
+}
@@ -0,0 +1,151 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type BookFileStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *bookFilePrepareStatement
+}
+
+type bookFilePrepareStatement struct {
+	Upsert                   *sql.Stmt
+	FindByBookId             *sql.Stmt
+	FindByBookIds            *sql.Stmt
+	FindOneByBookIdAndFormat *sql.Stmt
+}
+
+func (bfs *BookFileStore) prepareStatement() error {
+	storeName := "BookFileStore"
+	var err error
+	if bfs.ps.Upsert, err = prepareStatement(bfs.db, storeName, "Upsert", bookFileUpsert); err != nil {
+		return err
+	}
+	if bfs.ps.FindByBookId, err = prepareStatement(bfs.db, storeName, "FindByBookId", bookFileFindByBookId); err != nil {
+		return err
+	}
+	if bfs.ps.FindByBookIds, err = prepareStatement(bfs.db, storeName, "FindByBookIds", bookFileFindByBookIds); err != nil {
+		return err
+	}
+	if bfs.ps.FindOneByBookIdAndFormat, err = prepareStatement(bfs.db, storeName, "FindOneByBookIdAndFormat", bookFileFindOneByBookIdAndFormat); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewBookFileStore(log zerolog.Logger, db *sql.DB) (*BookFileStore, error) {
+	bfs := &BookFileStore{
+		db:  db,
+		log: log,
+		ps:  &bookFilePrepareStatement{},
+	}
+	err := bfs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return bfs, nil
+}
+
+const bookFileUpsert = `
+INSERT INTO "book_files" (
+	book_id, format, blob_key, content_type, size, metadata
+) VALUES (
+	$1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (book_id, format) DO UPDATE SET
+blob_key = EXCLUDED.blob_key,
+content_type = EXCLUDED.content_type,
+size = EXCLUDED.size,
+metadata = EXCLUDED.metadata,
+created_at = NOW()
+RETURNING id, created_at
+`
+
+func (bfs *BookFileStore) Upsert(ctx context.Context, file *store.BookFile) error {
+	err := stmt(ctx, bfs.ps.Upsert).QueryRowContext(ctx,
+		file.BookID, file.Format, file.BlobKey,
+		file.ContentType, file.Size, file.Metadata,
+	).Scan(&file.ID, &file.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to Upsert: %w", err)
+	}
+	return nil
+}
+
+const bookFileFindBase = `
+SELECT id, book_id, format, blob_key, content_type,
+size, metadata, created_at
+FROM "book_files"
+`
+
+const bookFileFindByBookId = bookFileFindBase + "WHERE book_id = $1 ORDER BY format"
+
+func (bfs *BookFileStore) FindByBookId(ctx context.Context, bookId int) ([]*store.BookFile, error) {
+	rows, err := stmt(ctx, bfs.ps.FindByBookId).QueryContext(ctx, bookId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByBookId: %w", err)
+	}
+	defer rows.Close()
+	files := []*store.BookFile{}
+	for rows.Next() {
+		file, err := bfs.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return files, nil
+}
+
+const bookFileFindByBookIds = bookFileFindBase + "WHERE book_id = ANY($1::INT[]) ORDER BY book_id, format"
+
+// FindByBookIds loads the files of a page of books in one query.
+func (bfs *BookFileStore) FindByBookIds(ctx context.Context, bookIds []int) ([]*store.BookFile, error) {
+	rows, err := stmt(ctx, bfs.ps.FindByBookIds).QueryContext(ctx, bookIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByBookIds: %w", err)
+	}
+	defer rows.Close()
+	files := []*store.BookFile{}
+	for rows.Next() {
+		file, err := bfs.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return files, nil
+}
+
+const bookFileFindOneByBookIdAndFormat = bookFileFindBase + "WHERE book_id = $1 AND format = $2"
+
+func (bfs *BookFileStore) FindOneByBookIdAndFormat(ctx context.Context, bookId int, format string) (*store.BookFile, error) {
+	row := stmt(ctx, bfs.ps.FindOneByBookIdAndFormat).QueryRowContext(ctx, bookId, format)
+	return bfs.scanRow(row)
+}
+
+func (bfs *BookFileStore) scanRow(row scanner) (*store.BookFile, error) {
+	file := &store.BookFile{}
+	err := row.Scan(
+		&file.ID, &file.BookID, &file.Format, &file.BlobKey,
+		&file.ContentType, &file.Size, &file.Metadata,
+		&file.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return file, nil
+}
@@ -0,0 +1,23 @@
+package postgresql
+
+import "testing"
+
+func TestSearchPattern(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "%"},
+		{"   ", "%"},
+		{"Tolkien", "%tolkien%"},
+		{" Le Petit Prince ", "%le petit prince%"},
+		{"100%", `%100\%%`},
+		{"snake_case", `%snake\_case%`},
+		{`C:\books`, `%c:\\books%`},
+	}
+	for _, tt := range tests {
+		if got := searchPattern(tt.query); got != tt.want {
+			t.Errorf("searchPattern(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
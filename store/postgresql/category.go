@@ -0,0 +1,107 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type CategoryStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *categoryPrepareStatement
+}
+
+type categoryPrepareStatement struct {
+	FindAll       *sql.Stmt
+	FindOneByName *sql.Stmt
+	Insert        *sql.Stmt
+}
+
+func (cs *CategoryStore) prepareStatement() error {
+	storeName := "CategoryStore"
+	var err error
+	if cs.ps.FindAll, err = prepareStatement(cs.db, storeName, "FindAll", categoryFindAll); err != nil {
+		return err
+	}
+	if cs.ps.FindOneByName, err = prepareStatement(cs.db, storeName, "FindOneByName", categoryFindOneByName); err != nil {
+		return err
+	}
+	if cs.ps.Insert, err = prepareStatement(cs.db, storeName, "Insert", categoryInsert); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewCategoryStore(log zerolog.Logger, db *sql.DB) (*CategoryStore, error) {
+	cs := &CategoryStore{
+		db:  db,
+		log: log,
+		ps:  &categoryPrepareStatement{},
+	}
+	err := cs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+const categoryFindAll = `
+SELECT c.id, c.name, COUNT(b.id)
+FROM "category" c
+LEFT JOIN "books" b ON b.category_id = c.id
+GROUP BY c.id
+ORDER BY c.name, c.id
+`
+
+func (cs *CategoryStore) FindAll(ctx context.Context) ([]*store.Category, error) {
+	rows, err := stmt(ctx, cs.ps.FindAll).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAll: %w", err)
+	}
+	defer rows.Close()
+	categories := []*store.Category{}
+	for rows.Next() {
+		category := &store.Category{}
+		if err = rows.Scan(&category.ID, &category.Name, &category.BookCount); err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return categories, nil
+}
+
+const categoryFindOneByName = `
+SELECT id, name
+FROM "category"
+WHERE lower(name) = lower($1)
+ORDER BY id
+LIMIT 1
+`
+
+const categoryInsert = `
+INSERT INTO "category" (name)
+VALUES ($1)
+RETURNING id, name
+`
+
+// FindOrInsertByName matches category names case-insensitively, the oldest
+// category wins when names were duplicated by hand.
+func (cs *CategoryStore) FindOrInsertByName(ctx context.Context, name string) (*store.Category, error) {
+	category := &store.Category{}
+	err := stmt(ctx, cs.ps.FindOneByName).QueryRowContext(ctx, name).Scan(&category.ID, &category.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = stmt(ctx, cs.ps.Insert).QueryRowContext(ctx, name).Scan(&category.ID, &category.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindOrInsertByName: %w", err)
+	}
+	return category, nil
+}
@@ -0,0 +1,472 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type CollectionStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *collectionPrepareStatement
+}
+
+type collectionPrepareStatement struct {
+	Insert             *sql.Stmt
+	Update             *sql.Stmt
+	DeleteById         *sql.Stmt
+	FindOneById        *sql.Stmt
+	FindOneBySlug      *sql.Stmt
+	FindByMember       *sql.Stmt
+	SearchPublic       *sql.Stmt
+	UpsertItem         *sql.Stmt
+	RemoveItem         *sql.Stmt
+	FindItems          *sql.Stmt
+	LockItems          *sql.Stmt
+	SetPosition        *sql.Stmt
+	Touch              *sql.Stmt
+	IsCollaborator     *sql.Stmt
+	FindCollaborators  *sql.Stmt
+	AddCollaborator    *sql.Stmt
+	RemoveCollaborator *sql.Stmt
+}
+
+func (cs *CollectionStore) prepareStatement() error {
+	storeName := "CollectionStore"
+	var err error
+	if cs.ps.Insert, err = prepareStatement(cs.db, storeName, "Insert", collectionInsert); err != nil {
+		return err
+	}
+	if cs.ps.Update, err = prepareStatement(cs.db, storeName, "Update", collectionUpdate); err != nil {
+		return err
+	}
+	if cs.ps.DeleteById, err = prepareStatement(cs.db, storeName, "DeleteById", collectionDeleteById); err != nil {
+		return err
+	}
+	if cs.ps.FindOneById, err = prepareStatement(cs.db, storeName, "FindOneById", collectionFindOneById); err != nil {
+		return err
+	}
+	if cs.ps.FindOneBySlug, err = prepareStatement(cs.db, storeName, "FindOneBySlug", collectionFindOneBySlug); err != nil {
+		return err
+	}
+	if cs.ps.FindByMember, err = prepareStatement(cs.db, storeName, "FindByMember", collectionFindByMember); err != nil {
+		return err
+	}
+	if cs.ps.SearchPublic, err = prepareStatement(cs.db, storeName, "SearchPublic", collectionSearchPublic); err != nil {
+		return err
+	}
+	if cs.ps.UpsertItem, err = prepareStatement(cs.db, storeName, "UpsertItem", collectionUpsertItem); err != nil {
+		return err
+	}
+	if cs.ps.RemoveItem, err = prepareStatement(cs.db, storeName, "RemoveItem", collectionRemoveItem); err != nil {
+		return err
+	}
+	if cs.ps.FindItems, err = prepareStatement(cs.db, storeName, "FindItems", collectionFindItems); err != nil {
+		return err
+	}
+	if cs.ps.LockItems, err = prepareStatement(cs.db, storeName, "LockItems", collectionLockItems); err != nil {
+		return err
+	}
+	if cs.ps.SetPosition, err = prepareStatement(cs.db, storeName, "SetPosition", collectionSetPosition); err != nil {
+		return err
+	}
+	if cs.ps.Touch, err = prepareStatement(cs.db, storeName, "Touch", collectionTouch); err != nil {
+		return err
+	}
+	if cs.ps.IsCollaborator, err = prepareStatement(cs.db, storeName, "IsCollaborator", collectionIsCollaborator); err != nil {
+		return err
+	}
+	if cs.ps.FindCollaborators, err = prepareStatement(cs.db, storeName, "FindCollaborators", collectionFindCollaborators); err != nil {
+		return err
+	}
+	if cs.ps.AddCollaborator, err = prepareStatement(cs.db, storeName, "AddCollaborator", collectionAddCollaborator); err != nil {
+		return err
+	}
+	if cs.ps.RemoveCollaborator, err = prepareStatement(cs.db, storeName, "RemoveCollaborator", collectionRemoveCollaborator); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewCollectionStore(log zerolog.Logger, db *sql.DB) (*CollectionStore, error) {
+	cs := &CollectionStore{
+		db:  db,
+		log: log,
+		ps:  &collectionPrepareStatement{},
+	}
+	err := cs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+const collectionColumns = `
+c.id, c.user_id,
+(SELECT u.fullname FROM "users" u WHERE u.id = c.user_id),
+c.slug, c.title, c.description, c.visibility,
+(SELECT COUNT(*) FROM "collection_items" ci WHERE ci.collection_id = c.id),
+c.created_at, c.updated_at
+`
+
+const collectionInsert = `
+INSERT INTO "collections" AS c (user_id, slug, title, description, visibility)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (slug) DO NOTHING
+RETURNING ` + collectionColumns
+
+func (cs *CollectionStore) Insert(ctx context.Context, collection *store.Collection) error {
+	row := stmt(ctx, cs.ps.Insert).QueryRowContext(ctx,
+		collection.UserID, collection.Slug, collection.Title,
+		collection.Description, collection.Visibility,
+	)
+	err := cs.scanInto(row, collection)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = store.ErrCollectionSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	return nil
+}
+
+const collectionUpdate = `
+UPDATE "collections" AS c SET
+title = $2,
+description = $3,
+visibility = $4,
+updated_at = NOW()
+WHERE c.id = $1
+RETURNING ` + collectionColumns
+
+func (cs *CollectionStore) Update(ctx context.Context, collection *store.Collection) error {
+	row := stmt(ctx, cs.ps.Update).QueryRowContext(ctx,
+		collection.ID, collection.Title, collection.Description, collection.Visibility,
+	)
+	if err := cs.scanInto(row, collection); err != nil {
+		return fmt.Errorf("failed to Update: %w", err)
+	}
+	return nil
+}
+
+const collectionDeleteById = `
+DELETE FROM "collections"
+WHERE id = $1
+`
+
+func (cs *CollectionStore) DeleteById(ctx context.Context, id int) error {
+	_, err := stmt(ctx, cs.ps.DeleteById).ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to DeleteById: %w", err)
+	}
+	return nil
+}
+
+const collectionFindBase = `SELECT ` + collectionColumns + `FROM "collections" c
+`
+
+const collectionFindOneById = collectionFindBase + "WHERE c.id = $1"
+
+func (cs *CollectionStore) FindOneById(ctx context.Context, id int) (*store.Collection, error) {
+	row := stmt(ctx, cs.ps.FindOneById).QueryRowContext(ctx, id)
+	return cs.scanRow(row)
+}
+
+const collectionFindOneBySlug = collectionFindBase + "WHERE c.slug = $1"
+
+func (cs *CollectionStore) FindOneBySlug(ctx context.Context, slug string) (*store.Collection, error) {
+	row := stmt(ctx, cs.ps.FindOneBySlug).QueryRowContext(ctx, slug)
+	return cs.scanRow(row)
+}
+
+const collectionFindByMember = collectionFindBase + `
+WHERE c.user_id = $1
+OR EXISTS (
+  SELECT 1 FROM "collection_collaborators" cc
+  WHERE cc.collection_id = c.id AND cc.user_id = $1
+)
+ORDER BY c.updated_at DESC, c.id DESC
+`
+
+// FindByMember lists the collections a user owns or collaborates on.
+func (cs *CollectionStore) FindByMember(ctx context.Context, userId int) ([]*store.Collection, error) {
+	rows, err := stmt(ctx, cs.ps.FindByMember).QueryContext(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByMember: %w", err)
+	}
+	return cs.scanRows(rows)
+}
+
+const collectionSearchPublic = collectionFindBase + `
+WHERE c.visibility = 'public'
+AND ($1 = '' OR c.title ILIKE '%' || $1 || '%' OR c.description ILIKE '%' || $1 || '%')
+ORDER BY c.updated_at DESC, c.id DESC
+LIMIT $2 OFFSET $3
+`
+
+// SearchPublic browses public collections, most recently changed first,
+// matching query against the title and description when it is not empty.
+func (cs *CollectionStore) SearchPublic(ctx context.Context, query string, limit, offset int) ([]*store.Collection, error) {
+	rows, err := stmt(ctx, cs.ps.SearchPublic).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to SearchPublic: %w", err)
+	}
+	return cs.scanRows(rows)
+}
+
+const collectionUpsertItem = `
+WITH touched AS (
+  UPDATE "collections" SET updated_at = NOW() WHERE id = $1
+)
+INSERT INTO "collection_items" AS ci (collection_id, book_id, position, note, added_by)
+VALUES ($1, $2, COALESCE(
+  (SELECT MAX(position) + 1 FROM "collection_items" WHERE collection_id = $1), 1
+), $3, $4)
+ON CONFLICT (collection_id, book_id) DO UPDATE SET
+note = EXCLUDED.note
+RETURNING ci.position, COALESCE(ci.added_by, 0), ci.added_at,
+(SELECT b.title FROM "books" b WHERE b.id = ci.book_id)
+`
+
+// UpsertItem appends a book to the collection, or changes its note when the
+// book is already in it.
+func (cs *CollectionStore) UpsertItem(ctx context.Context, item *store.CollectionItem) error {
+	err := stmt(ctx, cs.ps.UpsertItem).QueryRowContext(ctx, item.CollectionID, item.BookID, item.Note, item.AddedBy).
+		Scan(&item.Position, &item.AddedBy, &item.AddedAt, &item.Title)
+	if err != nil {
+		return fmt.Errorf("failed to UpsertItem: %w", err)
+	}
+	return nil
+}
+
+const collectionRemoveItem = `
+WITH touched AS (
+  UPDATE "collections" SET updated_at = NOW() WHERE id = $1
+)
+DELETE FROM "collection_items"
+WHERE collection_id = $1 AND book_id = $2
+`
+
+func (cs *CollectionStore) RemoveItem(ctx context.Context, id, bookId int) error {
+	_, err := stmt(ctx, cs.ps.RemoveItem).ExecContext(ctx, id, bookId)
+	if err != nil {
+		return fmt.Errorf("failed to RemoveItem: %w", err)
+	}
+	return nil
+}
+
+const collectionFindItems = `
+SELECT ci.collection_id, ci.book_id, b.title, ci.position, ci.note,
+COALESCE(ci.added_by, 0), ci.added_at
+FROM "collection_items" ci
+JOIN "books" b ON b.id = ci.book_id
+WHERE ci.collection_id = $1
+ORDER BY ci.position, ci.added_at
+LIMIT $2 OFFSET $3
+`
+
+func (cs *CollectionStore) FindItems(ctx context.Context, id, limit, offset int) ([]*store.CollectionItem, error) {
+	rows, err := stmt(ctx, cs.ps.FindItems).QueryContext(ctx, id, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindItems: %w", err)
+	}
+	defer rows.Close()
+	items := []*store.CollectionItem{}
+	for rows.Next() {
+		item := &store.CollectionItem{}
+		err = rows.Scan(
+			&item.CollectionID, &item.BookID, &item.Title, &item.Position,
+			&item.Note, &item.AddedBy, &item.AddedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+const collectionLockItems = `
+SELECT book_id
+FROM "collection_items"
+WHERE collection_id = $1
+FOR UPDATE
+`
+
+const collectionSetPosition = `
+UPDATE "collection_items" ci SET
+position = o.position
+FROM unnest($2::INT[]) WITH ORDINALITY AS o(book_id, position)
+WHERE ci.collection_id = $1 AND ci.book_id = o.book_id
+`
+
+const collectionTouch = `
+UPDATE "collections" SET
+updated_at = NOW()
+WHERE id = $1
+`
+
+// Reorder numbers the items in the order of bookIds, which must list every
+// item of the collection exactly once.
+func (cs *CollectionStore) Reorder(ctx context.Context, id int, bookIds []int) error {
+	err := withTx(ctx, cs.db, func(tx *sql.Tx) error {
+		rows, err := tx.StmtContext(ctx, cs.ps.LockItems).QueryContext(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to lock items: %w", err)
+		}
+		defer rows.Close()
+		current := map[int]bool{}
+		for rows.Next() {
+			var bookId int
+			if err = rows.Scan(&bookId); err != nil {
+				return fmt.Errorf("failed to scanRow: %w", err)
+			}
+			current[bookId] = true
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate rows: %w", err)
+		}
+		if len(current) != len(bookIds) {
+			return store.ErrCollectionOrderMismatch
+		}
+		for _, bookId := range bookIds {
+			if !current[bookId] {
+				return store.ErrCollectionOrderMismatch
+			}
+			delete(current, bookId)
+		}
+		if _, err = tx.StmtContext(ctx, cs.ps.SetPosition).ExecContext(ctx, id, bookIds); err != nil {
+			return fmt.Errorf("failed to set position: %w", err)
+		}
+		if _, err = tx.StmtContext(ctx, cs.ps.Touch).ExecContext(ctx, id); err != nil {
+			return fmt.Errorf("failed to touch collection: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to Reorder: %w", err)
+	}
+	return nil
+}
+
+const collectionIsCollaborator = `
+SELECT EXISTS (
+  SELECT 1 FROM "collection_collaborators"
+  WHERE collection_id = $1 AND user_id = $2
+)
+`
+
+func (cs *CollectionStore) IsCollaborator(ctx context.Context, id, userId int) (bool, error) {
+	var ok bool
+	if err := stmt(ctx, cs.ps.IsCollaborator).QueryRowContext(ctx, id, userId).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to IsCollaborator: %w", err)
+	}
+	return ok, nil
+}
+
+const collectionFindCollaborators = `
+SELECT cc.collection_id, cc.user_id, u.fullname, u.email, cc.invited_by, cc.created_at
+FROM "collection_collaborators" cc
+JOIN "users" u ON u.id = cc.user_id
+WHERE cc.collection_id = $1
+ORDER BY cc.created_at
+`
+
+func (cs *CollectionStore) FindCollaborators(ctx context.Context, id int) ([]*store.CollectionCollaborator, error) {
+	rows, err := stmt(ctx, cs.ps.FindCollaborators).QueryContext(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindCollaborators: %w", err)
+	}
+	defer rows.Close()
+	collaborators := []*store.CollectionCollaborator{}
+	for rows.Next() {
+		collaborator := &store.CollectionCollaborator{}
+		err = rows.Scan(
+			&collaborator.CollectionID, &collaborator.UserID, &collaborator.Fullname,
+			&collaborator.Email, &collaborator.InvitedBy, &collaborator.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		collaborators = append(collaborators, collaborator)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return collaborators, nil
+}
+
+const collectionAddCollaborator = `
+INSERT INTO "collection_collaborators" (collection_id, user_id, invited_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (collection_id, user_id) DO UPDATE SET
+invited_by = "collection_collaborators".invited_by
+RETURNING created_at
+`
+
+func (cs *CollectionStore) AddCollaborator(ctx context.Context, collaborator *store.CollectionCollaborator) error {
+	err := stmt(ctx, cs.ps.AddCollaborator).QueryRowContext(ctx,
+		collaborator.CollectionID, collaborator.UserID, collaborator.InvitedBy,
+	).Scan(&collaborator.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to AddCollaborator: %w", err)
+	}
+	return nil
+}
+
+const collectionRemoveCollaborator = `
+DELETE FROM "collection_collaborators"
+WHERE collection_id = $1 AND user_id = $2
+`
+
+func (cs *CollectionStore) RemoveCollaborator(ctx context.Context, id, userId int) error {
+	_, err := stmt(ctx, cs.ps.RemoveCollaborator).ExecContext(ctx, id, userId)
+	if err != nil {
+		return fmt.Errorf("failed to RemoveCollaborator: %w", err)
+	}
+	return nil
+}
+
+func (cs *CollectionStore) scanRows(rows *sql.Rows) ([]*store.Collection, error) {
+	defer rows.Close()
+	collections := []*store.Collection{}
+	for rows.Next() {
+		collection, err := cs.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return collections, nil
+}
+
+func (cs *CollectionStore) scanRow(row scanner) (*store.Collection, error) {
+	collection := &store.Collection{}
+	if err := cs.scanInto(row, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func (cs *CollectionStore) scanInto(row scanner, collection *store.Collection) error {
+	err := row.Scan(
+		&collection.ID, &collection.UserID, &collection.Owner, &collection.Slug,
+		&collection.Title, &collection.Description, &collection.Visibility,
+		&collection.ItemCount, &collection.CreatedAt, &collection.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return nil
+}
@@ -1,8 +1,13 @@
 package postgresql
 
 import (
+	"awesome-api/store"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/jackc/pgconn"
 )
 
 func prepareStatement(db *sql.DB, storeName, queryName, sql string) (*sql.Stmt, error) {
@@ -12,3 +17,104 @@ func prepareStatement(db *sql.DB, storeName, queryName, sql string) (*sql.Stmt,
 	}
 	return stmt, nil
 }
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// stmt joins the prepared statement to the transaction of ctx, started by
+// TxManager.WithTx, and returns it as it is outside of one. Its queries
+// report unique violations as store.ErrAlreadyExists.
+func stmt(ctx context.Context, s *sql.Stmt) *statement {
+	if tx, ok := txFromContext(ctx); ok {
+		return &statement{tx.StmtContext(ctx, s)}
+	}
+	return &statement{s}
+}
+
+type statement struct {
+	*sql.Stmt
+}
+
+func (s *statement) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	res, err := s.Stmt.ExecContext(ctx, args...)
+	return res, mapError(err)
+}
+
+func (s *statement) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	rows, err := s.Stmt.QueryContext(ctx, args...)
+	return rows, mapError(err)
+}
+
+func (s *statement) QueryRowContext(ctx context.Context, args ...interface{}) *row {
+	return &row{s.Stmt.QueryRowContext(ctx, args...)}
+}
+
+type row struct {
+	*sql.Row
+}
+
+func (r *row) Scan(dest ...interface{}) error {
+	return mapError(r.Row.Scan(dest...))
+}
+
+// uniqueViolation is store.ErrAlreadyExists, it unwraps to the
+// *pgconn.PgError naming the violated constraint.
+type uniqueViolation struct {
+	err error
+}
+
+func (e *uniqueViolation) Error() string {
+	return e.err.Error()
+}
+
+func (e *uniqueViolation) Unwrap() error {
+	return e.err
+}
+
+func (e *uniqueViolation) Is(target error) bool {
+	return target == store.ErrAlreadyExists
+}
+
+// mapError reports a unique violation as store.ErrAlreadyExists and
+// returns any other error as it is.
+func mapError(err error) error {
+	var pgErr *pgconn.PgError
+	if err == nil || errors.Is(err, store.ErrAlreadyExists) ||
+		!errors.As(err, &pgErr) || pgErr.Code != pgUniqueViolation {
+		return err
+	}
+	return &uniqueViolation{err: err}
+}
+
+// violatedConstraint returns the unique constraint err broke, if any.
+func violatedConstraint(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.Is(err, store.ErrAlreadyExists) && errors.As(err, &pgErr) {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// withTx runs fn inside a transaction, committing when fn succeeds and
+// rolling back otherwise. Prepared statements can join the transaction
+// through tx.StmtContext, unique violations come out as
+// store.ErrAlreadyExists. Within TxManager.WithTx fn runs in its
+// transaction, which commits or rolls back as a whole.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return mapError(fn(tx))
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return mapError(err)
+	}
+	if err = tx.Commit(); err != nil {
+		return mapError(fmt.Errorf("failed to commit tx: %w", err))
+	}
+	return nil
+}
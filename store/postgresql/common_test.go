@@ -0,0 +1,60 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgconn"
+)
+
+func TestMapError(t *testing.T) {
+	violation := &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "books__isbn__key"}
+	tests := []struct {
+		name       string
+		err        error
+		exists     bool
+		constraint string
+	}{
+		{"nil", nil, false, ""},
+		{"no rows", sql.ErrNoRows, false, ""},
+		{"serialization failure", &pgconn.PgError{Code: pgSerializationFailure}, false, ""},
+		{"unique violation", violation, true, "books__isbn__key"},
+		{"wrapped unique violation", fmt.Errorf("failed to Insert: %w", violation), true, "books__isbn__key"},
+		{"already mapped", mapError(fmt.Errorf("failed to Insert: %w", violation)), true, "books__isbn__key"},
+		{"already exists", store.ErrAlreadyExists, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mapError(tt.err)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("mapError(%v) = %v, lost the original error", tt.err, err)
+			}
+			if errors.Is(err, store.ErrAlreadyExists) != tt.exists {
+				t.Errorf("errors.Is(%v, store.ErrAlreadyExists) = %t, want %t", err, !tt.exists, tt.exists)
+			}
+			if got := violatedConstraint(fmt.Errorf("failed to Update: %w", err)); got != tt.constraint {
+				t.Errorf("violatedConstraint = %q, want %q", got, tt.constraint)
+			}
+		})
+	}
+}
+
+func TestISBNTaken(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       error
+	}{
+		{"books__isbn13__key", store.ErrBookISBNAlreadyExists},
+		{"books__isbn10__key", store.ErrBookISBNAlreadyExists},
+		{"collection_books_pkey", store.ErrAlreadyExists},
+	}
+	for _, tt := range tests {
+		err := mapError(&pgconn.PgError{Code: pgUniqueViolation, ConstraintName: tt.constraint})
+		if got := isbnTaken(fmt.Errorf("failed to insert book: %w", err)); !errors.Is(got, tt.want) {
+			t.Errorf("isbnTaken on %s = %v, want %v", tt.constraint, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package postgresql
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/jackc/pgx/v4/stdlib"
+)
+
+// testDB opens and migrates the database of TEST_DATABASE_URL, skipping
+// the test when it is unset. Tests leave their rows behind, so each one
+// creates the users and books it works on.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://../schema", "postgres", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Up(); err != nil && err != migrate.ErrNoChange {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func testUser(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`
+INSERT INTO "users" (email, fullname, is_verified)
+VALUES ($1, 'Test Reader', TRUE)
+RETURNING id`, fmt.Sprintf("reader-%d@example.org", time.Now().UnixNano())).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func testBook(t *testing.T, db *sql.DB, copies int) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`
+WITH c AS (INSERT INTO "category" (name) VALUES ('Test') RETURNING id)
+INSERT INTO "books" (title, author, synopsis, cover, category_id, copies)
+SELECT 'Test Book', 'Test Author', '', '', c.id, $1 FROM c
+RETURNING id`, copies).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
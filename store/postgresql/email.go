@@ -0,0 +1,257 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type EmailOutboxStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *emailOutboxPrepareStatement
+}
+
+type emailOutboxPrepareStatement struct {
+	Enqueue           *sql.Stmt
+	Claim             *sql.Stmt
+	MarkSent          *sql.Stmt
+	MarkFailed        *sql.Stmt
+	MarkUndeliverable *sql.Stmt
+	FindOneById       *sql.Stmt
+	FindByStatus      *sql.Stmt
+	Requeue           *sql.Stmt
+	Stats             *sql.Stmt
+}
+
+func (es *EmailOutboxStore) prepareStatement() error {
+	storeName := "EmailOutboxStore"
+	var err error
+	if es.ps.Enqueue, err = prepareStatement(es.db, storeName, "Enqueue", emailOutboxEnqueue); err != nil {
+		return err
+	}
+	if es.ps.Claim, err = prepareStatement(es.db, storeName, "Claim", emailOutboxClaim); err != nil {
+		return err
+	}
+	if es.ps.MarkSent, err = prepareStatement(es.db, storeName, "MarkSent", emailOutboxMarkSent); err != nil {
+		return err
+	}
+	if es.ps.MarkFailed, err = prepareStatement(es.db, storeName, "MarkFailed", emailOutboxMarkFailed); err != nil {
+		return err
+	}
+	if es.ps.MarkUndeliverable, err = prepareStatement(es.db, storeName, "MarkUndeliverable", emailOutboxMarkUndeliverable); err != nil {
+		return err
+	}
+	if es.ps.FindOneById, err = prepareStatement(es.db, storeName, "FindOneById", emailOutboxFindOneById); err != nil {
+		return err
+	}
+	if es.ps.FindByStatus, err = prepareStatement(es.db, storeName, "FindByStatus", emailOutboxFindByStatus); err != nil {
+		return err
+	}
+	if es.ps.Requeue, err = prepareStatement(es.db, storeName, "Requeue", emailOutboxRequeue); err != nil {
+		return err
+	}
+	if es.ps.Stats, err = prepareStatement(es.db, storeName, "Stats", emailOutboxStats); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewEmailOutboxStore(log zerolog.Logger, db *sql.DB) (*EmailOutboxStore, error) {
+	es := &EmailOutboxStore{
+		db:  db,
+		log: log,
+		ps:  &emailOutboxPrepareStatement{},
+	}
+	err := es.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+const emailOutboxColumns = `id, kind, recipient, subject, body, html_body, unsubscribe_url,
+status, attempts, next_attempt_at, last_error, created_at, sent_at`
+
+const emailOutboxEnqueue = `
+INSERT INTO "email_outbox" (kind, recipient, subject, body, html_body, unsubscribe_url)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, status, next_attempt_at, created_at
+`
+
+func (es *EmailOutboxStore) Enqueue(ctx context.Context, email *store.Email) error {
+	err := stmt(ctx, es.ps.Enqueue).QueryRowContext(ctx,
+		email.Kind, email.Recipient, email.Subject, email.Body, email.HTMLBody, email.UnsubscribeURL,
+	).Scan(&email.ID, &email.Status, &email.NextAttemptAt, &email.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to Enqueue: %w", err)
+	}
+	return nil
+}
+
+const emailOutboxClaim = `
+UPDATE "email_outbox" SET
+status = 'sending', locked_at = NOW(), attempts = attempts + 1
+WHERE id IN (
+	SELECT id FROM "email_outbox"
+	WHERE (status = 'pending' AND next_attempt_at <= NOW())
+	OR (status = 'sending' AND locked_at < NOW() - make_interval(secs => $2))
+	ORDER BY next_attempt_at, id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + emailOutboxColumns
+
+func (es *EmailOutboxStore) Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]*store.Email, error) {
+	rows, err := stmt(ctx, es.ps.Claim).QueryContext(ctx, limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to Claim: %w", err)
+	}
+	return es.scanRows(rows)
+}
+
+const emailOutboxMarkSent = `
+UPDATE "email_outbox" SET
+status = 'sent', sent_at = NOW(), locked_at = NULL, last_error = ''
+WHERE id = $1
+`
+
+func (es *EmailOutboxStore) MarkSent(ctx context.Context, id int) error {
+	if _, err := stmt(ctx, es.ps.MarkSent).ExecContext(ctx, id); err != nil {
+		return fmt.Errorf("failed to MarkSent: %w", err)
+	}
+	return nil
+}
+
+const emailOutboxMarkFailed = `
+UPDATE "email_outbox" SET
+status = CASE WHEN $4 THEN 'dead' ELSE 'pending' END,
+last_error = $2, next_attempt_at = $3, locked_at = NULL
+WHERE id = $1
+`
+
+func (es *EmailOutboxStore) MarkFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	if _, err := stmt(ctx, es.ps.MarkFailed).ExecContext(ctx, id, lastError, nextAttemptAt, dead); err != nil {
+		return fmt.Errorf("failed to MarkFailed: %w", err)
+	}
+	return nil
+}
+
+const emailOutboxMarkUndeliverable = `
+UPDATE "email_outbox" SET
+status = 'undeliverable', last_error = $2, locked_at = NULL
+WHERE id = $1
+`
+
+func (es *EmailOutboxStore) MarkUndeliverable(ctx context.Context, id int, reason string) error {
+	if _, err := stmt(ctx, es.ps.MarkUndeliverable).ExecContext(ctx, id, reason); err != nil {
+		return fmt.Errorf("failed to MarkUndeliverable: %w", err)
+	}
+	return nil
+}
+
+const emailOutboxFindOneById = `SELECT ` + emailOutboxColumns + ` FROM "email_outbox" WHERE id = $1`
+
+func (es *EmailOutboxStore) FindOneById(ctx context.Context, id int) (*store.Email, error) {
+	return es.scanRow(stmt(ctx, es.ps.FindOneById).QueryRowContext(ctx, id))
+}
+
+const emailOutboxFindByStatus = `
+SELECT ` + emailOutboxColumns + `
+FROM "email_outbox"
+WHERE status = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3
+`
+
+func (es *EmailOutboxStore) FindByStatus(ctx context.Context, status string, limit, offset int) ([]*store.Email, error) {
+	rows, err := stmt(ctx, es.ps.FindByStatus).QueryContext(ctx, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByStatus: %w", err)
+	}
+	return es.scanRows(rows)
+}
+
+const emailOutboxRequeue = `
+UPDATE "email_outbox" SET
+status = 'pending', attempts = 0, next_attempt_at = NOW()
+WHERE id = $1 AND status = 'dead'
+`
+
+func (es *EmailOutboxStore) Requeue(ctx context.Context, id int) error {
+	res, err := stmt(ctx, es.ps.Requeue).ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to Requeue: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to Requeue: %w", err)
+	}
+	if affected == 0 {
+		if _, err := es.FindOneById(ctx, id); err != nil {
+			return fmt.Errorf("failed to Requeue: %w", err)
+		}
+		return fmt.Errorf("failed to Requeue: %w", store.ErrEmailNotDead)
+	}
+	return nil
+}
+
+const emailOutboxStats = `
+SELECT status, COUNT(*), MIN(created_at)
+FROM "email_outbox"
+GROUP BY status
+ORDER BY status
+`
+
+func (es *EmailOutboxStore) Stats(ctx context.Context) ([]*store.EmailStats, error) {
+	rows, err := stmt(ctx, es.ps.Stats).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Stats: %w", err)
+	}
+	defer rows.Close()
+	stats := []*store.EmailStats{}
+	for rows.Next() {
+		stat := &store.EmailStats{}
+		if err := rows.Scan(&stat.Status, &stat.Count, &stat.Oldest); err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return stats, nil
+}
+
+func (es *EmailOutboxStore) scanRows(rows *sql.Rows) ([]*store.Email, error) {
+	defer rows.Close()
+	emails := []*store.Email{}
+	for rows.Next() {
+		email, err := es.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return emails, nil
+}
+
+func (es *EmailOutboxStore) scanRow(row scanner) (*store.Email, error) {
+	email := &store.Email{}
+	err := row.Scan(
+		&email.ID, &email.Kind, &email.Recipient, &email.Subject, &email.Body,
+		&email.HTMLBody, &email.UnsubscribeURL, &email.Status, &email.Attempts, &email.NextAttemptAt,
+		&email.LastError, &email.CreatedAt, &email.SentAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return email, nil
+}
@@ -0,0 +1,151 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type DomainEventStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *domainEventPrepareStatement
+}
+
+type domainEventPrepareStatement struct {
+	Claim          *sql.Stmt
+	MarkDispatched *sql.Stmt
+	MarkFailed     *sql.Stmt
+}
+
+func (es *DomainEventStore) prepareStatement() error {
+	storeName := "DomainEventStore"
+	var err error
+	if es.ps.Claim, err = prepareStatement(es.db, storeName, "Claim", domainEventClaim); err != nil {
+		return err
+	}
+	if es.ps.MarkDispatched, err = prepareStatement(es.db, storeName, "MarkDispatched", domainEventMarkDispatched); err != nil {
+		return err
+	}
+	if es.ps.MarkFailed, err = prepareStatement(es.db, storeName, "MarkFailed", domainEventMarkFailed); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewDomainEventStore(log zerolog.Logger, db *sql.DB) (*DomainEventStore, error) {
+	es := &DomainEventStore{
+		db:  db,
+		log: log,
+		ps:  &domainEventPrepareStatement{},
+	}
+	err := es.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// domainEventInsert is prepared by every store recording events, to run in
+// the transaction of the change through recordEvent.
+const domainEventInsert = `
+INSERT INTO "domain_events" (event_type, aggregate_id, actor_id, payload)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at
+`
+
+func recordEvent(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, event *store.DomainEvent) error {
+	err := tx.StmtContext(ctx, stmt).QueryRowContext(ctx,
+		event.Type, event.AggregateID, event.ActorID, string(event.Payload),
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record %s: %w", event.Type, err)
+	}
+	return nil
+}
+
+// domainEventClaim reads handled joined by commas, subscriber names have
+// none.
+const domainEventClaim = `
+WITH claimed AS (
+	UPDATE "domain_events" SET
+	status = 'dispatching', locked_at = NOW(), attempts = attempts + 1
+	WHERE id IN (
+		SELECT id FROM "domain_events"
+		WHERE (status = 'pending' AND next_attempt_at <= NOW())
+		OR (status = 'dispatching' AND locked_at < NOW() - make_interval(secs => $2))
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, event_type, aggregate_id, actor_id, payload,
+	array_to_string(handled, ',') AS handled, attempts, created_at
+)
+SELECT * FROM claimed ORDER BY id
+`
+
+func (es *DomainEventStore) Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]*store.DomainEvent, error) {
+	rows, err := stmt(ctx, es.ps.Claim).QueryContext(ctx, limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to Claim: %w", err)
+	}
+	defer rows.Close()
+	events := []*store.DomainEvent{}
+	for rows.Next() {
+		event := &store.DomainEvent{}
+		var payload []byte
+		var handled string
+		err := rows.Scan(
+			&event.ID, &event.Type, &event.AggregateID, &event.ActorID, &payload,
+			&handled, &event.Attempts, &event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		event.Payload = payload
+		if handled != "" {
+			event.Handled = strings.Split(handled, ",")
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return events, nil
+}
+
+const domainEventMarkDispatched = `
+UPDATE "domain_events" SET
+status = 'dispatched', dispatched_at = NOW(), locked_at = NULL, last_error = ''
+WHERE id = $1
+`
+
+func (es *DomainEventStore) MarkDispatched(ctx context.Context, id int64) error {
+	if _, err := stmt(ctx, es.ps.MarkDispatched).ExecContext(ctx, id); err != nil {
+		return fmt.Errorf("failed to MarkDispatched: %w", err)
+	}
+	return nil
+}
+
+const domainEventMarkFailed = `
+UPDATE "domain_events" SET
+status = CASE WHEN $5 THEN 'failed' ELSE 'pending' END,
+handled = $2::VARCHAR[], last_error = $3, next_attempt_at = $4, locked_at = NULL
+WHERE id = $1
+`
+
+func (es *DomainEventStore) MarkFailed(ctx context.Context, id int64, handled []string, lastError string, nextAttemptAt time.Time, dead bool) error {
+	if handled == nil {
+		handled = []string{}
+	}
+	_, err := stmt(ctx, es.ps.MarkFailed).ExecContext(ctx, id, handled, lastError, nextAttemptAt, dead)
+	if err != nil {
+		return fmt.Errorf("failed to MarkFailed: %w", err)
+	}
+	return nil
+}
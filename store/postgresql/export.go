@@ -0,0 +1,190 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type ExportStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *exportPrepareStatement
+}
+
+type exportPrepareStatement struct {
+	Insert         *sql.Stmt
+	FindOneById    *sql.Stmt
+	FindAll        *sql.Stmt
+	ClaimNext      *sql.Stmt
+	UpdateProgress *sql.Stmt
+	Finish         *sql.Stmt
+}
+
+func (es *ExportStore) prepareStatement() error {
+	storeName := "ExportStore"
+	var err error
+	if es.ps.Insert, err = prepareStatement(es.db, storeName, "Insert", exportInsert); err != nil {
+		return err
+	}
+	if es.ps.FindOneById, err = prepareStatement(es.db, storeName, "FindOneById", exportFindOneById); err != nil {
+		return err
+	}
+	if es.ps.FindAll, err = prepareStatement(es.db, storeName, "FindAll", exportFindAll); err != nil {
+		return err
+	}
+	if es.ps.ClaimNext, err = prepareStatement(es.db, storeName, "ClaimNext", exportClaimNext); err != nil {
+		return err
+	}
+	if es.ps.UpdateProgress, err = prepareStatement(es.db, storeName, "UpdateProgress", exportUpdateProgress); err != nil {
+		return err
+	}
+	if es.ps.Finish, err = prepareStatement(es.db, storeName, "Finish", exportFinish); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewExportStore(log zerolog.Logger, db *sql.DB) (*ExportStore, error) {
+	es := &ExportStore{
+		db:  db,
+		log: log,
+		ps:  &exportPrepareStatement{},
+	}
+	err := es.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+const exportColumns = `
+id, user_id, format, filter, status, blob_key, total, size,
+error, created_at, started_at, finished_at, heartbeat_at
+`
+
+const exportInsert = `
+INSERT INTO "export_jobs" (user_id, format, filter)
+VALUES ($1, $2, $3)
+RETURNING ` + exportColumns
+
+func (es *ExportStore) Insert(ctx context.Context, job *store.ExportJob) error {
+	filter, err := json.Marshal(job.Filter)
+	if err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	row := stmt(ctx, es.ps.Insert).QueryRowContext(ctx, job.UserID, job.Format, filter)
+	if err = es.scanInto(row, job); err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	return nil
+}
+
+const exportFindOneById = `SELECT ` + exportColumns + ` FROM "export_jobs" WHERE id = $1`
+
+func (es *ExportStore) FindOneById(ctx context.Context, id int) (*store.ExportJob, error) {
+	job := &store.ExportJob{}
+	if err := es.scanInto(stmt(ctx, es.ps.FindOneById).QueryRowContext(ctx, id), job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+const exportFindAll = `
+SELECT ` + exportColumns + `
+FROM "export_jobs"
+ORDER BY id DESC
+LIMIT $1 OFFSET $2
+`
+
+func (es *ExportStore) FindAll(ctx context.Context, limit, offset int) ([]*store.ExportJob, error) {
+	rows, err := stmt(ctx, es.ps.FindAll).QueryContext(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAll: %w", err)
+	}
+	defer rows.Close()
+	jobs := []*store.ExportJob{}
+	for rows.Next() {
+		job := &store.ExportJob{}
+		if err = es.scanInto(rows, job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return jobs, nil
+}
+
+const exportClaimNext = `
+UPDATE "export_jobs" SET
+status = 'running', started_at = NOW(), heartbeat_at = NOW(), total = 0
+WHERE id = (
+	SELECT id FROM "export_jobs"
+	WHERE status = 'queued'
+	OR (status = 'running' AND heartbeat_at < NOW() - make_interval(secs => $1))
+	ORDER BY id
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + exportColumns
+
+func (es *ExportStore) ClaimNext(ctx context.Context, staleAfter time.Duration) (*store.ExportJob, error) {
+	job := &store.ExportJob{}
+	if err := es.scanInto(stmt(ctx, es.ps.ClaimNext).QueryRowContext(ctx, staleAfter.Seconds()), job); err != nil {
+		return nil, fmt.Errorf("failed to ClaimNext: %w", err)
+	}
+	return job, nil
+}
+
+const exportUpdateProgress = `
+UPDATE "export_jobs" SET total = $2, heartbeat_at = NOW()
+WHERE id = $1
+`
+
+func (es *ExportStore) UpdateProgress(ctx context.Context, job *store.ExportJob) error {
+	if _, err := stmt(ctx, es.ps.UpdateProgress).ExecContext(ctx, job.ID, job.Total); err != nil {
+		return fmt.Errorf("failed to UpdateProgress: %w", err)
+	}
+	return nil
+}
+
+const exportFinish = `
+UPDATE "export_jobs" SET
+status = $2, error = $3, blob_key = $4, total = $5, size = $6,
+finished_at = NOW()
+WHERE id = $1
+RETURNING finished_at
+`
+
+func (es *ExportStore) Finish(ctx context.Context, job *store.ExportJob) error {
+	err := stmt(ctx, es.ps.Finish).QueryRowContext(ctx,
+		job.ID, job.Status, job.Error, job.BlobKey, job.Total, job.Size,
+	).Scan(&job.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to Finish: %w", err)
+	}
+	return nil
+}
+
+func (es *ExportStore) scanInto(row scanner, job *store.ExportJob) error {
+	var filter []byte
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.Format, &filter, &job.Status,
+		&job.BlobKey, &job.Total, &job.Size, &job.Error, &job.CreatedAt,
+		&job.StartedAt, &job.FinishedAt, &job.HeartbeatAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to scanRow: %w", err)
+	}
+	if err = json.Unmarshal(filter, &job.Filter); err != nil {
+		return fmt.Errorf("failed to decode filter: %w", err)
+	}
+	return nil
+}
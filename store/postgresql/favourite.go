@@ -0,0 +1,193 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type FavouriteStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *favouritePrepareStatement
+}
+
+type favouritePrepareStatement struct {
+	AddBook      *sql.Stmt
+	RemoveBook   *sql.Stmt
+	FindBooks    *sql.Stmt
+	AddAuthor    *sql.Stmt
+	RemoveAuthor *sql.Stmt
+	FindAuthors  *sql.Stmt
+}
+
+func (fs *FavouriteStore) prepareStatement() error {
+	storeName := "FavouriteStore"
+	var err error
+	if fs.ps.AddBook, err = prepareStatement(fs.db, storeName, "AddBook", favouriteAddBook); err != nil {
+		return err
+	}
+	if fs.ps.RemoveBook, err = prepareStatement(fs.db, storeName, "RemoveBook", favouriteRemoveBook); err != nil {
+		return err
+	}
+	if fs.ps.FindBooks, err = prepareStatement(fs.db, storeName, "FindBooks", favouriteFindBooks); err != nil {
+		return err
+	}
+	if fs.ps.AddAuthor, err = prepareStatement(fs.db, storeName, "AddAuthor", favouriteAddAuthor); err != nil {
+		return err
+	}
+	if fs.ps.RemoveAuthor, err = prepareStatement(fs.db, storeName, "RemoveAuthor", favouriteRemoveAuthor); err != nil {
+		return err
+	}
+	if fs.ps.FindAuthors, err = prepareStatement(fs.db, storeName, "FindAuthors", favouriteFindAuthors); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewFavouriteStore(log zerolog.Logger, db *sql.DB) (*FavouriteStore, error) {
+	fs := &FavouriteStore{
+		db:  db,
+		log: log,
+		ps:  &favouritePrepareStatement{},
+	}
+	err := fs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// bookAvailable tells whether a copy of the book b can be borrowed now,
+// counting copies the same way as the availability of a book does.
+const bookAvailable = `(b.copies > (
+  SELECT COUNT(*) FROM "loans" l
+  WHERE l.book_id = b.id AND l.status = 'active' AND l.due_at > NOW()
+) + (
+  SELECT COUNT(*) FROM "holds" h
+  WHERE h.book_id = b.id
+  AND (h.status = 'waiting' OR (h.status = 'ready' AND h.expires_at > NOW()))
+))`
+
+const favouriteAddBook = `
+INSERT INTO "favourite_books" (user_id, book_id, was_available)
+SELECT $1, b.id, ` + bookAvailable + `
+FROM "books" b
+WHERE b.id = $2
+ON CONFLICT (user_id, book_id) DO NOTHING
+`
+
+func (fs *FavouriteStore) AddBook(ctx context.Context, userId, bookId int) error {
+	_, err := stmt(ctx, fs.ps.AddBook).ExecContext(ctx, userId, bookId)
+	if err != nil {
+		return fmt.Errorf("failed to AddBook: %w", err)
+	}
+	return nil
+}
+
+const favouriteRemoveBook = `DELETE FROM "favourite_books" WHERE user_id = $1 AND book_id = $2`
+
+func (fs *FavouriteStore) RemoveBook(ctx context.Context, userId, bookId int) error {
+	_, err := stmt(ctx, fs.ps.RemoveBook).ExecContext(ctx, userId, bookId)
+	if err != nil {
+		return fmt.Errorf("failed to RemoveBook: %w", err)
+	}
+	return nil
+}
+
+const favouriteFindBooks = `
+SELECT f.user_id, f.book_id, b.title, b.author, ` + bookAvailable + `, f.created_at
+FROM "favourite_books" f
+JOIN "books" b ON b.id = f.book_id
+WHERE f.user_id = $1
+ORDER BY f.created_at DESC, f.book_id
+LIMIT $2 OFFSET $3
+`
+
+func (fs *FavouriteStore) FindBooks(ctx context.Context, userId, limit, offset int) ([]*store.FavouriteBook, error) {
+	rows, err := stmt(ctx, fs.ps.FindBooks).QueryContext(ctx, userId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindBooks: %w", err)
+	}
+	defer rows.Close()
+	books := []*store.FavouriteBook{}
+	for rows.Next() {
+		book := &store.FavouriteBook{}
+		err := rows.Scan(
+			&book.UserID, &book.BookID, &book.Title, &book.Author,
+			&book.Available, &book.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		books = append(books, book)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return books, nil
+}
+
+// The books already in the catalogue are not news to the user, only those
+// added after the author was favourited are alerted about.
+const favouriteAddAuthor = `
+INSERT INTO "favourite_authors" (user_id, author_id, last_book_id)
+VALUES ($1, $2, (SELECT COALESCE(MAX(id), 0) FROM "books"))
+ON CONFLICT (user_id, author_id) DO NOTHING
+`
+
+func (fs *FavouriteStore) AddAuthor(ctx context.Context, userId, authorId int) error {
+	_, err := stmt(ctx, fs.ps.AddAuthor).ExecContext(ctx, userId, authorId)
+	if err != nil {
+		return fmt.Errorf("failed to AddAuthor: %w", err)
+	}
+	return nil
+}
+
+const favouriteRemoveAuthor = `DELETE FROM "favourite_authors" WHERE user_id = $1 AND author_id = $2`
+
+func (fs *FavouriteStore) RemoveAuthor(ctx context.Context, userId, authorId int) error {
+	_, err := stmt(ctx, fs.ps.RemoveAuthor).ExecContext(ctx, userId, authorId)
+	if err != nil {
+		return fmt.Errorf("failed to RemoveAuthor: %w", err)
+	}
+	return nil
+}
+
+const favouriteFindAuthors = `
+SELECT f.user_id, f.author_id, a.name,
+(SELECT COUNT(DISTINCT ba.book_id) FROM "book_authors" ba WHERE ba.author_id = a.id),
+f.created_at
+FROM "favourite_authors" f
+JOIN "authors" a ON a.id = f.author_id
+WHERE f.user_id = $1
+ORDER BY f.created_at DESC, f.author_id
+LIMIT $2 OFFSET $3
+`
+
+func (fs *FavouriteStore) FindAuthors(ctx context.Context, userId, limit, offset int) ([]*store.FavouriteAuthor, error) {
+	rows, err := stmt(ctx, fs.ps.FindAuthors).QueryContext(ctx, userId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAuthors: %w", err)
+	}
+	defer rows.Close()
+	authors := []*store.FavouriteAuthor{}
+	for rows.Next() {
+		author := &store.FavouriteAuthor{}
+		err := rows.Scan(
+			&author.UserID, &author.AuthorID, &author.Name,
+			&author.BookCount, &author.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		authors = append(authors, author)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return authors, nil
+}
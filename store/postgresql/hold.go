@@ -0,0 +1,365 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type HoldStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *holdPrepareStatement
+}
+
+type holdPrepareStatement struct {
+	LockBook           *sql.Stmt
+	ExpireLapsedFor    *sql.Stmt
+	CountAvailability  *sql.Stmt
+	CountOwn           *sql.Stmt
+	Insert             *sql.Stmt
+	Cancel             *sql.Stmt
+	FindOneById        *sql.Stmt
+	FindByUserId       *sql.Stmt
+	FindOpenByUserId   *sql.Stmt
+	PromoteWaiting     *sql.Stmt
+	RecordEvent        *sql.Stmt
+	ExpireLapsed       *sql.Stmt
+	FindBookIdsWaiting *sql.Stmt
+}
+
+func (hs *HoldStore) prepareStatement() error {
+	storeName := "HoldStore"
+	var err error
+	if hs.ps.LockBook, err = prepareStatement(hs.db, storeName, "LockBook", loanLockBook); err != nil {
+		return err
+	}
+	if hs.ps.ExpireLapsedFor, err = prepareStatement(hs.db, storeName, "ExpireLapsedFor", holdExpireLapsedFor); err != nil {
+		return err
+	}
+	if hs.ps.CountAvailability, err = prepareStatement(hs.db, storeName, "CountAvailability", holdCountAvailability); err != nil {
+		return err
+	}
+	if hs.ps.CountOwn, err = prepareStatement(hs.db, storeName, "CountOwn", holdCountOwn); err != nil {
+		return err
+	}
+	if hs.ps.Insert, err = prepareStatement(hs.db, storeName, "Insert", holdInsert); err != nil {
+		return err
+	}
+	if hs.ps.Cancel, err = prepareStatement(hs.db, storeName, "Cancel", holdCancel); err != nil {
+		return err
+	}
+	if hs.ps.FindOneById, err = prepareStatement(hs.db, storeName, "FindOneById", holdFindOneById); err != nil {
+		return err
+	}
+	if hs.ps.FindByUserId, err = prepareStatement(hs.db, storeName, "FindByUserId", holdFindByUserId); err != nil {
+		return err
+	}
+	if hs.ps.FindOpenByUserId, err = prepareStatement(hs.db, storeName, "FindOpenByUserId", holdFindOpenByUserId); err != nil {
+		return err
+	}
+	if hs.ps.PromoteWaiting, err = prepareStatement(hs.db, storeName, "PromoteWaiting", holdPromoteWaiting); err != nil {
+		return err
+	}
+	if hs.ps.RecordEvent, err = prepareStatement(hs.db, storeName, "RecordEvent", domainEventInsert); err != nil {
+		return err
+	}
+	if hs.ps.ExpireLapsed, err = prepareStatement(hs.db, storeName, "ExpireLapsed", holdExpireLapsed); err != nil {
+		return err
+	}
+	if hs.ps.FindBookIdsWaiting, err = prepareStatement(hs.db, storeName, "FindBookIdsWaiting", holdFindBookIdsWaiting); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewHoldStore(log zerolog.Logger, db *sql.DB) (*HoldStore, error) {
+	hs := &HoldStore{
+		db:  db,
+		log: log,
+		ps:  &holdPrepareStatement{},
+	}
+	err := hs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return hs, nil
+}
+
+const holdColumns = `h.id, h.book_id, h.user_id, h.status, h.created_at, h.ready_at, h.expires_at, h.closed_at`
+
+// holdPosition is the 1-based place of a waiting hold in its book queue.
+const holdPosition = `
+CASE WHEN h.status = 'waiting' THEN (
+	SELECT COUNT(*) FROM "holds" w
+	WHERE w.book_id = h.book_id AND w.status = 'waiting'
+	AND (w.created_at, w.id) <= (h.created_at, h.id)
+) ELSE 0 END
+`
+
+const holdCountAvailability = `
+SELECT
+(SELECT COUNT(*) FROM "loans" WHERE book_id = $1 AND status = 'active' AND due_at > NOW()),
+(SELECT COUNT(*) FROM "holds" WHERE book_id = $1 AND status = 'ready'),
+(SELECT COUNT(*) FROM "holds" WHERE book_id = $1 AND status = 'waiting')
+`
+
+const holdExpireLapsedFor = `
+UPDATE "holds" SET
+status = 'expired', closed_at = NOW()
+WHERE book_id = $1 AND status = 'ready' AND expires_at <= NOW()
+`
+
+const holdCountOwn = `
+SELECT
+(SELECT COUNT(*) FROM "loans" WHERE user_id = $1 AND book_id = $2 AND status = 'active' AND due_at > NOW()),
+(SELECT COUNT(*) FROM "holds" WHERE user_id = $1 AND book_id = $2 AND status IN ('waiting', 'ready'))
+`
+
+const holdInsert = `
+INSERT INTO "holds" AS h (book_id, user_id)
+VALUES ($1, $2)
+RETURNING ` + holdColumns + `, ` + holdPosition
+
+type holdAvailability struct {
+	copies, onLoan, ready, waiting int
+}
+
+// lockAvailability locks the book row the same way LoanStore.Borrow does, so
+// borrowing, placing holds and promoting them never interleave for one title.
+func (hs *HoldStore) lockAvailability(ctx context.Context, tx *sql.Tx, bookId int) (*holdAvailability, error) {
+	a := &holdAvailability{}
+	if err := tx.StmtContext(ctx, hs.ps.LockBook).QueryRowContext(ctx, bookId).Scan(&a.copies); err != nil {
+		return nil, fmt.Errorf("failed to lock book: %w", err)
+	}
+	if _, err := tx.StmtContext(ctx, hs.ps.ExpireLapsedFor).ExecContext(ctx, bookId); err != nil {
+		return nil, fmt.Errorf("failed to expire lapsed holds: %w", err)
+	}
+	err := tx.StmtContext(ctx, hs.ps.CountAvailability).QueryRowContext(ctx, bookId).
+		Scan(&a.onLoan, &a.ready, &a.waiting)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count availability: %w", err)
+	}
+	return a, nil
+}
+
+func (hs *HoldStore) Place(ctx context.Context, userId, bookId int) (*store.Hold, error) {
+	var hold *store.Hold
+	err := withTx(ctx, hs.db, func(tx *sql.Tx) error {
+		a, err := hs.lockAvailability(ctx, tx, bookId)
+		if err != nil {
+			return err
+		}
+		var ownLoans, ownHolds int
+		err = tx.StmtContext(ctx, hs.ps.CountOwn).QueryRowContext(ctx, userId, bookId).
+			Scan(&ownLoans, &ownHolds)
+		if err != nil {
+			return fmt.Errorf("failed to count own loans and holds: %w", err)
+		}
+		switch {
+		case ownLoans > 0:
+			return store.ErrLoanAlreadyBorrowed
+		case ownHolds > 0:
+			return store.ErrHoldAlreadyPlaced
+		case a.copies-a.onLoan-a.ready-a.waiting > 0:
+			return store.ErrHoldCopyAvailable
+		}
+		row := tx.StmtContext(ctx, hs.ps.Insert).QueryRowContext(ctx, bookId, userId)
+		hold, err = hs.scanRow(row)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to Place: %w", err)
+	}
+	return hold, nil
+}
+
+const holdCancel = `
+UPDATE "holds" AS h SET
+status = 'cancelled', closed_at = NOW()
+WHERE h.id = $1 AND h.user_id = $2 AND h.status IN ('waiting', 'ready')
+RETURNING ` + holdColumns + `, 0`
+
+func (hs *HoldStore) Cancel(ctx context.Context, id, userId int) (*store.Hold, error) {
+	row := stmt(ctx, hs.ps.Cancel).QueryRowContext(ctx, id, userId)
+	hold, err := hs.scanRow(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err := hs.findOneById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if existing.UserID != userId {
+			return nil, fmt.Errorf("hold %d: %w", id, sql.ErrNoRows)
+		}
+		return nil, store.ErrHoldNotOpen
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to Cancel: %w", err)
+	}
+	return hold, nil
+}
+
+const holdFindOneById = `SELECT ` + holdColumns + `, ` + holdPosition + ` FROM "holds" h WHERE h.id = $1`
+
+func (hs *HoldStore) findOneById(ctx context.Context, id int) (*store.Hold, error) {
+	row := stmt(ctx, hs.ps.FindOneById).QueryRowContext(ctx, id)
+	return hs.scanRow(row)
+}
+
+const holdFindByUserId = `
+SELECT ` + holdColumns + `, ` + holdPosition + `
+FROM "holds" h
+WHERE h.user_id = $1
+ORDER BY h.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+const holdFindOpenByUserId = `
+SELECT ` + holdColumns + `, ` + holdPosition + `
+FROM "holds" h
+WHERE h.user_id = $1 AND h.status IN ('waiting', 'ready')
+ORDER BY h.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+func (hs *HoldStore) FindByUserId(ctx context.Context, userId int, openOnly bool, limit, offset int) ([]*store.Hold, error) {
+	query := hs.ps.FindByUserId
+	if openOnly {
+		query = hs.ps.FindOpenByUserId
+	}
+	rows, err := stmt(ctx, query).QueryContext(ctx, userId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByUserId: %w", err)
+	}
+	return hs.scanRows(rows)
+}
+
+const holdPromoteWaiting = `
+UPDATE "holds" AS h SET
+status = 'ready', ready_at = NOW(),
+expires_at = NOW() + make_interval(secs => $3)
+WHERE h.id IN (
+	SELECT id FROM "holds"
+	WHERE book_id = $1 AND status = 'waiting'
+	ORDER BY created_at, id
+	LIMIT $2
+)
+RETURNING ` + holdColumns + `, 0`
+
+// Promote hands every copy that is neither on loan nor reserved to the next
+// waiting holds in FIFO order and returns the holds that became ready, their
+// owners are notified through the hold.ready events.
+func (hs *HoldStore) Promote(ctx context.Context, bookId int, claimWindow time.Duration) ([]*store.Hold, error) {
+	var holds []*store.Hold
+	err := withTx(ctx, hs.db, func(tx *sql.Tx) error {
+		a, err := hs.lockAvailability(ctx, tx, bookId)
+		if err != nil {
+			return err
+		}
+		free := a.copies - a.onLoan - a.ready
+		if free <= 0 || a.waiting == 0 {
+			return nil
+		}
+		rows, err := tx.StmtContext(ctx, hs.ps.PromoteWaiting).QueryContext(ctx, bookId, free, claimWindow.Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to promote waiting holds: %w", err)
+		}
+		holds, err = hs.scanRows(rows)
+		if err != nil {
+			return err
+		}
+		for _, hold := range holds {
+			event, err := store.NewDomainEvent(ctx, store.EventHoldReady, hold.ID, store.HoldEventData{
+				ID:        hold.ID,
+				BookID:    hold.BookID,
+				UserID:    hold.UserID,
+				ExpiresAt: hold.ExpiresAt.Time,
+			})
+			if err != nil {
+				return err
+			}
+			if err = recordEvent(ctx, tx, hs.ps.RecordEvent, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to Promote: %w", err)
+	}
+	return holds, nil
+}
+
+const holdExpireLapsed = `
+UPDATE "holds" AS h SET
+status = 'expired', closed_at = NOW()
+WHERE h.status = 'ready' AND h.expires_at <= NOW()
+RETURNING ` + holdColumns + `, 0`
+
+func (hs *HoldStore) ExpireLapsed(ctx context.Context) ([]*store.Hold, error) {
+	rows, err := stmt(ctx, hs.ps.ExpireLapsed).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ExpireLapsed: %w", err)
+	}
+	return hs.scanRows(rows)
+}
+
+const holdFindBookIdsWaiting = `
+SELECT DISTINCT book_id
+FROM "holds"
+WHERE status = 'waiting'
+`
+
+func (hs *HoldStore) FindBookIdsWaiting(ctx context.Context) ([]int, error) {
+	rows, err := stmt(ctx, hs.ps.FindBookIdsWaiting).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindBookIdsWaiting: %w", err)
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return ids, nil
+}
+
+func (hs *HoldStore) scanRows(rows *sql.Rows) ([]*store.Hold, error) {
+	defer rows.Close()
+	holds := []*store.Hold{}
+	for rows.Next() {
+		hold, err := hs.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return holds, nil
+}
+
+func (hs *HoldStore) scanRow(row scanner) (*store.Hold, error) {
+	hold := &store.Hold{}
+	err := row.Scan(
+		&hold.ID, &hold.BookID, &hold.UserID, &hold.Status,
+		&hold.CreatedAt, &hold.ReadyAt, &hold.ExpiresAt,
+		&hold.ClosedAt, &hold.Position,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return hold, nil
+}
@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestPromoteInPlacementOrder(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	ls, err := NewLoanStore(zerolog.Nop(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := NewHoldStore(zerolog.Nop(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := store.LoanPolicy{Period: 14 * 24 * time.Hour, MaxLoans: 5, MaxRenewals: 2}
+	bookId := testBook(t, db, 1)
+	loan, err := ls.Borrow(ctx, testUser(t, db), bookId, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var holdIds []int
+	for i := 0; i < 4; i++ {
+		hold, err := hs.Place(ctx, testUser(t, db), bookId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hold.Position != i+1 {
+			t.Errorf("hold %d is at position %d, want %d", i+1, hold.Position, i+1)
+		}
+		holdIds = append(holdIds, hold.ID)
+	}
+
+	promoted, err := hs.Promote(ctx, bookId, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(promoted) != 0 {
+		t.Fatalf("promoted %d holds while the copy is on loan", len(promoted))
+	}
+
+	if _, err = ls.Return(ctx, loan.ID, loan.UserID); err != nil {
+		t.Fatal(err)
+	}
+	promoted, err = hs.Promote(ctx, bookId, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPromoted(t, db, promoted, holdIds[:1])
+
+	if _, err = db.Exec(`UPDATE "books" SET copies = 3 WHERE id = $1`, bookId); err != nil {
+		t.Fatal(err)
+	}
+	promoted, err = hs.Promote(ctx, bookId, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPromoted(t, db, promoted, holdIds[1:3])
+}
+
+// assertPromoted checks the holds became ready in the given order, each with
+// its hold.ready event.
+func assertPromoted(t *testing.T, db *sql.DB, promoted []*store.Hold, want []int) {
+	t.Helper()
+	if len(promoted) != len(want) {
+		t.Fatalf("promoted %d holds, want %d", len(promoted), len(want))
+	}
+	for i, hold := range promoted {
+		if hold.ID != want[i] || hold.Status != store.HoldStatusReady {
+			t.Errorf("promoted hold %d is %d %s, want %d ready", i+1, hold.ID, hold.Status, want[i])
+		}
+		var events int
+		err := db.QueryRow(`
+SELECT COUNT(*) FROM "domain_events"
+WHERE event_type = $1 AND aggregate_id = $2`, store.EventHoldReady, hold.ID).Scan(&events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if events != 1 {
+			t.Errorf("hold %d has %d hold.ready events, want 1", hold.ID, events)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type ImportStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *importPrepareStatement
+}
+
+type importPrepareStatement struct {
+	Insert            *sql.Stmt
+	FindOneById       *sql.Stmt
+	FindAll           *sql.Stmt
+	ClaimNext         *sql.Stmt
+	UpdateProgress    *sql.Stmt
+	Finish            *sql.Stmt
+	InsertError       *sql.Stmt
+	FindErrorsByJobId *sql.Stmt
+}
+
+func (is *ImportStore) prepareStatement() error {
+	storeName := "ImportStore"
+	var err error
+	if is.ps.Insert, err = prepareStatement(is.db, storeName, "Insert", importInsert); err != nil {
+		return err
+	}
+	if is.ps.FindOneById, err = prepareStatement(is.db, storeName, "FindOneById", importFindOneById); err != nil {
+		return err
+	}
+	if is.ps.FindAll, err = prepareStatement(is.db, storeName, "FindAll", importFindAll); err != nil {
+		return err
+	}
+	if is.ps.ClaimNext, err = prepareStatement(is.db, storeName, "ClaimNext", importClaimNext); err != nil {
+		return err
+	}
+	if is.ps.UpdateProgress, err = prepareStatement(is.db, storeName, "UpdateProgress", importUpdateProgress); err != nil {
+		return err
+	}
+	if is.ps.Finish, err = prepareStatement(is.db, storeName, "Finish", importFinish); err != nil {
+		return err
+	}
+	if is.ps.InsertError, err = prepareStatement(is.db, storeName, "InsertError", importInsertError); err != nil {
+		return err
+	}
+	if is.ps.FindErrorsByJobId, err = prepareStatement(is.db, storeName, "FindErrorsByJobId", importFindErrorsByJobId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewImportStore(log zerolog.Logger, db *sql.DB) (*ImportStore, error) {
+	is := &ImportStore{
+		db:  db,
+		log: log,
+		ps:  &importPrepareStatement{},
+	}
+	err := is.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return is, nil
+}
+
+const importColumns = `
+id, user_id, format, status, dry_run, blob_key, options,
+total, created, updated, failed, error, created_at,
+started_at, finished_at, heartbeat_at
+`
+
+const importInsert = `
+INSERT INTO "import_jobs" (
+	user_id, format, dry_run, blob_key, options
+) VALUES (
+	$1, $2, $3, $4, $5
+)
+RETURNING ` + importColumns
+
+func (is *ImportStore) Insert(ctx context.Context, job *store.ImportJob) error {
+	options, err := json.Marshal(job.Options)
+	if err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	row := stmt(ctx, is.ps.Insert).QueryRowContext(ctx,
+		job.UserID, job.Format, job.DryRun, job.BlobKey, options,
+	)
+	if err = is.scanInto(row, job); err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	return nil
+}
+
+const importFindOneById = `SELECT ` + importColumns + ` FROM "import_jobs" WHERE id = $1`
+
+func (is *ImportStore) FindOneById(ctx context.Context, id int) (*store.ImportJob, error) {
+	job := &store.ImportJob{}
+	if err := is.scanInto(stmt(ctx, is.ps.FindOneById).QueryRowContext(ctx, id), job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+const importFindAll = `
+SELECT ` + importColumns + `
+FROM "import_jobs"
+ORDER BY id DESC
+LIMIT $1 OFFSET $2
+`
+
+func (is *ImportStore) FindAll(ctx context.Context, limit, offset int) ([]*store.ImportJob, error) {
+	rows, err := stmt(ctx, is.ps.FindAll).QueryContext(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAll: %w", err)
+	}
+	defer rows.Close()
+	jobs := []*store.ImportJob{}
+	for rows.Next() {
+		job := &store.ImportJob{}
+		if err = is.scanInto(rows, job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return jobs, nil
+}
+
+// importClaimNext restarts the counters, a reclaimed job runs again from
+// the first record, which the upsert by ISBN makes safe.
+const importClaimNext = `
+UPDATE "import_jobs" SET
+status = 'running', started_at = NOW(), heartbeat_at = NOW(),
+total = 0, created = 0, updated = 0, failed = 0
+WHERE id = (
+	SELECT id FROM "import_jobs"
+	WHERE status = 'queued'
+	OR (status = 'running' AND heartbeat_at < NOW() - make_interval(secs => $1))
+	ORDER BY id
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + importColumns
+
+func (is *ImportStore) ClaimNext(ctx context.Context, staleAfter time.Duration) (*store.ImportJob, error) {
+	job := &store.ImportJob{}
+	if err := is.scanInto(stmt(ctx, is.ps.ClaimNext).QueryRowContext(ctx, staleAfter.Seconds()), job); err != nil {
+		return nil, fmt.Errorf("failed to ClaimNext: %w", err)
+	}
+	return job, nil
+}
+
+const importUpdateProgress = `
+UPDATE "import_jobs" SET
+total = $2, created = $3, updated = $4, failed = $5,
+heartbeat_at = NOW()
+WHERE id = $1
+`
+
+func (is *ImportStore) UpdateProgress(ctx context.Context, job *store.ImportJob) error {
+	_, err := stmt(ctx, is.ps.UpdateProgress).ExecContext(ctx,
+		job.ID, job.Total, job.Created, job.Updated, job.Failed,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to UpdateProgress: %w", err)
+	}
+	return nil
+}
+
+const importFinish = `
+UPDATE "import_jobs" SET
+status = $2, error = $3, total = $4, created = $5,
+updated = $6, failed = $7, finished_at = NOW()
+WHERE id = $1
+RETURNING finished_at
+`
+
+func (is *ImportStore) Finish(ctx context.Context, job *store.ImportJob) error {
+	err := stmt(ctx, is.ps.Finish).QueryRowContext(ctx,
+		job.ID, job.Status, job.Error, job.Total,
+		job.Created, job.Updated, job.Failed,
+	).Scan(&job.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to Finish: %w", err)
+	}
+	return nil
+}
+
+const importInsertError = `
+INSERT INTO "import_job_errors" (job_id, row_number, isbn, message)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (job_id, row_number) DO UPDATE SET
+isbn = EXCLUDED.isbn, message = EXCLUDED.message
+`
+
+func (is *ImportStore) InsertError(ctx context.Context, e *store.ImportError) error {
+	_, err := stmt(ctx, is.ps.InsertError).ExecContext(ctx, e.JobID, e.Row, e.ISBN, e.Message)
+	if err != nil {
+		return fmt.Errorf("failed to InsertError: %w", err)
+	}
+	return nil
+}
+
+const importFindErrorsByJobId = `
+SELECT job_id, row_number, isbn, message
+FROM "import_job_errors"
+WHERE job_id = $1
+ORDER BY row_number
+LIMIT $2 OFFSET $3
+`
+
+func (is *ImportStore) FindErrorsByJobId(ctx context.Context, jobId, limit, offset int) ([]*store.ImportError, error) {
+	rows, err := stmt(ctx, is.ps.FindErrorsByJobId).QueryContext(ctx, jobId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindErrorsByJobId: %w", err)
+	}
+	defer rows.Close()
+	errs := []*store.ImportError{}
+	for rows.Next() {
+		e := &store.ImportError{}
+		if err = rows.Scan(&e.JobID, &e.Row, &e.ISBN, &e.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		errs = append(errs, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return errs, nil
+}
+
+func (is *ImportStore) scanInto(row scanner, job *store.ImportJob) error {
+	var options []byte
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.Format, &job.Status, &job.DryRun,
+		&job.BlobKey, &options, &job.Total, &job.Created, &job.Updated,
+		&job.Failed, &job.Error, &job.CreatedAt, &job.StartedAt,
+		&job.FinishedAt, &job.HeartbeatAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to scanRow: %w", err)
+	}
+	if err = json.Unmarshal(options, &job.Options); err != nil {
+		return fmt.Errorf("failed to decode options: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type LoanStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *loanPrepareStatement
+}
+
+type loanPrepareStatement struct {
+	LockUser                 *sql.Stmt
+	LockBook                 *sql.Stmt
+	ExpireOverdueFor         *sql.Stmt
+	CountActive              *sql.Stmt
+	CountHolds               *sql.Stmt
+	Insert                   *sql.Stmt
+	FulfillHold              *sql.Stmt
+	RecordLoan               *sql.Stmt
+	Return                   *sql.Stmt
+	Renew                    *sql.Stmt
+	FindOneById              *sql.Stmt
+	FindByUserId             *sql.Stmt
+	FindActiveByUserId       *sql.Stmt
+	FindAvailabilityByBookId *sql.Stmt
+	ExpireOverdue            *sql.Stmt
+	RecordEvent              *sql.Stmt
+}
+
+func (ls *LoanStore) prepareStatement() error {
+	storeName := "LoanStore"
+	var err error
+	if ls.ps.LockUser, err = prepareStatement(ls.db, storeName, "LockUser", loanLockUser); err != nil {
+		return err
+	}
+	if ls.ps.LockBook, err = prepareStatement(ls.db, storeName, "LockBook", loanLockBook); err != nil {
+		return err
+	}
+	if ls.ps.ExpireOverdueFor, err = prepareStatement(ls.db, storeName, "ExpireOverdueFor", loanExpireOverdueFor); err != nil {
+		return err
+	}
+	if ls.ps.CountActive, err = prepareStatement(ls.db, storeName, "CountActive", loanCountActive); err != nil {
+		return err
+	}
+	if ls.ps.CountHolds, err = prepareStatement(ls.db, storeName, "CountHolds", loanCountHolds); err != nil {
+		return err
+	}
+	if ls.ps.Insert, err = prepareStatement(ls.db, storeName, "Insert", loanInsert); err != nil {
+		return err
+	}
+	if ls.ps.FulfillHold, err = prepareStatement(ls.db, storeName, "FulfillHold", loanFulfillHold); err != nil {
+		return err
+	}
+	if ls.ps.RecordLoan, err = prepareStatement(ls.db, storeName, "RecordLoan", bookEventInsert); err != nil {
+		return err
+	}
+	if ls.ps.Return, err = prepareStatement(ls.db, storeName, "Return", loanReturn); err != nil {
+		return err
+	}
+	if ls.ps.Renew, err = prepareStatement(ls.db, storeName, "Renew", loanRenew); err != nil {
+		return err
+	}
+	if ls.ps.FindOneById, err = prepareStatement(ls.db, storeName, "FindOneById", loanFindOneById); err != nil {
+		return err
+	}
+	if ls.ps.FindByUserId, err = prepareStatement(ls.db, storeName, "FindByUserId", loanFindByUserId); err != nil {
+		return err
+	}
+	if ls.ps.FindActiveByUserId, err = prepareStatement(ls.db, storeName, "FindActiveByUserId", loanFindActiveByUserId); err != nil {
+		return err
+	}
+	if ls.ps.FindAvailabilityByBookId, err = prepareStatement(ls.db, storeName, "FindAvailabilityByBookId", loanFindAvailabilityByBookId); err != nil {
+		return err
+	}
+	if ls.ps.ExpireOverdue, err = prepareStatement(ls.db, storeName, "ExpireOverdue", loanExpireOverdue); err != nil {
+		return err
+	}
+	if ls.ps.RecordEvent, err = prepareStatement(ls.db, storeName, "RecordEvent", domainEventInsert); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewLoanStore(log zerolog.Logger, db *sql.DB) (*LoanStore, error) {
+	ls := &LoanStore{
+		db:  db,
+		log: log,
+		ps:  &loanPrepareStatement{},
+	}
+	err := ls.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+const loanColumns = `id, book_id, user_id, status, borrowed_at, due_at, returned_at, renew_count`
+
+const loanLockUser = `SELECT id FROM "users" WHERE id = $1 FOR UPDATE`
+
+const loanLockBook = `SELECT copies FROM "books" WHERE id = $1 FOR UPDATE`
+
+const loanExpireOverdueFor = `
+UPDATE "loans" SET
+status = 'expired', returned_at = due_at
+WHERE status = 'active' AND due_at <= NOW()
+AND (user_id = $1 OR book_id = $2)
+`
+
+const loanCountActive = `
+SELECT
+COUNT(*) FILTER (WHERE user_id = $1),
+COUNT(*) FILTER (WHERE book_id = $2),
+COUNT(*) FILTER (WHERE user_id = $1 AND book_id = $2)
+FROM "loans"
+WHERE status = 'active' AND (user_id = $1 OR book_id = $2)
+`
+
+const loanCountHolds = `
+SELECT
+COUNT(*) FILTER (WHERE user_id = $1 AND status = 'ready'),
+COUNT(*) FILTER (WHERE user_id <> $1 AND status = 'ready'),
+COUNT(*) FILTER (WHERE user_id <> $1 AND status = 'waiting')
+FROM "holds"
+WHERE book_id = $2 AND (
+	status = 'waiting' OR (status = 'ready' AND expires_at > NOW())
+)
+`
+
+const loanInsert = `
+INSERT INTO "loans" (book_id, user_id, due_at)
+VALUES ($1, $2, NOW() + make_interval(secs => $3))
+RETURNING ` + loanColumns
+
+const loanFulfillHold = `
+UPDATE "holds" SET
+status = 'fulfilled', closed_at = NOW()
+WHERE user_id = $1 AND book_id = $2 AND status IN ('waiting', 'ready')
+`
+
+// Borrow locks the user row and then the book row, always in that order, so
+// concurrent borrows of the same user or the same title queue up behind each
+// other instead of both passing the limit checks. Copies reserved for ready
+// holds and copies owed to the waiting queue are not available to walk-in
+// borrowers.
+func (ls *LoanStore) Borrow(ctx context.Context, userId, bookId int, policy store.LoanPolicy) (*store.Loan, error) {
+	var loan *store.Loan
+	err := withTx(ctx, ls.db, func(tx *sql.Tx) error {
+		var id, copies int
+		if err := tx.StmtContext(ctx, ls.ps.LockUser).QueryRowContext(ctx, userId).Scan(&id); err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+		if err := tx.StmtContext(ctx, ls.ps.LockBook).QueryRowContext(ctx, bookId).Scan(&copies); err != nil {
+			return fmt.Errorf("failed to lock book: %w", err)
+		}
+		if _, err := tx.StmtContext(ctx, ls.ps.ExpireOverdueFor).ExecContext(ctx, userId, bookId); err != nil {
+			return fmt.Errorf("failed to expire overdue loans: %w", err)
+		}
+		var userLoans, bookLoans, sameLoans int
+		err := tx.StmtContext(ctx, ls.ps.CountActive).QueryRowContext(ctx, userId, bookId).
+			Scan(&userLoans, &bookLoans, &sameLoans)
+		if err != nil {
+			return fmt.Errorf("failed to count active loans: %w", err)
+		}
+		var ownReady, otherReady, otherWaiting int
+		err = tx.StmtContext(ctx, ls.ps.CountHolds).QueryRowContext(ctx, userId, bookId).
+			Scan(&ownReady, &otherReady, &otherWaiting)
+		if err != nil {
+			return fmt.Errorf("failed to count holds: %w", err)
+		}
+		switch {
+		case sameLoans > 0:
+			return store.ErrLoanAlreadyBorrowed
+		case userLoans >= policy.MaxLoans:
+			return store.ErrLoanLimitReached
+		case ownReady == 0 && bookLoans+otherReady+otherWaiting >= copies:
+			return store.ErrLoanNoCopyAvailable
+		}
+		row := tx.StmtContext(ctx, ls.ps.Insert).QueryRowContext(ctx, bookId, userId, policy.Period.Seconds())
+		if loan, err = ls.scanRow(row); err != nil {
+			return err
+		}
+		if _, err = tx.StmtContext(ctx, ls.ps.FulfillHold).ExecContext(ctx, userId, bookId); err != nil {
+			return fmt.Errorf("failed to fulfill hold: %w", err)
+		}
+		if _, err = tx.StmtContext(ctx, ls.ps.RecordLoan).ExecContext(ctx, bookId, userId, store.BookEventLoan); err != nil {
+			return fmt.Errorf("failed to record loan: %w", err)
+		}
+		event, err := store.NewDomainEvent(ctx, store.EventLoanCreated, loan.ID, store.LoanEventData{
+			ID:         loan.ID,
+			BookID:     loan.BookID,
+			UserID:     loan.UserID,
+			BorrowedAt: loan.BorrowedAt,
+			DueAt:      loan.DueAt,
+		})
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, ls.ps.RecordEvent, event)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to Borrow: %w", err)
+	}
+	return loan, nil
+}
+
+const loanReturn = `
+UPDATE "loans" SET
+status = 'returned', returned_at = NOW()
+WHERE id = $1 AND user_id = $2 AND status = 'active'
+RETURNING ` + loanColumns
+
+func (ls *LoanStore) Return(ctx context.Context, id, userId int) (*store.Loan, error) {
+	row := stmt(ctx, ls.ps.Return).QueryRowContext(ctx, id, userId)
+	loan, err := ls.scanRow(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ls.explainMiss(ctx, id, userId, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to Return: %w", err)
+	}
+	return loan, nil
+}
+
+const loanRenew = `
+UPDATE "loans" SET
+due_at = due_at + make_interval(secs => $3),
+renew_count = renew_count + 1
+WHERE id = $1 AND user_id = $2 AND status = 'active'
+AND due_at > NOW() AND renew_count < $4
+AND NOT EXISTS (
+	SELECT 1 FROM "holds" h
+	WHERE h.book_id = loans.book_id AND h.status IN ('waiting', 'ready')
+)
+RETURNING ` + loanColumns
+
+func (ls *LoanStore) Renew(ctx context.Context, id, userId int, policy store.LoanPolicy) (*store.Loan, error) {
+	row := stmt(ctx, ls.ps.Renew).QueryRowContext(ctx, id, userId, policy.Period.Seconds(), policy.MaxRenewals)
+	loan, err := ls.scanRow(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ls.explainMiss(ctx, id, userId, policy.MaxRenewals)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to Renew: %w", err)
+	}
+	return loan, nil
+}
+
+// explainMiss turns a conditional update that matched no row into the error
+// that tells the caller why.
+func (ls *LoanStore) explainMiss(ctx context.Context, id, userId, maxRenewals int) error {
+	loan, err := ls.FindOneById(ctx, id)
+	if err != nil {
+		return err
+	}
+	switch {
+	case loan.UserID != userId:
+		return fmt.Errorf("loan %d: %w", id, sql.ErrNoRows)
+	case loan.Status != store.LoanStatusActive || !loan.DueAt.After(time.Now()):
+		return store.ErrLoanNotActive
+	case maxRenewals > 0 && loan.RenewCount >= maxRenewals:
+		return store.ErrLoanRenewalExhausted
+	case maxRenewals > 0:
+		return store.ErrLoanHoldsWaiting
+	default:
+		return store.ErrLoanNotActive
+	}
+}
+
+const loanFindOneById = `SELECT ` + loanColumns + ` FROM "loans" WHERE id = $1`
+
+func (ls *LoanStore) FindOneById(ctx context.Context, id int) (*store.Loan, error) {
+	row := stmt(ctx, ls.ps.FindOneById).QueryRowContext(ctx, id)
+	return ls.scanRow(row)
+}
+
+const loanFindByUserId = `
+SELECT ` + loanColumns + `
+FROM "loans"
+WHERE user_id = $1
+ORDER BY borrowed_at DESC
+LIMIT $2 OFFSET $3
+`
+
+const loanFindActiveByUserId = `
+SELECT ` + loanColumns + `
+FROM "loans"
+WHERE user_id = $1 AND status = 'active'
+ORDER BY due_at
+LIMIT $2 OFFSET $3
+`
+
+func (ls *LoanStore) FindByUserId(ctx context.Context, userId int, activeOnly bool, limit, offset int) ([]*store.Loan, error) {
+	query := ls.ps.FindByUserId
+	if activeOnly {
+		query = ls.ps.FindActiveByUserId
+	}
+	rows, err := stmt(ctx, query).QueryContext(ctx, userId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByUserId: %w", err)
+	}
+	return ls.scanRows(rows)
+}
+
+const loanFindAvailabilityByBookId = `
+SELECT b.id, b.copies,
+(SELECT COUNT(*) FROM "loans" l WHERE l.book_id = b.id AND l.status = 'active' AND l.due_at > NOW()),
+(SELECT COUNT(*) FROM "holds" h WHERE h.book_id = b.id AND h.status = 'ready' AND h.expires_at > NOW()),
+(SELECT COUNT(*) FROM "holds" h WHERE h.book_id = b.id AND h.status = 'waiting')
+FROM "books" b
+WHERE b.id = $1
+`
+
+func (ls *LoanStore) FindAvailabilityByBookId(ctx context.Context, bookId int) (*store.Availability, error) {
+	availability := &store.Availability{}
+	err := stmt(ctx, ls.ps.FindAvailabilityByBookId).QueryRowContext(ctx, bookId).
+		Scan(
+			&availability.BookID, &availability.Copies, &availability.OnLoan,
+			&availability.Reserved, &availability.Waiting,
+		)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAvailabilityByBookId: %w", err)
+	}
+	return availability, nil
+}
+
+const loanExpireOverdue = `
+UPDATE "loans" SET
+status = 'expired', returned_at = due_at
+WHERE status = 'active' AND due_at <= NOW()
+RETURNING ` + loanColumns
+
+func (ls *LoanStore) ExpireOverdue(ctx context.Context) ([]*store.Loan, error) {
+	rows, err := stmt(ctx, ls.ps.ExpireOverdue).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ExpireOverdue: %w", err)
+	}
+	return ls.scanRows(rows)
+}
+
+func (ls *LoanStore) scanRows(rows *sql.Rows) ([]*store.Loan, error) {
+	defer rows.Close()
+	loans := []*store.Loan{}
+	for rows.Next() {
+		loan, err := ls.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, loan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return loans, nil
+}
+
+func (ls *LoanStore) scanRow(row scanner) (*store.Loan, error) {
+	loan := &store.Loan{}
+	err := row.Scan(
+		&loan.ID, &loan.BookID, &loan.UserID, &loan.Status,
+		&loan.BorrowedAt, &loan.DueAt, &loan.ReturnedAt,
+		&loan.RenewCount,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return loan, nil
+}
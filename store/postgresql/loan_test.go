@@ -0,0 +1,80 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestBorrowConcurrently(t *testing.T) {
+	db := testDB(t)
+	ls, err := NewLoanStore(zerolog.Nop(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := store.LoanPolicy{Period: 14 * 24 * time.Hour, MaxLoans: 2, MaxRenewals: 2}
+	tests := []struct {
+		name    string
+		borrows func() (userIds, bookIds []int)
+		want    error
+	}{
+		{
+			name: "copies of a book",
+			borrows: func() ([]int, []int) {
+				bookId := testBook(t, db, 2)
+				var userIds, bookIds []int
+				for i := 0; i < 8; i++ {
+					userIds = append(userIds, testUser(t, db))
+					bookIds = append(bookIds, bookId)
+				}
+				return userIds, bookIds
+			},
+			want: store.ErrLoanNoCopyAvailable,
+		},
+		{
+			name: "loans of a user",
+			borrows: func() ([]int, []int) {
+				userId := testUser(t, db)
+				var userIds, bookIds []int
+				for i := 0; i < 8; i++ {
+					userIds = append(userIds, userId)
+					bookIds = append(bookIds, testBook(t, db, 1))
+				}
+				return userIds, bookIds
+			},
+			want: store.ErrLoanLimitReached,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userIds, bookIds := tt.borrows()
+			errs := make([]error, len(userIds))
+			var wg sync.WaitGroup
+			for i := range userIds {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, errs[i] = ls.Borrow(context.Background(), userIds[i], bookIds[i], policy)
+				}(i)
+			}
+			wg.Wait()
+			borrowed := 0
+			for _, err := range errs {
+				switch {
+				case err == nil:
+					borrowed++
+				case !errors.Is(err, tt.want):
+					t.Errorf("err = %v, want %v", err, tt.want)
+				}
+			}
+			if borrowed != 2 {
+				t.Errorf("%d borrows went through, want 2", borrowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/rs/zerolog"
+)
+
+// notificationChannel is where the notifications__notify trigger announces
+// new notifications.
+const notificationChannel = "notifications"
+
+type NotificationStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *notificationPrepareStatement
+}
+
+type notificationPrepareStatement struct {
+	Insert       *sql.Stmt
+	FindByUserId *sql.Stmt
+	FindAfter    *sql.Stmt
+	CountUnread  *sql.Stmt
+	MarkRead     *sql.Stmt
+	MarkAllRead  *sql.Stmt
+	QueueLoanDue *sql.Stmt
+}
+
+func (ns *NotificationStore) prepareStatement() error {
+	storeName := "NotificationStore"
+	var err error
+	if ns.ps.Insert, err = prepareStatement(ns.db, storeName, "Insert", notificationInsert); err != nil {
+		return err
+	}
+	if ns.ps.FindByUserId, err = prepareStatement(ns.db, storeName, "FindByUserId", notificationFindByUserId); err != nil {
+		return err
+	}
+	if ns.ps.FindAfter, err = prepareStatement(ns.db, storeName, "FindAfter", notificationFindAfter); err != nil {
+		return err
+	}
+	if ns.ps.CountUnread, err = prepareStatement(ns.db, storeName, "CountUnread", notificationCountUnread); err != nil {
+		return err
+	}
+	if ns.ps.MarkRead, err = prepareStatement(ns.db, storeName, "MarkRead", notificationMarkRead); err != nil {
+		return err
+	}
+	if ns.ps.MarkAllRead, err = prepareStatement(ns.db, storeName, "MarkAllRead", notificationMarkAllRead); err != nil {
+		return err
+	}
+	if ns.ps.QueueLoanDue, err = prepareStatement(ns.db, storeName, "QueueLoanDue", notificationQueueLoanDue); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewNotificationStore(log zerolog.Logger, db *sql.DB) (*NotificationStore, error) {
+	ns := &NotificationStore{
+		db:  db,
+		log: log,
+		ps:  &notificationPrepareStatement{},
+	}
+	err := ns.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+const notificationColumns = `id, user_id, kind, data, read_at, created_at`
+
+const notificationInsert = `
+INSERT INTO "notifications" (user_id, kind, data)
+VALUES ($1, $2, $3)
+RETURNING id, created_at
+`
+
+func (ns *NotificationStore) Insert(ctx context.Context, notification *store.Notification) error {
+	data := notification.Data
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+	err := stmt(ctx, ns.ps.Insert).QueryRowContext(ctx,
+		notification.UserID, notification.Kind, string(data),
+	).Scan(&notification.ID, &notification.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	return nil
+}
+
+const notificationFindByUserId = `
+SELECT ` + notificationColumns + `
+FROM "notifications"
+WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+ORDER BY id DESC
+LIMIT $3 OFFSET $4
+`
+
+func (ns *NotificationStore) FindByUserId(ctx context.Context, userId int, unreadOnly bool, limit, offset int) ([]*store.Notification, error) {
+	rows, err := stmt(ctx, ns.ps.FindByUserId).QueryContext(ctx, userId, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByUserId: %w", err)
+	}
+	return ns.scanRows(rows)
+}
+
+const notificationFindAfter = `
+SELECT ` + notificationColumns + `
+FROM "notifications"
+WHERE user_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+func (ns *NotificationStore) FindAfter(ctx context.Context, userId, afterId, limit int) ([]*store.Notification, error) {
+	rows, err := stmt(ctx, ns.ps.FindAfter).QueryContext(ctx, userId, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAfter: %w", err)
+	}
+	return ns.scanRows(rows)
+}
+
+const notificationCountUnread = `
+SELECT COUNT(*) FROM "notifications" WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (ns *NotificationStore) CountUnread(ctx context.Context, userId int) (int, error) {
+	var count int
+	if err := stmt(ctx, ns.ps.CountUnread).QueryRowContext(ctx, userId).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to CountUnread: %w", err)
+	}
+	return count, nil
+}
+
+// notificationMarkRead keeps the first read_at of a notification read
+// twice.
+const notificationMarkRead = `
+UPDATE "notifications" SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2
+`
+
+func (ns *NotificationStore) MarkRead(ctx context.Context, id, userId int) error {
+	res, err := stmt(ctx, ns.ps.MarkRead).ExecContext(ctx, id, userId)
+	if err != nil {
+		return fmt.Errorf("failed to MarkRead: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to MarkRead: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("failed to MarkRead: %w", sql.ErrNoRows)
+	}
+	return nil
+}
+
+const notificationMarkAllRead = `
+UPDATE "notifications" SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (ns *NotificationStore) MarkAllRead(ctx context.Context, userId int) (int64, error) {
+	res, err := stmt(ctx, ns.ps.MarkAllRead).ExecContext(ctx, userId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to MarkAllRead: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to MarkAllRead: %w", err)
+	}
+	return affected, nil
+}
+
+// notificationQueueLoanDue remembers the due date every reminder was for,
+// so a renewed loan is reminded of again before its new due date.
+const notificationQueueLoanDue = `
+WITH due AS (
+	UPDATE "loans" l SET due_notified_for = l.due_at
+	FROM "books" b
+	WHERE b.id = l.book_id
+	AND l.status = 'active'
+	AND l.due_at <= NOW() + make_interval(secs => $1)
+	AND l.due_notified_for IS DISTINCT FROM l.due_at
+	RETURNING l.id, l.user_id, l.book_id, b.title, l.due_at
+)
+INSERT INTO "notifications" (user_id, kind, data)
+SELECT user_id, 'loan_due', jsonb_build_object(
+	'loan_id', id, 'book_id', book_id, 'title', title, 'due_at', due_at
+)
+FROM due
+`
+
+func (ns *NotificationStore) QueueLoanDue(ctx context.Context, within time.Duration) (int64, error) {
+	res, err := stmt(ctx, ns.ps.QueueLoanDue).ExecContext(ctx, within.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to QueueLoanDue: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to QueueLoanDue: %w", err)
+	}
+	return affected, nil
+}
+
+// notificationPayload is a notifications row as row_to_json writes it.
+type notificationPayload struct {
+	ID        int             `json:"id"`
+	UserID    int             `json:"user_id"`
+	Kind      string          `json:"kind"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Listen holds a connection of the pool for as long as it listens, the
+// connection is closed rather than given back once done.
+func (ns *NotificationStore) Listen(ctx context.Context, fn func(notification *store.Notification)) error {
+	conn, err := ns.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to Listen: %w", err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(driverConn interface{}) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+		defer pgConn.Close(context.Background())
+		if _, err := pgConn.Exec(ctx, "LISTEN "+notificationChannel); err != nil {
+			return err
+		}
+		for {
+			received, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			payload := notificationPayload{}
+			if err := json.Unmarshal([]byte(received.Payload), &payload); err != nil {
+				ns.log.Warn().Err(err).Msg("skipped unreadable notification")
+				continue
+			}
+			fn(&store.Notification{
+				ID:        payload.ID,
+				UserID:    payload.UserID,
+				Kind:      payload.Kind,
+				Data:      payload.Data,
+				CreatedAt: payload.CreatedAt,
+			})
+		}
+	})
+	return fmt.Errorf("failed to Listen: %w", err)
+}
+
+func (ns *NotificationStore) scanRows(rows *sql.Rows) ([]*store.Notification, error) {
+	defer rows.Close()
+	notifications := []*store.Notification{}
+	for rows.Next() {
+		notification := &store.Notification{}
+		var data []byte
+		err := rows.Scan(
+			&notification.ID, &notification.UserID, &notification.Kind, &data,
+			&notification.ReadAt, &notification.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		notification.Data = data
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return notifications, nil
+}
@@ -0,0 +1,111 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type NotificationPreferenceStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *notificationPreferencePrepareStatement
+}
+
+type notificationPreferencePrepareStatement struct {
+	FindByUserId *sql.Stmt
+	Upsert       *sql.Stmt
+	IsEnabled    *sql.Stmt
+}
+
+func (ns *NotificationPreferenceStore) prepareStatement() error {
+	storeName := "NotificationPreferenceStore"
+	var err error
+	if ns.ps.FindByUserId, err = prepareStatement(ns.db, storeName, "FindByUserId", notificationPreferenceFindByUserId); err != nil {
+		return err
+	}
+	if ns.ps.Upsert, err = prepareStatement(ns.db, storeName, "Upsert", notificationPreferenceUpsert); err != nil {
+		return err
+	}
+	if ns.ps.IsEnabled, err = prepareStatement(ns.db, storeName, "IsEnabled", notificationPreferenceIsEnabled); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewNotificationPreferenceStore(log zerolog.Logger, db *sql.DB) (*NotificationPreferenceStore, error) {
+	ns := &NotificationPreferenceStore{
+		db:  db,
+		log: log,
+		ps:  &notificationPreferencePrepareStatement{},
+	}
+	err := ns.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+const notificationPreferenceFindByUserId = `
+SELECT $1::INT, c.category, COALESCE(p.enabled, TRUE)
+FROM (VALUES ('holds', 1), ('alerts', 2)) AS c(category, ord)
+LEFT JOIN "notification_preferences" p ON p.user_id = $1 AND p.category = c.category
+ORDER BY c.ord
+`
+
+func (ns *NotificationPreferenceStore) FindByUserId(ctx context.Context, userId int) ([]*store.NotificationPreference, error) {
+	rows, err := stmt(ctx, ns.ps.FindByUserId).QueryContext(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByUserId: %w", err)
+	}
+	defer rows.Close()
+	preferences := []*store.NotificationPreference{}
+	for rows.Next() {
+		preference := &store.NotificationPreference{}
+		if err := rows.Scan(&preference.UserID, &preference.Category, &preference.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		preferences = append(preferences, preference)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return preferences, nil
+}
+
+const notificationPreferenceUpsert = `
+INSERT INTO "notification_preferences" (user_id, category, enabled)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, category) DO UPDATE SET
+enabled = EXCLUDED.enabled,
+updated_at = NOW()
+`
+
+func (ns *NotificationPreferenceStore) Upsert(ctx context.Context, preference *store.NotificationPreference) error {
+	_, err := stmt(ctx, ns.ps.Upsert).ExecContext(ctx,
+		preference.UserID, preference.Category, preference.Enabled,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to Upsert: %w", err)
+	}
+	return nil
+}
+
+const notificationPreferenceIsEnabled = `
+SELECT COALESCE((
+	SELECT enabled FROM "notification_preferences"
+	WHERE user_id = $1 AND category = $2
+), TRUE)
+`
+
+func (ns *NotificationPreferenceStore) IsEnabled(ctx context.Context, userId int, category string) (bool, error) {
+	var enabled bool
+	err := stmt(ctx, ns.ps.IsEnabled).QueryRowContext(ctx, userId, category).Scan(&enabled)
+	if err != nil {
+		return false, fmt.Errorf("failed to IsEnabled: %w", err)
+	}
+	return enabled, nil
+}
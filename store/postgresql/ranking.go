@@ -0,0 +1,218 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type RankingStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *rankingPrepareStatement
+}
+
+type rankingPrepareStatement struct {
+	RecordView      *sql.Stmt
+	DeleteRankings  *sql.Stmt
+	RefreshTrending *sql.Stmt
+	RefreshMostRead *sql.Stmt
+	RefreshTopRated *sql.Stmt
+	PruneEvents     *sql.Stmt
+	FindRanking     *sql.Stmt
+}
+
+func (rs *RankingStore) prepareStatement() error {
+	storeName := "RankingStore"
+	var err error
+	if rs.ps.RecordView, err = prepareStatement(rs.db, storeName, "RecordView", bookEventInsert); err != nil {
+		return err
+	}
+	if rs.ps.DeleteRankings, err = prepareStatement(rs.db, storeName, "DeleteRankings", rankingDeleteRankings); err != nil {
+		return err
+	}
+	if rs.ps.RefreshTrending, err = prepareStatement(rs.db, storeName, "RefreshTrending", rankingRefreshTrending); err != nil {
+		return err
+	}
+	if rs.ps.RefreshMostRead, err = prepareStatement(rs.db, storeName, "RefreshMostRead", rankingRefreshMostRead); err != nil {
+		return err
+	}
+	if rs.ps.RefreshTopRated, err = prepareStatement(rs.db, storeName, "RefreshTopRated", rankingRefreshTopRated); err != nil {
+		return err
+	}
+	if rs.ps.PruneEvents, err = prepareStatement(rs.db, storeName, "PruneEvents", rankingPruneEvents); err != nil {
+		return err
+	}
+	if rs.ps.FindRanking, err = prepareStatement(rs.db, storeName, "FindRanking", rankingFindRanking); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewRankingStore(log zerolog.Logger, db *sql.DB) (*RankingStore, error) {
+	rs := &RankingStore{
+		db:  db,
+		log: log,
+		ps:  &rankingPrepareStatement{},
+	}
+	err := rs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// bookEventInsert records an event of a book, at most once a day per
+// user. Other stores record their events with it too.
+const bookEventInsert = `
+INSERT INTO "book_events" (book_id, user_id, kind)
+VALUES ($1, NULLIF($2, 0), $3)
+ON CONFLICT DO NOTHING
+`
+
+func (rs *RankingStore) RecordView(ctx context.Context, bookId, userId int) error {
+	_, err := stmt(ctx, rs.ps.RecordView).ExecContext(ctx, bookId, userId, store.BookEventView)
+	if err != nil {
+		return fmt.Errorf("failed to RecordView: %w", err)
+	}
+	return nil
+}
+
+const rankingDeleteRankings = `DELETE FROM "book_rankings"`
+
+// rankingInsertHead and rankingInsertTail wrap a query of book_id and score
+// into the ranking $1, numbered within every category and across all of
+// them, keeping the first $2 books of each.
+const rankingInsertHead = `
+INSERT INTO "book_rankings" (ranking, category_id, position, book_id, score)
+SELECT $1::VARCHAR, category_id, position, book_id, score
+FROM (
+  SELECT s.book_id, s.score, c.category_id,
+  ROW_NUMBER() OVER (PARTITION BY c.category_id ORDER BY s.score DESC, s.book_id) AS position
+  FROM (`
+
+const rankingInsertTail = `) s
+  JOIN "books" b ON b.id = s.book_id
+  CROSS JOIN LATERAL (VALUES (b.category_id), (0)) AS c(category_id)
+) r
+WHERE position <= $2
+`
+
+// A user counts once per book and kind over the window, however often they
+// come back. Reads and loans say more about a book than a look at its page.
+const rankingRefreshTrending = rankingInsertHead + `
+  SELECT book_id, (
+    COUNT(DISTINCT user_id) FILTER (WHERE kind = 'view')
+    + 3 * COUNT(DISTINCT user_id) FILTER (WHERE kind <> 'view')
+  )::FLOAT8 AS score
+  FROM "book_events"
+  WHERE user_id IS NOT NULL
+  AND created_at > NOW() - make_interval(secs => $3)
+  GROUP BY book_id
+` + rankingInsertTail
+
+const rankingRefreshMostRead = rankingInsertHead + `
+  SELECT book_id, COUNT(DISTINCT user_id)::FLOAT8 AS score
+  FROM "book_events"
+  WHERE kind IN ('read', 'loan') AND user_id IS NOT NULL
+  AND created_at > NOW() - make_interval(secs => $3)
+  GROUP BY book_id
+` + rankingInsertTail
+
+// The Bayesian average pulls books with few ratings towards the mean of all
+// ratings, weighing the mean as much as an average book's ratings count.
+const rankingRefreshTopRated = rankingInsertHead + `
+  WITH rated AS (
+    SELECT book_id, COUNT(*) AS n, SUM(rating) AS total
+    FROM "book_rating"
+    GROUP BY book_id
+  ), prior AS (
+    SELECT AVG(n) AS c, SUM(total) / SUM(n) AS m FROM rated
+  )
+  SELECT r.book_id, ((p.c * p.m + r.total) / (p.c + r.n))::FLOAT8 AS score
+  FROM rated r CROSS JOIN prior p
+` + rankingInsertTail
+
+// Refresh recomputes every ranking in one transaction, readers keep seeing
+// the previous rankings until it commits.
+func (rs *RankingStore) Refresh(ctx context.Context, policy store.RankingPolicy) error {
+	err := withTx(ctx, rs.db, func(tx *sql.Tx) error {
+		if _, err := tx.StmtContext(ctx, rs.ps.DeleteRankings).ExecContext(ctx); err != nil {
+			return fmt.Errorf("failed to delete rankings: %w", err)
+		}
+		_, err := tx.StmtContext(ctx, rs.ps.RefreshTrending).
+			ExecContext(ctx, store.RankingTrending, policy.Size, policy.TrendingWindow.Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to refresh trending: %w", err)
+		}
+		_, err = tx.StmtContext(ctx, rs.ps.RefreshMostRead).
+			ExecContext(ctx, store.RankingMostRead, policy.Size, policy.MostReadWindow.Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to refresh most read: %w", err)
+		}
+		_, err = tx.StmtContext(ctx, rs.ps.RefreshTopRated).
+			ExecContext(ctx, store.RankingTopRated, policy.Size)
+		if err != nil {
+			return fmt.Errorf("failed to refresh top rated: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to Refresh: %w", err)
+	}
+	return nil
+}
+
+const rankingPruneEvents = `
+DELETE FROM "book_events"
+WHERE created_at < $1
+`
+
+func (rs *RankingStore) PruneEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := stmt(ctx, rs.ps.PruneEvents).ExecContext(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to PruneEvents: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return count, nil
+}
+
+const rankingFindRanking = `
+SELECT r.ranking, r.category_id, r.position, r.book_id, b.title, r.score, r.computed_at
+FROM "book_rankings" r
+JOIN "books" b ON b.id = r.book_id
+WHERE r.ranking = $1 AND r.category_id = $2
+ORDER BY r.position
+LIMIT $3 OFFSET $4
+`
+
+func (rs *RankingStore) FindRanking(ctx context.Context, ranking string, categoryId, limit, offset int) ([]*store.RankedBook, error) {
+	rows, err := stmt(ctx, rs.ps.FindRanking).QueryContext(ctx, ranking, categoryId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindRanking: %w", err)
+	}
+	defer rows.Close()
+	books := []*store.RankedBook{}
+	for rows.Next() {
+		book := &store.RankedBook{}
+		err = rows.Scan(
+			&book.Ranking, &book.CategoryID, &book.Position, &book.BookID,
+			&book.Title, &book.Score, &book.ComputedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		books = append(books, book)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return books, nil
+}
@@ -0,0 +1,231 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type ReadingStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *readingPrepareStatement
+}
+
+type readingPrepareStatement struct {
+	UpsertProgress       *sql.Stmt
+	FindProgressByUserId *sql.Stmt
+	FindOneProgress      *sql.Stmt
+	UpsertStatus         *sql.Stmt
+	DeleteStatus         *sql.Stmt
+	FindStatusByUserId   *sql.Stmt
+	CountStatusByUserId  *sql.Stmt
+}
+
+func (rs *ReadingStore) prepareStatement() error {
+	storeName := "ReadingStore"
+	var err error
+	if rs.ps.UpsertProgress, err = prepareStatement(rs.db, storeName, "UpsertProgress", readingUpsertProgress); err != nil {
+		return err
+	}
+	if rs.ps.FindProgressByUserId, err = prepareStatement(rs.db, storeName, "FindProgressByUserId", readingFindProgressByUserId); err != nil {
+		return err
+	}
+	if rs.ps.FindOneProgress, err = prepareStatement(rs.db, storeName, "FindOneProgress", readingFindOneProgress); err != nil {
+		return err
+	}
+	if rs.ps.UpsertStatus, err = prepareStatement(rs.db, storeName, "UpsertStatus", readingUpsertStatus); err != nil {
+		return err
+	}
+	if rs.ps.DeleteStatus, err = prepareStatement(rs.db, storeName, "DeleteStatus", readingDeleteStatus); err != nil {
+		return err
+	}
+	if rs.ps.FindStatusByUserId, err = prepareStatement(rs.db, storeName, "FindStatusByUserId", readingFindStatusByUserId); err != nil {
+		return err
+	}
+	if rs.ps.CountStatusByUserId, err = prepareStatement(rs.db, storeName, "CountStatusByUserId", readingCountStatusByUserId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewReadingStore(log zerolog.Logger, db *sql.DB) (*ReadingStore, error) {
+	rs := &ReadingStore{
+		db:  db,
+		log: log,
+		ps:  &readingPrepareStatement{},
+	}
+	err := rs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+const readingUpsertProgress = `
+INSERT INTO "reading_progress" (
+	user_id, book_id, position, percentage
+) VALUES (
+	$1, $2, $3, $4
+)
+ON CONFLICT (user_id, book_id) DO UPDATE SET
+position = EXCLUDED.position,
+percentage = EXCLUDED.percentage,
+updated_at = NOW()
+RETURNING updated_at
+`
+
+func (rs *ReadingStore) UpsertProgress(ctx context.Context, progress *store.ReadingProgress) error {
+	err := stmt(ctx, rs.ps.UpsertProgress).QueryRowContext(ctx,
+		progress.UserID, progress.BookID,
+		progress.Position, progress.Percentage,
+	).Scan(&progress.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to UpsertProgress: %w", err)
+	}
+	return nil
+}
+
+const readingProgressFindBase = `
+SELECT user_id, book_id, position, percentage, updated_at
+FROM "reading_progress"
+`
+
+const readingFindProgressByUserId = readingProgressFindBase + `
+WHERE user_id = $1
+ORDER BY updated_at DESC
+LIMIT $2 OFFSET $3
+`
+
+func (rs *ReadingStore) FindProgressByUserId(ctx context.Context, userId, limit, offset int) ([]*store.ReadingProgress, error) {
+	rows, err := stmt(ctx, rs.ps.FindProgressByUserId).QueryContext(ctx, userId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindProgressByUserId: %w", err)
+	}
+	defer rows.Close()
+	progresses := []*store.ReadingProgress{}
+	for rows.Next() {
+		progress, err := rs.scanProgress(rows)
+		if err != nil {
+			return nil, err
+		}
+		progresses = append(progresses, progress)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return progresses, nil
+}
+
+const readingFindOneProgress = readingProgressFindBase + "WHERE user_id = $1 AND book_id = $2"
+
+func (rs *ReadingStore) FindOneProgress(ctx context.Context, userId, bookId int) (*store.ReadingProgress, error) {
+	row := stmt(ctx, rs.ps.FindOneProgress).QueryRowContext(ctx, userId, bookId)
+	return rs.scanProgress(row)
+}
+
+const readingUpsertStatus = `
+INSERT INTO "reading_status" (
+	user_id, book_id, status
+) VALUES (
+	$1, $2, $3
+)
+ON CONFLICT (user_id, book_id) DO UPDATE SET
+status = EXCLUDED.status,
+updated_at = NOW()
+RETURNING updated_at
+`
+
+func (rs *ReadingStore) UpsertStatus(ctx context.Context, status *store.ReadingStatus) error {
+	err := stmt(ctx, rs.ps.UpsertStatus).QueryRowContext(ctx,
+		status.UserID, status.BookID, status.Status,
+	).Scan(&status.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to UpsertStatus: %w", err)
+	}
+	return nil
+}
+
+const readingDeleteStatus = `
+DELETE FROM "reading_status"
+WHERE user_id = $1 AND book_id = $2 AND status = $3
+`
+
+func (rs *ReadingStore) DeleteStatus(ctx context.Context, userId, bookId int, status string) error {
+	_, err := stmt(ctx, rs.ps.DeleteStatus).ExecContext(ctx, userId, bookId, status)
+	if err != nil {
+		return fmt.Errorf("failed to DeleteStatus: %w", err)
+	}
+	return nil
+}
+
+const readingFindStatusByUserId = `
+SELECT user_id, book_id, status, updated_at
+FROM "reading_status"
+WHERE user_id = $1 AND status = $2
+ORDER BY updated_at DESC
+LIMIT $3 OFFSET $4
+`
+
+func (rs *ReadingStore) FindStatusByUserId(ctx context.Context, userId int, status string, limit, offset int) ([]*store.ReadingStatus, error) {
+	rows, err := stmt(ctx, rs.ps.FindStatusByUserId).QueryContext(ctx, userId, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindStatusByUserId: %w", err)
+	}
+	defer rows.Close()
+	statuses := []*store.ReadingStatus{}
+	for rows.Next() {
+		s := &store.ReadingStatus{}
+		if err = rows.Scan(&s.UserID, &s.BookID, &s.Status, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		statuses = append(statuses, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return statuses, nil
+}
+
+const readingCountStatusByUserId = `
+SELECT status, COUNT(*)
+FROM "reading_status"
+WHERE user_id = $1
+GROUP BY status
+`
+
+func (rs *ReadingStore) CountStatusByUserId(ctx context.Context, userId int) (map[string]int, error) {
+	rows, err := stmt(ctx, rs.ps.CountStatusByUserId).QueryContext(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to CountStatusByUserId: %w", err)
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err = rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		counts[status] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return counts, nil
+}
+
+func (rs *ReadingStore) scanProgress(row scanner) (*store.ReadingProgress, error) {
+	progress := &store.ReadingProgress{}
+	err := row.Scan(
+		&progress.UserID, &progress.BookID, &progress.Position,
+		&progress.Percentage, &progress.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanProgress: %w", err)
+	}
+	return progress, nil
+}
@@ -0,0 +1,242 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type RecommendationStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *recommendationPrepareStatement
+}
+
+type recommendationPrepareStatement struct {
+	StreamInteractions *sql.Stmt
+	DeleteSimilarities *sql.Stmt
+	InsertSimilarities *sql.Stmt
+	FindForUser        *sql.Stmt
+	FindSimilar        *sql.Stmt
+	FindPopular        *sql.Stmt
+}
+
+func (rs *RecommendationStore) prepareStatement() error {
+	storeName := "RecommendationStore"
+	var err error
+	if rs.ps.StreamInteractions, err = prepareStatement(rs.db, storeName, "StreamInteractions", recommendationStreamInteractions); err != nil {
+		return err
+	}
+	if rs.ps.DeleteSimilarities, err = prepareStatement(rs.db, storeName, "DeleteSimilarities", recommendationDeleteSimilarities); err != nil {
+		return err
+	}
+	if rs.ps.InsertSimilarities, err = prepareStatement(rs.db, storeName, "InsertSimilarities", recommendationInsertSimilarities); err != nil {
+		return err
+	}
+	if rs.ps.FindForUser, err = prepareStatement(rs.db, storeName, "FindForUser", recommendationFindForUser); err != nil {
+		return err
+	}
+	if rs.ps.FindSimilar, err = prepareStatement(rs.db, storeName, "FindSimilar", recommendationFindSimilar); err != nil {
+		return err
+	}
+	if rs.ps.FindPopular, err = prepareStatement(rs.db, storeName, "FindPopular", recommendationFindPopular); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewRecommendationStore(log zerolog.Logger, db *sql.DB) (*RecommendationStore, error) {
+	rs := &RecommendationStore{
+		db:  db,
+		log: log,
+		ps:  &recommendationPrepareStatement{},
+	}
+	err := rs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// recommendationInteractions weighs every book a user rated or has in their
+// reading history. A rating wins over history, so a book read and disliked
+// counts as little as its rating says.
+const recommendationInteractions = `
+SELECT user_id, book_id, COALESCE(MAX(rated), MAX(read))::FLOAT8 AS weight
+FROM (
+  SELECT user_id, book_id, rating / 5.0 AS rated, NULL::REAL AS read FROM "book_rating"
+  UNION ALL
+  SELECT user_id, book_id, NULL, 1.0 FROM "book_readers"
+  UNION ALL
+  SELECT user_id, book_id, NULL, CASE status
+    WHEN 'finished' THEN 1.0
+    WHEN 'reading' THEN 0.8
+    ELSE 0.4
+  END FROM "reading_status"
+) i
+GROUP BY user_id, book_id
+`
+
+const recommendationStreamInteractions = recommendationInteractions + "ORDER BY user_id, book_id"
+
+func (rs *RecommendationStore) StreamInteractions(ctx context.Context, fn func(*store.Interaction) error) error {
+	rows, err := stmt(ctx, rs.ps.StreamInteractions).QueryContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to StreamInteractions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		interaction := &store.Interaction{}
+		if err = rows.Scan(&interaction.UserID, &interaction.BookID, &interaction.Weight); err != nil {
+			return fmt.Errorf("failed to scanRow: %w", err)
+		}
+		if err = fn(interaction); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return nil
+}
+
+const recommendationDeleteSimilarities = `DELETE FROM "book_similarities"`
+
+const recommendationInsertSimilarities = `
+INSERT INTO "book_similarities" (book_id, similar_book_id, score)
+SELECT * FROM unnest($1::INT[], $2::INT[], $3::FLOAT8[])
+`
+
+// similarityBatchSize bounds the rows inserted by one statement.
+const similarityBatchSize = 5000
+
+// ReplaceSimilarities swaps the whole table in one transaction, so readers
+// see either the previous computation or the new one.
+func (rs *RecommendationStore) ReplaceSimilarities(ctx context.Context, similarities []*store.BookSimilarity) error {
+	err := withTx(ctx, rs.db, func(tx *sql.Tx) error {
+		if _, err := tx.StmtContext(ctx, rs.ps.DeleteSimilarities).ExecContext(ctx); err != nil {
+			return fmt.Errorf("failed to delete similarities: %w", err)
+		}
+		insert := tx.StmtContext(ctx, rs.ps.InsertSimilarities)
+		for start := 0; start < len(similarities); start += similarityBatchSize {
+			end := start + similarityBatchSize
+			if end > len(similarities) {
+				end = len(similarities)
+			}
+			batch := similarities[start:end]
+			bookIds := make([]int, len(batch))
+			similarIds := make([]int, len(batch))
+			scores := make([]float64, len(batch))
+			for i, similarity := range batch {
+				bookIds[i] = similarity.BookID
+				similarIds[i] = similarity.SimilarBookID
+				scores[i] = similarity.Score
+			}
+			if _, err := insert.ExecContext(ctx, bookIds, similarIds, scores); err != nil {
+				return fmt.Errorf("failed to insert similarities: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ReplaceSimilarities: %w", err)
+	}
+	return nil
+}
+
+const recommendationFindForUser = `
+WITH mine AS (
+  SELECT book_id, weight FROM (` + recommendationInteractions + `) i
+  WHERE user_id = $1
+)
+SELECT b.id, b.title, b.category_id, SUM(s.score * m.weight)::FLOAT8 AS score
+FROM mine m
+JOIN "book_similarities" s ON s.book_id = m.book_id
+JOIN "books" b ON b.id = s.similar_book_id
+WHERE s.similar_book_id NOT IN (SELECT book_id FROM mine)
+GROUP BY b.id
+ORDER BY score DESC, b.id
+LIMIT $2
+`
+
+// FindForUser scores the neighbours of every book the user knows by how
+// similar they are and how much the user liked the book.
+func (rs *RecommendationStore) FindForUser(ctx context.Context, userId, limit int) ([]*store.Recommendation, error) {
+	rows, err := stmt(ctx, rs.ps.FindForUser).QueryContext(ctx, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindForUser: %w", err)
+	}
+	return rs.scanRows(rows)
+}
+
+const recommendationFindSimilar = `
+SELECT b.id, b.title, b.category_id, s.score::FLOAT8
+FROM "book_similarities" s
+JOIN "books" b ON b.id = s.similar_book_id
+WHERE s.book_id = $1
+ORDER BY s.score DESC, b.id
+LIMIT $2
+`
+
+func (rs *RecommendationStore) FindSimilar(ctx context.Context, bookId, limit int) ([]*store.Recommendation, error) {
+	rows, err := stmt(ctx, rs.ps.FindSimilar).QueryContext(ctx, bookId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindSimilar: %w", err)
+	}
+	return rs.scanRows(rows)
+}
+
+const recommendationFindPopular = `
+WITH mine AS (
+  SELECT book_id FROM (` + recommendationInteractions + `) i
+  WHERE user_id = $1
+), liked AS (
+  SELECT DISTINCT b.category_id FROM "books" b JOIN mine m ON m.book_id = b.id
+), ranked AS (
+  SELECT b.id, b.title, b.category_id, p.popularity,
+  ROW_NUMBER() OVER (PARTITION BY b.category_id ORDER BY p.popularity DESC, b.id) AS rank
+  FROM "books" b
+  CROSS JOIN LATERAL (
+    SELECT (b.reader + COALESCE(SUM(br.rating) / 5.0, 0))::FLOAT8 AS popularity
+    FROM "book_rating" br WHERE br.book_id = b.id
+  ) p
+  WHERE b.id NOT IN (SELECT book_id FROM mine)
+  AND ($2::INT = 0 OR b.category_id = $2)
+  AND (NOT EXISTS (SELECT 1 FROM liked) OR b.category_id IN (SELECT category_id FROM liked))
+)
+SELECT id, title, category_id, popularity
+FROM ranked
+ORDER BY rank, popularity DESC, id
+LIMIT $3
+`
+
+func (rs *RecommendationStore) FindPopular(ctx context.Context, userId, categoryId, limit int) ([]*store.Recommendation, error) {
+	rows, err := stmt(ctx, rs.ps.FindPopular).QueryContext(ctx, userId, categoryId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindPopular: %w", err)
+	}
+	return rs.scanRows(rows)
+}
+
+func (rs *RecommendationStore) scanRows(rows *sql.Rows) ([]*store.Recommendation, error) {
+	defer rows.Close()
+	recommendations := []*store.Recommendation{}
+	for rows.Next() {
+		recommendation := &store.Recommendation{}
+		err := rows.Scan(
+			&recommendation.BookID, &recommendation.Title,
+			&recommendation.CategoryID, &recommendation.Score,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return recommendations, nil
+}
@@ -0,0 +1,508 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type ReviewStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *reviewPrepareStatement
+}
+
+type reviewPrepareStatement struct {
+	UpsertRating            *sql.Stmt
+	Insert                  *sql.Stmt
+	LockOwn                 *sql.Stmt
+	InsertRevision          *sql.Stmt
+	UpdateRating            *sql.Stmt
+	Update                  *sql.Stmt
+	Delete                  *sql.Stmt
+	FindOneById             *sql.Stmt
+	FindApprovedByHelpful   *sql.Stmt
+	FindApprovedByNewest    *sql.Stmt
+	FindByStatus            *sql.Stmt
+	FindFlagged             *sql.Stmt
+	Moderate                *sql.Stmt
+	ResolveFlags            *sql.Stmt
+	FindRevisionsByReviewId *sql.Stmt
+	FindOpenFlagsByReviewId *sql.Stmt
+	Flag                    *sql.Stmt
+	Vote                    *sql.Stmt
+	Unvote                  *sql.Stmt
+}
+
+func (rs *ReviewStore) prepareStatement() error {
+	storeName := "ReviewStore"
+	var err error
+	if rs.ps.UpsertRating, err = prepareStatement(rs.db, storeName, "UpsertRating", reviewUpsertRating); err != nil {
+		return err
+	}
+	if rs.ps.Insert, err = prepareStatement(rs.db, storeName, "Insert", reviewInsert); err != nil {
+		return err
+	}
+	if rs.ps.LockOwn, err = prepareStatement(rs.db, storeName, "LockOwn", reviewLockOwn); err != nil {
+		return err
+	}
+	if rs.ps.InsertRevision, err = prepareStatement(rs.db, storeName, "InsertRevision", reviewInsertRevision); err != nil {
+		return err
+	}
+	if rs.ps.UpdateRating, err = prepareStatement(rs.db, storeName, "UpdateRating", reviewUpdateRating); err != nil {
+		return err
+	}
+	if rs.ps.Update, err = prepareStatement(rs.db, storeName, "Update", reviewUpdate); err != nil {
+		return err
+	}
+	if rs.ps.Delete, err = prepareStatement(rs.db, storeName, "Delete", reviewDelete); err != nil {
+		return err
+	}
+	if rs.ps.FindOneById, err = prepareStatement(rs.db, storeName, "FindOneById", reviewFindOneById); err != nil {
+		return err
+	}
+	if rs.ps.FindApprovedByHelpful, err = prepareStatement(rs.db, storeName, "FindApprovedByHelpful", reviewFindApprovedByHelpful); err != nil {
+		return err
+	}
+	if rs.ps.FindApprovedByNewest, err = prepareStatement(rs.db, storeName, "FindApprovedByNewest", reviewFindApprovedByNewest); err != nil {
+		return err
+	}
+	if rs.ps.FindByStatus, err = prepareStatement(rs.db, storeName, "FindByStatus", reviewFindByStatus); err != nil {
+		return err
+	}
+	if rs.ps.FindFlagged, err = prepareStatement(rs.db, storeName, "FindFlagged", reviewFindFlagged); err != nil {
+		return err
+	}
+	if rs.ps.Moderate, err = prepareStatement(rs.db, storeName, "Moderate", reviewModerate); err != nil {
+		return err
+	}
+	if rs.ps.ResolveFlags, err = prepareStatement(rs.db, storeName, "ResolveFlags", reviewResolveFlags); err != nil {
+		return err
+	}
+	if rs.ps.FindRevisionsByReviewId, err = prepareStatement(rs.db, storeName, "FindRevisionsByReviewId", reviewFindRevisionsByReviewId); err != nil {
+		return err
+	}
+	if rs.ps.FindOpenFlagsByReviewId, err = prepareStatement(rs.db, storeName, "FindOpenFlagsByReviewId", reviewFindOpenFlagsByReviewId); err != nil {
+		return err
+	}
+	if rs.ps.Flag, err = prepareStatement(rs.db, storeName, "Flag", reviewFlag); err != nil {
+		return err
+	}
+	if rs.ps.Vote, err = prepareStatement(rs.db, storeName, "Vote", reviewVote); err != nil {
+		return err
+	}
+	if rs.ps.Unvote, err = prepareStatement(rs.db, storeName, "Unvote", reviewUnvote); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewReviewStore(log zerolog.Logger, db *sql.DB) (*ReviewStore, error) {
+	rs := &ReviewStore{
+		db:  db,
+		log: log,
+		ps:  &reviewPrepareStatement{},
+	}
+	err := rs.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// reviewColumns reads the rating from book_rating, which stays the single
+// source of a user's rating whether or not it comes with a review.
+const reviewColumns = `
+r.id, r.book_id, r.user_id,
+(SELECT u.fullname FROM "users" u WHERE u.id = r.user_id),
+(SELECT br.rating FROM "book_rating" br WHERE br.book_id = r.book_id AND br.user_id = r.user_id),
+r.body, r.status, r.helpful_count, r.flag_count, r.moderated_by,
+r.moderated_at, r.moderation_note, r.created_at, r.updated_at
+`
+
+const reviewUpsertRating = `
+INSERT INTO "book_rating" (book_id, user_id, rating)
+VALUES ($1, $2, $3)
+ON CONFLICT (book_id, user_id) DO UPDATE SET
+rating = EXCLUDED.rating
+`
+
+const reviewInsert = `
+INSERT INTO "reviews" AS r (book_id, user_id, body)
+VALUES ($1, $2, $3)
+ON CONFLICT (book_id, user_id) DO NOTHING
+RETURNING ` + reviewColumns
+
+// Insert stores the rating and the review together. A user writes at most
+// one review per book, later changes go through Update.
+func (rs *ReviewStore) Insert(ctx context.Context, review *store.Review) error {
+	err := withTx(ctx, rs.db, func(tx *sql.Tx) error {
+		_, err := tx.StmtContext(ctx, rs.ps.UpsertRating).ExecContext(ctx, review.BookID, review.UserID, review.Rating)
+		if err != nil {
+			return fmt.Errorf("failed to upsert rating: %w", err)
+		}
+		row := tx.StmtContext(ctx, rs.ps.Insert).QueryRowContext(ctx, review.BookID, review.UserID, review.Body)
+		err = rs.scanInto(row, review)
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrReviewAlreadyWritten
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	return nil
+}
+
+const reviewLockOwn = `
+SELECT r.body, br.rating
+FROM "reviews" r
+JOIN "book_rating" br ON br.book_id = r.book_id AND br.user_id = r.user_id
+WHERE r.id = $1 AND r.user_id = $2
+FOR UPDATE OF r
+`
+
+const reviewInsertRevision = `
+INSERT INTO "review_revisions" (review_id, body, rating)
+VALUES ($1, $2, $3)
+`
+
+const reviewUpdateRating = `
+UPDATE "book_rating" br SET
+rating = $2
+FROM "reviews" r
+WHERE r.id = $1 AND br.book_id = r.book_id AND br.user_id = r.user_id
+`
+
+const reviewUpdate = `
+UPDATE "reviews" AS r SET
+body = $2, status = 'pending', updated_at = NOW()
+WHERE r.id = $1
+RETURNING ` + reviewColumns
+
+// Update keeps the previous body and rating as a revision and sends the
+// edited review back to the moderation queue.
+func (rs *ReviewStore) Update(ctx context.Context, review *store.Review) error {
+	err := withTx(ctx, rs.db, func(tx *sql.Tx) error {
+		var body string
+		var rating float64
+		err := tx.StmtContext(ctx, rs.ps.LockOwn).QueryRowContext(ctx, review.ID, review.UserID).Scan(&body, &rating)
+		if err != nil {
+			return fmt.Errorf("failed to lock review: %w", err)
+		}
+		if _, err = tx.StmtContext(ctx, rs.ps.InsertRevision).ExecContext(ctx, review.ID, body, rating); err != nil {
+			return fmt.Errorf("failed to insert revision: %w", err)
+		}
+		if _, err = tx.StmtContext(ctx, rs.ps.UpdateRating).ExecContext(ctx, review.ID, review.Rating); err != nil {
+			return fmt.Errorf("failed to update rating: %w", err)
+		}
+		row := tx.StmtContext(ctx, rs.ps.Update).QueryRowContext(ctx, review.ID, review.Body)
+		return rs.scanInto(row, review)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to Update: %w", err)
+	}
+	return nil
+}
+
+const reviewDelete = `DELETE FROM "reviews" WHERE id = $1 AND user_id = $2`
+
+// Delete removes the review together with its history, flags and votes.
+// The rating itself is kept.
+func (rs *ReviewStore) Delete(ctx context.Context, id, userId int) error {
+	res, err := stmt(ctx, rs.ps.Delete).ExecContext(ctx, id, userId)
+	if err != nil {
+		return fmt.Errorf("failed to Delete: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to Delete: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("failed to Delete: %w", sql.ErrNoRows)
+	}
+	return nil
+}
+
+const reviewFindOneById = `SELECT ` + reviewColumns + ` FROM "reviews" r WHERE r.id = $1`
+
+func (rs *ReviewStore) FindOneById(ctx context.Context, id int) (*store.Review, error) {
+	row := stmt(ctx, rs.ps.FindOneById).QueryRowContext(ctx, id)
+	return rs.scanRow(row)
+}
+
+const reviewFindApprovedByHelpful = `
+SELECT ` + reviewColumns + `
+FROM "reviews" r
+WHERE r.book_id = $1 AND r.status = 'approved'
+ORDER BY r.helpful_count DESC, r.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+const reviewFindApprovedByNewest = `
+SELECT ` + reviewColumns + `
+FROM "reviews" r
+WHERE r.book_id = $1 AND r.status = 'approved'
+ORDER BY r.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+func (rs *ReviewStore) FindApprovedByBookId(ctx context.Context, bookId int, sort string, limit, offset int) ([]*store.Review, error) {
+	query := rs.ps.FindApprovedByHelpful
+	if sort == store.ReviewSortNewest {
+		query = rs.ps.FindApprovedByNewest
+	}
+	rows, err := stmt(ctx, query).QueryContext(ctx, bookId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindApprovedByBookId: %w", err)
+	}
+	return rs.scanRows(rows)
+}
+
+const reviewFindByStatus = `
+SELECT ` + reviewColumns + `
+FROM "reviews" r
+WHERE r.status = $1
+ORDER BY r.updated_at
+LIMIT $2 OFFSET $3
+`
+
+const reviewFindFlagged = `
+SELECT ` + reviewColumns + `
+FROM "reviews" r
+WHERE r.flag_count > 0
+ORDER BY r.flag_count DESC, r.updated_at
+LIMIT $1 OFFSET $2
+`
+
+// FindForModeration lists the oldest reviews first for a status queue, or
+// the most reported ones first for the flagged queue.
+func (rs *ReviewStore) FindForModeration(ctx context.Context, queue string, limit, offset int) ([]*store.Review, error) {
+	var rows *sql.Rows
+	var err error
+	if queue == store.ReviewQueueFlagged {
+		rows, err = stmt(ctx, rs.ps.FindFlagged).QueryContext(ctx, limit, offset)
+	} else {
+		rows, err = stmt(ctx, rs.ps.FindByStatus).QueryContext(ctx, queue, limit, offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindForModeration: %w", err)
+	}
+	return rs.scanRows(rows)
+}
+
+const reviewModerate = `
+UPDATE "reviews" AS r SET
+status = $2, moderated_by = $3, moderated_at = NOW(),
+moderation_note = $4, flag_count = 0
+WHERE r.id = $1
+RETURNING ` + reviewColumns
+
+const reviewResolveFlags = `
+UPDATE "review_flags" SET
+resolved = TRUE
+WHERE review_id = $1 AND NOT resolved
+`
+
+// Moderate records the decision and resolves every open flag, the
+// moderator has seen them when deciding.
+func (rs *ReviewStore) Moderate(ctx context.Context, id, moderatorId int, status, note string) (*store.Review, error) {
+	var review *store.Review
+	err := withTx(ctx, rs.db, func(tx *sql.Tx) error {
+		row := tx.StmtContext(ctx, rs.ps.Moderate).QueryRowContext(ctx, id, status, moderatorId, note)
+		var err error
+		if review, err = rs.scanRow(row); err != nil {
+			return err
+		}
+		if _, err = tx.StmtContext(ctx, rs.ps.ResolveFlags).ExecContext(ctx, id); err != nil {
+			return fmt.Errorf("failed to resolve flags: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to Moderate: %w", err)
+	}
+	return review, nil
+}
+
+const reviewFindRevisionsByReviewId = `
+SELECT id, review_id, body, rating, created_at
+FROM "review_revisions"
+WHERE review_id = $1
+ORDER BY created_at DESC, id DESC
+`
+
+func (rs *ReviewStore) FindRevisionsByReviewId(ctx context.Context, id int) ([]*store.ReviewRevision, error) {
+	rows, err := stmt(ctx, rs.ps.FindRevisionsByReviewId).QueryContext(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindRevisionsByReviewId: %w", err)
+	}
+	defer rows.Close()
+	revisions := []*store.ReviewRevision{}
+	for rows.Next() {
+		rev := &store.ReviewRevision{}
+		if err = rows.Scan(&rev.ID, &rev.ReviewID, &rev.Body, &rev.Rating, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return revisions, nil
+}
+
+const reviewFindOpenFlagsByReviewId = `
+SELECT review_id, user_id, reason, created_at
+FROM "review_flags"
+WHERE review_id = $1 AND NOT resolved
+ORDER BY created_at
+`
+
+func (rs *ReviewStore) FindOpenFlagsByReviewId(ctx context.Context, id int) ([]*store.ReviewFlag, error) {
+	rows, err := stmt(ctx, rs.ps.FindOpenFlagsByReviewId).QueryContext(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindOpenFlagsByReviewId: %w", err)
+	}
+	defer rows.Close()
+	flags := []*store.ReviewFlag{}
+	for rows.Next() {
+		flag := &store.ReviewFlag{}
+		if err = rows.Scan(&flag.ReviewID, &flag.UserID, &flag.Reason, &flag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return flags, nil
+}
+
+// reviewFlag counts a flag once per user. Flagging again after a moderator
+// resolved the previous flag reopens it.
+const reviewFlag = `
+WITH flagged AS (
+	INSERT INTO "review_flags" (review_id, user_id, reason)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (review_id, user_id) DO UPDATE SET
+	reason = EXCLUDED.reason, resolved = FALSE, created_at = NOW()
+	WHERE review_flags.resolved
+	RETURNING review_id
+)
+UPDATE "reviews" SET
+flag_count = flag_count + 1
+WHERE id IN (SELECT review_id FROM flagged)
+`
+
+// visibleToOthers loads a review another user is about to flag or vote on.
+// Only approved reviews are public, and a review cannot be judged by its
+// own author.
+func (rs *ReviewStore) visibleToOthers(ctx context.Context, id, userId int) error {
+	review, err := rs.FindOneById(ctx, id)
+	if err != nil {
+		return err
+	}
+	switch {
+	case review.Status != store.ReviewStatusApproved:
+		return fmt.Errorf("review %d: %w", id, sql.ErrNoRows)
+	case review.UserID == userId:
+		return store.ErrReviewOwn
+	}
+	return nil
+}
+
+func (rs *ReviewStore) Flag(ctx context.Context, id, userId int, reason string) error {
+	if err := rs.visibleToOthers(ctx, id, userId); err != nil {
+		return fmt.Errorf("failed to Flag: %w", err)
+	}
+	if _, err := stmt(ctx, rs.ps.Flag).ExecContext(ctx, id, userId, reason); err != nil {
+		return fmt.Errorf("failed to Flag: %w", err)
+	}
+	return nil
+}
+
+const reviewVote = `
+WITH voted AS (
+	INSERT INTO "review_votes" (review_id, user_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	RETURNING review_id
+)
+UPDATE "reviews" AS r SET
+helpful_count = helpful_count + (SELECT COUNT(*) FROM voted)
+WHERE r.id = $1
+RETURNING ` + reviewColumns
+
+func (rs *ReviewStore) Vote(ctx context.Context, id, userId int) (*store.Review, error) {
+	if err := rs.visibleToOthers(ctx, id, userId); err != nil {
+		return nil, fmt.Errorf("failed to Vote: %w", err)
+	}
+	row := stmt(ctx, rs.ps.Vote).QueryRowContext(ctx, id, userId)
+	review, err := rs.scanRow(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Vote: %w", err)
+	}
+	return review, nil
+}
+
+const reviewUnvote = `
+WITH unvoted AS (
+	DELETE FROM "review_votes"
+	WHERE review_id = $1 AND user_id = $2
+	RETURNING review_id
+)
+UPDATE "reviews" AS r SET
+helpful_count = helpful_count - (SELECT COUNT(*) FROM unvoted)
+WHERE r.id = $1
+RETURNING ` + reviewColumns
+
+func (rs *ReviewStore) Unvote(ctx context.Context, id, userId int) (*store.Review, error) {
+	row := stmt(ctx, rs.ps.Unvote).QueryRowContext(ctx, id, userId)
+	review, err := rs.scanRow(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Unvote: %w", err)
+	}
+	return review, nil
+}
+
+func (rs *ReviewStore) scanRows(rows *sql.Rows) ([]*store.Review, error) {
+	defer rows.Close()
+	reviews := []*store.Review{}
+	for rows.Next() {
+		review, err := rs.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return reviews, nil
+}
+
+func (rs *ReviewStore) scanRow(row scanner) (*store.Review, error) {
+	review := &store.Review{}
+	if err := rs.scanInto(row, review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (rs *ReviewStore) scanInto(row scanner, review *store.Review) error {
+	err := row.Scan(
+		&review.ID, &review.BookID, &review.UserID, &review.Reviewer,
+		&review.Rating, &review.Body, &review.Status, &review.HelpfulCount,
+		&review.FlagCount, &review.ModeratedBy, &review.ModeratedAt,
+		&review.ModerationNote, &review.CreatedAt, &review.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type ShelfStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *shelfPrepareStatement
+}
+
+type shelfPrepareStatement struct {
+	Insert             *sql.Stmt
+	FindByUserId       *sql.Stmt
+	FindOneById        *sql.Stmt
+	DeleteById         *sql.Stmt
+	AddBook            *sql.Stmt
+	RemoveBook         *sql.Stmt
+	FindBooksByShelfId *sql.Stmt
+}
+
+func (ss *ShelfStore) prepareStatement() error {
+	storeName := "ShelfStore"
+	var err error
+	if ss.ps.Insert, err = prepareStatement(ss.db, storeName, "Insert", shelfInsert); err != nil {
+		return err
+	}
+	if ss.ps.FindByUserId, err = prepareStatement(ss.db, storeName, "FindByUserId", shelfFindByUserId); err != nil {
+		return err
+	}
+	if ss.ps.FindOneById, err = prepareStatement(ss.db, storeName, "FindOneById", shelfFindOneById); err != nil {
+		return err
+	}
+	if ss.ps.DeleteById, err = prepareStatement(ss.db, storeName, "DeleteById", shelfDeleteById); err != nil {
+		return err
+	}
+	if ss.ps.AddBook, err = prepareStatement(ss.db, storeName, "AddBook", shelfAddBook); err != nil {
+		return err
+	}
+	if ss.ps.RemoveBook, err = prepareStatement(ss.db, storeName, "RemoveBook", shelfRemoveBook); err != nil {
+		return err
+	}
+	if ss.ps.FindBooksByShelfId, err = prepareStatement(ss.db, storeName, "FindBooksByShelfId", shelfFindBooksByShelfId); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewShelfStore(log zerolog.Logger, db *sql.DB) (*ShelfStore, error) {
+	ss := &ShelfStore{
+		db:  db,
+		log: log,
+		ps:  &shelfPrepareStatement{},
+	}
+	err := ss.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+const shelfInsert = `
+INSERT INTO "shelves" (user_id, name)
+VALUES ($1, $2)
+RETURNING id, created_at
+`
+
+func (ss *ShelfStore) Insert(ctx context.Context, shelf *store.Shelf) error {
+	err := stmt(ctx, ss.ps.Insert).QueryRowContext(ctx, shelf.UserID, shelf.Name).
+		Scan(&shelf.ID, &shelf.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to Insert: %w", err)
+	}
+	return nil
+}
+
+const shelfFindBase = `
+SELECT s.id, s.user_id, s.name, s.created_at,
+(SELECT COUNT(*) FROM "shelf_books" sb WHERE sb.shelf_id = s.id)
+FROM "shelves" s
+`
+
+const shelfFindByUserId = shelfFindBase + "WHERE s.user_id = $1 ORDER BY s.name"
+
+func (ss *ShelfStore) FindByUserId(ctx context.Context, userId int) ([]*store.Shelf, error) {
+	rows, err := stmt(ctx, ss.ps.FindByUserId).QueryContext(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindByUserId: %w", err)
+	}
+	defer rows.Close()
+	shelves := []*store.Shelf{}
+	for rows.Next() {
+		shelf, err := ss.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, shelf)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return shelves, nil
+}
+
+const shelfFindOneById = shelfFindBase + "WHERE s.id = $1"
+
+func (ss *ShelfStore) FindOneById(ctx context.Context, id int) (*store.Shelf, error) {
+	row := stmt(ctx, ss.ps.FindOneById).QueryRowContext(ctx, id)
+	return ss.scanRow(row)
+}
+
+const shelfDeleteById = `
+DELETE FROM "shelves"
+WHERE id = $1
+`
+
+func (ss *ShelfStore) DeleteById(ctx context.Context, id int) error {
+	_, err := stmt(ctx, ss.ps.DeleteById).ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to DeleteById: %w", err)
+	}
+	return nil
+}
+
+const shelfAddBook = `
+INSERT INTO "shelf_books" (shelf_id, book_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+func (ss *ShelfStore) AddBook(ctx context.Context, shelfId, bookId int) error {
+	_, err := stmt(ctx, ss.ps.AddBook).ExecContext(ctx, shelfId, bookId)
+	if err != nil {
+		return fmt.Errorf("failed to AddBook: %w", err)
+	}
+	return nil
+}
+
+const shelfRemoveBook = `
+DELETE FROM "shelf_books"
+WHERE shelf_id = $1 AND book_id = $2
+`
+
+func (ss *ShelfStore) RemoveBook(ctx context.Context, shelfId, bookId int) error {
+	_, err := stmt(ctx, ss.ps.RemoveBook).ExecContext(ctx, shelfId, bookId)
+	if err != nil {
+		return fmt.Errorf("failed to RemoveBook: %w", err)
+	}
+	return nil
+}
+
+const shelfFindBooksByShelfId = `
+SELECT shelf_id, book_id, added_at
+FROM "shelf_books"
+WHERE shelf_id = $1
+ORDER BY added_at DESC
+LIMIT $2 OFFSET $3
+`
+
+func (ss *ShelfStore) FindBooksByShelfId(ctx context.Context, shelfId, limit, offset int) ([]*store.ShelfBook, error) {
+	rows, err := stmt(ctx, ss.ps.FindBooksByShelfId).QueryContext(ctx, shelfId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindBooksByShelfId: %w", err)
+	}
+	defer rows.Close()
+	books := []*store.ShelfBook{}
+	for rows.Next() {
+		book := &store.ShelfBook{}
+		if err = rows.Scan(&book.ShelfID, &book.BookID, &book.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan: %w", err)
+		}
+		books = append(books, book)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return books, nil
+}
+
+func (ss *ShelfStore) scanRow(row scanner) (*store.Shelf, error) {
+	shelf := &store.Shelf{}
+	err := row.Scan(
+		&shelf.ID, &shelf.UserID, &shelf.Name,
+		&shelf.CreatedAt, &shelf.BookCount,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	return shelf, nil
+}
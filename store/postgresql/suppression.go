@@ -0,0 +1,124 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type SuppressionStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *suppressionPrepareStatement
+}
+
+type suppressionPrepareStatement struct {
+	Add          *sql.Stmt
+	Remove       *sql.Stmt
+	FindAll      *sql.Stmt
+	IsSuppressed *sql.Stmt
+}
+
+func (ss *SuppressionStore) prepareStatement() error {
+	storeName := "SuppressionStore"
+	var err error
+	if ss.ps.Add, err = prepareStatement(ss.db, storeName, "Add", suppressionAdd); err != nil {
+		return err
+	}
+	if ss.ps.Remove, err = prepareStatement(ss.db, storeName, "Remove", suppressionRemove); err != nil {
+		return err
+	}
+	if ss.ps.FindAll, err = prepareStatement(ss.db, storeName, "FindAll", suppressionFindAll); err != nil {
+		return err
+	}
+	if ss.ps.IsSuppressed, err = prepareStatement(ss.db, storeName, "IsSuppressed", suppressionIsSuppressed); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewSuppressionStore(log zerolog.Logger, db *sql.DB) (*SuppressionStore, error) {
+	ss := &SuppressionStore{
+		db:  db,
+		log: log,
+		ps:  &suppressionPrepareStatement{},
+	}
+	err := ss.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+const suppressionAdd = `
+INSERT INTO "email_suppressions" (email, reason, detail)
+VALUES (LOWER($1), $2, $3)
+ON CONFLICT (email) DO UPDATE SET
+reason = EXCLUDED.reason,
+detail = EXCLUDED.detail,
+updated_at = NOW()
+RETURNING email, created_at, updated_at
+`
+
+func (ss *SuppressionStore) Add(ctx context.Context, suppression *store.Suppression) error {
+	err := stmt(ctx, ss.ps.Add).QueryRowContext(ctx,
+		suppression.Email, suppression.Reason, suppression.Detail,
+	).Scan(&suppression.Email, &suppression.CreatedAt, &suppression.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to Add: %w", err)
+	}
+	return nil
+}
+
+const suppressionRemove = `DELETE FROM "email_suppressions" WHERE email = LOWER($1)`
+
+func (ss *SuppressionStore) Remove(ctx context.Context, email string) error {
+	if _, err := stmt(ctx, ss.ps.Remove).ExecContext(ctx, email); err != nil {
+		return fmt.Errorf("failed to Remove: %w", err)
+	}
+	return nil
+}
+
+const suppressionFindAll = `
+SELECT email, reason, detail, created_at, updated_at
+FROM "email_suppressions"
+ORDER BY updated_at DESC, email
+LIMIT $1 OFFSET $2
+`
+
+func (ss *SuppressionStore) FindAll(ctx context.Context, limit, offset int) ([]*store.Suppression, error) {
+	rows, err := stmt(ctx, ss.ps.FindAll).QueryContext(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindAll: %w", err)
+	}
+	defer rows.Close()
+	suppressions := []*store.Suppression{}
+	for rows.Next() {
+		suppression := &store.Suppression{}
+		err := rows.Scan(
+			&suppression.Email, &suppression.Reason, &suppression.Detail,
+			&suppression.CreatedAt, &suppression.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		suppressions = append(suppressions, suppression)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return suppressions, nil
+}
+
+const suppressionIsSuppressed = `SELECT EXISTS (SELECT 1 FROM "email_suppressions" WHERE email = LOWER($1))`
+
+func (ss *SuppressionStore) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	var suppressed bool
+	if err := stmt(ctx, ss.ps.IsSuppressed).QueryRowContext(ctx, email).Scan(&suppressed); err != nil {
+		return false, fmt.Errorf("failed to IsSuppressed: %w", err)
+	}
+	return suppressed, nil
+}
@@ -0,0 +1,94 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/rs/zerolog"
+)
+
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgUniqueViolation      = "23505"
+)
+
+const (
+	// txMaxAttempts bounds how often a transaction is run, a transaction
+	// still failing to serialize after that returns its error.
+	txMaxAttempts = 5
+	txRetryBase   = 10 * time.Millisecond
+)
+
+type txCtxKey struct{}
+
+func txFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txCtxKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// TxManager runs transactions at the serializable level, retrying those
+// aborted by a concurrent one. Checks made in fn, such as looking up a row
+// before inserting it, hold for the whole transaction.
+type TxManager struct {
+	log zerolog.Logger
+	db  *sql.DB
+}
+
+func NewTxManager(log zerolog.Logger, db *sql.DB) *TxManager {
+	return &TxManager{
+		log: log,
+		db:  db,
+	}
+}
+
+func (tm *TxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := txFromContext(ctx); ok {
+		return fn(ctx)
+	}
+	for attempt := 1; ; attempt++ {
+		err := tm.run(ctx, fn)
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) || attempt >= txMaxAttempts {
+			return mapError(err)
+		}
+		// Jitter keeps the transactions that collided from colliding again.
+		delay := txRetryBase*time.Duration(attempt) + time.Duration(rand.Int63n(int64(txRetryBase)))
+		tm.log.Debug().Err(err).Int("attempt", attempt).Dur("retry_in", delay).Msg("retrying transaction")
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (tm *TxManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := tm.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	if err = fn(context.WithValue(ctx, txCtxKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+}
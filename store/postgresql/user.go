@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/rs/zerolog"
 )
@@ -17,11 +18,16 @@ type UserStore struct {
 
 type userPrepareStatement struct {
 	Insert                   *sql.Stmt
+	RecordEvent              *sql.Stmt
 	FindOneById              *sql.Stmt
 	FindOneByEmail           *sql.Stmt
 	FindOneCredentialByEmail *sql.Stmt
 	UpdateTokenIdById        *sql.Stmt
 	DeleteTokenIdById        *sql.Stmt
+	UpdateLocaleById         *sql.Stmt
+	Verify                   *sql.Stmt
+	UpdateRoleById           *sql.Stmt
+	UpdateRoleByEmails       *sql.Stmt
 }
 
 func (us *UserStore) prepareStatement() error {
@@ -30,6 +36,9 @@ func (us *UserStore) prepareStatement() error {
 	if us.ps.Insert, err = prepareStatement(us.db, storeName, "Insert", userInsert); err != nil {
 		return err
 	}
+	if us.ps.RecordEvent, err = prepareStatement(us.db, storeName, "RecordEvent", domainEventInsert); err != nil {
+		return err
+	}
 	if us.ps.FindOneByEmail, err = prepareStatement(us.db, storeName, "FindOneByEmail", userFindOneByEmail); err != nil {
 		return err
 	}
@@ -45,6 +54,18 @@ func (us *UserStore) prepareStatement() error {
 	if us.ps.DeleteTokenIdById, err = prepareStatement(us.db, storeName, "DeleteTokenIdById", userDeleteTokenIdById); err != nil {
 		return err
 	}
+	if us.ps.UpdateLocaleById, err = prepareStatement(us.db, storeName, "UpdateLocaleById", userUpdateLocaleById); err != nil {
+		return err
+	}
+	if us.ps.Verify, err = prepareStatement(us.db, storeName, "Verify", userVerify); err != nil {
+		return err
+	}
+	if us.ps.UpdateRoleById, err = prepareStatement(us.db, storeName, "UpdateRoleById", userUpdateRoleById); err != nil {
+		return err
+	}
+	if us.ps.UpdateRoleByEmails, err = prepareStatement(us.db, storeName, "UpdateRoleByEmails", userUpdateRoleByEmails); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -62,7 +83,7 @@ func NewUserStore(log zerolog.Logger, db *sql.DB) (*UserStore, error) {
 }
 
 const userFindOneBase = `
-SELECT id, email, fullname, is_verified,
+SELECT id, email, fullname, is_verified, role, locale,
 token_id, token_verification, token_expiration
 FROM "users"
 `
@@ -70,32 +91,48 @@ FROM "users"
 const userFindOneByEmail = userFindOneBase + "WHERE email = $1"
 
 func (us *UserStore) FindOneByEmail(ctx context.Context, email string) (*store.User, error) {
-	row := us.ps.FindOneByEmail.QueryRowContext(ctx, email)
+	row := stmt(ctx, us.ps.FindOneByEmail).QueryRowContext(ctx, email)
 	return us.scanRow(row)
 }
 
 const userFindOneById = userFindOneBase + "WHERE id = $1"
 
 func (us *UserStore) FindOneById(ctx context.Context, id int) (*store.User, error) {
-	row := us.ps.FindOneById.QueryRowContext(ctx, id)
+	row := stmt(ctx, us.ps.FindOneById).QueryRowContext(ctx, id)
 	return us.scanRow(row)
 }
 
 const userInsert = `
 INSERT INTO "users" (
-	email, password, fullname, is_verified,
+	email, password, fullname, is_verified, locale,
 	token_verification, token_expiration
 ) VALUES (
-	$1, $2, $3, $4, $5, $6
+	$1, $2, $3, $4, $5, $6, $7
 )
+RETURNING id
 `
 
 func (us *UserStore) Insert(ctx context.Context, usr *store.UserRegister) error {
-	_, err := us.ps.Insert.ExecContext(ctx,
-		usr.Email, usr.Password, usr.Fullname,
-		usr.IsVerified, usr.TokenVerification,
-		usr.TokenExpiration,
-	)
+	err := withTx(ctx, us.db, func(tx *sql.Tx) error {
+		err := tx.StmtContext(ctx, us.ps.Insert).QueryRowContext(ctx,
+			usr.Email, usr.Password, usr.Fullname,
+			usr.IsVerified, usr.Locale, usr.TokenVerification,
+			usr.TokenExpiration,
+		).Scan(&usr.ID)
+		if err != nil {
+			return err
+		}
+		event, err := store.NewDomainEvent(ctx, store.EventUserRegistered, usr.ID, store.UserEventData{
+			ID:       usr.ID,
+			Email:    usr.Email,
+			Fullname: usr.Fullname,
+			Locale:   usr.Locale,
+		})
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, us.ps.RecordEvent, event)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to Insert: %w", err)
 	}
@@ -109,7 +146,7 @@ WHERE email = $1
 `
 
 func (us *UserStore) FindOneCredentialByEmail(ctx context.Context, email string) (*store.User, error) {
-	row := us.ps.FindOneCredentialByEmail.QueryRowContext(ctx, email)
+	row := stmt(ctx, us.ps.FindOneCredentialByEmail).QueryRowContext(ctx, email)
 	user := &store.User{}
 	err := row.Scan(
 		&user.ID, &user.Email, &user.Password,
@@ -128,7 +165,7 @@ WHERE id = $2
 `
 
 func (us *UserStore) UpdateTokenIdById(ctx context.Context, token string, id int) error {
-	_, err := us.ps.UpdateTokenIdById.ExecContext(ctx, token, id)
+	_, err := stmt(ctx, us.ps.UpdateTokenIdById).ExecContext(ctx, token, id)
 	if err != nil {
 		return fmt.Errorf("failed to UpdateTokenIdById: %w", err)
 	}
@@ -142,18 +179,106 @@ WHERE id = $1
 `
 
 func (us *UserStore) DeleteTokenIdById(ctx context.Context, id int) error {
-	_, err := us.ps.DeleteTokenIdById.ExecContext(ctx, id)
+	_, err := stmt(ctx, us.ps.DeleteTokenIdById).ExecContext(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to DeleteTokenIdById: %w", err)
 	}
 	return nil
 }
 
-func (us *UserStore) scanRow(row *sql.Row) (*store.User, error) {
+const userUpdateLocaleById = `
+UPDATE "users" SET
+locale = $1
+WHERE id = $2
+`
+
+func (us *UserStore) UpdateLocaleById(ctx context.Context, locale string, id int) error {
+	_, err := stmt(ctx, us.ps.UpdateLocaleById).ExecContext(ctx, locale, id)
+	if err != nil {
+		return fmt.Errorf("failed to UpdateLocaleById: %w", err)
+	}
+	return nil
+}
+
+// userVerify compares token_expiration, a unix time, with the current
+// one.
+const userVerify = `
+UPDATE "users" SET
+is_verified = TRUE, token_verification = NULL, token_expiration = NULL
+WHERE id = $1 AND NOT is_verified
+AND token_verification = $2
+AND token_expiration::BIGINT >= EXTRACT(EPOCH FROM NOW())
+RETURNING id, email, fullname, is_verified, role, locale,
+token_id, token_verification, token_expiration
+`
+
+func (us *UserStore) Verify(ctx context.Context, id int, token string) (*store.User, error) {
+	var user *store.User
+	err := withTx(ctx, us.db, func(tx *sql.Tx) error {
+		var err error
+		user, err = us.scanRow(tx.StmtContext(ctx, us.ps.Verify).QueryRowContext(ctx, id, token))
+		if err != nil {
+			return err
+		}
+		event, err := store.NewDomainEvent(ctx, store.EventUserVerified, user.ID, store.UserEventData{
+			ID:       user.ID,
+			Email:    user.Email,
+			Fullname: user.Fullname,
+			Locale:   user.Locale,
+		})
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, us.ps.RecordEvent, event)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to Verify: %w", err)
+	}
+	return user, nil
+}
+
+const userUpdateRoleById = `
+UPDATE "users" SET
+role = $1
+WHERE id = $2
+RETURNING id
+`
+
+func (us *UserStore) UpdateRoleById(ctx context.Context, role string, id int) error {
+	err := stmt(ctx, us.ps.UpdateRoleById).QueryRowContext(ctx, role, id).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to UpdateRoleById: %w", err)
+	}
+	return nil
+}
+
+const userUpdateRoleByEmails = `
+UPDATE "users" SET
+role = $1
+WHERE lower(email) = ANY($2::TEXT[]) AND is_verified AND role <> $1
+`
+
+func (us *UserStore) UpdateRoleByEmails(ctx context.Context, role string, emails []string) (int64, error) {
+	lowered := make([]string, len(emails))
+	for i, email := range emails {
+		lowered[i] = strings.ToLower(email)
+	}
+	res, err := stmt(ctx, us.ps.UpdateRoleByEmails).ExecContext(ctx, role, lowered)
+	if err != nil {
+		return 0, fmt.Errorf("failed to UpdateRoleByEmails: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to UpdateRoleByEmails: %w", err)
+	}
+	return n, nil
+}
+
+func (us *UserStore) scanRow(row scanner) (*store.User, error) {
 	user := &store.User{}
 	err := row.Scan(
 		&user.ID, &user.Email, &user.Fullname,
-		&user.IsVerified, &user.TokenID, &user.TokenVerification,
+		&user.IsVerified, &user.Role, &user.Locale, &user.TokenID, &user.TokenVerification,
 		&user.TokenExpiration,
 	)
 	if err != nil {
@@ -0,0 +1,396 @@
+package postgresql
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type WebhookStore struct {
+	log zerolog.Logger
+	db  *sql.DB
+	ps  *webhookPrepareStatement
+}
+
+type webhookPrepareStatement struct {
+	InsertSubscription             *sql.Stmt
+	UpdateSubscription             *sql.Stmt
+	DeleteSubscription             *sql.Stmt
+	FindSubscriptionById           *sql.Stmt
+	FindSubscriptions              *sql.Stmt
+	Enqueue                        *sql.Stmt
+	Claim                          *sql.Stmt
+	MarkDelivered                  *sql.Stmt
+	MarkFailed                     *sql.Stmt
+	FindDeliveryById               *sql.Stmt
+	FindDeliveriesBySubscriptionId *sql.Stmt
+	Redeliver                      *sql.Stmt
+}
+
+func (ws *WebhookStore) prepareStatement() error {
+	storeName := "WebhookStore"
+	var err error
+	if ws.ps.InsertSubscription, err = prepareStatement(ws.db, storeName, "InsertSubscription", webhookInsertSubscription); err != nil {
+		return err
+	}
+	if ws.ps.UpdateSubscription, err = prepareStatement(ws.db, storeName, "UpdateSubscription", webhookUpdateSubscription); err != nil {
+		return err
+	}
+	if ws.ps.DeleteSubscription, err = prepareStatement(ws.db, storeName, "DeleteSubscription", webhookDeleteSubscription); err != nil {
+		return err
+	}
+	if ws.ps.FindSubscriptionById, err = prepareStatement(ws.db, storeName, "FindSubscriptionById", webhookFindSubscriptionById); err != nil {
+		return err
+	}
+	if ws.ps.FindSubscriptions, err = prepareStatement(ws.db, storeName, "FindSubscriptions", webhookFindSubscriptions); err != nil {
+		return err
+	}
+	if ws.ps.Enqueue, err = prepareStatement(ws.db, storeName, "Enqueue", webhookEnqueue); err != nil {
+		return err
+	}
+	if ws.ps.Claim, err = prepareStatement(ws.db, storeName, "Claim", webhookClaim); err != nil {
+		return err
+	}
+	if ws.ps.MarkDelivered, err = prepareStatement(ws.db, storeName, "MarkDelivered", webhookMarkDelivered); err != nil {
+		return err
+	}
+	if ws.ps.MarkFailed, err = prepareStatement(ws.db, storeName, "MarkFailed", webhookMarkFailed); err != nil {
+		return err
+	}
+	if ws.ps.FindDeliveryById, err = prepareStatement(ws.db, storeName, "FindDeliveryById", webhookFindDeliveryById); err != nil {
+		return err
+	}
+	if ws.ps.FindDeliveriesBySubscriptionId, err = prepareStatement(ws.db, storeName, "FindDeliveriesBySubscriptionId", webhookFindDeliveriesBySubscriptionId); err != nil {
+		return err
+	}
+	if ws.ps.Redeliver, err = prepareStatement(ws.db, storeName, "Redeliver", webhookRedeliver); err != nil {
+		return err
+	}
+	return nil
+}
+
+func NewWebhookStore(log zerolog.Logger, db *sql.DB) (*WebhookStore, error) {
+	ws := &WebhookStore{
+		db:  db,
+		log: log,
+		ps:  &webhookPrepareStatement{},
+	}
+	err := ws.prepareStatement()
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// webhookSubscriptionColumns reads events joined by commas, event names
+// have none.
+const webhookSubscriptionColumns = `id, url, array_to_string(events, ','), secret, active,
+failure_count, disabled_at, created_by, created_at, updated_at`
+
+const webhookInsertSubscription = `
+INSERT INTO "webhook_subscriptions" (url, events, secret, active, created_by)
+VALUES ($1, $2::VARCHAR[], $3, $4, $5)
+RETURNING id, failure_count, disabled_at, created_at, updated_at
+`
+
+func (ws *WebhookStore) InsertSubscription(ctx context.Context, subscription *store.WebhookSubscription) error {
+	err := stmt(ctx, ws.ps.InsertSubscription).QueryRowContext(ctx,
+		subscription.URL, subscription.Events, subscription.Secret, subscription.Active, subscription.CreatedBy,
+	).Scan(
+		&subscription.ID, &subscription.FailureCount, &subscription.DisabledAt,
+		&subscription.CreatedAt, &subscription.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to InsertSubscription: %w", err)
+	}
+	return nil
+}
+
+const webhookUpdateSubscription = `
+UPDATE "webhook_subscriptions" SET
+url = $2, events = $3::VARCHAR[], secret = $4,
+failure_count = CASE WHEN $5 AND NOT active THEN 0 ELSE failure_count END,
+disabled_at = CASE WHEN $5 THEN NULL ELSE COALESCE(disabled_at, NOW()) END,
+active = $5,
+updated_at = NOW()
+WHERE id = $1
+RETURNING failure_count, disabled_at, created_by, created_at, updated_at
+`
+
+func (ws *WebhookStore) UpdateSubscription(ctx context.Context, subscription *store.WebhookSubscription) error {
+	err := stmt(ctx, ws.ps.UpdateSubscription).QueryRowContext(ctx,
+		subscription.ID, subscription.URL, subscription.Events, subscription.Secret, subscription.Active,
+	).Scan(
+		&subscription.FailureCount, &subscription.DisabledAt, &subscription.CreatedBy,
+		&subscription.CreatedAt, &subscription.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to UpdateSubscription: %w", err)
+	}
+	return nil
+}
+
+const webhookDeleteSubscription = `DELETE FROM "webhook_subscriptions" WHERE id = $1`
+
+func (ws *WebhookStore) DeleteSubscription(ctx context.Context, id int) error {
+	if _, err := stmt(ctx, ws.ps.DeleteSubscription).ExecContext(ctx, id); err != nil {
+		return fmt.Errorf("failed to DeleteSubscription: %w", err)
+	}
+	return nil
+}
+
+const webhookFindSubscriptionById = `
+SELECT ` + webhookSubscriptionColumns + `
+FROM "webhook_subscriptions"
+WHERE id = $1
+`
+
+func (ws *WebhookStore) FindSubscriptionById(ctx context.Context, id int) (*store.WebhookSubscription, error) {
+	return ws.scanSubscription(stmt(ctx, ws.ps.FindSubscriptionById).QueryRowContext(ctx, id))
+}
+
+const webhookFindSubscriptions = `
+SELECT ` + webhookSubscriptionColumns + `
+FROM "webhook_subscriptions"
+ORDER BY id
+LIMIT $1 OFFSET $2
+`
+
+func (ws *WebhookStore) FindSubscriptions(ctx context.Context, limit, offset int) ([]*store.WebhookSubscription, error) {
+	rows, err := stmt(ctx, ws.ps.FindSubscriptions).QueryContext(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindSubscriptions: %w", err)
+	}
+	defer rows.Close()
+	subscriptions := []*store.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := ws.scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return subscriptions, nil
+}
+
+const webhookEnqueue = `
+INSERT INTO "webhook_deliveries" (subscription_id, event, payload)
+SELECT id, $1, $2
+FROM "webhook_subscriptions"
+WHERE active AND $1 = ANY(events)
+`
+
+func (ws *WebhookStore) Enqueue(ctx context.Context, event string, payload []byte) (int64, error) {
+	res, err := stmt(ctx, ws.ps.Enqueue).ExecContext(ctx, event, string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to Enqueue: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to Enqueue: %w", err)
+	}
+	return affected, nil
+}
+
+const webhookDeliveryColumns = `id, subscription_id, event, payload, status, attempts,
+next_attempt_at, response_status, response_body, last_error, created_at, delivered_at`
+
+const webhookClaim = `
+WITH claimed AS (
+	UPDATE "webhook_deliveries" SET
+	status = 'sending', locked_at = NOW(), attempts = attempts + 1
+	WHERE id IN (
+		SELECT d.id FROM "webhook_deliveries" d
+		JOIN "webhook_subscriptions" s ON s.id = d.subscription_id
+		WHERE s.active AND (
+			(d.status = 'pending' AND d.next_attempt_at <= NOW())
+			OR (d.status = 'sending' AND d.locked_at < NOW() - make_interval(secs => $2))
+		)
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT $1
+		FOR UPDATE OF d SKIP LOCKED
+	)
+	RETURNING ` + webhookDeliveryColumns + `
+)
+SELECT c.*, s.url, s.secret
+FROM claimed c
+JOIN "webhook_subscriptions" s ON s.id = c.subscription_id
+ORDER BY c.id
+`
+
+func (ws *WebhookStore) Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]*store.WebhookDelivery, error) {
+	rows, err := stmt(ctx, ws.ps.Claim).QueryContext(ctx, limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to Claim: %w", err)
+	}
+	defer rows.Close()
+	deliveries := []*store.WebhookDelivery{}
+	for rows.Next() {
+		delivery := &store.WebhookDelivery{}
+		var payload []byte
+		err := rows.Scan(
+			&delivery.ID, &delivery.SubscriptionID, &delivery.Event, &payload, &delivery.Status,
+			&delivery.Attempts, &delivery.NextAttemptAt, &delivery.ResponseStatus, &delivery.ResponseBody,
+			&delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt, &delivery.URL, &delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scanRow: %w", err)
+		}
+		delivery.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+const webhookMarkDelivered = `
+WITH delivery AS (
+	UPDATE "webhook_deliveries" SET
+	status = 'succeeded', delivered_at = NOW(), locked_at = NULL,
+	response_status = $2, response_body = $3, last_error = ''
+	WHERE id = $1
+	RETURNING subscription_id
+)
+UPDATE "webhook_subscriptions" s SET failure_count = 0
+FROM delivery
+WHERE s.id = delivery.subscription_id AND s.failure_count > 0
+`
+
+func (ws *WebhookStore) MarkDelivered(ctx context.Context, delivery *store.WebhookDelivery) error {
+	_, err := stmt(ctx, ws.ps.MarkDelivered).ExecContext(ctx,
+		delivery.ID, delivery.ResponseStatus, delivery.ResponseBody,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to MarkDelivered: %w", err)
+	}
+	return nil
+}
+
+// webhookMarkFailed reads the old failure_count and active on the right of
+// SET, so disabled_at is only set by the failure that disables.
+const webhookMarkFailed = `
+WITH delivery AS (
+	UPDATE "webhook_deliveries" SET
+	status = CASE WHEN $6 THEN 'failed' ELSE 'pending' END,
+	response_status = $2, response_body = $3, last_error = $4,
+	next_attempt_at = $5, locked_at = NULL
+	WHERE id = $1
+	RETURNING subscription_id
+)
+UPDATE "webhook_subscriptions" s SET
+failure_count = s.failure_count + 1,
+active = s.active AND s.failure_count + 1 < $7,
+disabled_at = CASE WHEN s.active AND s.failure_count + 1 >= $7 THEN NOW() ELSE s.disabled_at END
+FROM delivery
+WHERE s.id = delivery.subscription_id
+RETURNING s.disabled_at IS NOT NULL AND s.disabled_at = NOW()
+`
+
+func (ws *WebhookStore) MarkFailed(ctx context.Context, delivery *store.WebhookDelivery, nextAttemptAt time.Time, dead bool, disableAfter int) (bool, error) {
+	var disabled bool
+	err := stmt(ctx, ws.ps.MarkFailed).QueryRowContext(ctx,
+		delivery.ID, delivery.ResponseStatus, delivery.ResponseBody, delivery.LastError,
+		nextAttemptAt, dead, disableAfter,
+	).Scan(&disabled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to MarkFailed: %w", err)
+	}
+	return disabled, nil
+}
+
+const webhookFindDeliveryById = `
+SELECT ` + webhookDeliveryColumns + `
+FROM "webhook_deliveries"
+WHERE id = $1
+`
+
+func (ws *WebhookStore) FindDeliveryById(ctx context.Context, id int) (*store.WebhookDelivery, error) {
+	return ws.scanDelivery(stmt(ctx, ws.ps.FindDeliveryById).QueryRowContext(ctx, id))
+}
+
+const webhookFindDeliveriesBySubscriptionId = `
+SELECT ` + webhookDeliveryColumns + `
+FROM "webhook_deliveries"
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3
+`
+
+func (ws *WebhookStore) FindDeliveriesBySubscriptionId(ctx context.Context, subscriptionId, limit, offset int) ([]*store.WebhookDelivery, error) {
+	rows, err := stmt(ctx, ws.ps.FindDeliveriesBySubscriptionId).QueryContext(ctx, subscriptionId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindDeliveriesBySubscriptionId: %w", err)
+	}
+	defer rows.Close()
+	deliveries := []*store.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := ws.scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+const webhookRedeliver = `
+INSERT INTO "webhook_deliveries" (subscription_id, event, payload)
+SELECT subscription_id, event, payload
+FROM "webhook_deliveries"
+WHERE id = $1
+RETURNING ` + webhookDeliveryColumns
+
+func (ws *WebhookStore) Redeliver(ctx context.Context, id int) (*store.WebhookDelivery, error) {
+	delivery, err := ws.scanDelivery(stmt(ctx, ws.ps.Redeliver).QueryRowContext(ctx, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to Redeliver: %w", err)
+	}
+	return delivery, nil
+}
+
+func (ws *WebhookStore) scanSubscription(row scanner) (*store.WebhookSubscription, error) {
+	subscription := &store.WebhookSubscription{}
+	var events string
+	err := row.Scan(
+		&subscription.ID, &subscription.URL, &events, &subscription.Secret, &subscription.Active,
+		&subscription.FailureCount, &subscription.DisabledAt, &subscription.CreatedBy,
+		&subscription.CreatedAt, &subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	subscription.Events = strings.Split(events, ",")
+	return subscription, nil
+}
+
+func (ws *WebhookStore) scanDelivery(row scanner) (*store.WebhookDelivery, error) {
+	delivery := &store.WebhookDelivery{}
+	var payload []byte
+	err := row.Scan(
+		&delivery.ID, &delivery.SubscriptionID, &delivery.Event, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.ResponseStatus, &delivery.ResponseBody,
+		&delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scanRow: %w", err)
+	}
+	delivery.Payload = payload
+	return delivery, nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	BookEventView = "view"
+	BookEventRead = "read"
+	BookEventLoan = "loan"
+)
+
+const (
+	RankingTrending = "trending"
+	RankingMostRead = "most_read"
+	RankingTopRated = "top_rated"
+)
+
+// RankingAllCategories is the category id of the rankings across the whole
+// catalogue.
+const RankingAllCategories = 0
+
+// RankingPolicy sets the time windows the rankings look back over and how
+// many books each ranking keeps, per category.
+type RankingPolicy struct {
+	TrendingWindow time.Duration
+	MostReadWindow time.Duration
+	Size           int
+}
+
+type RankedBook struct {
+	Ranking    string
+	CategoryID int
+	Position   int
+	BookID     int
+	Title      string
+	Score      float64
+	ComputedAt time.Time
+}
+
+type RankingStore interface {
+	// RecordView counts a view of the book by a signed in user.
+	RecordView(ctx context.Context, bookId, userId int) error
+	Refresh(ctx context.Context, policy RankingPolicy) error
+	PruneEvents(ctx context.Context, before time.Time) (int64, error)
+	FindRanking(ctx context.Context, ranking string, categoryId, limit, offset int) ([]*RankedBook, error)
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ReadingStatusWantToRead = "want_to_read"
+	ReadingStatusReading    = "reading"
+	ReadingStatusFinished   = "finished"
+)
+
+type ReadingProgress struct {
+	UserID     int
+	BookID     int
+	Position   string
+	Percentage float64
+	UpdatedAt  time.Time
+}
+
+type ReadingStatus struct {
+	UserID    int
+	BookID    int
+	Status    string
+	UpdatedAt time.Time
+}
+
+type ReadingStore interface {
+	UpsertProgress(ctx context.Context, progress *ReadingProgress) error
+	FindProgressByUserId(ctx context.Context, userId, limit, offset int) ([]*ReadingProgress, error)
+	FindOneProgress(ctx context.Context, userId, bookId int) (*ReadingProgress, error)
+	UpsertStatus(ctx context.Context, status *ReadingStatus) error
+	DeleteStatus(ctx context.Context, userId, bookId int, status string) error
+	FindStatusByUserId(ctx context.Context, userId int, status string, limit, offset int) ([]*ReadingStatus, error)
+	CountStatusByUserId(ctx context.Context, userId int) (map[string]int, error)
+}
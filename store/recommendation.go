@@ -0,0 +1,37 @@
+package store
+
+import "context"
+
+// Interaction is how strongly a user showed interest in a book, between 0
+// and 1. A rating decides it when there is one, reading history otherwise.
+type Interaction struct {
+	UserID int
+	BookID int
+	Weight float64
+}
+
+type BookSimilarity struct {
+	BookID        int
+	SimilarBookID int
+	Score         float64
+}
+
+type Recommendation struct {
+	BookID     int
+	Title      string
+	CategoryID int
+	Score      float64
+}
+
+type RecommendationStore interface {
+	// StreamInteractions calls fn for every interaction, grouped by user.
+	StreamInteractions(ctx context.Context, fn func(interaction *Interaction) error) error
+	ReplaceSimilarities(ctx context.Context, similarities []*BookSimilarity) error
+	FindForUser(ctx context.Context, userId, limit int) ([]*Recommendation, error)
+	FindSimilar(ctx context.Context, bookId, limit int) ([]*Recommendation, error)
+	// FindPopular ranks books by popularity within each category and takes
+	// the top of every category in turn. A non zero userId leaves out the
+	// books the user already knows and keeps to the categories they read,
+	// a non zero categoryId keeps to that category.
+	FindPopular(ctx context.Context, userId, categoryId, limit int) ([]*Recommendation, error)
+}
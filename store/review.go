@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+	ReviewStatusHidden   = "hidden"
+)
+
+const (
+	ReviewSortHelpful = "helpful"
+	ReviewSortNewest  = "newest"
+)
+
+// ReviewQueueFlagged selects reviews with unresolved flags, whatever their
+// status, when listing the moderation queue.
+const ReviewQueueFlagged = "flagged"
+
+type ReviewError string
+
+func (e ReviewError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrReviewAlreadyWritten = ReviewError("review is already written for the book")
+	ErrReviewOwn            = ReviewError("review is written by the same user")
+)
+
+type Review struct {
+	ID             int
+	BookID         int
+	UserID         int
+	Reviewer       string
+	Rating         float64
+	Body           string
+	Status         string
+	HelpfulCount   int
+	FlagCount      int
+	ModeratedBy    sql.NullInt64
+	ModeratedAt    sql.NullTime
+	ModerationNote string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type ReviewRevision struct {
+	ID        int
+	ReviewID  int
+	Body      string
+	Rating    float64
+	CreatedAt time.Time
+}
+
+type ReviewFlag struct {
+	ReviewID  int
+	UserID    int
+	Reason    string
+	CreatedAt time.Time
+}
+
+type ReviewStore interface {
+	Insert(ctx context.Context, review *Review) error
+	Update(ctx context.Context, review *Review) error
+	Delete(ctx context.Context, id, userId int) error
+	FindOneById(ctx context.Context, id int) (*Review, error)
+	FindApprovedByBookId(ctx context.Context, bookId int, sort string, limit, offset int) ([]*Review, error)
+	FindForModeration(ctx context.Context, queue string, limit, offset int) ([]*Review, error)
+	Moderate(ctx context.Context, id, moderatorId int, status, note string) (*Review, error)
+	FindRevisionsByReviewId(ctx context.Context, id int) ([]*ReviewRevision, error)
+	FindOpenFlagsByReviewId(ctx context.Context, id int) ([]*ReviewFlag, error)
+	Flag(ctx context.Context, id, userId int, reason string) error
+	Vote(ctx context.Context, id, userId int) (*Review, error)
+	Unvote(ctx context.Context, id, userId int) (*Review, error)
+}
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'patron';
+
+ALTER TABLE books ALTER COLUMN cover SET DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS cover_updated_at TIMESTAMPTZ;
+
+COMMIT;
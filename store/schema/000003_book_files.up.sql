@@ -0,0 +1,20 @@
+BEGIN;
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS language VARCHAR(35) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS book_files (
+  id SERIAL NOT NULL,
+  book_id INT NOT NULL,
+  format VARCHAR(10) NOT NULL,
+  blob_key VARCHAR(150) NOT NULL,
+  content_type VARCHAR(100) NOT NULL,
+  size BIGINT NOT NULL,
+  metadata JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT book_files__pkey PRIMARY KEY (id),
+  CONSTRAINT book_files__books__fk FOREIGN KEY (book_id) REFERENCES books(id),
+  CONSTRAINT book_files__book_format__key UNIQUE (book_id, format)
+);
+
+COMMIT;
@@ -0,0 +1,65 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS book_readers (
+  book_id INT NOT NULL,
+  user_id INT NOT NULL,
+  first_read_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT book_readers__pkey PRIMARY KEY (book_id, user_id),
+  CONSTRAINT book_readers__books__fk FOREIGN KEY (book_id) REFERENCES books(id),
+  CONSTRAINT book_readers__users__fk FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS book_readers__users__idx ON book_readers(user_id);
+
+CREATE TABLE IF NOT EXISTS reading_progress (
+  user_id INT NOT NULL,
+  book_id INT NOT NULL,
+  position TEXT NOT NULL DEFAULT '',
+  percentage REAL NOT NULL DEFAULT 0,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT reading_progress__pkey PRIMARY KEY (user_id, book_id),
+  CONSTRAINT reading_progress__books__fk FOREIGN KEY (book_id) REFERENCES books(id),
+  CONSTRAINT reading_progress__users__fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT reading_progress__percentage__check CHECK (percentage BETWEEN 0 AND 100)
+);
+CREATE INDEX IF NOT EXISTS reading_progress__updated_at__idx ON reading_progress(user_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS reading_status (
+  user_id INT NOT NULL,
+  book_id INT NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT reading_status__pkey PRIMARY KEY (user_id, book_id),
+  CONSTRAINT reading_status__books__fk FOREIGN KEY (book_id) REFERENCES books(id),
+  CONSTRAINT reading_status__users__fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT reading_status__status__check CHECK (status IN ('want_to_read', 'reading', 'finished'))
+);
+CREATE INDEX IF NOT EXISTS reading_status__status__idx ON reading_status(user_id, status);
+
+CREATE TABLE IF NOT EXISTS shelves (
+  id SERIAL NOT NULL,
+  user_id INT NOT NULL,
+  name VARCHAR(60) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT shelves__pkey PRIMARY KEY (id),
+  CONSTRAINT shelves__users__fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT shelves__user_name__key UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS shelf_books (
+  shelf_id INT NOT NULL,
+  book_id INT NOT NULL,
+  added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT shelf_books__pkey PRIMARY KEY (shelf_id, book_id),
+  CONSTRAINT shelf_books__shelves__fk FOREIGN KEY (shelf_id) REFERENCES shelves(id) ON DELETE CASCADE,
+  CONSTRAINT shelf_books__books__fk FOREIGN KEY (book_id) REFERENCES books(id)
+);
+
+-- books.reader counts distinct readers from now on. The downloads it
+-- counted so far name no user, they are kept as they are.
+
+COMMIT;
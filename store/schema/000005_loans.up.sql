@@ -0,0 +1,26 @@
+BEGIN;
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS copies INT NOT NULL DEFAULT 1;
+ALTER TABLE books ADD CONSTRAINT books__copies__check CHECK (copies >= 0);
+
+CREATE TABLE IF NOT EXISTS loans (
+  id SERIAL NOT NULL,
+  book_id INT NOT NULL,
+  user_id INT NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'active',
+  borrowed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  due_at TIMESTAMPTZ NOT NULL,
+  returned_at TIMESTAMPTZ,
+  renew_count INT NOT NULL DEFAULT 0,
+
+  CONSTRAINT loans__pkey PRIMARY KEY (id),
+  CONSTRAINT loans__books__fk FOREIGN KEY (book_id) REFERENCES books(id),
+  CONSTRAINT loans__users__fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT loans__status__check CHECK (status IN ('active', 'returned', 'expired'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS loans__active_user_book__key ON loans(user_id, book_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS loans__active_book__idx ON loans(book_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS loans__active_due_at__idx ON loans(due_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS loans__users__idx ON loans(user_id, borrowed_at DESC);
+
+COMMIT;
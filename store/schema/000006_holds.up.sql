@@ -0,0 +1,23 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS holds (
+  id SERIAL NOT NULL,
+  book_id INT NOT NULL,
+  user_id INT NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'waiting',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ready_at TIMESTAMPTZ,
+  expires_at TIMESTAMPTZ,
+  closed_at TIMESTAMPTZ,
+
+  CONSTRAINT holds__pkey PRIMARY KEY (id),
+  CONSTRAINT holds__books__fk FOREIGN KEY (book_id) REFERENCES books(id),
+  CONSTRAINT holds__users__fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT holds__status__check CHECK (status IN ('waiting', 'ready', 'fulfilled', 'cancelled', 'expired'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS holds__open_user_book__key ON holds(user_id, book_id) WHERE status IN ('waiting', 'ready');
+CREATE INDEX IF NOT EXISTS holds__waiting_book__idx ON holds(book_id, created_at, id) WHERE status = 'waiting';
+CREATE INDEX IF NOT EXISTS holds__ready_expires_at__idx ON holds(expires_at) WHERE status = 'ready';
+CREATE INDEX IF NOT EXISTS holds__users__idx ON holds(user_id, created_at DESC);
+
+COMMIT;
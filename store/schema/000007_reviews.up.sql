@@ -0,0 +1,62 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS reviews (
+  id SERIAL NOT NULL,
+  book_id INT NOT NULL,
+  user_id INT NOT NULL,
+  body TEXT NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'pending',
+  helpful_count INT NOT NULL DEFAULT 0,
+  flag_count INT NOT NULL DEFAULT 0,
+  moderated_by INT,
+  moderated_at TIMESTAMPTZ,
+  moderation_note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT reviews__pkey PRIMARY KEY (id),
+  CONSTRAINT reviews__book_user__key UNIQUE (book_id, user_id),
+  CONSTRAINT reviews__book_rating__fk FOREIGN KEY (book_id, user_id) REFERENCES book_rating(book_id, user_id) ON DELETE CASCADE,
+  CONSTRAINT reviews__moderated_by__fk FOREIGN KEY (moderated_by) REFERENCES users(id),
+  CONSTRAINT reviews__status__check CHECK (status IN ('pending', 'approved', 'rejected', 'hidden'))
+);
+CREATE INDEX IF NOT EXISTS reviews__approved_helpful__idx ON reviews(book_id, helpful_count DESC, created_at DESC) WHERE status = 'approved';
+CREATE INDEX IF NOT EXISTS reviews__approved_created_at__idx ON reviews(book_id, created_at DESC) WHERE status = 'approved';
+CREATE INDEX IF NOT EXISTS reviews__pending__idx ON reviews(updated_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS reviews__flagged__idx ON reviews(flag_count DESC) WHERE flag_count > 0;
+
+CREATE TABLE IF NOT EXISTS review_revisions (
+  id SERIAL NOT NULL,
+  review_id INT NOT NULL,
+  body TEXT NOT NULL,
+  rating REAL NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT review_revisions__pkey PRIMARY KEY (id),
+  CONSTRAINT review_revisions__reviews__fk FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS review_revisions__reviews__idx ON review_revisions(review_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS review_flags (
+  review_id INT NOT NULL,
+  user_id INT NOT NULL,
+  reason TEXT NOT NULL,
+  resolved BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT review_flags__pkey PRIMARY KEY (review_id, user_id),
+  CONSTRAINT review_flags__reviews__fk FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE,
+  CONSTRAINT review_flags__users__fk FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS review_votes (
+  review_id INT NOT NULL,
+  user_id INT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT review_votes__pkey PRIMARY KEY (review_id, user_id),
+  CONSTRAINT review_votes__reviews__fk FOREIGN KEY (review_id) REFERENCES reviews(id) ON DELETE CASCADE,
+  CONSTRAINT review_votes__users__fk FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+COMMIT;
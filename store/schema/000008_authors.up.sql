@@ -0,0 +1,54 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS authors (
+  id SERIAL NOT NULL,
+  name VARCHAR(128) NOT NULL,
+  bio TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT authors__pkey PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS authors__name__key ON authors(lower(name));
+
+CREATE TABLE IF NOT EXISTS book_authors (
+  book_id INT NOT NULL,
+  author_id INT NOT NULL,
+  role VARCHAR(10) NOT NULL DEFAULT 'author',
+  position SMALLINT NOT NULL DEFAULT 0,
+
+  CONSTRAINT book_authors__pkey PRIMARY KEY (book_id, author_id, role),
+  CONSTRAINT book_authors__books__fk FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  CONSTRAINT book_authors__authors__fk FOREIGN KEY (author_id) REFERENCES authors(id),
+  CONSTRAINT book_authors__role__check CHECK (role IN ('author', 'editor', 'translator'))
+);
+CREATE INDEX IF NOT EXISTS book_authors__authors__idx ON book_authors(author_id);
+
+-- books.author stays as a display string kept in sync with book_authors,
+-- it is no longer limited to a single short name.
+ALTER TABLE books ALTER COLUMN author TYPE TEXT;
+
+-- Split the existing free text authors on ";", "&" and " and ", and link
+-- every part, in the order it was written, as an author of the book. Commas
+-- are left alone, as in "Tolkien, J. R. R." they do not separate authors:
+-- such values stay one author for manual cleanup.
+CREATE TEMP TABLE author_parts ON COMMIT DROP AS
+SELECT b.id AS book_id, left(btrim(p.name), 128) AS name, p.ord AS position
+FROM books b,
+LATERAL regexp_split_to_table(b.author, '\s*(;|&|\s+and\s+)\s*') WITH ORDINALITY AS p(name, ord)
+WHERE btrim(p.name) <> '';
+
+INSERT INTO authors (name)
+SELECT DISTINCT ON (lower(name)) name
+FROM author_parts
+ORDER BY lower(name), name
+ON CONFLICT (lower(name)) DO NOTHING;
+
+INSERT INTO book_authors (book_id, author_id, role, position)
+SELECT DISTINCT ON (ap.book_id, a.id) ap.book_id, a.id, 'author', ap.position - 1
+FROM author_parts ap
+JOIN authors a ON lower(a.name) = lower(ap.name)
+ORDER BY ap.book_id, a.id, ap.position
+ON CONFLICT DO NOTHING;
+
+COMMIT;
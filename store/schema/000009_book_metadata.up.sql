@@ -0,0 +1,27 @@
+BEGIN;
+
+-- A work groups the editions of the same book, each edition being a row
+-- of books with its own identifiers.
+CREATE TABLE IF NOT EXISTS works (
+  id SERIAL NOT NULL,
+  title VARCHAR(60) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT works__pkey PRIMARY KEY (id)
+);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn13 CHAR(13);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn10 CHAR(10);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS publisher VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS published_date DATE;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS page_count INT;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS edition VARCHAR(60) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS work_id INT;
+
+ALTER TABLE books ADD CONSTRAINT books__isbn13__key UNIQUE (isbn13);
+ALTER TABLE books ADD CONSTRAINT books__isbn10__key UNIQUE (isbn10);
+ALTER TABLE books ADD CONSTRAINT books__page_count__check CHECK (page_count > 0);
+ALTER TABLE books ADD CONSTRAINT books__works__fk FOREIGN KEY (work_id) REFERENCES works(id);
+CREATE INDEX IF NOT EXISTS books__works__idx ON books(work_id);
+
+COMMIT;
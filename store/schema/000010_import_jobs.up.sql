@@ -0,0 +1,38 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+  id SERIAL NOT NULL,
+  user_id INT NOT NULL,
+  format VARCHAR(10) NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'queued',
+  dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+  blob_key VARCHAR(150) NOT NULL,
+  options JSONB NOT NULL DEFAULT '{}',
+  total INT NOT NULL DEFAULT 0,
+  created INT NOT NULL DEFAULT 0,
+  updated INT NOT NULL DEFAULT 0,
+  failed INT NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at TIMESTAMPTZ,
+  heartbeat_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ,
+
+  CONSTRAINT import_jobs__pkey PRIMARY KEY (id),
+  CONSTRAINT import_jobs__users__fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT import_jobs__format__check CHECK (format IN ('csv', 'marc', 'marcxml')),
+  CONSTRAINT import_jobs__status__check CHECK (status IN ('queued', 'running', 'succeeded', 'failed'))
+);
+CREATE INDEX IF NOT EXISTS import_jobs__queued__idx ON import_jobs(id) WHERE status = 'queued';
+
+CREATE TABLE IF NOT EXISTS import_job_errors (
+  job_id INT NOT NULL,
+  row_number INT NOT NULL,
+  isbn VARCHAR(20) NOT NULL DEFAULT '',
+  message TEXT NOT NULL,
+
+  CONSTRAINT import_job_errors__pkey PRIMARY KEY (job_id, row_number),
+  CONSTRAINT import_job_errors__import_jobs__fk FOREIGN KEY (job_id) REFERENCES import_jobs(id) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+  id SERIAL NOT NULL,
+  user_id INT NOT NULL,
+  format VARCHAR(10) NOT NULL,
+  filter JSONB NOT NULL DEFAULT '{}',
+  status VARCHAR(10) NOT NULL DEFAULT 'queued',
+  blob_key VARCHAR(150) NOT NULL DEFAULT '',
+  total INT NOT NULL DEFAULT 0,
+  size BIGINT NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at TIMESTAMPTZ,
+  heartbeat_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ,
+
+  CONSTRAINT export_jobs__pkey PRIMARY KEY (id),
+  CONSTRAINT export_jobs__users__fk FOREIGN KEY (user_id) REFERENCES users(id),
+  CONSTRAINT export_jobs__format__check CHECK (format IN ('csv', 'jsonl', 'marcxml')),
+  CONSTRAINT export_jobs__status__check CHECK (status IN ('queued', 'running', 'succeeded', 'failed'))
+);
+CREATE INDEX IF NOT EXISTS export_jobs__queued__idx ON export_jobs(id) WHERE status = 'queued';
+
+COMMIT;
@@ -0,0 +1,49 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS collections (
+  id SERIAL NOT NULL,
+  user_id INT NOT NULL,
+  slug VARCHAR(80) NOT NULL,
+  title VARCHAR(100) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  visibility VARCHAR(10) NOT NULL DEFAULT 'private',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT collections__pkey PRIMARY KEY (id),
+  CONSTRAINT collections__slug__key UNIQUE (slug),
+  CONSTRAINT collections__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT collections__visibility__check CHECK (visibility IN ('private', 'unlisted', 'public'))
+);
+CREATE INDEX IF NOT EXISTS collections__users__idx ON collections(user_id);
+CREATE INDEX IF NOT EXISTS collections__public__idx ON collections(updated_at DESC) WHERE visibility = 'public';
+
+CREATE TABLE IF NOT EXISTS collection_items (
+  collection_id INT NOT NULL,
+  book_id INT NOT NULL,
+  position INT NOT NULL,
+  note TEXT NOT NULL DEFAULT '',
+  added_by INT,
+  added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT collection_items__pkey PRIMARY KEY (collection_id, book_id),
+  CONSTRAINT collection_items__collections__fk FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+  CONSTRAINT collection_items__books__fk FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  CONSTRAINT collection_items__added_by__fk FOREIGN KEY (added_by) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS collection_items__position__idx ON collection_items(collection_id, position);
+
+CREATE TABLE IF NOT EXISTS collection_collaborators (
+  collection_id INT NOT NULL,
+  user_id INT NOT NULL,
+  invited_by INT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT collection_collaborators__pkey PRIMARY KEY (collection_id, user_id),
+  CONSTRAINT collection_collaborators__collections__fk FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+  CONSTRAINT collection_collaborators__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT collection_collaborators__invited_by__fk FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS collection_collaborators__users__idx ON collection_collaborators(user_id);
+
+COMMIT;
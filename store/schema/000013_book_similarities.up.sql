@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS book_similarities (
+  book_id INT NOT NULL,
+  similar_book_id INT NOT NULL,
+  score REAL NOT NULL,
+  computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT book_similarities__pkey PRIMARY KEY (book_id, similar_book_id),
+  CONSTRAINT book_similarities__books__fk FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  CONSTRAINT book_similarities__similar_books__fk FOREIGN KEY (similar_book_id) REFERENCES books(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS book_similarities__score__idx ON book_similarities(book_id, score DESC);
+
+COMMIT;
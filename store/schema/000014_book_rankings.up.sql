@@ -0,0 +1,42 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS book_events (
+  id BIGSERIAL NOT NULL,
+  book_id INT NOT NULL,
+  user_id INT,
+  kind VARCHAR(8) NOT NULL,
+  occurred_on DATE NOT NULL DEFAULT CURRENT_DATE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT book_events__pkey PRIMARY KEY (id),
+  CONSTRAINT book_events__books__fk FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  CONSTRAINT book_events__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+  CONSTRAINT book_events__kind__check CHECK (kind IN ('view', 'read', 'loan'))
+);
+-- A user counts once a day per book and kind, so progress syncs and page
+-- reloads do not inflate the rankings.
+CREATE UNIQUE INDEX IF NOT EXISTS book_events__daily__key ON book_events(kind, book_id, user_id, occurred_on) WHERE user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS book_events__created_at__idx ON book_events(created_at);
+
+CREATE TABLE IF NOT EXISTS book_rankings (
+  ranking VARCHAR(16) NOT NULL,
+  category_id INT NOT NULL,
+  position INT NOT NULL,
+  book_id INT NOT NULL,
+  score DOUBLE PRECISION NOT NULL,
+  computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT book_rankings__pkey PRIMARY KEY (ranking, category_id, position),
+  CONSTRAINT book_rankings__books__fk FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  CONSTRAINT book_rankings__ranking__check CHECK (ranking IN ('trending', 'most_read', 'top_rated'))
+);
+
+-- Seed the read and loan history we already have.
+INSERT INTO book_events (book_id, user_id, kind, occurred_on, created_at)
+SELECT book_id, user_id, 'read', first_read_at::DATE, first_read_at FROM book_readers
+ON CONFLICT DO NOTHING;
+INSERT INTO book_events (book_id, user_id, kind, occurred_on, created_at)
+SELECT book_id, user_id, 'loan', borrowed_at::DATE, borrowed_at FROM loans
+ON CONFLICT DO NOTHING;
+
+COMMIT;
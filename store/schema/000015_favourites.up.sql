@@ -0,0 +1,58 @@
+BEGIN;
+
+-- An available alert goes out when was_available flips.
+CREATE TABLE IF NOT EXISTS favourite_books (
+  user_id INT NOT NULL,
+  book_id INT NOT NULL,
+  was_available BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT favourite_books__pkey PRIMARY KEY (user_id, book_id),
+  CONSTRAINT favourite_books__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT favourite_books__books__fk FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS favourite_books__books__idx ON favourite_books(book_id);
+
+-- Books above last_book_id are new to the user.
+CREATE TABLE IF NOT EXISTS favourite_authors (
+  user_id INT NOT NULL,
+  author_id INT NOT NULL,
+  last_book_id INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT favourite_authors__pkey PRIMARY KEY (user_id, author_id),
+  CONSTRAINT favourite_authors__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT favourite_authors__authors__fk FOREIGN KEY (author_id) REFERENCES authors(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS favourite_authors__authors__idx ON favourite_authors(author_id);
+
+CREATE TABLE IF NOT EXISTS alert_preferences (
+  user_id INT NOT NULL,
+  alert_type VARCHAR(16) NOT NULL,
+  frequency VARCHAR(8) NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT alert_preferences__pkey PRIMARY KEY (user_id, alert_type),
+  CONSTRAINT alert_preferences__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT alert_preferences__alert_type__check CHECK (alert_type IN ('new_book', 'available')),
+  CONSTRAINT alert_preferences__frequency__check CHECK (frequency IN ('instant', 'weekly'))
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+  id SERIAL NOT NULL,
+  user_id INT NOT NULL,
+  alert_type VARCHAR(16) NOT NULL,
+  book_id INT NOT NULL,
+  author_id INT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  sent_at TIMESTAMPTZ,
+
+  CONSTRAINT alerts__pkey PRIMARY KEY (id),
+  CONSTRAINT alerts__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT alerts__books__fk FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  CONSTRAINT alerts__authors__fk FOREIGN KEY (author_id) REFERENCES authors(id) ON DELETE CASCADE,
+  CONSTRAINT alerts__alert_type__check CHECK (alert_type IN ('new_book', 'available'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS alerts__pending__key ON alerts(user_id, alert_type, book_id) WHERE sent_at IS NULL;
+
+COMMIT;
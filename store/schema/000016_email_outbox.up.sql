@@ -0,0 +1,25 @@
+BEGIN;
+
+-- A mail left sending by a dead worker is taken over once locked_at is
+-- stale.
+CREATE TABLE IF NOT EXISTS email_outbox (
+  id SERIAL NOT NULL,
+  kind VARCHAR(32) NOT NULL,
+  recipient VARCHAR(128) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  body TEXT NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  locked_at TIMESTAMPTZ,
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  sent_at TIMESTAMPTZ,
+
+  CONSTRAINT email_outbox__pkey PRIMARY KEY (id),
+  CONSTRAINT email_outbox__status__check CHECK (status IN ('pending', 'sending', 'sent', 'dead'))
+);
+CREATE INDEX IF NOT EXISTS email_outbox__due__idx ON email_outbox(next_attempt_at) WHERE status IN ('pending', 'sending');
+CREATE INDEX IF NOT EXISTS email_outbox__status__idx ON email_outbox(status, id);
+
+COMMIT;
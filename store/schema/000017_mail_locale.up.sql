@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(16) NOT NULL DEFAULT 'en';
+
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS html_body TEXT NOT NULL DEFAULT '';
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Users without a preference for a category get its mails.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  user_id INT NOT NULL,
+  category VARCHAR(16) NOT NULL,
+  enabled BOOLEAN NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT notification_preferences__pkey PRIMARY KEY (user_id, category),
+  CONSTRAINT notification_preferences__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT notification_preferences__category__check CHECK (category IN ('holds', 'alerts'))
+);
+
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS unsubscribe_url TEXT NOT NULL DEFAULT '';
+
+COMMIT;
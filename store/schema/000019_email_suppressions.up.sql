@@ -0,0 +1,20 @@
+BEGIN;
+
+ALTER TABLE email_outbox ALTER COLUMN status TYPE VARCHAR(16);
+ALTER TABLE email_outbox DROP CONSTRAINT IF EXISTS email_outbox__status__check;
+ALTER TABLE email_outbox ADD CONSTRAINT email_outbox__status__check
+  CHECK (status IN ('pending', 'sending', 'sent', 'dead', 'undeliverable'));
+
+-- email is kept lowercased.
+CREATE TABLE IF NOT EXISTS email_suppressions (
+  email VARCHAR(128) NOT NULL,
+  reason VARCHAR(16) NOT NULL,
+  detail TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT email_suppressions__pkey PRIMARY KEY (email),
+  CONSTRAINT email_suppressions__reason__check CHECK (reason IN ('hard_bounce', 'complaint', 'manual'))
+);
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+-- The client renders the text of a notification out of its data.
+CREATE TABLE IF NOT EXISTS notifications (
+  id SERIAL NOT NULL,
+  user_id INT NOT NULL,
+  kind VARCHAR(32) NOT NULL,
+  data JSONB NOT NULL DEFAULT '{}',
+  read_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT notifications__pkey PRIMARY KEY (id),
+  CONSTRAINT notifications__users__fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT notifications__kind__check CHECK (kind IN ('loan_due', 'hold_ready', 'review_approved'))
+);
+CREATE INDEX IF NOT EXISTS notifications__users__idx ON notifications(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS notifications__unread__idx ON notifications(user_id) WHERE read_at IS NULL;
+
+-- pg_notify sends once the transaction commits.
+CREATE OR REPLACE FUNCTION notifications__notify() RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('notifications', row_to_json(NEW)::TEXT);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notifications__notify ON notifications;
+CREATE TRIGGER notifications__notify AFTER INSERT ON notifications
+  FOR EACH ROW EXECUTE FUNCTION notifications__notify();
+
+-- A renewal moves due_at past due_notified_for, the reminder goes out
+-- again.
+ALTER TABLE loans ADD COLUMN IF NOT EXISTS due_notified_for TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,43 @@
+BEGIN;
+
+-- The deliveries of a disabled subscription wait until it is enabled again.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+  id SERIAL NOT NULL,
+  url TEXT NOT NULL,
+  events VARCHAR(32)[] NOT NULL,
+  secret VARCHAR(128) NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  failure_count INT NOT NULL DEFAULT 0,
+  disabled_at TIMESTAMPTZ,
+  created_by INT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT webhook_subscriptions__pkey PRIMARY KEY (id),
+  CONSTRAINT webhook_subscriptions__users__fk FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- A delivery keeps the outcome of its last attempt.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id SERIAL NOT NULL,
+  subscription_id INT NOT NULL,
+  event VARCHAR(32) NOT NULL,
+  payload JSONB NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  locked_at TIMESTAMPTZ,
+  response_status INT,
+  response_body TEXT NOT NULL DEFAULT '',
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  delivered_at TIMESTAMPTZ,
+
+  CONSTRAINT webhook_deliveries__pkey PRIMARY KEY (id),
+  CONSTRAINT webhook_deliveries__webhook_subscriptions__fk FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+  CONSTRAINT webhook_deliveries__status__check CHECK (status IN ('pending', 'sending', 'succeeded', 'failed'))
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries__due__idx ON webhook_deliveries(next_attempt_at) WHERE status IN ('pending', 'sending');
+CREATE INDEX IF NOT EXISTS webhook_deliveries__webhook_subscriptions__idx ON webhook_deliveries(subscription_id, id DESC);
+
+COMMIT;
@@ -0,0 +1,51 @@
+BEGIN;
+
+-- A retry only runs the subscribers missing from handled.
+CREATE TABLE IF NOT EXISTS domain_events (
+  id BIGSERIAL NOT NULL,
+  event_type VARCHAR(32) NOT NULL,
+  aggregate_id INT NOT NULL,
+  actor_id INT,
+  payload JSONB NOT NULL,
+  status VARCHAR(11) NOT NULL DEFAULT 'pending',
+  handled VARCHAR(32)[] NOT NULL DEFAULT '{}',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  locked_at TIMESTAMPTZ,
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  dispatched_at TIMESTAMPTZ,
+
+  CONSTRAINT domain_events__pkey PRIMARY KEY (id),
+  CONSTRAINT domain_events__status__check CHECK (status IN ('pending', 'dispatching', 'dispatched', 'failed'))
+);
+CREATE INDEX IF NOT EXISTS domain_events__due__idx ON domain_events(next_attempt_at) WHERE status IN ('pending', 'dispatching');
+
+-- No foreign keys, entries outlive what they are about.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGSERIAL NOT NULL,
+  event_id BIGINT NOT NULL,
+  event_type VARCHAR(32) NOT NULL,
+  aggregate_id INT NOT NULL,
+  actor_id INT,
+  payload JSONB NOT NULL,
+  occurred_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CONSTRAINT audit_log__pkey PRIMARY KEY (id),
+  CONSTRAINT audit_log__event_id__key UNIQUE (event_id)
+);
+CREATE INDEX IF NOT EXISTS audit_log__actor__idx ON audit_log(actor_id, id DESC);
+
+-- search_text is kept up to date by the search index subscriber.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '';
+UPDATE books b SET search_text = lower(concat_ws(' ',
+  b.title, b.author, b.isbn13, b.isbn10, b.publisher,
+  (SELECT string_agg(a.name, ' ') FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE ba.book_id = b.id)
+));
+CREATE INDEX IF NOT EXISTS books__search_text__idx ON books USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS books__lower_title__idx ON books USING GIN (lower(title) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS books__lower_author__idx ON books USING GIN (lower(author) gin_trgm_ops);
+
+COMMIT;
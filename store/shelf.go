@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type Shelf struct {
+	ID        int
+	UserID    int
+	Name      string
+	BookCount int
+	CreatedAt time.Time
+}
+
+type ShelfBook struct {
+	ShelfID int
+	BookID  int
+	AddedAt time.Time
+}
+
+type ShelfStore interface {
+	Insert(ctx context.Context, shelf *Shelf) error
+	FindByUserId(ctx context.Context, userId int) ([]*Shelf, error)
+	FindOneById(ctx context.Context, id int) (*Shelf, error)
+	DeleteById(ctx context.Context, id int) error
+	AddBook(ctx context.Context, shelfId, bookId int) error
+	RemoveBook(ctx context.Context, shelfId, bookId int) error
+	FindBooksByShelfId(ctx context.Context, shelfId, limit, offset int) ([]*ShelfBook, error)
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	SuppressionReasonHardBounce = "hard_bounce"
+	SuppressionReasonComplaint  = "complaint"
+	SuppressionReasonManual     = "manual"
+)
+
+// Suppression is an address no mail is sent to anymore, Detail tells why,
+// such as the diagnostic of the bounce.
+type Suppression struct {
+	Email     string
+	Reason    string
+	Detail    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type SuppressionStore interface {
+	// Add suppresses the address, or updates the reason and detail of an
+	// address already suppressed.
+	Add(ctx context.Context, suppression *Suppression) error
+	Remove(ctx context.Context, email string) error
+	FindAll(ctx context.Context, limit, offset int) ([]*Suppression, error)
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+}
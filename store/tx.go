@@ -0,0 +1,23 @@
+package store
+
+import "context"
+
+type TxError string
+
+func (e TxError) Error() string {
+	return string(e)
+}
+
+// ErrAlreadyExists is returned by the stores and TxManager.WithTx when a
+// write broke a unique constraint, as when two requests create the same
+// row.
+const ErrAlreadyExists = TxError("row already exists")
+
+type TxManager interface {
+	// WithTx runs fn in one transaction, carried by the ctx fn gets: every
+	// store call made with it joins the transaction, which commits when fn
+	// returns nil and rolls back otherwise. fn runs again from the start
+	// after a serialization failure or deadlock, so it must not have side
+	// effects outside the stores. Nested calls join the outer transaction.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -11,25 +11,46 @@ type User struct {
 	Password          sql.NullString
 	Fullname          string
 	IsVerified        bool
+	Role              string
+	Locale            string
 	TokenID           sql.NullString
 	TokenVerification sql.NullString
 	TokenExpiration   sql.NullString
 }
 
+const (
+	RolePatron    = "patron"
+	RoleLibrarian = "librarian"
+	RoleAdmin     = "admin"
+)
+
 type UserRegister struct {
+	ID                int
 	Email             string
 	Password          string
 	Fullname          string
 	IsVerified        bool
+	Locale            string
 	TokenVerification string
 	TokenExpiration   string
 }
 
 type UserStore interface {
+	// Insert registers the user and records user.registered with it.
 	Insert(ctx context.Context, usr *UserRegister) error
 	FindOneById(ctx context.Context, id int) (*User, error)
 	FindOneByEmail(ctx context.Context, email string) (*User, error)
 	FindOneCredentialByEmail(ctx context.Context, email string) (*User, error)
 	UpdateTokenIdById(ctx context.Context, token string, id int) error
 	DeleteTokenIdById(ctx context.Context, id int) error
+	UpdateLocaleById(ctx context.Context, locale string, id int) error
+	// Verify activates the user when the token is theirs and not expired,
+	// it returns sql.ErrNoRows otherwise, as for a user already verified.
+	// It records user.verified with the change.
+	Verify(ctx context.Context, id int, token string) (*User, error)
+	// UpdateRoleById returns sql.ErrNoRows when there is no such user.
+	UpdateRoleById(ctx context.Context, role string, id int) error
+	// UpdateRoleByEmails gives the role to the verified users with one of
+	// the emails, compared case insensitively, and returns how many changed.
+	UpdateRoleByEmails(ctx context.Context, role string, emails []string) (int64, error)
 }
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Webhook events are the domain events of the same name.
+const (
+	WebhookEventBookCreated  = EventBookCreated
+	WebhookEventBookUpdated  = EventBookUpdated
+	WebhookEventLoanCreated  = EventLoanCreated
+	WebhookEventUserVerified = EventUserVerified
+)
+
+// WebhookEvents lists every event a subscription can listen to.
+var WebhookEvents = []string{
+	WebhookEventBookCreated,
+	WebhookEventBookUpdated,
+	WebhookEventLoanCreated,
+	WebhookEventUserVerified,
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySending   = "sending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription posts the events it listens to to URL, signed with
+// Secret. FailureCount counts the failed attempts in a row.
+type WebhookSubscription struct {
+	ID           int
+	URL          string
+	Events       []string
+	Secret       string
+	Active       bool
+	FailureCount int
+	DisabledAt   sql.NullTime
+	CreatedBy    sql.NullInt64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// WebhookDelivery is one event on its way to one subscription, with the
+// outcome of its last attempt. URL and Secret are those of the
+// subscription, only set on claimed deliveries.
+type WebhookDelivery struct {
+	ID             int
+	SubscriptionID int
+	Event          string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	ResponseStatus sql.NullInt64
+	ResponseBody   string
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    sql.NullTime
+	URL            string
+	Secret         string
+}
+
+type WebhookStore interface {
+	InsertSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	// UpdateSubscription saves the url, events, secret and active flag,
+	// enabling a subscription again clears its failure count.
+	UpdateSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int) error
+	FindSubscriptionById(ctx context.Context, id int) (*WebhookSubscription, error)
+	FindSubscriptions(ctx context.Context, limit, offset int) ([]*WebhookSubscription, error)
+	// Enqueue queues a delivery of the payload to every active
+	// subscription listening to the event and returns how many were queued.
+	Enqueue(ctx context.Context, event string, payload []byte) (int64, error)
+	// Claim moves up to limit due deliveries of active subscriptions to
+	// sending and counts the attempt, taking over deliveries whose worker
+	// did not report back within staleAfter.
+	Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]*WebhookDelivery, error)
+	// MarkDelivered records the response of a successful attempt and clears
+	// the failure count of the subscription.
+	MarkDelivered(ctx context.Context, delivery *WebhookDelivery) error
+	// MarkFailed records the response or error of a failed attempt and
+	// schedules the next one, or gives up when dead is set. It counts the
+	// failure against the subscription and disables it after disableAfter
+	// failures in a row, telling whether it did.
+	MarkFailed(ctx context.Context, delivery *WebhookDelivery, nextAttemptAt time.Time, dead bool, disableAfter int) (bool, error)
+	FindDeliveryById(ctx context.Context, id int) (*WebhookDelivery, error)
+	FindDeliveriesBySubscriptionId(ctx context.Context, subscriptionId, limit, offset int) ([]*WebhookDelivery, error)
+	// Redeliver queues a new delivery of the payload of a past one.
+	Redeliver(ctx context.Context, id int) (*WebhookDelivery, error)
+}
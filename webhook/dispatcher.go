@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"awesome-api/scheduler"
+	"awesome-api/store"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// responseBodyLimit is how much of a response body the delivery log
+	// keeps.
+	responseBodyLimit = 2 << 10
+	userAgent         = "awesome-api-webhooks/1.0"
+)
+
+// DispatcherConfig disables a subscription failing DisableAfter attempts
+// in a row.
+type DispatcherConfig struct {
+	Workers      int
+	BatchSize    int
+	MaxAttempts  int
+	RetryBase    time.Duration
+	RetryMax     time.Duration
+	DisableAfter int
+	Timeout      time.Duration
+}
+
+// Dispatcher does not follow redirects.
+type Dispatcher struct {
+	log          zerolog.Logger
+	webhookStore store.WebhookStore
+	client       *http.Client
+	config       DispatcherConfig
+	backoff      scheduler.Backoff
+}
+
+func NewDispatcher(log zerolog.Logger, webhookStore store.WebhookStore, config DispatcherConfig) *Dispatcher {
+	return &Dispatcher{
+		log:          log,
+		webhookStore: webhookStore,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config:  config,
+		backoff: scheduler.Backoff{Base: config.RetryBase, Max: config.RetryMax},
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) error {
+	return scheduler.Queue[*store.WebhookDelivery]{
+		BatchSize: d.config.BatchSize,
+		Workers:   d.config.Workers,
+		Claim:     d.webhookStore.Claim,
+		Process:   d.deliver,
+	}.Run(ctx)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *store.WebhookDelivery) {
+	log := d.log.With().
+		Int("delivery_id", delivery.ID).
+		Int("subscription_id", delivery.SubscriptionID).
+		Str("event", delivery.Event).
+		Int("attempt", delivery.Attempts).
+		Logger()
+	start := time.Now()
+	postErr := d.post(ctx, delivery)
+	if postErr == nil {
+		if err := d.webhookStore.MarkDelivered(ctx, delivery); err != nil {
+			err = fmt.Errorf("webhookStore.MarkDelivered: %w", err)
+			log.Error().Err(err).Msg("failed to mark webhook delivered")
+			return
+		}
+		log.Info().Dur("took", time.Since(start)).Msg("webhook delivered")
+		return
+	}
+	delivery.LastError = postErr.Error()
+	dead := delivery.Attempts >= d.config.MaxAttempts
+	next := time.Now().Add(d.backoff.Delay(delivery.Attempts))
+	disabled, err := d.webhookStore.MarkFailed(ctx, delivery, next, dead, d.config.DisableAfter)
+	if err != nil {
+		err = fmt.Errorf("webhookStore.MarkFailed: %w", err)
+		log.Error().Err(err).Msg("failed to mark webhook failed")
+		return
+	}
+	if disabled {
+		log.Warn().Msg("webhook subscription disabled after repeated failures")
+	}
+	if dead {
+		log.Error().Err(postErr).Msg("webhook delivery given up")
+		return
+	}
+	log.Warn().Err(postErr).Time("next_attempt_at", next).Msg("webhook delivery failed")
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery *store.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderId, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, responseBodyLimit))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	delivery.ResponseStatus = sql.NullInt64{Int64: int64(res.StatusCode), Valid: true}
+	delivery.ResponseBody = validUTF8(body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("receiver responded %s", res.Status)
+	}
+	return nil
+}
+
+// A text column rejects NUL bytes and invalid UTF-8, as in a binary body or
+// one cut mid rune.
+func validUTF8(body []byte) string {
+	return strings.ToValidUTF8(strings.ReplaceAll(string(body), "\x00", ""), "\uFFFD")
+}
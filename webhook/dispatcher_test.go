@@ -0,0 +1,405 @@
+package webhook
+
+import (
+	"awesome-api/store"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// memStore keeps subscriptions and deliveries in memory, following the
+// claim, failure count and disable rules of the postgres store.
+type memStore struct {
+	store.WebhookStore
+	mu            sync.Mutex
+	subscriptions map[int]*store.WebhookSubscription
+	deliveries    []*store.WebhookDelivery
+}
+
+func newMemStore(subscriptions ...*store.WebhookSubscription) *memStore {
+	ms := &memStore{
+		subscriptions: map[int]*store.WebhookSubscription{},
+	}
+	for _, subscription := range subscriptions {
+		subscription.Active = true
+		ms.subscriptions[subscription.ID] = subscription
+	}
+	return ms
+}
+
+func (ms *memStore) add(subscriptionId int, event string, payload string) *store.WebhookDelivery {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delivery := &store.WebhookDelivery{
+		ID:             len(ms.deliveries) + 1,
+		SubscriptionID: subscriptionId,
+		Event:          event,
+		Payload:        json.RawMessage(payload),
+		Status:         store.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+	}
+	ms.deliveries = append(ms.deliveries, delivery)
+	return delivery
+}
+
+// makeDue moves every pending delivery to now, as if its retry delay was
+// over.
+func (ms *memStore) makeDue() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, delivery := range ms.deliveries {
+		delivery.NextAttemptAt = time.Now()
+	}
+}
+
+func (ms *memStore) Claim(ctx context.Context, limit int, staleAfter time.Duration) ([]*store.WebhookDelivery, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	claimed := []*store.WebhookDelivery{}
+	for _, delivery := range ms.deliveries {
+		subscription := ms.subscriptions[delivery.SubscriptionID]
+		if len(claimed) == limit || !subscription.Active ||
+			delivery.Status != store.WebhookDeliveryPending || delivery.NextAttemptAt.After(time.Now()) {
+			continue
+		}
+		delivery.Status = store.WebhookDeliverySending
+		delivery.Attempts++
+		claim := *delivery
+		claim.URL = subscription.URL
+		claim.Secret = subscription.Secret
+		claimed = append(claimed, &claim)
+	}
+	return claimed, nil
+}
+
+func (ms *memStore) MarkDelivered(ctx context.Context, claim *store.WebhookDelivery) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delivery := ms.deliveries[claim.ID-1]
+	delivery.Status = store.WebhookDeliverySucceeded
+	delivery.ResponseStatus = claim.ResponseStatus
+	delivery.ResponseBody = claim.ResponseBody
+	delivery.DeliveredAt = sql.NullTime{Time: time.Now(), Valid: true}
+	ms.subscriptions[delivery.SubscriptionID].FailureCount = 0
+	return nil
+}
+
+func (ms *memStore) MarkFailed(ctx context.Context, claim *store.WebhookDelivery, nextAttemptAt time.Time, dead bool, disableAfter int) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delivery := ms.deliveries[claim.ID-1]
+	delivery.Status = store.WebhookDeliveryPending
+	if dead {
+		delivery.Status = store.WebhookDeliveryFailed
+	}
+	delivery.ResponseStatus = claim.ResponseStatus
+	delivery.ResponseBody = claim.ResponseBody
+	delivery.LastError = claim.LastError
+	delivery.NextAttemptAt = nextAttemptAt
+	subscription := ms.subscriptions[delivery.SubscriptionID]
+	subscription.FailureCount++
+	if subscription.Active && subscription.FailureCount >= disableAfter {
+		subscription.Active = false
+		subscription.DisabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (ms *memStore) Redeliver(ctx context.Context, id int) (*store.WebhookDelivery, error) {
+	past := ms.delivery(id)
+	return ms.add(past.SubscriptionID, past.Event, string(past.Payload)), nil
+}
+
+func (ms *memStore) delivery(id int) store.WebhookDelivery {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return *ms.deliveries[id-1]
+}
+
+func (ms *memStore) subscription(id int) store.WebhookSubscription {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return *ms.subscriptions[id]
+}
+
+// receiver records the requests it gets and answers them with handle.
+type receiver struct {
+	mu       sync.Mutex
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func (rc *receiver) server(t *testing.T, handle func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		rc.requests = append(rc.requests, receivedRequest{header: r.Header.Clone(), body: body})
+		rc.mu.Unlock()
+		handle(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (rc *receiver) received() []receivedRequest {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedRequest(nil), rc.requests...)
+}
+
+func testConfig() DispatcherConfig {
+	return DispatcherConfig{
+		Workers:      2,
+		BatchSize:    10,
+		MaxAttempts:  5,
+		RetryBase:    time.Minute,
+		RetryMax:     time.Hour,
+		DisableAfter: 10,
+		Timeout:      time.Second,
+	}
+}
+
+func run(t *testing.T, ms *memStore, config DispatcherConfig) {
+	t.Helper()
+	if err := NewDispatcher(zerolog.Nop(), ms, config).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	rc := &receiver{}
+	server := rc.server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ms := newMemStore(&store.WebhookSubscription{ID: 1, URL: server.URL, Secret: "s3cret"})
+	delivery := ms.add(1, store.WebhookEventBookCreated, `{"id":"9","event":"book.created"}`)
+
+	run(t, ms, testConfig())
+
+	requests := rc.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	header := requests[0].header
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("bad %s: %v", HeaderTimestamp, err)
+	}
+	if !Verify("s3cret", timestamp, requests[0].body, header.Get(HeaderSignature)) {
+		t.Errorf("signature %q does not verify against the subscription secret", header.Get(HeaderSignature))
+	}
+	if Verify("other", timestamp, requests[0].body, header.Get(HeaderSignature)) {
+		t.Error("signature verifies against another secret")
+	}
+	if header.Get(HeaderId) != strconv.Itoa(delivery.ID) || header.Get(HeaderEvent) != store.WebhookEventBookCreated {
+		t.Errorf("%s = %q, %s = %q", HeaderId, header.Get(HeaderId), HeaderEvent, header.Get(HeaderEvent))
+	}
+	if header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", header.Get("Content-Type"))
+	}
+	if string(requests[0].body) != string(delivery.Payload) {
+		t.Errorf("body = %s, want %s", requests[0].body, delivery.Payload)
+	}
+}
+
+func TestDispatcherDelivered(t *testing.T) {
+	rc := &receiver{}
+	server := rc.server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "queued")
+	})
+	subscription := &store.WebhookSubscription{ID: 1, URL: server.URL, Secret: "s3cret", FailureCount: 3}
+	ms := newMemStore(subscription)
+	ms.add(1, store.WebhookEventLoanCreated, `{}`)
+
+	run(t, ms, testConfig())
+
+	delivery := ms.delivery(1)
+	if delivery.Status != store.WebhookDeliverySucceeded {
+		t.Fatalf("status = %s, want %s", delivery.Status, store.WebhookDeliverySucceeded)
+	}
+	if delivery.ResponseStatus.Int64 != http.StatusAccepted || delivery.ResponseBody != "queued" {
+		t.Errorf("response = %d %q", delivery.ResponseStatus.Int64, delivery.ResponseBody)
+	}
+	if !delivery.DeliveredAt.Valid {
+		t.Error("delivered_at is not set")
+	}
+	if got := ms.subscription(1).FailureCount; got != 0 {
+		t.Errorf("failure count = %d, want 0", got)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		handle  func(w http.ResponseWriter, r *http.Request)
+		status  int64
+		lastErr string
+	}{
+		{
+			name: "server error",
+			handle: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(w, "boom")
+			},
+			status:  http.StatusInternalServerError,
+			lastErr: "500",
+		},
+		{
+			name: "redirect",
+			handle: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/elsewhere", http.StatusFound)
+			},
+			status:  http.StatusFound,
+			lastErr: "302",
+		},
+		{
+			name: "timeout",
+			handle: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			},
+			lastErr: "Client.Timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &receiver{}
+			server := rc.server(t, tt.handle)
+			ms := newMemStore(&store.WebhookSubscription{ID: 1, URL: server.URL, Secret: "s3cret"})
+			ms.add(1, store.WebhookEventBookUpdated, `{}`)
+			config := testConfig()
+			config.Timeout = 100 * time.Millisecond
+			config.MaxAttempts = 3
+
+			// Each attempt waits twice as long as the one before, the last
+			// one gives up.
+			for attempt, delay := range []time.Duration{time.Minute, 2 * time.Minute, 0} {
+				before := time.Now()
+				run(t, ms, config)
+				after := time.Now()
+
+				delivery := ms.delivery(1)
+				if delivery.Attempts != attempt+1 {
+					t.Fatalf("attempts = %d, want %d", delivery.Attempts, attempt+1)
+				}
+				if delivery.ResponseStatus.Int64 != tt.status {
+					t.Errorf("response status = %d, want %d", delivery.ResponseStatus.Int64, tt.status)
+				}
+				if !strings.Contains(delivery.LastError, tt.lastErr) {
+					t.Errorf("last error = %q, want it to mention %q", delivery.LastError, tt.lastErr)
+				}
+				if delay == 0 {
+					if delivery.Status != store.WebhookDeliveryFailed {
+						t.Errorf("status = %s after %d attempts, want %s", delivery.Status, delivery.Attempts, store.WebhookDeliveryFailed)
+					}
+					break
+				}
+				if delivery.Status != store.WebhookDeliveryPending {
+					t.Fatalf("status = %s, want %s", delivery.Status, store.WebhookDeliveryPending)
+				}
+				next := delivery.NextAttemptAt
+				if next.Before(before.Add(delay)) || next.After(after.Add(delay)) {
+					t.Errorf("next attempt in %s, want %s", next.Sub(before).Round(time.Second), delay)
+				}
+
+				// Not due yet, nothing is sent.
+				sent := len(rc.received())
+				run(t, ms, config)
+				if len(rc.received()) != sent {
+					t.Fatal("delivery retried before its next attempt")
+				}
+				ms.makeDue()
+			}
+		})
+	}
+}
+
+func TestDispatcherDisablesFailingSubscription(t *testing.T) {
+	rc := &receiver{}
+	server := rc.server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ms := newMemStore(&store.WebhookSubscription{ID: 1, URL: server.URL, Secret: "s3cret"})
+	ms.add(1, store.WebhookEventBookCreated, `{"n":1}`)
+	ms.add(1, store.WebhookEventBookCreated, `{"n":2}`)
+	config := testConfig()
+	config.DisableAfter = 3
+
+	run(t, ms, config)
+	if subscription := ms.subscription(1); !subscription.Active || subscription.FailureCount != 2 {
+		t.Fatalf("active = %t, failure count = %d after 2 failures", subscription.Active, subscription.FailureCount)
+	}
+	ms.makeDue()
+	run(t, ms, config)
+
+	subscription := ms.subscription(1)
+	if subscription.Active || !subscription.DisabledAt.Valid {
+		t.Fatalf("subscription still active after %d failures", subscription.FailureCount)
+	}
+	// The remaining delivery waits for the subscription to be enabled
+	// again instead of failing.
+	sent := len(rc.received())
+	ms.makeDue()
+	run(t, ms, config)
+	if len(rc.received()) != sent {
+		t.Error("delivered to a disabled subscription")
+	}
+	for _, id := range []int{1, 2} {
+		if status := ms.delivery(id).Status; status != store.WebhookDeliveryPending {
+			t.Errorf("delivery %d status = %s, want %s", id, status, store.WebhookDeliveryPending)
+		}
+	}
+}
+
+func TestDispatcherRedelivers(t *testing.T) {
+	rc := &receiver{}
+	server := rc.server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	ms := newMemStore(&store.WebhookSubscription{ID: 1, URL: server.URL, Secret: "s3cret"})
+	payload := `{"id":"17","event":"book.updated","created_at":"2024-03-01T12:00:00Z","data":{"id":3}}`
+	ms.add(1, store.WebhookEventBookUpdated, payload)
+	run(t, ms, testConfig())
+
+	redelivery, err := ms.Redeliver(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, ms, testConfig())
+
+	requests := rc.received()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if string(requests[1].body) != payload {
+		t.Errorf("redelivered body = %s, want %s", requests[1].body, payload)
+	}
+	if got := requests[1].header.Get(HeaderId); got != strconv.Itoa(redelivery.ID) {
+		t.Errorf("%s = %s, want the new delivery %d", HeaderId, got, redelivery.ID)
+	}
+	timestamp, _ := strconv.ParseInt(requests[1].header.Get(HeaderTimestamp), 10, 64)
+	if !Verify("s3cret", timestamp, requests[1].body, requests[1].header.Get(HeaderSignature)) {
+		t.Error("redelivery signature does not verify")
+	}
+	if status := ms.delivery(redelivery.ID).Status; status != store.WebhookDeliverySucceeded {
+		t.Errorf("redelivery status = %s, want %s", status, store.WebhookDeliverySucceeded)
+	}
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"awesome-api/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Envelope.ID is the same for every attempt and redelivery, receivers use
+// it to drop duplicates.
+type Envelope struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type Publisher struct {
+	log          zerolog.Logger
+	webhookStore store.WebhookStore
+}
+
+func NewPublisher(log zerolog.Logger, webhookStore store.WebhookStore) *Publisher {
+	return &Publisher{
+		log:          log,
+		webhookStore: webhookStore,
+	}
+}
+
+func (p *Publisher) Publish(ctx context.Context, event *store.DomainEvent) error {
+	id := strconv.FormatInt(event.ID, 10)
+	payload, err := json.Marshal(Envelope{
+		ID:        id,
+		Event:     event.Type,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s payload: %w", event.Type, err)
+	}
+	count, err := p.webhookStore.Enqueue(ctx, event.Type, payload)
+	if err != nil {
+		return fmt.Errorf("webhookStore.Enqueue: %w", err)
+	}
+	if count > 0 {
+		p.log.Debug().Str("event", event.Type).Str("event_id", id).Int64("count", count).Msg("webhook event queued")
+	}
+	return nil
+}
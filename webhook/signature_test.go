@@ -0,0 +1,44 @@
+package webhook
+
+import "testing"
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1","event":"book.created"}`)
+	signature := Sign("s3cret", 1700000000, body)
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		signature string
+		want      bool
+	}{
+		{"valid", "s3cret", 1700000000, string(body), signature, true},
+		{"other secret", "other", 1700000000, string(body), signature, false},
+		{"replayed at another time", "s3cret", 1700000060, string(body), signature, false},
+		{"changed body", "s3cret", 1700000000, `{"id":"2","event":"book.created"}`, signature, false},
+		{"missing prefix", "s3cret", 1700000000, string(body), signature[len("sha256="):], false},
+		{"empty", "s3cret", 1700000000, string(body), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, []byte(tt.body), tt.signature); got != tt.want {
+				t.Errorf("Verify = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 64 || a == b {
+		t.Errorf("secrets %q and %q", a, b)
+	}
+}